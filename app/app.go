@@ -1,27 +1,80 @@
 package app
 
 import (
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	"nokowebapi/apis"
 	"nokowebapi/apis/middlewares"
+	"nokowebapi/apis/utils"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
 	controllers2 "pharma-cash-go/app/controllers"
+	"pharma-cash-go/app/costing"
+	"pharma-cash-go/app/expiry"
 	factories2 "pharma-cash-go/app/factories"
+	"pharma-cash-go/app/locations"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/opnames"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/pricing"
+	repositories2 "pharma-cash-go/app/repositories"
+	schedulers2 "pharma-cash-go/app/schedulers"
+	"pharma-cash-go/app/sessions"
+	"pharma-cash-go/app/trash"
+	"time"
 )
 
+func Permissions(DB *gorm.DB) nokocore.PolicyImpl {
+	policy := globals.GetPolicy()
+	permissions.Grant(policy)
+	if err := utils.LoadPermissions(DB); err != nil {
+		console.Error(fmt.Sprintf("panic: %s", err.Error()))
+	}
+
+	return policy
+}
+
 func Controllers(group *echo.Group, DB *gorm.DB) {
 	auth := group.Group("/auth")
 	auth.Use(middlewares.JWTAuth(DB))
 
 	controllers2.GuestController(group, DB)
+	controllers2.FileController(group, DB)
 	controllers2.UserController(auth, DB)
 	controllers2.AdminController(auth, DB)
 	controllers2.ProductController(auth, DB)
+	controllers2.BarcodeController(auth, DB)
+	controllers2.ProductAttachmentController(auth, DB)
+	controllers2.ProductUnitController(auth, DB)
+	controllers2.PriceController(auth, DB)
+	controllers2.GoodsReceiptController(auth, DB)
+	controllers2.PriceChangeController(auth, DB)
+	controllers2.DrugController(auth, DB)
+	controllers2.InteractionController(auth, DB)
+	controllers2.ControlledRegisterController(auth, DB)
+	controllers2.InventoryController(auth, DB)
+	controllers2.ExpiryController(auth, DB)
+	controllers2.LocationController(auth, DB)
+	controllers2.StockTransferController(auth, DB)
+	controllers2.TrashController(auth, DB)
+	controllers2.PermissionController(auth, DB)
 	controllers2.UnitController(auth, DB)
 	controllers2.PackagingController(auth, DB)
 	controllers2.ShopController(auth, DB)
 	controllers2.StokOpnameController(auth, DB)
+	controllers2.CycleCountController(auth, DB)
+}
+
+func Schedulers(DB *gorm.DB) schedulers2.SchedulerImpl {
+	scheduler := schedulers2.NewScheduler(DB)
+	scheduler.Add("price_changes", 0, pricing.PriceChangeJob)
+	scheduler.Add("trash_purge", time.Hour, trash.PurgeJob)
+	scheduler.Add("expiry_alerts", 24*time.Hour, expiry.FlagJob)
+	scheduler.Add("cycle_counts", 24*time.Hour, opnames.CycleCountJob)
+	scheduler.Add("session_purge", sessions.GetPurgeInterval(), sessions.PurgeJob)
+	return scheduler
 }
 
 func Factories(DB *gorm.DB) apis.FactoryData {
@@ -35,19 +88,105 @@ func Factories(DB *gorm.DB) apis.FactoryData {
 }
 
 func Migrations(DB *gorm.DB) error {
-	return apis.Migrations(DB, []any{
+	var err error
+	nokocore.KeepVoid(err)
+
+	err = apis.Migrations(DB, []any{
+		new(models2.ActiveIngredient),
 		new(models2.Barcode),
 		new(models2.Cart),
 		new(models2.Category),
+		new(models2.ControlledRegister),
+		new(models2.CostEntry),
+		new(models2.CostLayer),
+		new(models2.CycleCount),
+		new(models2.CycleCountItem),
+		new(models2.DrugInteraction),
 		new(models2.Employee),
+		new(models2.ExpiryAlert),
+		new(models2.GoodsReceipt),
+		new(models2.InteractionAcknowledgement),
+		new(models2.Location),
 		new(models2.Package),
+		new(models2.PriceChange),
+		new(models2.PriceChangeItem),
+		new(models2.PriceHistory),
 		new(models2.Product),
+		new(models2.ProductAttachment),
 		new(models2.ProductCategory),
+		new(models2.ProductIngredient),
+		new(models2.ProductStock),
+		new(models2.ProductUnit),
 		new(models2.Shift),
+		new(models2.StockMovement),
+		new(models2.StockTransfer),
+		new(models2.StockTransferItem),
 		new(models2.Transaction),
 		new(models2.Unit),
+		new(models2.WriteOff),
+		new(models2.WriteOffItem),
 		&models2.StockOpname{},
+		&models2.StockOpnameItem{},
+		&models2.StockOpnameStatusChange{},
+		&models2.CountEntry{},
 		&models2.CartVerificationOpname{},
 		&models2.VerificationOpname{},
 	})
+
+	if err != nil {
+		return err
+	}
+
+	// replaced by active flag, auto migrations never drop columns
+	if DB.Migrator().HasColumn(&models2.Barcode{}, "closed") {
+		if err = DB.Migrator().DropColumn(&models2.Barcode{}, "closed"); err != nil {
+			return err
+		}
+	}
+
+	// replaced by interaction acknowledgements, they keep the acknowledging pharmacist
+	if DB.Migrator().HasColumn(&models2.Transaction{}, "acknowledged_interactions") {
+		if err = DB.Migrator().DropColumn(&models2.Transaction{}, "acknowledged_interactions"); err != nil {
+			return err
+		}
+	}
+
+	if err = repositories2.NewBarcodeRepository(DB).SyncProductBarcodes(); err != nil {
+		return err
+	}
+
+	if err = repositories2.NewProductUnitRepository(DB).SyncProductUnits(); err != nil {
+		return err
+	}
+
+	if err = repositories2.NewCartVerificationOpnameRepository(DB).SyncStockOpnames(); err != nil {
+		return err
+	}
+
+	locationService := locations.NewLocationService(DB)
+	if err = locationService.SyncLocations(); err != nil {
+		return err
+	}
+
+	// stock before locations is put on the selling location
+	if err = locationService.SyncProducts(); err != nil {
+		return err
+	}
+
+	// stock opnames before locations and cycle count scopes
+	if err = opnames.NewOpnameService(DB).SyncStockOpnames(); err != nil {
+		return err
+	}
+
+	// opening cost layers of products stocked before inventory valuation
+	if err = costing.NewCostingService(DB).SyncProducts(); err != nil {
+		return err
+	}
+
+	// full-text search is optional, product search with 'mode=fts' will be rejected
+	if err = repositories2.NewProductSearchRepository(DB).Migrate(); err != nil {
+		console.Error(fmt.Sprintf("%s, build with the 'sqlite_fts5' tag", err.Error()))
+	}
+
+	return nil
 }
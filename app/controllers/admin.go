@@ -6,6 +6,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
 	"nokowebapi/apis/models"
 	"nokowebapi/apis/repositories"
 	"nokowebapi/apis/schemas"
@@ -14,6 +15,7 @@ import (
 	"nokowebapi/nokocore"
 	"nokowebapi/sqlx"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
 	utils2 "pharma-cash-go/app/utils"
@@ -32,12 +34,6 @@ func CreateEmployee(DB *gorm.DB) echo.HandlerFunc {
 		var employee *models2.Employee
 		nokocore.KeepVoid(err, user, employee)
 
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		employeeBody := new(schemas2.EmployeeBody)
 		if err = ctx.Bind(employeeBody); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -172,12 +168,6 @@ func UpdateEmployee(DB *gorm.DB) echo.HandlerFunc {
 		var employee *models2.Employee
 		nokocore.KeepVoid(err, userID, employeeID, user, employee)
 
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		userID = extras.ParseQueryToString(ctx, "user_id")
 		employeeID = extras.ParseQueryToString(ctx, "employee_id")
 
@@ -336,12 +326,6 @@ func GetAllUsers(DB *gorm.DB) echo.HandlerFunc {
 		var users []models.User
 		nokocore.KeepVoid(err, users)
 
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		// get pagination request
 		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
 
@@ -376,12 +360,6 @@ func GetAllEmployees(DB *gorm.DB) echo.HandlerFunc {
 		var employees []models2.Employee
 		nokocore.KeepVoid(err, employees)
 
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		// get pagination request
 		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
 
@@ -420,14 +398,8 @@ func DeleteUser(DB *gorm.DB) echo.HandlerFunc {
 		var employee *models2.Employee
 		nokocore.KeepVoid(err, userID, employeeID, user, employee)
 
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
 		forced := extras.ParseQueryToBool(ctx, "forced")
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		userID = extras.ParseQueryToString(ctx, "user_id")
 		employeeID = extras.ParseQueryToString(ctx, "employee_id")
 
@@ -525,11 +497,11 @@ func DeleteUser(DB *gorm.DB) echo.HandlerFunc {
 
 func AdminController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
-	group.POST("/user", CreateEmployee(DB))
-	group.PUT("/user", UpdateEmployee(DB))
-	group.GET("/users", GetAllUsers(DB))
-	group.GET("/employees", GetAllEmployees(DB))
-	group.DELETE("/user", DeleteUser(DB))
+	group.POST("/user", CreateEmployee(DB), middlewares.RequirePermission(permissions.UserManage))
+	group.PUT("/user", UpdateEmployee(DB), middlewares.RequirePermission(permissions.UserManage))
+	group.GET("/users", GetAllUsers(DB), middlewares.RequirePermission(permissions.UserManage))
+	group.GET("/employees", GetAllEmployees(DB), middlewares.RequirePermission(permissions.UserManage))
+	group.DELETE("/user", DeleteUser(DB), middlewares.RequirePermission(permissions.UserManage))
 
 	return group
 }
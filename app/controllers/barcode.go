@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+func GetProductByBarcode(DB *gorm.DB) echo.HandlerFunc {
+
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var barcode *models2.Barcode
+		var product *models2.Product
+		nokocore.KeepVoid(err, barcode, product)
+
+		code := strings.TrimSpace(ctx.Param("code"))
+		if code == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'code'.", nil)
+		}
+
+		// inactive barcodes are kept to reserve the code, but not for lookups
+		if barcode, err = barcodeRepository.SafeFirst("code = ? AND active = ?", code, true); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if barcode == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Barcode not found.", nil)
+		}
+
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit", "Barcodes", "Attachments"}
+		if product, err = productRepository.SafePreFirst(preloads, "id = ?", barcode.ProductID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		barcodeResult := schemas2.ToBarcodeResult(barcode, product.Barcode)
+		productResult := schemas2.ToProductResult(product)
+		return extras.NewMessageBodyOk(ctx, "Successfully get product.", &nokocore.MapAny{
+			"barcode": barcodeResult,
+			"product": productResult,
+		})
+	}
+}
+
+func GetAllBarcodesByProductId(DB *gorm.DB) echo.HandlerFunc {
+
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var barcodes []models2.Barcode
+		nokocore.KeepVoid(err, productID, product, barcodes)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if barcodes, err = barcodeRepository.SafeMany(0, -1, "product_id = ?", product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcodes.", nil)
+		}
+
+		barcodeResults := schemas2.ToBarcodeResults(barcodes, product.Barcode)
+		return extras.NewMessageBodyOk(ctx, "Successfully get barcodes.", &nokocore.MapAny{
+			"barcodes": barcodeResults,
+		})
+	}
+}
+
+func CreateBarcode(DB *gorm.DB) echo.HandlerFunc {
+
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var check *models2.Barcode
+		nokocore.KeepVoid(err, productID, product, check)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		barcodeBody := new(schemas2.BarcodeBody)
+		if err = ctx.Bind(barcodeBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(barcodeBody); err != nil {
+			return err
+		}
+
+		barcode := schemas2.ToBarcodeModel(barcodeBody)
+		if barcode.Code == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Barcode is required.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		// barcode is unique across all products, including inactive barcodes
+		if check, err = barcodeRepository.First("code = ?", barcode.Code); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if check != nil {
+			return extras.NewMessageBodyConflict(ctx, "Barcode already exists.", nil)
+		}
+
+		barcode.ProductID = product.ID
+		if err = barcodeRepository.Create(barcode); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create barcode.", nil)
+		}
+
+		barcodeResult := schemas2.ToBarcodeResult(barcode, product.Barcode)
+		return extras.NewMessageBodyOk(ctx, "Successfully create barcode.", &nokocore.MapAny{
+			"barcode": barcodeResult,
+		})
+	}
+}
+
+func UpdateBarcode(DB *gorm.DB) echo.HandlerFunc {
+
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var barcodeID string
+		var product *models2.Product
+		var barcode *models2.Barcode
+		var check *models2.Barcode
+		nokocore.KeepVoid(err, productID, barcodeID, product, barcode, check)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		barcodeID = ctx.Param("barcodeId")
+		if err = sqlx.ValidateUUID(barcodeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'barcode_id'.", nil)
+		}
+
+		barcodeBody := new(schemas2.BarcodeBody)
+		if err = ctx.Bind(barcodeBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(barcodeBody); err != nil {
+			return err
+		}
+
+		newBarcode := schemas2.ToBarcodeModel(barcodeBody)
+		if newBarcode.Code == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Barcode is required.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if barcode, err = barcodeRepository.SafeFirst("uuid = ? AND product_id = ?", barcodeID, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if barcode == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Barcode not found.", nil)
+		}
+
+		// primary barcode follows the product barcode
+		if barcode.Code == product.Barcode && (newBarcode.Code != barcode.Code || !newBarcode.Active) {
+			return extras.NewMessageBodyConflict(ctx, "Primary barcode must be changed from the product.", nil)
+		}
+
+		if check, err = barcodeRepository.First("code = ? AND id <> ?", newBarcode.Code, barcode.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if check != nil {
+			return extras.NewMessageBodyConflict(ctx, "Barcode already exists.", nil)
+		}
+
+		barcode.Code = newBarcode.Code
+		barcode.Level = newBarcode.Level
+		barcode.Active = newBarcode.Active
+		if err = barcodeRepository.SafeUpdate(barcode, "id = ?", barcode.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update barcode.", nil)
+		}
+
+		barcodeResult := schemas2.ToBarcodeResult(barcode, product.Barcode)
+		return extras.NewMessageBodyOk(ctx, "Successfully update barcode.", &nokocore.MapAny{
+			"barcode": barcodeResult,
+		})
+	}
+}
+
+func DeleteBarcode(DB *gorm.DB) echo.HandlerFunc {
+
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var barcodeID string
+		var product *models2.Product
+		var barcode *models2.Barcode
+		nokocore.KeepVoid(err, productID, barcodeID, product, barcode)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		barcodeID = ctx.Param("barcodeId")
+		if err = sqlx.ValidateUUID(barcodeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'barcode_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if barcode, err = barcodeRepository.SafeFirst("uuid = ? AND product_id = ?", barcodeID, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if barcode == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Barcode not found.", nil)
+		}
+
+		if barcode.Code == product.Barcode {
+			return extras.NewMessageBodyConflict(ctx, "Primary barcode must be changed from the product.", nil)
+		}
+
+		// releases the code, deactivate the barcode to keep it reserved
+		if err = barcodeRepository.Delete(barcode, "id = ?", barcode.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to delete barcode.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully delete barcode.", nil)
+	}
+}
+
+func BarcodeController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/barcode/:code", GetProductByBarcode(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.GET("/product/:productId/barcodes", GetAllBarcodesByProductId(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.POST("/product/:productId/barcode", CreateBarcode(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.PUT("/product/:productId/barcode/:barcodeId", UpdateBarcode(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.DELETE("/product/:productId/barcode/:barcodeId", DeleteBarcode(DB), middlewares.RequirePermission(permissions.ProductWrite))
+
+	return group
+}
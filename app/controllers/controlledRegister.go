@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/registers"
+	"pharma-cash-go/app/reports"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+	"time"
+)
+
+func GetAllControlledRegisters(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	controlledRegisterRepository := repositories2.NewControlledRegisterRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var controlledRegisters []models2.ControlledRegister
+		nokocore.KeepVoid(err, productID, product, controlledRegisters)
+
+		if productID = extras.ParseQueryToString(ctx, "product_id"); productID != "" {
+			if err = sqlx.ValidateUUID(productID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+			}
+
+			// deleted products keep their register entries
+			if product, err = productRepository.First("uuid = ?", productID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+			}
+
+			if product == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+			}
+		}
+
+		kind := strings.ToLower(extras.ParseQueryToString(ctx, "kind"))
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		controlledRegisters, err = controlledRegisterRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Preload("Product", func(tx *gorm.DB) *gorm.DB {
+				return tx.Unscoped()
+			}).Preload("Product.Unit")
+			if product != nil {
+				stmt = stmt.Where("product_id = ?", product.ID)
+			}
+			if kind != "" {
+				stmt = stmt.Where("kind = ?", kind)
+			}
+			stmt = stmt.Order("recorded_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get controlled registers.", nil)
+		}
+
+		controlledRegisterResults := schemas2.ToControlledRegisterResults(controlledRegisters)
+		return extras.NewMessageBodyOk(ctx, "Successfully get controlled registers.", &nokocore.MapAny{
+			"controlledRegisters": controlledRegisterResults,
+		})
+	}
+}
+
+// GetControlledRegisterReport method, monthly opening, received, dispensed and closing
+// quantities per controlled product, as json, xlsx or pdf with 'format=xlsx' or 'format=pdf'.
+func GetControlledRegisterReport(DB *gorm.DB) echo.HandlerFunc {
+
+	registerService := registers.NewRegisterService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var from time.Time
+		var report *registers.RegisterReport
+		nokocore.KeepVoid(err, from, report)
+
+		format := strings.ToLower(extras.ParseQueryToString(ctx, "format"))
+		if format == "" {
+			format = reports.FormatJson
+		}
+
+		writeTable, contentType, ok := reports.GetTableWriter(format)
+		if !ok && format != reports.FormatJson {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'format'.", nil)
+		}
+
+		// current month by default
+		if month := extras.ParseQueryToString(ctx, "month"); month != "" {
+			if from, err = time.Parse("2006-01", month); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'month'.", nil)
+			}
+
+		} else {
+			now := nokocore.GetTimeUtcNow()
+			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+
+		if report, err = registerService.Report(from, from.AddDate(0, 1, 0)); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get controlled register report.", nil)
+		}
+
+		if format == reports.FormatJson {
+			return extras.NewMessageBodyOk(ctx, "Successfully get controlled register report.", &nokocore.MapAny{
+				"month": from.Format("2006-01"),
+				"rows":  report.Rows,
+			})
+		}
+
+		buffer := new(bytes.Buffer)
+		if err = writeTable(report.ToTable(), buffer); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to write controlled register report.", nil)
+		}
+
+		fileName := fmt.Sprintf("controlled-register-%s.%s", from.Format("2006-01"), format)
+		ctx.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		return ctx.Blob(http.StatusOK, contentType, buffer.Bytes())
+	}
+}
+
+func ControlledRegisterController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/controlled-registers", GetAllControlledRegisters(DB), middlewares.RequirePermission(permissions.RegisterRead))
+	group.GET("/controlled-register/report", GetControlledRegisterReport(DB), middlewares.RequirePermission(permissions.RegisterRead))
+
+	return group
+}
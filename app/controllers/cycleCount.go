@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/opnames"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+)
+
+var cycleCountPreloads = []string{"Location", "Items.Product"}
+
+func GetAllCycleCounts(DB *gorm.DB) echo.HandlerFunc {
+
+	cycleCountRepository := repositories2.NewCycleCountRepository(DB)
+	locationRepository := repositories2.NewLocationRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var location *models2.Location
+		var cycleCounts []models2.CycleCount
+		nokocore.KeepVoid(err, location, cycleCounts)
+
+		if locationID := extras.ParseQueryToString(ctx, "location_id"); locationID != "" {
+			if err = sqlx.ValidateUUID(locationID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'location_id'.", nil)
+			}
+
+			if location, err = locationRepository.SafeFirst("uuid = ?", locationID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+			}
+
+			if location == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Location not found.", nil)
+			}
+		}
+
+		started := extras.ParseQueryToString(ctx, "started")
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		cycleCounts, err = cycleCountRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx
+			for i, preload := range cycleCountPreloads {
+				nokocore.KeepVoid(i)
+				stmt = stmt.Preload(preload)
+			}
+			if location != nil {
+				stmt = stmt.Where("location_id = ?", location.ID)
+			}
+			if started != "" {
+				if extras.ParseQueryToBool(ctx, "started") {
+					stmt = stmt.Where("COALESCE(stock_opname_id, 0) <> 0")
+				} else {
+					stmt = stmt.Where("COALESCE(stock_opname_id, 0) = 0")
+				}
+			}
+			stmt = stmt.Order("proposed_on DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get cycle counts.", nil)
+		}
+
+		cycleCountResults := schemas2.ToCycleCountResults(cycleCounts)
+		return extras.NewMessageBodyOk(ctx, "Successfully get cycle counts.", &nokocore.MapAny{
+			"cycleCounts": cycleCountResults,
+		})
+	}
+}
+
+func GetCycleCountById(DB *gorm.DB) echo.HandlerFunc {
+
+	cycleCountRepository := repositories2.NewCycleCountRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var cycleCountID string
+		var cycleCount *models2.CycleCount
+		nokocore.KeepVoid(err, cycleCountID, cycleCount)
+
+		cycleCountID = ctx.Param("cycleCountId")
+		if err = sqlx.ValidateUUID(cycleCountID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'cycle_count_id'.", nil)
+		}
+
+		if cycleCount, err = cycleCountRepository.SafePreFirst(cycleCountPreloads, "uuid = ?", cycleCountID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get cycle count.", nil)
+		}
+
+		if cycleCount == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Cycle count not found.", nil)
+		}
+
+		cycleCountResult := schemas2.ToCycleCountResult(cycleCount)
+		return extras.NewMessageBodyOk(ctx, "Successfully get cycle count.", &nokocore.MapAny{
+			"cycleCount": cycleCountResult,
+		})
+	}
+}
+
+func ProposeCycleCounts(DB *gorm.DB) echo.HandlerFunc {
+
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var proposed int
+		nokocore.KeepVoid(err, proposed)
+
+		if proposed, err = opnameService.Propose(nokocore.GetTimeUtcNow()); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to propose cycle counts.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully propose cycle counts.", &nokocore.MapAny{
+			"proposed": proposed,
+		})
+	}
+}
+
+func CycleCountController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/warehouse/cycle-counts", GetAllCycleCounts(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.POST("/warehouse/cycle-counts", ProposeCycleCounts(DB), middlewares.RequirePermission(permissions.OpnamePlan))
+	group.GET("/warehouse/cycle-count/:cycleCountId", GetCycleCountById(DB), middlewares.RequirePermission(permissions.OpnameRead))
+
+	return group
+}
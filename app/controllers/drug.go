@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+func GetAllActiveIngredients(DB *gorm.DB) echo.HandlerFunc {
+
+	activeIngredientRepository := repositories2.NewActiveIngredientRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var activeIngredients []models2.ActiveIngredient
+		nokocore.KeepVoid(err, activeIngredients)
+
+		name := strings.TrimSpace(extras.ParseQueryToString(ctx, "name"))
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		activeIngredients, err = activeIngredientRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx
+			if name != "" {
+				stmt = stmt.Where("ingredient_name LIKE ?", "%"+name+"%")
+			}
+			stmt = stmt.Order("ingredient_name ASC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get active ingredients.", nil)
+		}
+
+		activeIngredientResults := schemas2.ToActiveIngredientResults(activeIngredients)
+		return extras.NewMessageBodyOk(ctx, "Successfully get active ingredients.", &nokocore.MapAny{
+			"activeIngredients": activeIngredientResults,
+		})
+	}
+}
+
+func CreateActiveIngredient(DB *gorm.DB) echo.HandlerFunc {
+
+	activeIngredientRepository := repositories2.NewActiveIngredientRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var check *models2.ActiveIngredient
+		nokocore.KeepVoid(err, check)
+
+		activeIngredientBody := new(schemas2.ActiveIngredientBody)
+		if err = ctx.Bind(activeIngredientBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(activeIngredientBody); err != nil {
+			return err
+		}
+
+		activeIngredient := schemas2.ToActiveIngredientModel(activeIngredientBody)
+		if activeIngredient.IngredientName == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Ingredient name is required.", nil)
+		}
+
+		if check, err = activeIngredientRepository.First("ingredient_name = ?", activeIngredient.IngredientName); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get active ingredient.", nil)
+		}
+
+		if check != nil {
+			return extras.NewMessageBodyConflict(ctx, "Active ingredient already exists.", nil)
+		}
+
+		if err = activeIngredientRepository.Create(activeIngredient); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create active ingredient.", nil)
+		}
+
+		activeIngredientResult := schemas2.ToActiveIngredientResult(activeIngredient)
+		return extras.NewMessageBodyOk(ctx, "Successfully create active ingredient.", &nokocore.MapAny{
+			"activeIngredient": activeIngredientResult,
+		})
+	}
+}
+
+func GetAllProductIngredients(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		nokocore.KeepVoid(err, productID, product)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Ingredients.ActiveIngredient"}, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		productIngredientResults := schemas2.ToProductIngredientResults(product.Ingredients)
+		return extras.NewMessageBodyOk(ctx, "Successfully get product ingredients.", &nokocore.MapAny{
+			"ingredients": productIngredientResults,
+			"substance":   schemas2.ToSubstance(product),
+			"drugClass":   product.DrugClass,
+		})
+	}
+}
+
+// SetProductIngredients method, replaces all active ingredients of the product,
+// unknown ingredient names are created.
+func SetProductIngredients(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		nokocore.KeepVoid(err, productID, product)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		productIngredientsBody := new(schemas2.ProductIngredientsBody)
+		if err = ctx.Bind(productIngredientsBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(productIngredientsBody); err != nil {
+			return err
+		}
+
+		for i, productIngredientBody := range productIngredientsBody.Ingredients {
+			nokocore.KeepVoid(i)
+
+			if err = ctx.Validate(&productIngredientBody); err != nil {
+				return err
+			}
+
+			if productIngredientBody.IngredientID == "" && strings.TrimSpace(productIngredientBody.IngredientName) == "" {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Ingredient id or name is required.", nil)
+			}
+
+			if decimal.RequireFromString(productIngredientBody.Strength).Sign() <= 0 {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Ingredient strength must be greater than zero.", nil)
+			}
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		errIngredientNotFound := errors.New("active ingredient not found")
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			var activeIngredient *models2.ActiveIngredient
+			nokocore.KeepVoid(activeIngredient)
+
+			activeIngredientRepository := repositories2.NewActiveIngredientRepository(tx)
+			productIngredientRepository := repositories2.NewProductIngredientRepository(tx)
+
+			if err = tx.Unscoped().Where("product_id = ?", product.ID).Delete(&models2.ProductIngredient{}).Error; err != nil {
+				return err
+			}
+
+			for i, productIngredientBody := range productIngredientsBody.Ingredients {
+				nokocore.KeepVoid(i)
+
+				if ingredientID := productIngredientBody.IngredientID; ingredientID != "" {
+					if activeIngredient, err = activeIngredientRepository.SafeFirst("uuid = ?", ingredientID); err != nil {
+						return err
+					}
+
+					if activeIngredient == nil {
+						return errIngredientNotFound
+					}
+
+				} else {
+					ingredientName := nokocore.ToTitleCase(strings.TrimSpace(productIngredientBody.IngredientName))
+					if activeIngredient, err = activeIngredientRepository.SafeFirst("ingredient_name = ?", ingredientName); err != nil {
+						return err
+					}
+
+					// can be automatic build
+					if activeIngredient == nil {
+						activeIngredient = &models2.ActiveIngredient{
+							IngredientName: ingredientName,
+						}
+
+						if err = activeIngredientRepository.Create(activeIngredient); err != nil {
+							return err
+						}
+					}
+				}
+
+				productIngredient := schemas2.ToProductIngredientModel(&productIngredientBody, product, activeIngredient)
+				if err = productIngredientRepository.Create(productIngredient); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			if errors.Is(err, errIngredientNotFound) {
+				return extras.NewMessageBodyNotFound(ctx, "Active ingredient not found.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update product ingredients.", nil)
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Ingredients.ActiveIngredient"}, "id = ?", product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		productIngredientResults := schemas2.ToProductIngredientResults(product.Ingredients)
+		return extras.NewMessageBodyOk(ctx, "Successfully update product ingredients.", &nokocore.MapAny{
+			"ingredients": productIngredientResults,
+			"substance":   schemas2.ToSubstance(product),
+			"drugClass":   product.DrugClass,
+		})
+	}
+}
+
+func DrugController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/ingredients", GetAllActiveIngredients(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.POST("/ingredient", CreateActiveIngredient(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.GET("/product/:productId/ingredients", GetAllProductIngredients(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.PUT("/product/:productId/ingredients", SetProductIngredients(DB), middlewares.RequirePermission(permissions.ProductWrite))
+
+	return group
+}
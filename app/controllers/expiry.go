@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/expiry"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+var writeOffPreloads = []string{"User", "Items.Product"}
+
+// GetExpiryReport method, stock expired or expiring within 'days', 90 by default,
+// with quantities and value at cost per window.
+func GetExpiryReport(DB *gorm.DB) echo.HandlerFunc {
+
+	expiryService := expiry.NewExpiryService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var report *expiry.ExpiryReport
+		nokocore.KeepVoid(err, report)
+
+		days := expiry.Windows[len(expiry.Windows)-1]
+		if extras.ParseQueryToString(ctx, "days") != "" {
+			if days = extras.ParseQueryToInt(ctx, "days"); days <= 0 {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'days'.", nil)
+			}
+		}
+
+		if report, err = expiryService.Report(nokocore.GetTimeUtcNow(), days); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get expiry report.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get expiry report.", &nokocore.MapAny{
+			"date":    report.Date,
+			"days":    report.Days,
+			"rows":    report.Rows,
+			"summary": report.Summary,
+			"total":   report.Total,
+		})
+	}
+}
+
+func GetAllExpiryAlerts(DB *gorm.DB) echo.HandlerFunc {
+
+	expiryAlertRepository := repositories2.NewExpiryAlertRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var expiryAlerts []models2.ExpiryAlert
+		nokocore.KeepVoid(err, expiryAlerts)
+
+		// newest alerts first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		expiryAlerts, err = expiryAlertRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Product", func(tx *gorm.DB) *gorm.DB {
+				return tx.Unscoped()
+			})
+			stmt = stmt.Order("flagged_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get expiry alerts.", nil)
+		}
+
+		expiryAlertResults := schemas2.ToExpiryAlertResults(expiryAlerts)
+		return extras.NewMessageBodyOk(ctx, "Successfully get expiry alerts.", &nokocore.MapAny{
+			"expiryAlerts": expiryAlertResults,
+		})
+	}
+}
+
+// QuarantineExpiredStock method, expired stock is written off and no longer sellable.
+func QuarantineExpiredStock(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	writeOffRepository := repositories2.NewWriteOffRepository(DB)
+	expiryService := expiry.NewExpiryService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var products []models2.Product
+		var writeOff *models2.WriteOff
+		nokocore.KeepVoid(err, products, writeOff)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		quarantineBody := new(schemas2.QuarantineBody)
+		if err = ctx.Bind(quarantineBody); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Unable to bind request body.", nil)
+		}
+
+		if err = ctx.Validate(quarantineBody); err != nil {
+			return err
+		}
+
+		for i, productID := range quarantineBody.ProductIDs {
+			nokocore.KeepVoid(i)
+
+			if err = sqlx.ValidateUUID(productID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_ids'.", nil)
+			}
+		}
+
+		var productIDs []uint
+		if len(quarantineBody.ProductIDs) > 0 {
+			if products, err = productRepository.SafeMany(0, -1, "uuid IN ?", quarantineBody.ProductIDs); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get products.", nil)
+			}
+
+			if len(products) != len(quarantineBody.ProductIDs) {
+				return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+			}
+
+			productIDs = make([]uint, len(products))
+			for i, product := range products {
+				productIDs[i] = product.ID
+			}
+		}
+
+		note := strings.TrimSpace(quarantineBody.Note)
+		if writeOff, err = expiryService.Quarantine(productIDs, jwtAuthInfo.User.ID, note, nokocore.GetTimeUtcNow()); err != nil {
+			if errors.Is(err, expiry.ErrNothingExpired) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "No expired stock to quarantine.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to quarantine expired stock.", nil)
+		}
+
+		if writeOff, err = writeOffRepository.SafePreFirst(writeOffPreloads, "id = ?", writeOff.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get write-off.", nil)
+		}
+
+		writeOffResult := schemas2.ToWriteOffResult(writeOff)
+		return extras.NewMessageBodyOk(ctx, "Successfully quarantine expired stock.", &nokocore.MapAny{
+			"writeOff": writeOffResult,
+		})
+	}
+}
+
+func GetAllWriteOffs(DB *gorm.DB) echo.HandlerFunc {
+
+	writeOffRepository := repositories2.NewWriteOffRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var writeOffs []models2.WriteOff
+		nokocore.KeepVoid(err, writeOffs)
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		writeOffs, err = writeOffRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Preload("Items.Product", func(tx *gorm.DB) *gorm.DB {
+				return tx.Unscoped()
+			})
+			stmt = stmt.Order("created_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get write-offs.", nil)
+		}
+
+		writeOffResults := schemas2.ToWriteOffResults(writeOffs)
+		return extras.NewMessageBodyOk(ctx, "Successfully get write-offs.", &nokocore.MapAny{
+			"writeOffs": writeOffResults,
+		})
+	}
+}
+
+func GetWriteOffById(DB *gorm.DB) echo.HandlerFunc {
+
+	writeOffRepository := repositories2.NewWriteOffRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var writeOffID string
+		var writeOff *models2.WriteOff
+		nokocore.KeepVoid(err, writeOffID, writeOff)
+
+		writeOffID = ctx.Param("writeOffId")
+		if err = sqlx.ValidateUUID(writeOffID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'write_off_id'.", nil)
+		}
+
+		if writeOff, err = writeOffRepository.SafePreFirst(writeOffPreloads, "uuid = ?", writeOffID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get write-off.", nil)
+		}
+
+		if writeOff == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Write-off not found.", nil)
+		}
+
+		writeOffResult := schemas2.ToWriteOffResult(writeOff)
+		return extras.NewMessageBodyOk(ctx, "Successfully get write-off.", &nokocore.MapAny{
+			"writeOff": writeOffResult,
+		})
+	}
+}
+
+func ExpiryController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/expiry", GetExpiryReport(DB), middlewares.RequirePermission(permissions.ExpiryRead))
+	group.GET("/expiry/alerts", GetAllExpiryAlerts(DB), middlewares.RequirePermission(permissions.ExpiryRead))
+	group.POST("/expiry/quarantine", QuarantineExpiredStock(DB), middlewares.RequirePermission(permissions.ExpiryQuarantine))
+	group.GET("/write-offs", GetAllWriteOffs(DB), middlewares.RequirePermission(permissions.ExpiryRead))
+	group.GET("/write-off/:writeOffId", GetWriteOffById(DB), middlewares.RequirePermission(permissions.ExpiryRead))
+
+	return group
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/costing"
+	"pharma-cash-go/app/locations"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/pricing"
+	"pharma-cash-go/app/registers"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+)
+
+func GetAllGoodsReceiptsByProductId(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	goodsReceiptRepository := repositories2.NewGoodsReceiptRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var goodsReceipts []models2.GoodsReceipt
+		nokocore.KeepVoid(err, productID, product, goodsReceipts)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		// latest receipts first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		goodsReceipts, err = goodsReceiptRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Preload("Product.Package").Preload("Product.Unit").Preload("Product.UnitLevels.Unit")
+			stmt = stmt.Where("product_id = ?", product.ID).Order("id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get goods receipts.", nil)
+		}
+
+		goodsReceiptResults := schemas2.ToGoodsReceiptResults(goodsReceipts)
+		return extras.NewMessageBodyOk(ctx, "Successfully get goods receipts.", &nokocore.MapAny{
+			"goodsReceipts": goodsReceiptResults,
+		})
+	}
+}
+
+func CreateGoodsReceipt(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	locationRepository := repositories2.NewLocationRepository(DB)
+	pricingService := pricing.NewPricingService(DB)
+	locationService := locations.NewLocationService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var location *models2.Location
+		var quantity int
+		nokocore.KeepVoid(err, productID, product, location, quantity)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		goodsReceiptBody := new(schemas2.GoodsReceiptBody)
+		if err = ctx.Bind(goodsReceiptBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(goodsReceiptBody); err != nil {
+			return err
+		}
+
+		for i, unitQuantityBody := range goodsReceiptBody.Quantities {
+			nokocore.KeepVoid(i)
+
+			if err = ctx.Validate(&unitQuantityBody); err != nil {
+				return err
+			}
+		}
+
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit"}
+		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if goodsReceiptBody.LocationID != "" {
+			if location, err = locationRepository.SafeFirst("uuid = ?", goodsReceiptBody.LocationID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+			}
+
+			if location == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Location not found.", nil)
+			}
+
+		} else {
+			if location, err = locationService.GetReceiving(); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get receiving location.", nil)
+			}
+		}
+
+		// quantities at any unit level, kept in base units
+		if quantity, err = schemas2.ToUnitTotal(product, goodsReceiptBody.Quantities, goodsReceiptBody.PackageTotal, goodsReceiptBody.UnitExtra); err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid goods receipt quantities.", err.Error())
+		}
+
+		if quantity <= 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Goods receipt quantity must be greater than zero.", nil)
+		}
+
+		goodsReceipt := schemas2.ToGoodsReceiptModel(goodsReceiptBody, product)
+		goodsReceipt.UserID = jwtAuthInfo.User.ID
+		goodsReceipt.Quantity = quantity
+		goodsReceipt.LocationID = location.ID
+
+		// new purchase price is priced by the product pricing rule
+		previous := *product
+		product.PurchasePrice = goodsReceipt.UnitCost
+		product.SetStock(product.Stock + quantity)
+		pricingService.Apply(product)
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			productRepository := repositories2.NewProductRepository(tx)
+			goodsReceiptRepository := repositories2.NewGoodsReceiptRepository(tx)
+			pricingService := pricing.NewPricingService(tx)
+			registerService := registers.NewRegisterService(tx)
+			costingService := costing.NewCostingService(tx)
+			locationService := locations.NewLocationService(tx)
+
+			if err = goodsReceiptRepository.Create(goodsReceipt); err != nil {
+				return err
+			}
+
+			if err = costingService.Receive(product, goodsReceipt.ID, quantity, goodsReceipt.UnitCost, goodsReceipt.UUID.String()); err != nil {
+				return err
+			}
+
+			if err = registerService.Receipt(product, quantity, jwtAuthInfo.User.ID, goodsReceipt.UUID.String()); err != nil {
+				return err
+			}
+
+			// unit levels are owned by the product units controller
+			unitLevels := product.UnitLevels
+			product.UnitLevels = nil
+			if err = productRepository.SafeUpdate(product, "id = ?", product.ID); err != nil {
+				return err
+			}
+			product.UnitLevels = unitLevels
+
+			if err = locationService.Move(product.ID, location.ID, quantity); err != nil {
+				return err
+			}
+
+			if err = pricingService.Record(product, &previous, pricing.SourceReceipt, jwtAuthInfo.User.ID); err != nil {
+				return err
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create goods receipt.", nil)
+		}
+
+		goodsReceipt.User = *jwtAuthInfo.User
+		goodsReceipt.Product = *product
+		goodsReceiptResult := schemas2.ToGoodsReceiptResult(goodsReceipt)
+		productResult := schemas2.ToProductResult(product)
+		return extras.NewMessageBodyOk(ctx, "Successfully create goods receipt.", &nokocore.MapAny{
+			"goodsReceipt": goodsReceiptResult,
+			"product":      productResult,
+		})
+	}
+}
+
+func GoodsReceiptController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/product/:productId/receipts", GetAllGoodsReceiptsByProductId(DB), middlewares.RequirePermission(permissions.ReceiptRead))
+	group.POST("/product/:productId/receipt", CreateGoodsReceipt(DB), middlewares.RequirePermission(permissions.ReceiptWrite))
+
+	return group
+}
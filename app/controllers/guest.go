@@ -3,6 +3,7 @@ package controllers
 import (
 	"database/sql"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	"nokowebapi/apis/extras"
@@ -13,9 +14,11 @@ import (
 	"nokowebapi/console"
 	"nokowebapi/globals"
 	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
 	models2 "pharma-cash-go/app/models"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
+	"time"
 )
 
 func GetMessage(DB *gorm.DB) echo.HandlerFunc {
@@ -38,8 +41,7 @@ func SetLogin(DB *gorm.DB) echo.HandlerFunc {
 	nokocore.KeepVoid(DB)
 
 	jwtConfig := globals.GetJwtConfig()
-	signingMethod := jwtConfig.GetSigningMethod()
-	expiresIn := jwtConfig.GetExpiresIn()
+	refreshExpiresIn := jwtConfig.GetRefreshExpiresIn()
 
 	userRepository := repositories.NewUserRepository(DB)
 	sessionRepository := repositories.NewSessionRepository(DB)
@@ -73,15 +75,15 @@ func SetLogin(DB *gorm.DB) echo.HandlerFunc {
 
 		jwtClaimsDataAccess := nokocore.NewEmptyJwtClaimsDataAccess()
 		timeUtcNow := nokocore.GetTimeUtcNow()
-		expires := timeUtcNow.Add(expiresIn)
 
+		// the session lives as long as its refresh token
 		session := &models.Session{
-			UserID:         user.ID,
-			TokenID:        jwtClaimsDataAccess.GetIdentity(),
-			RefreshTokenID: sql.NullString{},
-			IPAddress:      ipAddr,
-			UserAgent:      userAgent,
-			Expires:        expires,
+			UserID:           user.ID,
+			TokenID:          jwtClaimsDataAccess.GetIdentity(),
+			RefreshTokenHash: sql.NullString{},
+			IPAddress:        ipAddr,
+			UserAgent:        userAgent,
+			Expires:          timeUtcNow.Add(refreshExpiresIn),
 		}
 
 		if err = sessionRepository.Create(session); err != nil {
@@ -89,22 +91,16 @@ func SetLogin(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create session.", nil)
 		}
 
-		roles := utils.ToUserRolesArrayString(user.Roles)
 		sessionUUID := session.UUID.String()
 
-		jwtClaimsDataAccess.SetSubject("NokoWebApiToken")
-		jwtClaimsDataAccess.SetIssuer(jwtConfig.Issuer)
-		jwtClaimsDataAccess.SetAudience(jwtConfig.Audience)
-		jwtClaimsDataAccess.SetIssuedAt(timeUtcNow)
-		jwtClaimsDataAccess.SetExpiresAt(expires)
-		jwtClaimsDataAccess.SetUser(user.Username)
-		jwtClaimsDataAccess.SetSessionID(sessionUUID)
-		jwtClaimsDataAccess.SetRoles(roles)
-		jwtClaimsDataAccess.SetAdmin(user.Admin)
-		jwtClaimsDataAccess.SetLevel(user.Level)
+		// refresh token depends on the session id
+		refreshToken, refreshTokenHash := nokocore.NewRefreshToken(sessionUUID)
+		if err = DB.Model(session).Update("refresh_token_hash", refreshTokenHash).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create session.", nil)
+		}
 
-		jwtClaims := nokocore.ToJwtClaims(jwtClaimsDataAccess, signingMethod)
-		jwtToken := nokocore.GenerateJwtToken(jwtClaims, jwtConfig.SecretKey)
+		jwtToken := newAccessToken(jwtClaimsDataAccess, user, sessionUUID, timeUtcNow)
 
 		preloads := []string{"Shift"}
 		if employee, err = employeeRepository.SafePreFirst(preloads, "user_id = ?", user.ID); err != nil {
@@ -119,18 +115,149 @@ func SetLogin(DB *gorm.DB) echo.HandlerFunc {
 
 		userResult := schemas.ToUserResult(user)
 		return extras.NewMessageBodyOk(ctx, "Successfully logged in.", &nokocore.MapAny{
-			"accessToken": jwtToken,
-			"user":        userResult,
-			"shift":       shift,
+			"accessToken":  jwtToken,
+			"refreshToken": refreshToken,
+			"user":         userResult,
+			"shift":        shift,
+		})
+	}
+}
+
+// SetRefreshToken method, rotates the refresh token, a rotated token presented
+// again is treated as stolen and the whole session is revoked.
+func SetRefreshToken(DB *gorm.DB) echo.HandlerFunc {
+	nokocore.KeepVoid(DB)
+
+	jwtConfig := globals.GetJwtConfig()
+	idleTimeout := jwtConfig.GetIdleTimeout()
+
+	sessionRepository := repositories.NewSessionRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var sessionID string
+		var session *models.Session
+		nokocore.KeepVoid(err, sessionID, session)
+
+		refreshTokenBody := new(schemas.RefreshTokenBody)
+		if err = ctx.Bind(refreshTokenBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(refreshTokenBody); err != nil {
+			return err
+		}
+
+		refreshToken := refreshTokenBody.RefreshToken
+		if sessionID, err = nokocore.GetSessionIDFromRefreshToken(refreshToken); err != nil {
+			return extras.NewMessageBodyUnauthorized(ctx, "Invalid refresh token.", nil)
+		}
+
+		if err = sqlx.ValidateUUID(sessionID); err != nil {
+			return extras.NewMessageBodyUnauthorized(ctx, "Invalid refresh token.", nil)
+		}
+
+		preloads := []string{"User.Roles"}
+		if session, err = sessionRepository.SafePreFirst(preloads, "uuid = ?", sessionID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get session.", nil)
+		}
+
+		if session == nil {
+			return extras.NewMessageBodyUnauthorized(ctx, "Invalid refresh token.", nil)
+		}
+
+		user := session.User
+
+		// don't let gorm update the user
+		session.User = models.User{}
+
+		timeUtcNow := nokocore.GetTimeUtcNow()
+		if session.IsExpired(timeUtcNow, idleTimeout) {
+			if err = sessionRepository.SafeDelete(session, "id = ?", session.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			}
+
+			return extras.NewMessageBodyUnauthorized(ctx, "Session expired.", nil)
+		}
+
+		// reused token, revoke the whole session
+		if !session.RefreshTokenHash.Valid || !nokocore.RefreshTokenEquals(refreshToken, session.RefreshTokenHash.String) {
+			console.Warn(fmt.Sprintf("refresh token reused, revoking session %s", sessionID))
+			if err = sessionRepository.SafeDelete(session, "id = ?", session.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			}
+
+			return extras.NewMessageBodyUnauthorized(ctx, "Refresh token reused, session revoked.", nil)
+		}
+
+		if user.UUID == uuid.Nil {
+			return extras.NewMessageBodyUnauthorized(ctx, "User not found.", nil)
+		}
+
+		jwtClaimsDataAccess := nokocore.NewEmptyJwtClaimsDataAccess()
+		refreshToken, refreshTokenHash := nokocore.NewRefreshToken(sessionID)
+
+		// rotate only from the token we checked, a concurrent rotation wins
+		tx := DB.Model(&models.Session{}).
+			Where("id = ? AND refresh_token_hash = ?", session.ID, session.RefreshTokenHash.String).
+			Updates(map[string]any{
+				"token_id":           jwtClaimsDataAccess.GetIdentity(),
+				"refresh_token_hash": refreshTokenHash,
+				"updated_at":         timeUtcNow,
+			})
+
+		if err = tx.Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update session.", nil)
+		}
+
+		if tx.RowsAffected < 1 {
+			console.Warn(fmt.Sprintf("refresh token reused, revoking session %s", sessionID))
+			if err = sessionRepository.SafeDelete(session, "id = ?", session.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			}
+
+			return extras.NewMessageBodyUnauthorized(ctx, "Refresh token reused, session revoked.", nil)
+		}
+
+		jwtToken := newAccessToken(jwtClaimsDataAccess, &user, sessionID, timeUtcNow)
+		return extras.NewMessageBodyOk(ctx, "Successfully refresh token.", &nokocore.MapAny{
+			"accessToken":  jwtToken,
+			"refreshToken": refreshToken,
 		})
 	}
 }
 
+// newAccessToken method, the identity of the claims must be the session token id.
+func newAccessToken(jwtClaimsDataAccess nokocore.JwtClaimsDataAccessImpl, user *models.User, sessionID string, timeUtcNow time.Time) string {
+	jwtConfig := globals.GetJwtConfig()
+	signingMethod := jwtConfig.GetSigningMethod()
+	expires := timeUtcNow.Add(jwtConfig.GetExpiresIn())
+
+	roles := utils.ToUserRolesArrayString(user.Roles)
+
+	jwtClaimsDataAccess.SetSubject("NokoWebApiToken")
+	jwtClaimsDataAccess.SetIssuer(jwtConfig.Issuer)
+	jwtClaimsDataAccess.SetAudience(jwtConfig.Audience)
+	jwtClaimsDataAccess.SetIssuedAt(timeUtcNow)
+	jwtClaimsDataAccess.SetExpiresAt(expires)
+	jwtClaimsDataAccess.SetUser(user.Username)
+	jwtClaimsDataAccess.SetSessionID(sessionID)
+	jwtClaimsDataAccess.SetRoles(roles)
+	jwtClaimsDataAccess.SetAdmin(user.Admin)
+	jwtClaimsDataAccess.SetLevel(user.Level)
+
+	jwtClaims := nokocore.ToJwtClaims(jwtClaimsDataAccess, signingMethod)
+	return nokocore.GenerateJwtToken(jwtClaims, jwtConfig.SecretKey)
+}
+
 func GuestController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
 	group.GET("/", GetMessage(DB))
 	group.GET("/ping", GetPong(DB))
 	group.POST("/login", SetLogin(DB))
+	group.POST("/refresh-token", SetRefreshToken(DB))
 
 	return group
 }
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"net/http/httptest"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"testing"
+)
+
+type guestTest struct {
+	DB   *gorm.DB
+	Echo *echo.Echo
+}
+
+func newGuestTest(t *testing.T) *guestTest {
+	var err error
+	var DB *gorm.DB
+	nokocore.KeepVoid(err, DB)
+
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	if DB, err = gorm.Open(sqlite.Open(dsn), config); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []any{
+		&models.User{},
+		&models.Role{},
+		&models.Session{},
+		&models2.Shift{},
+		&models2.Employee{},
+	}
+
+	if err = DB.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &models.User{
+		Username: "officer",
+		Password: "Officer@1234",
+		Level:    1,
+	}
+
+	// repositories fill base model fields before create
+	userRepository := repositories.NewUserRepository(DB)
+	if err = userRepository.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Validator = sqlx.NewValidator()
+	e.HTTPErrorHandler = extras.EchoHTTPErrorHandler()
+	GuestController(e.Group("/api/v1"), DB)
+
+	return &guestTest{
+		DB:   DB,
+		Echo: e,
+	}
+}
+
+func (g *guestTest) post(path string, body any) (int, nokocore.MapAny) {
+	var buf bytes.Buffer
+	nokocore.NoErr(json.NewEncoder(&buf).Encode(body))
+
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	g.Echo.ServeHTTP(rec, req)
+
+	data := nokocore.MapAny{}
+	if messageBody := (nokocore.MapAny{}); json.Unmarshal(rec.Body.Bytes(), &messageBody) == nil {
+		if temp, ok := messageBody["data"].(map[string]any); ok {
+			data = temp
+		}
+	}
+
+	return rec.Code, data
+}
+
+func (g *guestTest) login(t *testing.T) string {
+	code, data := g.post("/api/v1/login", nokocore.MapAny{"username": "officer", "password": "Officer@1234"})
+	if code != http.StatusOK {
+		t.Fatal(fmt.Errorf("login status should be 200, got %d", code))
+	}
+
+	refreshToken, ok := data["refreshToken"].(string)
+	if !ok || refreshToken == "" {
+		t.Fatal(errors.New("login should return a refresh token"))
+	}
+
+	return refreshToken
+}
+
+func TestSetRefreshTokenRotate(t *testing.T) {
+	g := newGuestTest(t)
+	refreshToken := g.login(t)
+
+	for i := 0; i < 2; i++ {
+		code, data := g.post("/api/v1/refresh-token", nokocore.MapAny{"refreshToken": refreshToken})
+		if code != http.StatusOK {
+			t.Error(fmt.Errorf("refresh status should be 200, got %d", code))
+			return
+		}
+
+		rotated, ok := data["refreshToken"].(string)
+		if !ok || rotated == "" || rotated == refreshToken {
+			t.Error(errors.New("refresh token should be rotated"))
+			return
+		}
+
+		if accessToken, ok := data["accessToken"].(string); !ok || accessToken == "" {
+			t.Error(errors.New("refresh should return an access token"))
+			return
+		}
+
+		sessionID := nokocore.Unwrap(nokocore.GetSessionIDFromRefreshToken(rotated))
+		if sessionID != nokocore.Unwrap(nokocore.GetSessionIDFromRefreshToken(refreshToken)) {
+			t.Error(errors.New("rotated refresh token should keep the session"))
+			return
+		}
+
+		// only the hash of the current refresh token is kept
+		session := &models.Session{}
+		if err := g.DB.First(session, "uuid = ?", sessionID).Error; err != nil {
+			t.Error(err)
+			return
+		}
+
+		if session.RefreshTokenHash.String != nokocore.HashRefreshToken(rotated) {
+			t.Error(errors.New("session should keep the hash of the rotated refresh token"))
+			return
+		}
+
+		refreshToken = rotated
+	}
+}
+
+func TestSetRefreshTokenReuse(t *testing.T) {
+	g := newGuestTest(t)
+	refreshToken := g.login(t)
+
+	code, data := g.post("/api/v1/refresh-token", nokocore.MapAny{"refreshToken": refreshToken})
+	if code != http.StatusOK {
+		t.Error(fmt.Errorf("refresh status should be 200, got %d", code))
+		return
+	}
+
+	rotated, ok := data["refreshToken"].(string)
+	if !ok || rotated == "" {
+		t.Error(errors.New("refresh should return a refresh token"))
+		return
+	}
+
+	// the rotated refresh token is presented again
+	if code, _ = g.post("/api/v1/refresh-token", nokocore.MapAny{"refreshToken": refreshToken}); code != http.StatusUnauthorized {
+		t.Error(fmt.Errorf("reused refresh status should be 401, got %d", code))
+		return
+	}
+
+	sessionID := nokocore.Unwrap(nokocore.GetSessionIDFromRefreshToken(refreshToken))
+	session := &models.Session{}
+	if err := g.DB.Unscoped().First(session, "uuid = ?", sessionID).Error; err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !session.DeletedAt.Valid {
+		t.Error(errors.New("session should be revoked"))
+		return
+	}
+
+	// the latest refresh token is revoked together with the session
+	if code, _ = g.post("/api/v1/refresh-token", nokocore.MapAny{"refreshToken": rotated}); code != http.StatusUnauthorized {
+		t.Error(fmt.Errorf("revoked refresh status should be 401, got %d", code))
+		return
+	}
+}
+
+func TestSetRefreshTokenInvalid(t *testing.T) {
+	g := newGuestTest(t)
+	g.login(t)
+
+	for i, refreshToken := range []string{"garbage", fmt.Sprintf("%s.token", nokocore.NewUUID())} {
+		nokocore.KeepVoid(i)
+
+		if code, _ := g.post("/api/v1/refresh-token", nokocore.MapAny{"refreshToken": refreshToken}); code != http.StatusUnauthorized {
+			t.Error(fmt.Errorf("refresh status of '%s' should be 401, got %d", refreshToken, code))
+		}
+	}
+}
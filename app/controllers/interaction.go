@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/interactions"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+func GetAllDrugInteractions(DB *gorm.DB) echo.HandlerFunc {
+
+	drugInteractionRepository := repositories2.NewDrugInteractionRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var drugInteractions []models2.DrugInteraction
+		nokocore.KeepVoid(err, drugInteractions)
+
+		ingredient := strings.TrimSpace(extras.ParseQueryToString(ctx, "ingredient"))
+		severity := strings.ToLower(extras.ParseQueryToString(ctx, "severity"))
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		drugInteractions, err = drugInteractionRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Ingredient").Preload("InteractsWith")
+			if ingredient != "" {
+				ingredients := DB.Model(&models2.ActiveIngredient{}).Select("id").Where("ingredient_name LIKE ?", "%"+ingredient+"%")
+				stmt = stmt.Where("ingredient_id IN (?) OR interacts_with_id IN (?)", ingredients, ingredients)
+			}
+			if severity != "" {
+				stmt = stmt.Where("severity = ?", severity)
+			}
+			stmt = stmt.Order("id ASC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get drug interactions.", nil)
+		}
+
+		drugInteractionResults := schemas2.ToDrugInteractionResults(drugInteractions)
+		return extras.NewMessageBodyOk(ctx, "Successfully get drug interactions.", &nokocore.MapAny{
+			"drugInteractions": drugInteractionResults,
+		})
+	}
+}
+
+func SaveDrugInteraction(DB *gorm.DB) echo.HandlerFunc {
+
+	interactionService := interactions.NewInteractionService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var created bool
+		var drugInteraction *models2.DrugInteraction
+		nokocore.KeepVoid(err, created, drugInteraction)
+
+		drugInteractionBody := new(schemas2.DrugInteractionBody)
+		if err = ctx.Bind(drugInteractionBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(drugInteractionBody); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(drugInteractionBody.Ingredient) == "" || strings.TrimSpace(drugInteractionBody.InteractsWith) == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Ingredient names are required.", nil)
+		}
+
+		drugInteraction, created, err = interactionService.Save(drugInteractionBody.Ingredient, drugInteractionBody.InteractsWith, drugInteractionBody.Severity, drugInteractionBody.Note)
+		if err != nil {
+			if errors.Is(err, interactions.ErrInvalidSeverity) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid interaction severity.", nil)
+			}
+
+			if errors.Is(err, interactions.ErrSameIngredient) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Ingredient cannot interact with itself.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to save drug interaction.", nil)
+		}
+
+		statusText := "update"
+		if created {
+			statusText = "create"
+		}
+
+		drugInteractionResult := schemas2.ToDrugInteractionResult(drugInteraction)
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully %s drug interaction.", statusText), &nokocore.MapAny{
+			"drugInteraction": drugInteractionResult,
+		})
+	}
+}
+
+func DeleteDrugInteraction(DB *gorm.DB) echo.HandlerFunc {
+
+	drugInteractionRepository := repositories2.NewDrugInteractionRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var interactionID string
+		var drugInteraction *models2.DrugInteraction
+		nokocore.KeepVoid(err, interactionID, drugInteraction)
+
+		interactionID = ctx.Param("interactionId")
+		if err = sqlx.ValidateUUID(interactionID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'interaction_id'.", nil)
+		}
+
+		if drugInteraction, err = drugInteractionRepository.SafeFirst("uuid = ?", interactionID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get drug interaction.", nil)
+		}
+
+		if drugInteraction == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Drug interaction not found.", nil)
+		}
+
+		// pairs are unique, the pair can be added again
+		if err = drugInteractionRepository.Delete(drugInteraction, "id = ?", drugInteraction.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to delete drug interaction.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully delete drug interaction.", nil)
+	}
+}
+
+// ImportDrugInteractions method, csv file with 'ingredient, interacts with, severity, note' rows.
+func ImportDrugInteractions(DB *gorm.DB) echo.HandlerFunc {
+	nokocore.KeepVoid(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var fileHeader *multipart.FileHeader
+		var file multipart.File
+		var result *interactions.ImportResult
+		nokocore.KeepVoid(err, fileHeader, file, result)
+
+		if fileHeader, err = ctx.FormFile("file"); err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "File is required.", nil)
+		}
+
+		if fileHeader.Size > interactions.MaxImportSize {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("File size exceeds %s.", nokocore.ToFileSizeFormat(interactions.MaxImportSize)), nil)
+		}
+
+		if file, err = fileHeader.Open(); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to open file.", nil)
+		}
+
+		defer file.Close()
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			interactionService := interactions.NewInteractionService(tx)
+			if result, err = interactionService.Import(io.LimitReader(file, interactions.MaxImportSize)); err != nil {
+				return err
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Failed to import drug interactions.", err.Error())
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully import drug interactions.", &nokocore.MapAny{
+			"import": result,
+		})
+	}
+}
+
+func InteractionController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/interactions", GetAllDrugInteractions(DB), middlewares.RequirePermission(permissions.InteractionRead))
+	group.POST("/interaction", SaveDrugInteraction(DB), middlewares.RequirePermission(permissions.InteractionWrite))
+	group.DELETE("/interaction/:interactionId", DeleteDrugInteraction(DB), middlewares.RequirePermission(permissions.InteractionWrite))
+	group.POST("/interactions/import", ImportDrugInteractions(DB), middlewares.RequirePermission(permissions.InteractionWrite))
+
+	return group
+}
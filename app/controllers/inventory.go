@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/costing"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"time"
+)
+
+// GetInventoryValuation method, stock quantity and value per product as of
+// 'as_of' (ISO 8601), now by default.
+func GetInventoryValuation(DB *gorm.DB) echo.HandlerFunc {
+
+	costingService := costing.NewCostingService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var asOf time.Time
+		var valuation *costing.Valuation
+		nokocore.KeepVoid(err, asOf, valuation)
+
+		asOf = nokocore.GetTimeUtcNow()
+		if value := extras.ParseQueryToString(ctx, "as_of"); value != "" {
+			if asOf, err = nokocore.ParseTimeUtcByStringISO8601(value); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'as_of'.", nil)
+			}
+		}
+
+		if valuation, err = costingService.Valuation(asOf); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get inventory valuation.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get inventory valuation.", &nokocore.MapAny{
+			"asOf":   nokocore.ToTimeUtcStringISO8601(valuation.AsOf),
+			"method": valuation.Method,
+			"rows":   valuation.Rows,
+			"total":  valuation.Total,
+		})
+	}
+}
+
+// GetCostOfGoodsSold method, cost of goods sold per product between 'from' and 'to'
+// (ISO 8601), the current month by default.
+func GetCostOfGoodsSold(DB *gorm.DB) echo.HandlerFunc {
+
+	costingService := costing.NewCostingService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var from time.Time
+		var to time.Time
+		var costOfGoods *costing.CostOfGoods
+		nokocore.KeepVoid(err, from, to, costOfGoods)
+
+		now := nokocore.GetTimeUtcNow()
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+
+		if value := extras.ParseQueryToString(ctx, "from"); value != "" {
+			if from, err = nokocore.ParseTimeUtcByStringISO8601(value); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'from'.", nil)
+			}
+		}
+
+		if value := extras.ParseQueryToString(ctx, "to"); value != "" {
+			if to, err = nokocore.ParseTimeUtcByStringISO8601(value); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'to'.", nil)
+			}
+		}
+
+		if !from.Before(to) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Parameter 'from' must be before 'to'.", nil)
+		}
+
+		if costOfGoods, err = costingService.CostOfGoodsSold(from, to); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get cost of goods sold.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get cost of goods sold.", &nokocore.MapAny{
+			"from":  nokocore.ToTimeUtcStringISO8601(costOfGoods.From),
+			"to":    nokocore.ToTimeUtcStringISO8601(costOfGoods.To),
+			"rows":  costOfGoods.Rows,
+			"total": costOfGoods.Total,
+		})
+	}
+}
+
+func GetAllProductCostLayers(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	costLayerRepository := repositories2.NewCostLayerRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var costLayers []models2.CostLayer
+		nokocore.KeepVoid(err, productID, product, costLayers)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		// consumed layers only with 'all=true'
+		all := extras.ParseQueryToBool(ctx, "all")
+
+		costLayers, err = costLayerRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Where("product_id = ?", product.ID)
+			if !all {
+				stmt = stmt.Where("remaining > 0")
+			}
+			return stmt.Order("received_at ASC, id ASC"), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get cost layers.", nil)
+		}
+
+		for i := range costLayers {
+			costLayers[i].Product = *product
+		}
+
+		costLayerResults := schemas2.ToCostLayerResults(costLayers)
+		return extras.NewMessageBodyOk(ctx, "Successfully get cost layers.", &nokocore.MapAny{
+			"costLayers": costLayerResults,
+			"method":     costing.GetMethod(),
+		})
+	}
+}
+
+func InventoryController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/inventory/valuation", GetInventoryValuation(DB), middlewares.RequirePermission(permissions.InventoryRead))
+	group.GET("/inventory/cogs", GetCostOfGoodsSold(DB), middlewares.RequirePermission(permissions.InventoryRead))
+	group.GET("/product/:productId/cost-layers", GetAllProductCostLayers(DB), middlewares.RequirePermission(permissions.InventoryRead))
+
+	return group
+}
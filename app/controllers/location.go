@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+func GetAllLocations(DB *gorm.DB) echo.HandlerFunc {
+
+	locationRepository := repositories2.NewLocationRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var locations []models2.Location
+		nokocore.KeepVoid(err, locations)
+
+		locations, err = locationRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			return tx.Order("selling DESC, code ASC"), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get locations.", nil)
+		}
+
+		locationResults := schemas2.ToLocationResults(locations)
+		return extras.NewMessageBodyOk(ctx, "Successfully get locations.", &nokocore.MapAny{
+			"locations": locationResults,
+		})
+	}
+}
+
+// saveLocation method, only one selling and one receiving location at a time.
+func saveLocation(DB *gorm.DB, location *models2.Location) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		locationRepository := repositories2.NewLocationRepository(tx)
+
+		if location.Selling {
+			if err = tx.Model(&models2.Location{}).Where("id <> ?", location.ID).UpdateColumn("selling", false).Error; err != nil {
+				return err
+			}
+		}
+
+		if location.Receiving {
+			if err = tx.Model(&models2.Location{}).Where("id <> ?", location.ID).UpdateColumn("receiving", false).Error; err != nil {
+				return err
+			}
+		}
+
+		if location.ID == 0 {
+			return locationRepository.Create(location)
+		}
+
+		return locationRepository.SafeUpdate(location, "id = ?", location.ID)
+	})
+}
+
+func CreateLocation(DB *gorm.DB) echo.HandlerFunc {
+
+	locationRepository := repositories2.NewLocationRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var location *models2.Location
+		nokocore.KeepVoid(err, location)
+
+		locationBody := new(schemas2.LocationBody)
+		if err = ctx.Bind(locationBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(locationBody); err != nil {
+			return err
+		}
+
+		newLocation := schemas2.ToLocationModel(locationBody)
+		if location, err = locationRepository.First("code = ?", newLocation.Code); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+		}
+
+		if location != nil {
+			return extras.NewMessageBodyConflict(ctx, "Location code already exists.", nil)
+		}
+
+		if err = saveLocation(DB, newLocation); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create location.", nil)
+		}
+
+		locationResult := schemas2.ToLocationResult(newLocation)
+		return extras.NewMessageBodyOk(ctx, "Successfully create location.", &nokocore.MapAny{
+			"location": locationResult,
+		})
+	}
+}
+
+// UpdateLocation method, the selling location is kept until another location is selling.
+func UpdateLocation(DB *gorm.DB) echo.HandlerFunc {
+
+	locationRepository := repositories2.NewLocationRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var locationID string
+		var location *models2.Location
+		var check *models2.Location
+		nokocore.KeepVoid(err, locationID, location, check)
+
+		locationID = ctx.Param("locationId")
+		if err = sqlx.ValidateUUID(locationID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'location_id'.", nil)
+		}
+
+		locationBody := new(schemas2.LocationBody)
+		if err = ctx.Bind(locationBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(locationBody); err != nil {
+			return err
+		}
+
+		if location, err = locationRepository.SafeFirst("uuid = ?", locationID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+		}
+
+		if location == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Location not found.", nil)
+		}
+
+		if location.Selling && !locationBody.Selling {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Another location must be selling first.", nil)
+		}
+
+		newLocation := schemas2.ToLocationModel(locationBody)
+		if check, err = locationRepository.First("code = ? AND id <> ?", newLocation.Code, location.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+		}
+
+		if check != nil {
+			return extras.NewMessageBodyConflict(ctx, "Location code already exists.", nil)
+		}
+
+		location.Code = newLocation.Code
+		location.LocationName = newLocation.LocationName
+		location.Description = newLocation.Description
+		location.Selling = newLocation.Selling
+		location.Receiving = newLocation.Receiving
+
+		if err = saveLocation(DB, location); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update location.", nil)
+		}
+
+		locationResult := schemas2.ToLocationResult(location)
+		return extras.NewMessageBodyOk(ctx, "Successfully update location.", &nokocore.MapAny{
+			"location": locationResult,
+		})
+	}
+}
+
+func GetAllProductStocks(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	productStockRepository := repositories2.NewProductStockRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var productStocks []models2.ProductStock
+		nokocore.KeepVoid(err, productID, product, productStocks)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Unit", "UnitLevels.Unit"}, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		productStocks, err = productStockRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Location").Where("product_id = ?", product.ID)
+			return stmt.Order("location_id ASC"), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product stocks.", nil)
+		}
+
+		productStockResults := schemas2.ToProductStockResults(productStocks, product)
+		return extras.NewMessageBodyOk(ctx, "Successfully get product stocks.", &nokocore.MapAny{
+			"stocks": productStockResults,
+			"stock":  product.Stock,
+		})
+	}
+}
+
+func GetAllProductStockMovements(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	stockMovementRepository := repositories2.NewStockMovementRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var stockMovements []models2.StockMovement
+		nokocore.KeepVoid(err, productID, product, stockMovements)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		kind := strings.ToLower(extras.ParseQueryToString(ctx, "kind"))
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		stockMovements, err = stockMovementRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Location").Preload("User").Where("product_id = ?", product.ID)
+			if kind != "" {
+				stmt = stmt.Where("kind = ?", kind)
+			}
+			stmt = stmt.Order("created_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock movements.", nil)
+		}
+
+		stockMovementResults := schemas2.ToStockMovementResults(stockMovements)
+		return extras.NewMessageBodyOk(ctx, "Successfully get stock movements.", &nokocore.MapAny{
+			"stockMovements": stockMovementResults,
+		})
+	}
+}
+
+func LocationController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/locations", GetAllLocations(DB), middlewares.RequirePermission(permissions.LocationRead))
+	group.POST("/location", CreateLocation(DB), middlewares.RequirePermission(permissions.LocationWrite))
+	group.PUT("/location/:locationId", UpdateLocation(DB), middlewares.RequirePermission(permissions.LocationWrite))
+	group.GET("/product/:productId/stocks", GetAllProductStocks(DB), middlewares.RequirePermission(permissions.InventoryRead))
+	group.GET("/product/:productId/stock-movements", GetAllProductStockMovements(DB), middlewares.RequirePermission(permissions.InventoryRead))
+
+	return group
+}
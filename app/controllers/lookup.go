@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+)
+
+// LookupController struct, product lookups named by a unique type ex. units and packages,
+// lookups differ only in model, body, result and the products using them.
+type LookupController[T any, B any, R any] struct {
+	Name            string // ex. unit, used by messages, parameters and data keys
+	Column          string // unique type column ex. unit_type
+	ProductColumn   string // product column moved by merges ex. unit_id
+	ProductQuery    string // products using the lookup, named by '@id'
+	ProductPreloads []string
+
+	// MergeQuery, products unable to follow the merge, named by '@id' and '@target'
+	MergeQuery string
+
+	NewRepository func(DB *gorm.DB) repositories.BaseRepositoryImpl[T]
+	GetBaseModel  func(model *T) *models.BaseModel
+	GetType       func(body *B) string
+	SetType       func(model *T, value string)
+	ToModel       func(body *B) *T
+	ToResult      func(model *T) R
+
+	// OnMerge, moves anything else of the merged lookup in the same transaction
+	OnMerge func(tx *gorm.DB, model *T, target *T) error
+}
+
+func (l *LookupController[T, B, R]) getTitle() string {
+	return nokocore.ToTitleCase(l.Name)
+}
+
+func (l *LookupController[T, B, R]) getParam() string {
+	return fmt.Sprintf("%sId", l.Name)
+}
+
+func (l *LookupController[T, B, R]) getLookupID(ctx echo.Context) (string, error) {
+	lookupID := ctx.Param(l.getParam())
+	if err := sqlx.ValidateUUID(lookupID); err != nil {
+		console.Error(fmt.Sprintf("panic: %s", err.Error()))
+		return "", extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("Invalid parameter '%s_id'.", l.Name), nil)
+	}
+
+	return lookupID, nil
+}
+
+func (l *LookupController[T, B, R]) Create(DB *gorm.DB) echo.HandlerFunc {
+
+	lookupRepository := l.NewRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var lookup *T
+		nokocore.KeepVoid(err, lookup)
+
+		body := new(B)
+
+		if err = ctx.Bind(body); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(body); err != nil {
+			return err
+		}
+
+		// normalized text
+		lookupType := nokocore.ToTitleCase(l.GetType(body))
+
+		if lookup, err = lookupRepository.SafeFirst(fmt.Sprintf("%s = ?", l.Column), lookupType); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if lookup != nil {
+			return extras.NewMessageBodyOk(ctx, fmt.Sprintf("%s already exists.", l.getTitle()), &nokocore.MapAny{
+				l.Name: l.ToResult(lookup),
+			})
+		}
+
+		lookup = l.ToModel(body)
+		l.SetType(lookup, lookupType)
+		if err = lookupRepository.Create(lookup); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to create %s.", l.Name), nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully create %s.", l.Name), &nokocore.MapAny{
+			l.Name: l.ToResult(lookup),
+		})
+	}
+}
+
+func (l *LookupController[T, B, R]) GetAll(DB *gorm.DB) echo.HandlerFunc {
+
+	return func(ctx echo.Context) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+
+		var lookups []T
+		tx := DB.Offset(pagination.Offset).Limit(pagination.Limit).Find(&lookups)
+		if err = tx.Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to get %ss.", l.Name), nil)
+		}
+
+		var lookupResults []R
+		for i := range lookups {
+			lookupResults = append(lookupResults, l.ToResult(&lookups[i]))
+		}
+
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully get %ss.", l.Name), &nokocore.MapAny{
+			fmt.Sprintf("%ss", l.Name): lookupResults,
+		})
+	}
+}
+
+func (l *LookupController[T, B, R]) Update(DB *gorm.DB) echo.HandlerFunc {
+
+	lookupRepository := l.NewRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var lookupID string
+		var lookup *T
+		var check *T
+		nokocore.KeepVoid(err, lookupID, lookup, check)
+
+		if lookupID, err = l.getLookupID(ctx); lookupID == "" {
+			return err
+		}
+
+		body := new(B)
+
+		if err = ctx.Bind(body); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(body); err != nil {
+			return err
+		}
+
+		// normalized text
+		lookupType := nokocore.ToTitleCase(l.GetType(body))
+		if lookupType == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("%s type is required.", l.getTitle()), nil)
+		}
+
+		if lookup, err = lookupRepository.SafeFirst("uuid = ?", lookupID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if lookup == nil {
+			return extras.NewMessageBodyNotFound(ctx, fmt.Sprintf("%s not found.", l.getTitle()), nil)
+		}
+
+		// lookup type is unique, including deleted lookups
+		ID := l.GetBaseModel(lookup).ID
+		if check, err = lookupRepository.First(fmt.Sprintf("%s = ? AND id <> ?", l.Column), lookupType, ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if check != nil {
+			return extras.NewMessageBodyConflict(ctx, fmt.Sprintf("%s type already exists, merge the %ss instead.", l.getTitle(), l.Name), &nokocore.MapAny{
+				l.Name: l.ToResult(check),
+			})
+		}
+
+		l.SetType(lookup, lookupType)
+		if err = lookupRepository.SafeUpdate(lookup, "id = ?", ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to update %s.", l.Name), nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully update %s.", l.Name), &nokocore.MapAny{
+			l.Name: l.ToResult(lookup),
+		})
+	}
+}
+
+func (l *LookupController[T, B, R]) Delete(DB *gorm.DB) echo.HandlerFunc {
+
+	lookupRepository := l.NewRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var lookupID string
+		var lookup *T
+		var products []models2.Product
+		nokocore.KeepVoid(err, lookupID, lookup, products)
+
+		forced := extras.ParseQueryToBool(ctx, "forced")
+
+		if lookupID, err = l.getLookupID(ctx); lookupID == "" {
+			return err
+		}
+
+		if lookup, err = lookupRepository.First("uuid = ?", lookupID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if lookup == nil {
+			return extras.NewMessageBodyNotFound(ctx, fmt.Sprintf("%s not found.", l.getTitle()), nil)
+		}
+
+		baseModel := l.GetBaseModel(lookup)
+		if !forced && baseModel.DeletedAt.Valid {
+			return extras.NewMessageBodyOk(ctx, fmt.Sprintf("%s already deleted.", l.getTitle()), nil)
+		}
+
+		// products restrict the lookup deletion, deleted products only restrict the forced deletion
+		ID := sql.Named("id", baseModel.ID)
+		if forced {
+			products, err = productRepository.PreMany(l.ProductPreloads, 0, -1, l.ProductQuery, ID)
+		} else {
+			products, err = productRepository.SafePreMany(l.ProductPreloads, 0, -1, l.ProductQuery, ID)
+		}
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get products.", nil)
+		}
+
+		if len(products) > 0 {
+			productResults := schemas2.ToProductResults(products)
+			return extras.NewMessageBodyConflict(ctx, fmt.Sprintf("%s is still used by products.", l.getTitle()), &nokocore.MapAny{
+				l.Name:     l.ToResult(lookup),
+				"products": productResults,
+			})
+		}
+
+		if forced {
+			err = lookupRepository.Delete(lookup, "id = ?", baseModel.ID)
+		} else {
+			err = lookupRepository.SafeDelete(lookup, "id = ?", baseModel.ID)
+		}
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to delete %s.", l.Name), nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully delete %s.", l.Name), nil)
+	}
+}
+
+func (l *LookupController[T, B, R]) Merge(DB *gorm.DB) echo.HandlerFunc {
+
+	lookupRepository := l.NewRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var lookupID string
+		var lookup *T
+		var target *T
+		var products []models2.Product
+		var moved int64
+		nokocore.KeepVoid(err, lookupID, lookup, target, products, moved)
+
+		errMergeConflict := errors.New("products are unable to follow the merge")
+
+		if lookupID, err = l.getLookupID(ctx); lookupID == "" {
+			return err
+		}
+
+		mergeBody := new(schemas2.MergeBody)
+
+		if err = ctx.Bind(mergeBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(mergeBody); err != nil {
+			return err
+		}
+
+		if mergeBody.TargetID == lookupID {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("Unable to merge %s into itself.", l.Name), nil)
+		}
+
+		if lookup, err = lookupRepository.First("uuid = ?", lookupID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if lookup == nil {
+			return extras.NewMessageBodyNotFound(ctx, fmt.Sprintf("%s not found.", l.getTitle()), nil)
+		}
+
+		if target, err = lookupRepository.SafeFirst("uuid = ?", mergeBody.TargetID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Unable to get %s.", l.Name), nil)
+		}
+
+		if target == nil {
+			return extras.NewMessageBodyNotFound(ctx, fmt.Sprintf("Target %s not found.", l.Name), nil)
+		}
+
+		ID := l.GetBaseModel(lookup).ID
+		targetID := l.GetBaseModel(target).ID
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			lookupRepository := l.NewRepository(tx)
+			productRepository := repositories2.NewProductRepository(tx)
+
+			// checked in the transaction, includes deleted products
+			if l.MergeQuery != "" {
+				if products, err = productRepository.PreMany(l.ProductPreloads, 0, -1, l.MergeQuery, sql.Named("id", ID), sql.Named("target", targetID)); err != nil {
+					return err
+				}
+
+				if len(products) > 0 {
+					return errMergeConflict
+				}
+			}
+
+			// move every product, including deleted products
+			stmt := tx.Unscoped().Model(&models2.Product{}).Where(fmt.Sprintf("%s = ?", l.ProductColumn), ID).Update(l.ProductColumn, targetID)
+			if err = stmt.Error; err != nil {
+				return err
+			}
+
+			moved = stmt.RowsAffected
+
+			if l.OnMerge != nil {
+				if err = l.OnMerge(tx, lookup, target); err != nil {
+					return err
+				}
+			}
+
+			// merged lookup is removed permanently, the lookup type can be reused
+			if err = lookupRepository.Delete(lookup, "id = ?", ID); err != nil {
+				return err
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			if errors.Is(err, errMergeConflict) {
+				productResults := schemas2.ToProductResults(products)
+				return extras.NewMessageBodyConflict(ctx, fmt.Sprintf("Products use both %s and target %s.", l.Name, l.Name), &nokocore.MapAny{
+					l.Name:     l.ToResult(lookup),
+					"products": productResults,
+				})
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to merge %s.", l.Name), nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully merge %s.", l.Name), &nokocore.MapAny{
+			l.Name:          l.ToResult(target),
+			"movedProducts": moved,
+		})
+	}
+}
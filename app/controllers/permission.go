@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/permissions"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+func GetAllPermissions(DB *gorm.DB) echo.HandlerFunc {
+	nokocore.KeepVoid(DB)
+
+	return func(ctx echo.Context) error {
+		permissionResults := schemas2.ToPermissionResults(globals.GetPolicy())
+		return extras.NewMessageBodyOk(ctx, "Successfully get permissions.", &nokocore.MapAny{
+			"permissions": permissionResults,
+		})
+	}
+}
+
+// getEditablePermission method, policy managers are never locked out by database roles.
+func getEditablePermission(ctx echo.Context) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(ctx.Param("permission")))
+	if !globals.GetPolicy().IsGranted(name) {
+		return "", extras.NewMessageBodyNotFound(ctx, "Permission not found.", nil)
+	}
+
+	if name == permissions.PolicyManage {
+		return "", extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("Permission '%s' is unable to be changed.", name), nil)
+	}
+
+	return name, nil
+}
+
+// UpdatePermission method, assigned roles replace the roles from defaults and config.
+func UpdatePermission(DB *gorm.DB) echo.HandlerFunc {
+
+	permissionRepository := repositories.NewPermissionRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var name string
+		var permission *models.Permission
+		nokocore.KeepVoid(err, name, permission)
+
+		if name, err = getEditablePermission(ctx); name == "" {
+			return err
+		}
+
+		permissionBody := new(schemas2.PermissionBody)
+		if err = ctx.Bind(permissionBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(permissionBody); err != nil {
+			return err
+		}
+
+		roles := make([]string, 0, len(permissionBody.Roles))
+		for i, role := range permissionBody.Roles {
+			nokocore.KeepVoid(i)
+
+			if role = strings.TrimSpace(role); role == "" || strings.Trim(role, nokocore.AlphaNum) != "" {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("Invalid role '%s'.", permissionBody.Roles[i]), nil)
+			}
+
+			roles = nokocore.RolesAdd(roles, nokocore.ToPascalCase(role))
+		}
+
+		if permission, err = permissionRepository.First("permission_name = ?", name); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get permission.", nil)
+		}
+
+		if permission == nil {
+			permission = &models.Permission{
+				PermissionName: name,
+				Roles:          nokocore.RolesPack(roles),
+			}
+
+			err = permissionRepository.Create(permission)
+
+		} else {
+			permission.Roles = nokocore.RolesPack(roles)
+			permission.DeletedAt = gorm.DeletedAt{}
+			err = permissionRepository.Update(permission, "id = ?", permission.ID)
+		}
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update permission.", nil)
+		}
+
+		policy := globals.GetPolicy()
+		policy.Assign(name, roles)
+
+		permissionResult := schemas2.ToPermissionResult(policy, name)
+		return extras.NewMessageBodyOk(ctx, "Successfully update permission.", &nokocore.MapAny{
+			"permission": permissionResult,
+		})
+	}
+}
+
+// ResetPermission method, roles from defaults and config are used again.
+func ResetPermission(DB *gorm.DB) echo.HandlerFunc {
+
+	permissionRepository := repositories.NewPermissionRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var name string
+		var permission *models.Permission
+		nokocore.KeepVoid(err, name, permission)
+
+		if name, err = getEditablePermission(ctx); name == "" {
+			return err
+		}
+
+		if permission, err = permissionRepository.First("permission_name = ?", name); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get permission.", nil)
+		}
+
+		if permission != nil {
+			if err = permissionRepository.Delete(permission, "id = ?", permission.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to reset permission.", nil)
+			}
+		}
+
+		policy := globals.GetPolicy()
+		policy.Unassign(name)
+
+		permissionResult := schemas2.ToPermissionResult(policy, name)
+		return extras.NewMessageBodyOk(ctx, "Successfully reset permission.", &nokocore.MapAny{
+			"permission": permissionResult,
+		})
+	}
+}
+
+func PermissionController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/policy/permissions", GetAllPermissions(DB), middlewares.RequirePermission(permissions.PolicyManage))
+	group.PUT("/policy/permission/:permission", UpdatePermission(DB), middlewares.RequirePermission(permissions.PolicyManage))
+	group.DELETE("/policy/permission/:permission", ResetPermission(DB), middlewares.RequirePermission(permissions.PolicyManage))
+
+	return group
+}
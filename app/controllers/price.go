@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+)
+
+func GetAllPriceHistoriesByProductId(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	priceHistoryRepository := repositories2.NewPriceHistoryRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var priceHistories []models2.PriceHistory
+		nokocore.KeepVoid(err, productID, product, priceHistories)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		// latest price changes first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		priceHistories, err = priceHistoryRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Product").Where("product_id = ?", product.ID).Order("id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price histories.", nil)
+		}
+
+		priceHistoryResults := schemas2.ToPriceHistoryResults(priceHistories)
+		return extras.NewMessageBodyOk(ctx, "Successfully get price histories.", &nokocore.MapAny{
+			"priceHistories": priceHistoryResults,
+		})
+	}
+}
+
+func PriceController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/product/:productId/prices", GetAllPriceHistoriesByProductId(DB), middlewares.RequirePermission(permissions.PriceRead))
+
+	return group
+}
@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/pricing"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+var priceChangePreloads = []string{"User", "Items.Product"}
+
+func GetAllPriceChanges(DB *gorm.DB) echo.HandlerFunc {
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var priceChanges []models2.PriceChange
+		nokocore.KeepVoid(err, priceChanges)
+
+		status := strings.ToLower(extras.ParseQueryToString(ctx, "status"))
+
+		// upcoming price changes first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		priceChanges, err = priceChangeRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Preload("Items.Product")
+			if status != "" {
+				stmt = stmt.Where("status = ?", status)
+			}
+			stmt = stmt.Order("effective_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price changes.", nil)
+		}
+
+		priceChangeResults := schemas2.ToPriceChangeResults(priceChanges)
+		return extras.NewMessageBodyOk(ctx, "Successfully get price changes.", &nokocore.MapAny{
+			"priceChanges": priceChangeResults,
+		})
+	}
+}
+
+func GetPriceChangeById(DB *gorm.DB) echo.HandlerFunc {
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var priceChangeID string
+		var priceChange *models2.PriceChange
+		nokocore.KeepVoid(err, priceChangeID, priceChange)
+
+		priceChangeID = ctx.Param("priceChangeId")
+		if err = sqlx.ValidateUUID(priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'price_change_id'.", nil)
+		}
+
+		if priceChange, err = priceChangeRepository.SafePreFirst(priceChangePreloads, "uuid = ?", priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price change.", nil)
+		}
+
+		if priceChange == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Price change not found.", nil)
+		}
+
+		priceChangeResult := schemas2.ToPriceChangeResult(priceChange)
+		return extras.NewMessageBodyOk(ctx, "Successfully get price change.", &nokocore.MapAny{
+			"priceChange": priceChangeResult,
+		})
+	}
+}
+
+func CreatePriceChange(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var product *models2.Product
+		var products []models2.Product
+		nokocore.KeepVoid(err, product, products)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		priceChangeBody := new(schemas2.PriceChangeBody)
+		if err = ctx.Bind(priceChangeBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(priceChangeBody); err != nil {
+			return err
+		}
+
+		if len(priceChangeBody.Items) == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Price change items are required.", nil)
+		}
+
+		priceChange := schemas2.ToPriceChangeModel(priceChangeBody)
+		priceChange.UserID = jwtAuthInfo.User.ID
+
+		// only one item for each product
+		productIDs := make(map[uint]bool)
+		for i, priceChangeItemBody := range priceChangeBody.Items {
+			nokocore.KeepVoid(i)
+
+			if err = ctx.Validate(&priceChangeItemBody); err != nil {
+				return err
+			}
+
+			pricingRule := strings.ToLower(priceChangeItemBody.PricingRule)
+			if pricingRule != "" && !models2.IsPricingRule(pricingRule) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid pricing rule.", nil)
+			}
+
+			if pricingRule == models2.PricingRuleCostPlus && priceChangeItemBody.SalePrice != "" {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Sale price is only for fixed pricing rule.", nil)
+			}
+
+			if pricingRule == models2.PricingRuleFixed && priceChangeItemBody.SalePrice == "" {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Sale price is required for fixed pricing rule.", nil)
+			}
+
+			if pricingRule == "" && priceChangeItemBody.PurchasePrice == "" && priceChangeItemBody.SalePrice == "" {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Price change item has no changes.", nil)
+			}
+
+			if product, err = productRepository.SafeFirst("uuid = ?", priceChangeItemBody.ProductID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+			}
+
+			if product == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+			}
+
+			if productIDs[product.ID] {
+				return extras.NewMessageBodyConflict(ctx, "Product is already in price change.", nil)
+			}
+
+			productIDs[product.ID] = true
+			products = append(products, *product)
+			priceChangeItem := schemas2.ToPriceChangeItemModel(&priceChangeItemBody, product)
+			priceChange.Items = append(priceChange.Items, *priceChangeItem)
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			priceChangeRepository := repositories2.NewPriceChangeRepository(tx)
+			priceChangeItemRepository := repositories2.NewPriceChangeItemRepository(tx)
+
+			// items are created one by one
+			priceChangeItems := priceChange.Items
+			priceChange.Items = nil
+			if err = priceChangeRepository.Create(priceChange); err != nil {
+				return err
+			}
+
+			for i := range priceChangeItems {
+				priceChangeItems[i].PriceChangeID = priceChange.ID
+
+				if err = priceChangeItemRepository.Create(&priceChangeItems[i]); err != nil {
+					return err
+				}
+			}
+
+			priceChange.Items = priceChangeItems
+			return nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create price change.", nil)
+		}
+
+		// associations are injected after create, never saved
+		for i := range priceChange.Items {
+			priceChange.Items[i].Product = products[i]
+		}
+
+		priceChange.User = *jwtAuthInfo.User
+		priceChangeResult := schemas2.ToPriceChangeResult(priceChange)
+		return extras.NewMessageBodyOk(ctx, "Successfully create price change.", &nokocore.MapAny{
+			"priceChange": priceChangeResult,
+		})
+	}
+}
+
+func PreviewPriceChange(DB *gorm.DB) echo.HandlerFunc {
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(DB)
+	priceChangeService := pricing.NewPriceChangeService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var priceChangeID string
+		var priceChange *models2.PriceChange
+		var products []models2.Product
+		nokocore.KeepVoid(err, priceChangeID, priceChange, products)
+
+		priceChangeID = ctx.Param("priceChangeId")
+		if err = sqlx.ValidateUUID(priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'price_change_id'.", nil)
+		}
+
+		if priceChange, err = priceChangeRepository.SafePreFirst(priceChangePreloads, "uuid = ?", priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price change.", nil)
+		}
+
+		if priceChange == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Price change not found.", nil)
+		}
+
+		if products, err = priceChangeService.Preview(priceChange); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to preview price change.", nil)
+		}
+
+		priceChangeResult := schemas2.ToPriceChangeResult(priceChange)
+		priceChangePreviewResults := schemas2.ToPriceChangePreviewResults(priceChange.Items, products)
+		return extras.NewMessageBodyOk(ctx, "Successfully preview price change.", &nokocore.MapAny{
+			"priceChange": priceChangeResult,
+			"preview":     priceChangePreviewResults,
+		})
+	}
+}
+
+func CancelPriceChange(DB *gorm.DB) echo.HandlerFunc {
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var priceChangeID string
+		var priceChange *models2.PriceChange
+		nokocore.KeepVoid(err, priceChangeID, priceChange)
+
+		priceChangeID = ctx.Param("priceChangeId")
+		if err = sqlx.ValidateUUID(priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'price_change_id'.", nil)
+		}
+
+		if priceChange, err = priceChangeRepository.SafePreFirst(priceChangePreloads, "uuid = ?", priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price change.", nil)
+		}
+
+		if priceChange == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Price change not found.", nil)
+		}
+
+		// scheduler may apply it at the same time
+		now := nokocore.GetTimeUtcNow()
+		stmt := DB.Model(&models2.PriceChange{}).Where("id = ? AND status = ?", priceChange.ID, models2.PriceChangeStatusScheduled)
+		stmt = stmt.Updates(map[string]any{
+			"status":       models2.PriceChangeStatusCancelled,
+			"cancelled_at": now,
+		})
+
+		if err = stmt.Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to cancel price change.", nil)
+		}
+
+		if stmt.RowsAffected == 0 {
+			return extras.NewMessageBodyConflict(ctx, "Only scheduled price change can be cancelled.", nil)
+		}
+
+		priceChange.Status = models2.PriceChangeStatusCancelled
+		priceChange.CancelledAt = sql.NullTime{Time: now, Valid: true}
+		priceChangeResult := schemas2.ToPriceChangeResult(priceChange)
+		return extras.NewMessageBodyOk(ctx, "Successfully cancel price change.", &nokocore.MapAny{
+			"priceChange": priceChangeResult,
+		})
+	}
+}
+
+func RevertPriceChange(DB *gorm.DB) echo.HandlerFunc {
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(DB)
+	priceChangeService := pricing.NewPriceChangeService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var priceChangeID string
+		var priceChange *models2.PriceChange
+		nokocore.KeepVoid(err, priceChangeID, priceChange)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		priceChangeID = ctx.Param("priceChangeId")
+		if err = sqlx.ValidateUUID(priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'price_change_id'.", nil)
+		}
+
+		if priceChange, err = priceChangeRepository.SafeFirst("uuid = ?", priceChangeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price change.", nil)
+		}
+
+		if priceChange == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Price change not found.", nil)
+		}
+
+		if err = priceChangeService.RevertChange(priceChange, jwtAuthInfo.User.ID); err != nil {
+			if errors.Is(err, pricing.ErrPriceChangeNotApplied) {
+				return extras.NewMessageBodyConflict(ctx, "Only applied price change can be reverted.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to revert price change.", nil)
+		}
+
+		// previous prices are stored by apply
+		if priceChange, err = priceChangeRepository.SafePreFirst(priceChangePreloads, "id = ?", priceChange.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get price change.", nil)
+		}
+
+		priceChangeResult := schemas2.ToPriceChangeResult(priceChange)
+		return extras.NewMessageBodyOk(ctx, "Successfully revert price change.", &nokocore.MapAny{
+			"priceChange": priceChangeResult,
+		})
+	}
+}
+
+func PriceChangeController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/price-changes", GetAllPriceChanges(DB), middlewares.RequirePermission(permissions.PriceRead))
+	group.POST("/price-change", CreatePriceChange(DB), middlewares.RequirePermission(permissions.PriceWrite))
+	group.GET("/price-change/:priceChangeId", GetPriceChangeById(DB), middlewares.RequirePermission(permissions.PriceRead))
+	group.GET("/price-change/:priceChangeId/preview", PreviewPriceChange(DB), middlewares.RequirePermission(permissions.PriceRead))
+	group.POST("/price-change/:priceChangeId/cancel", CancelPriceChange(DB), middlewares.RequirePermission(permissions.PriceWrite))
+	group.POST("/price-change/:priceChangeId/revert", RevertPriceChange(DB), middlewares.RequirePermission(permissions.PriceWrite))
+
+	return group
+}
@@ -3,36 +3,39 @@ package controllers
 import (
 	"fmt"
 	"github.com/labstack/echo/v4"
-	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"nokowebapi/apis/extras"
-	"nokowebapi/apis/utils"
+	"nokowebapi/apis/middlewares"
 	"nokowebapi/console"
 	"nokowebapi/nokocore"
 	"nokowebapi/sqlx"
+	"pharma-cash-go/app/costing"
+	"pharma-cash-go/app/locations"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/pricing"
+	"pharma-cash-go/app/registers"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
 )
 
 func CreateProduct(DB *gorm.DB) echo.HandlerFunc {
 
 	packageRepository := repositories2.NewPackageRepository(DB)
 	unitRepository := repositories2.NewUnitRepository(DB)
-	productRepository := repositories2.NewProductRepository(DB)
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	pricingService := pricing.NewPricingService(DB)
 
 	return func(ctx echo.Context) error {
 		var err error
 		var packageModel *models2.Package
 		var unit *models2.Unit
-		nokocore.KeepVoid(err, packageModel, unit)
+		var barcode *models2.Barcode
+		nokocore.KeepVoid(err, packageModel, unit, barcode)
 
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		productBody := new(schemas2.ProductBody)
 		if err = ctx.Bind(&productBody); err != nil {
 			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
@@ -44,6 +47,28 @@ func CreateProduct(DB *gorm.DB) echo.HandlerFunc {
 
 		product := schemas2.ToProductModel(productBody)
 
+		if !models2.IsPricingRule(product.PricingRule) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid pricing rule.", nil)
+		}
+
+		if product.PricingRule == models2.PricingRuleFixed && product.SalePrice.Sign() <= 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Sale price is required by fixed pricing rule.", nil)
+		}
+
+		if !models2.IsDrugClass(product.DrugClass) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid drug class.", nil)
+		}
+
+		// barcode is unique across all product barcodes
+		if barcode, err = barcodeRepository.First("code = ?", product.Barcode); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if barcode != nil {
+			return extras.NewMessageBodyConflict(ctx, "Barcode already exists.", nil)
+		}
+
 		if packageID := productBody.PackageID; packageID != "" {
 			if packageModel, err = packageRepository.SafeFirst("uuid = ?", packageID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -110,18 +135,39 @@ func CreateProduct(DB *gorm.DB) echo.HandlerFunc {
 		product.UnitID = unit.ID
 		product.Unit = *unit
 
-		// tax product price and income
-		//margin := product.PurchasePrice.Mul(decimal.NewFromFloat(product.ProfitMargin))
-		//result := product.PurchasePrice.Add(margin)
-		//tax := result.Mul(decimal.NewFromFloat(product.VAT))
-		//product.SalePrice = result.Add(tax)
+		pricingService.Apply(product)
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			productRepository := repositories2.NewProductRepository(tx)
+			pricingService := pricing.NewPricingService(tx)
+			registerService := registers.NewRegisterService(tx)
+			costingService := costing.NewCostingService(tx)
+			locationService := locations.NewLocationService(tx)
+
+			if err = productRepository.Create(product); err != nil {
+				return err
+			}
+
+			if err = pricingService.Record(product, nil, pricing.SourceCreate, jwtAuthInfo.User.ID); err != nil {
+				return err
+			}
+
+			if err = registerService.Sync(product, jwtAuthInfo.User.ID, "opening stock"); err != nil {
+				return err
+			}
+
+			if err = costingService.Sync(product, "opening stock"); err != nil {
+				return err
+			}
 
-		// only tax product price
-		margin := product.PurchasePrice.Mul(decimal.NewFromFloat(product.ProfitMargin))
-		tax := product.PurchasePrice.Mul(decimal.NewFromFloat(product.VAT))
-		product.SalePrice = product.PurchasePrice.Add(margin).Add(tax)
+			if err = locationService.Sync(product); err != nil {
+				return err
+			}
 
-		if err = productRepository.Create(product); err != nil {
+			return nil
+		})
+
+		if err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create product.", nil)
 		}
@@ -136,24 +182,86 @@ func CreateProduct(DB *gorm.DB) echo.HandlerFunc {
 func GetAllProductsByName(DB *gorm.DB) echo.HandlerFunc {
 
 	productRepository := repositories2.NewProductRepository(DB)
+	productSearchRepository := repositories2.NewProductSearchRepository(DB)
 
 	return func(ctx echo.Context) error {
 		var err error
 		var products []models2.Product
-		nokocore.KeepVoid(err, products)
+		var rows []repositories2.ProductSearchRow
+		nokocore.KeepVoid(err, products, rows)
 
 		keywords := extras.ParseQueryToString(ctx, "keywords")
+		mode := strings.ToLower(extras.ParseQueryToString(ctx, "mode"))
 
 		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
 
-		preloads := []string{"Categories", "Package", "Unit"}
-		query := "brand LIKE ? OR product_name LIKE ? OR barcode LIKE ?"
-		args := []any{"%" + keywords + "%", "%" + keywords + "%", "%" + keywords + "%"}
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit", "Attachments"}
+
+		// full-text search requires fts5, ranking would be meaningless on like queries
+		if mode == "fts" && !productSearchRepository.Available() {
+			console.Error("panic: products full-text search is not available, build with the 'sqlite_fts5' tag")
+			return extras.NewMessageBodyServiceUnavailable(ctx, "Full-text search is not available.", nil)
+		}
+
+		// keywords shorter than a trigram are unable to be matched, falls back to like queries
+		if mode == "fts" && repositories2.ToProductSearchMatch(keywords) != "" {
+			if rows, err = productSearchRepository.Search(keywords, pagination.Offset, pagination.Limit); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get products.", nil)
+			}
+
+			size := len(rows)
+			productIds := make([]uint, size)
+			for i, row := range rows {
+				productIds[i] = row.ProductID
+			}
+
+			if size > 0 {
+				if products, err = productRepository.SafePreMany(preloads, 0, -1, "id IN ?", productIds); err != nil {
+					console.Error(fmt.Sprintf("panic: %s", err.Error()))
+					return extras.NewMessageBodyInternalServerError(ctx, "Failed to get products.", nil)
+				}
+			}
+
+			productMap := make(map[uint]*models2.Product, len(products))
+			for i := range products {
+				productMap[products[i].ID] = &products[i]
+			}
+
+			// keep ranking order from search results
+			productSearchResults := make([]schemas2.ProductSearchResult, 0, size)
+			for i, row := range rows {
+				nokocore.KeepVoid(i)
+				if product, ok := productMap[row.ProductID]; ok {
+					productSearchResults = append(productSearchResults, schemas2.ToProductSearchResult(product, row.Score))
+				}
+			}
+
+			return extras.NewMessageBodyOk(ctx, "Successfully get products.", &nokocore.MapAny{
+				"products": productSearchResults,
+			})
+		}
+
+		query := "brand LIKE ? OR product_name LIKE ? OR barcode LIKE ? OR id IN (SELECT product_id FROM barcodes WHERE code LIKE ? AND active = ?)"
+		args := []any{"%" + keywords + "%", "%" + keywords + "%", "%" + keywords + "%", "%" + keywords + "%", true}
 		if products, err = productRepository.SafePreMany(preloads, pagination.Offset, pagination.Limit, query, args...); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get products.", nil)
 		}
 
+		if mode == "fts" {
+			size := len(products)
+			productSearchResults := make([]schemas2.ProductSearchResult, size)
+			for i, product := range products {
+				nokocore.KeepVoid(i)
+				productSearchResults[i] = schemas2.ToProductSearchResult(&product, 0)
+			}
+
+			return extras.NewMessageBodyOk(ctx, "Successfully get products.", &nokocore.MapAny{
+				"products": productSearchResults,
+			})
+		}
+
 		size := len(products)
 		productResults := make([]schemas2.ProductResult, size)
 		for i, product := range products {
@@ -184,7 +292,7 @@ func GetProductDetailByProductId(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
 		}
 
-		preloads := []string{"Categories", "Package", "Unit"}
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit", "Barcodes", "Attachments"}
 		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
@@ -202,6 +310,8 @@ func UpdateProduct(DB *gorm.DB) echo.HandlerFunc {
 	productRepository := repositories2.NewProductRepository(DB)
 	packageRepository := repositories2.NewPackageRepository(DB)
 	unitRepository := repositories2.NewUnitRepository(DB)
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	pricingService := pricing.NewPricingService(DB)
 
 	return func(ctx echo.Context) error {
 		var err error
@@ -210,7 +320,10 @@ func UpdateProduct(DB *gorm.DB) echo.HandlerFunc {
 		var newProduct *models2.Product
 		var packageModel *models2.Package
 		var unit *models2.Unit
-		nokocore.KeepVoid(err, productID, product, packageModel, unit)
+		var barcode *models2.Barcode
+		nokocore.KeepVoid(err, productID, product, packageModel, unit, barcode)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
 		productID = ctx.Param("productId")
 		if err = sqlx.ValidateUUID(productID); err != nil {
@@ -230,12 +343,69 @@ func UpdateProduct(DB *gorm.DB) echo.HandlerFunc {
 
 		newProduct = schemas2.ToProductModel(productBody)
 
-		preloads := []string{"Categories", "Package", "Unit"}
+		if !models2.IsPricingRule(newProduct.PricingRule) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid pricing rule.", nil)
+		}
+
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit", "Attachments"}
 		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", err.Error())
 		}
 
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		// fixed price is kept if sale price is not given
+		if newProduct.PricingRule == models2.PricingRuleFixed && newProduct.SalePrice.Sign() <= 0 {
+			newProduct.SalePrice = product.SalePrice
+		}
+
+		if newProduct.PricingRule == models2.PricingRuleFixed && newProduct.SalePrice.Sign() <= 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Sale price is required by fixed pricing rule.", nil)
+		}
+
+		// drug master data is kept if not given
+		if productBody.DrugClass == "" {
+			newProduct.DrugClass = product.DrugClass
+		}
+
+		if !models2.IsDrugClass(newProduct.DrugClass) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid drug class.", nil)
+		}
+
+		if newProduct.DosageForm == "" {
+			newProduct.DosageForm = product.DosageForm
+		}
+
+		if newProduct.Route == "" {
+			newProduct.Route = product.Route
+		}
+
+		if newProduct.Manufacturer == "" {
+			newProduct.Manufacturer = product.Manufacturer
+		}
+
+		if newProduct.AuthorizationNo == "" {
+			newProduct.AuthorizationNo = product.AuthorizationNo
+		}
+
+		// unit scale is the factor of the package unit level
+		if err = product.CheckUnitScale(newProduct.UnitScale); err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid unit scale.", err.Error())
+		}
+
+		// barcode is unique across all product barcodes
+		if barcode, err = barcodeRepository.First("code = ? AND product_id <> ?", newProduct.Barcode, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", err.Error())
+		}
+
+		if barcode != nil {
+			return extras.NewMessageBodyConflict(ctx, "Barcode already exists.", nil)
+		}
+
 		if packageID := productBody.PackageID; packageID != "" {
 			if packageModel, err = packageRepository.SafeFirst("uuid = ?", packageID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -305,11 +475,48 @@ func UpdateProduct(DB *gorm.DB) echo.HandlerFunc {
 		newProduct.UUID = product.UUID
 		newProduct.CreatedAt = product.CreatedAt
 
-		if err = productRepository.SafeUpdate(newProduct, "id = ?", product.ID); err != nil {
+		pricingService.Apply(newProduct)
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			productRepository := repositories2.NewProductRepository(tx)
+			pricingService := pricing.NewPricingService(tx)
+			registerService := registers.NewRegisterService(tx)
+			costingService := costing.NewCostingService(tx)
+			locationService := locations.NewLocationService(tx)
+
+			if err = productRepository.SafeUpdate(newProduct, "id = ?", product.ID); err != nil {
+				return err
+			}
+
+			if err = pricingService.Record(newProduct, product, pricing.SourceUpdate, jwtAuthInfo.User.ID); err != nil {
+				return err
+			}
+
+			if err = registerService.Sync(newProduct, jwtAuthInfo.User.ID, "stock update"); err != nil {
+				return err
+			}
+
+			if err = costingService.Sync(newProduct, "stock update"); err != nil {
+				return err
+			}
+
+			if err = locationService.Sync(newProduct); err != nil {
+				return err
+			}
+
+			return nil
+		})
+
+		if err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update product.", err.Error())
 		}
 
+		if product, err = productRepository.SafePreFirst(preloads, "id = ?", product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", err.Error())
+		}
+
 		return extras.NewMessageBodyOk(ctx, "Successfully update product.", &nokocore.MapAny{
 			"product": schemas2.ToProductResult(product),
 		})
@@ -334,11 +541,17 @@ func DeleteProduct(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
 		}
 
-		if product, err = productRepository.First("uuid = ?", productID); err != nil {
+		// categories are cleared before save, restored products keep them
+		preloads := []string{"Categories"}
+		if product, err = productRepository.PreFirst(preloads, "uuid = ?", productID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
 		}
 
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
 		if !forced && product.DeletedAt.Valid {
 			return extras.NewMessageBodyOk(ctx, "Product already deleted.", nil)
 		}
@@ -363,11 +576,11 @@ func DeleteProduct(DB *gorm.DB) echo.HandlerFunc {
 
 func ProductController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
-	group.GET("/products", GetAllProductsByName(DB))
-	group.POST("/product", CreateProduct(DB))
-	group.GET("/product/:productId", GetProductDetailByProductId(DB))
-	group.PUT("/product/:productId", UpdateProduct(DB))
-	group.DELETE("/product/:productId", DeleteProduct(DB))
+	group.GET("/products", GetAllProductsByName(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.POST("/product", CreateProduct(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.GET("/product/:productId", GetProductDetailByProductId(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.PUT("/product/:productId", UpdateProduct(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.DELETE("/product/:productId", DeleteProduct(DB), middlewares.RequirePermission(permissions.ProductWrite))
 
 	return group
 }
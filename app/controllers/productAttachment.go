@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"path/filepath"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"pharma-cash-go/app/storages"
+	utils2 "pharma-cash-go/app/utils"
+	"strings"
+)
+
+// attachmentContentTypes, images get thumbnails, documents are stored as is
+var attachmentContentTypes = map[string]string{
+	"image/jpeg":      models2.AttachmentKindImage,
+	"image/png":       models2.AttachmentKindImage,
+	"image/gif":       models2.AttachmentKindImage,
+	"image/webp":      models2.AttachmentKindImage,
+	"application/pdf": models2.AttachmentKindDocument,
+}
+
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+func UploadProductAttachment(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var fileHeader *multipart.FileHeader
+		var file multipart.File
+		var data []byte
+		var thumbnail []byte
+		nokocore.KeepVoid(err, productID, product, fileHeader, file, data, thumbnail)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		config := storages.GetConfig()
+		storage := storages.GetStorage()
+
+		if fileHeader, err = ctx.FormFile("file"); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if fileHeader.Size > config.MaxSize {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("File size exceeds %s.", nokocore.ToFileSizeFormat(config.MaxSize)), nil)
+		}
+
+		if file, err = fileHeader.Open(); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", nil)
+		}
+
+		defer file.Close()
+
+		if data, err = io.ReadAll(io.LimitReader(file, config.MaxSize+1)); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", nil)
+		}
+
+		if int64(len(data)) > config.MaxSize {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("File size exceeds %s.", nokocore.ToFileSizeFormat(config.MaxSize)), nil)
+		}
+
+		// never trust the client content type
+		contentType := http.DetectContentType(data)
+		kind, ok := attachmentContentTypes[contentType]
+		if !ok {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Unsupported file type.", &nokocore.MapAny{
+				"contentType": contentType,
+			})
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Attachments"}, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		fileID := nokocore.NewUUID()
+		storageKey := fmt.Sprintf("products/%s/%s%s", product.UUID, fileID, attachmentExtensions[contentType])
+
+		// webp has no decoder in the standard library, serve it without thumbnail
+		var thumbnailKey string
+		if kind == models2.AttachmentKindImage {
+			if thumbnail, err = utils2.MakeThumbnail(bytes.NewReader(data), utils2.ThumbnailSize); err != nil {
+				console.Warn(fmt.Sprintf("unable to make thumbnail, %s", err.Error()))
+			} else {
+				thumbnailKey = fmt.Sprintf("products/%s/%s_thumb.jpg", product.UUID, fileID)
+			}
+		}
+
+		// first image become primary image
+		isPrimary := kind == models2.AttachmentKindImage && schemas2.ToPrimaryImage(product.Attachments) == nil
+		if value := ctx.FormValue("primary"); value != "" && kind == models2.AttachmentKindImage {
+			isPrimary = nokocore.ParseEnvToBool(value)
+		}
+
+		if err = storage.Put(storageKey, bytes.NewReader(data)); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to store attachment.", nil)
+		}
+
+		if thumbnailKey != "" {
+			if err = storage.Put(thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				nokocore.KeepVoid(storage.Delete(storageKey))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to store attachment.", nil)
+			}
+		}
+
+		attachment := &models2.ProductAttachment{
+			ProductID:    product.ID,
+			Kind:         kind,
+			FileName:     filepath.Base(fileHeader.Filename),
+			ContentType:  contentType,
+			Size:         int64(len(data)),
+			StorageKey:   storageKey,
+			ThumbnailKey: thumbnailKey,
+			IsPrimary:    isPrimary,
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			attachmentRepository := repositories2.NewProductAttachmentRepository(tx)
+
+			if isPrimary {
+				if err = tx.Model(&models2.ProductAttachment{}).Where("product_id = ?", product.ID).Update("is_primary", false).Error; err != nil {
+					return err
+				}
+			}
+
+			return attachmentRepository.Create(attachment)
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			nokocore.KeepVoid(storage.Delete(storageKey))
+			if thumbnailKey != "" {
+				nokocore.KeepVoid(storage.Delete(thumbnailKey))
+			}
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create attachment.", nil)
+		}
+
+		attachmentResult := schemas2.ToProductAttachmentResult(attachment)
+		return extras.NewMessageBodyOk(ctx, "Successfully upload attachment.", &nokocore.MapAny{
+			"attachment": attachmentResult,
+		})
+	}
+}
+
+func GetAllProductAttachments(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	attachmentRepository := repositories2.NewProductAttachmentRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var attachments []models2.ProductAttachment
+		nokocore.KeepVoid(err, productID, product, attachments)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		kind := strings.ToLower(extras.ParseQueryToString(ctx, "kind"))
+
+		query := "product_id = ?"
+		args := []any{product.ID}
+		if kind != "" {
+			query += " AND kind = ?"
+			args = append(args, kind)
+		}
+
+		if attachments, err = attachmentRepository.SafeMany(0, -1, query, args...); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get attachments.", nil)
+		}
+
+		attachmentResults := schemas2.ToProductAttachmentResults(attachments)
+		return extras.NewMessageBodyOk(ctx, "Successfully get attachments.", &nokocore.MapAny{
+			"attachments": attachmentResults,
+		})
+	}
+}
+
+func SetPrimaryProductAttachment(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	attachmentRepository := repositories2.NewProductAttachmentRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var attachmentID string
+		var product *models2.Product
+		var attachment *models2.ProductAttachment
+		nokocore.KeepVoid(err, productID, attachmentID, product, attachment)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		attachmentID = ctx.Param("attachmentId")
+		if err = sqlx.ValidateUUID(attachmentID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'attachment_id'.", nil)
+		}
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if attachment, err = attachmentRepository.SafeFirst("uuid = ? AND product_id = ?", attachmentID, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get attachment.", nil)
+		}
+
+		if attachment == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Attachment not found.", nil)
+		}
+
+		if attachment.Kind != models2.AttachmentKindImage {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Only images can be the primary image.", nil)
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			attachmentRepository := repositories2.NewProductAttachmentRepository(tx)
+
+			if err = tx.Model(&models2.ProductAttachment{}).Where("product_id = ?", product.ID).Update("is_primary", false).Error; err != nil {
+				return err
+			}
+
+			attachment.IsPrimary = true
+			return attachmentRepository.SafeUpdate(attachment, "id = ?", attachment.ID)
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update attachment.", nil)
+		}
+
+		attachmentResult := schemas2.ToProductAttachmentResult(attachment)
+		return extras.NewMessageBodyOk(ctx, "Successfully update attachment.", &nokocore.MapAny{
+			"attachment": attachmentResult,
+		})
+	}
+}
+
+func DeleteProductAttachment(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	attachmentRepository := repositories2.NewProductAttachmentRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var attachmentID string
+		var product *models2.Product
+		var attachment *models2.ProductAttachment
+		nokocore.KeepVoid(err, productID, attachmentID, product, attachment)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		attachmentID = ctx.Param("attachmentId")
+		if err = sqlx.ValidateUUID(attachmentID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'attachment_id'.", nil)
+		}
+
+		storage := storages.GetStorage()
+
+		if product, err = productRepository.SafeFirst("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if attachment, err = attachmentRepository.SafeFirst("uuid = ? AND product_id = ?", attachmentID, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get attachment.", nil)
+		}
+
+		if attachment == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Attachment not found.", nil)
+		}
+
+		if err = attachmentRepository.Delete(attachment, "id = ?", attachment.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to delete attachment.", nil)
+		}
+
+		// files are removed after the record, orphan files are harmless
+		for i, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+			nokocore.KeepVoid(i)
+
+			if key == "" {
+				continue
+			}
+
+			if err = storage.Delete(key); err != nil {
+				console.Warn(fmt.Sprintf("unable to delete attachment file '%s', %s", key, err.Error()))
+			}
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully delete attachment.", nil)
+	}
+}
+
+// GetAttachmentFile method, serves stored attachment files, image tags are unable to send bearer tokens.
+func GetAttachmentFile(DB *gorm.DB) echo.HandlerFunc {
+
+	attachmentRepository := repositories2.NewProductAttachmentRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var attachment *models2.ProductAttachment
+		var reader io.ReadCloser
+		nokocore.KeepVoid(err, attachment, reader)
+
+		key := ctx.Param("*")
+		if key == "" {
+			return extras.NewMessageBodyNotFound(ctx, "File not found.", nil)
+		}
+
+		storage := storages.GetStorage()
+
+		// only serve files known by attachments
+		if attachment, err = attachmentRepository.SafeFirst("storage_key = ? OR thumbnail_key = ?", key, key); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get attachment.", nil)
+		}
+
+		if attachment == nil {
+			return extras.NewMessageBodyNotFound(ctx, "File not found.", nil)
+		}
+
+		contentType := attachment.ContentType
+		if key == attachment.ThumbnailKey {
+			contentType = "image/jpeg"
+		}
+
+		if reader, err = storage.Open(key); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyNotFound(ctx, "File not found.", nil)
+		}
+
+		defer reader.Close()
+
+		header := ctx.Response().Header()
+		header.Set("Cache-Control", "public, max-age=86400")
+		if attachment.Kind == models2.AttachmentKindDocument {
+			header.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.FileName))
+		}
+
+		return ctx.Stream(http.StatusOK, contentType, reader)
+	}
+}
+
+func ProductAttachmentController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/product/:productId/attachments", GetAllProductAttachments(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.POST("/product/:productId/attachment", UploadProductAttachment(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.PUT("/product/:productId/attachment/:attachmentId/primary", SetPrimaryProductAttachment(DB), middlewares.RequirePermission(permissions.ProductWrite))
+	group.DELETE("/product/:productId/attachment/:attachmentId", DeleteProductAttachment(DB), middlewares.RequirePermission(permissions.ProductWrite))
+
+	return group
+}
+
+func FileController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/files/*", GetAttachmentFile(DB))
+
+	return group
+}
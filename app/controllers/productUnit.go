@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+)
+
+func GetAllUnitsByProductId(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		nokocore.KeepVoid(err, productID, product)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		preloads := []string{"Package", "Unit", "UnitLevels.Unit"}
+		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		productUnitResults := schemas2.ToProductUnitResults(product.GetUnitLevels())
+		stockResults := schemas2.ToUnitQuantityResults(product.ToUnitQuantities(product.Stock))
+		return extras.NewMessageBodyOk(ctx, "Successfully get units.", &nokocore.MapAny{
+			"units":      productUnitResults,
+			"stock":      product.Stock,
+			"stockUnits": stockResults,
+		})
+	}
+}
+
+func UpdateUnitsByProductId(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	unitRepository := repositories2.NewUnitRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var unit *models2.Unit
+		nokocore.KeepVoid(err, productID, product, unit)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		productUnitsBody := new(schemas2.ProductUnitsBody)
+		if err = ctx.Bind(productUnitsBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(productUnitsBody); err != nil {
+			return err
+		}
+
+		// units are ordered from the base unit
+		var levels []models2.ProductUnit
+		for i, productUnitBody := range productUnitsBody.Units {
+			if err = ctx.Validate(&productUnitBody); err != nil {
+				return err
+			}
+
+			unit = nil
+			if unitID := productUnitBody.UnitID; unitID != "" {
+				if unit, err = unitRepository.SafeFirst("uuid = ?", unitID); err != nil {
+					console.Error(fmt.Sprintf("panic: %s", err.Error()))
+					return extras.NewMessageBodyInternalServerError(ctx, "Failed to get unit.", nil)
+				}
+			}
+
+			// can be automatic build
+			if unit == nil {
+				if unitType := nokocore.ToTitleCase(productUnitBody.UnitType); unitType != "" {
+					if unit, err = unitRepository.SafeFirst("unit_type = ?", unitType); err != nil {
+						console.Error(fmt.Sprintf("panic: %s", err.Error()))
+						return extras.NewMessageBodyInternalServerError(ctx, "Failed to get unit.", nil)
+					}
+
+					if unit == nil {
+						unit = &models2.Unit{
+							UnitType: unitType,
+						}
+						if err = unitRepository.Create(unit); err != nil {
+							console.Error(fmt.Sprintf("panic: %s", err.Error()))
+							return extras.NewMessageBodyInternalServerError(ctx, "Failed create unit.", nil)
+						}
+					}
+
+				} else {
+					return extras.NewMessageBodyNotFound(ctx, "Unit not found.", nil)
+				}
+			}
+
+			levels = append(levels, models2.ProductUnit{
+				UnitID: unit.ID,
+				Unit:   *unit,
+				Level:  i,
+				Factor: productUnitBody.Factor,
+			})
+		}
+
+		if err = models2.CheckUnitLevels(levels); err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid unit levels.", err.Error())
+		}
+
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels"}
+		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			productRepository := repositories2.NewProductRepository(tx)
+			productUnitRepository := repositories2.NewProductUnitRepository(tx)
+
+			for i, productUnit := range product.UnitLevels {
+				nokocore.KeepVoid(i)
+
+				if err = productUnitRepository.Delete(&productUnit, "id = ?", productUnit.ID); err != nil {
+					return err
+				}
+			}
+
+			for i := range levels {
+				levels[i].ProductID = product.ID
+
+				if err = productUnitRepository.Create(&levels[i]); err != nil {
+					return err
+				}
+			}
+
+			// stock stays in base units, package total and unit extra follow the top level
+			product.UnitID = levels[0].UnitID
+			product.Unit = levels[0].Unit
+			product.UnitScale = levels[len(levels)-1].Factor
+			product.UnitLevels = nil
+
+			if err = productRepository.SafeUpdate(product, "id = ?", product.ID); err != nil {
+				return err
+			}
+
+			product.UnitLevels = levels
+			return nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update units.", nil)
+		}
+
+		productUnitResults := schemas2.ToProductUnitResults(product.GetUnitLevels())
+		stockResults := schemas2.ToUnitQuantityResults(product.ToUnitQuantities(product.Stock))
+		return extras.NewMessageBodyOk(ctx, "Successfully update units.", &nokocore.MapAny{
+			"units":      productUnitResults,
+			"stock":      product.Stock,
+			"stockUnits": stockResults,
+		})
+	}
+}
+
+func ProductUnitController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/product/:productId/units", GetAllUnitsByProductId(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.PUT("/product/:productId/units", UpdateUnitsByProductId(DB), middlewares.RequirePermission(permissions.ProductWrite))
+
+	return group
+}
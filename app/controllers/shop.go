@@ -8,13 +8,19 @@ import (
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"nokowebapi/apis/extras"
-	"nokowebapi/apis/utils"
+	"nokowebapi/apis/middlewares"
 	"nokowebapi/console"
 	"nokowebapi/nokocore"
 	"nokowebapi/sqlx"
+	"pharma-cash-go/app/costing"
+	"pharma-cash-go/app/interactions"
+	"pharma-cash-go/app/locations"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/registers"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
 )
 
 func GetAllCarts(DB *gorm.DB) echo.HandlerFunc {
@@ -32,10 +38,6 @@ func GetAllCarts(DB *gorm.DB) echo.HandlerFunc {
 
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		user := jwtAuthInfo.User
 		userID := user.ID
 
@@ -73,7 +75,7 @@ func GetAllCarts(DB *gorm.DB) echo.HandlerFunc {
 		}
 
 		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
-		preloads := []string{"Product", "Product.Categories", "Product.Package", "Product.Unit"}
+		preloads := []string{"Product", "Product.Categories", "Product.Package", "Product.Unit", "Product.UnitLevels.Unit", "Product.Attachments"}
 		if carts, err = cartRepository.SafePreMany(preloads, pagination.Offset, pagination.Limit, "user_id = ? AND  transaction_id = ? AND closed = FALSE", userID, transaction.ID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get carts.", nil)
@@ -108,16 +110,13 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 		var product *models2.Product
 		var transaction *models2.Transaction
 		var carts []models2.Cart
-		nokocore.KeepVoid(err, transactionID, product, transaction, carts)
+		var quantity int
+		nokocore.KeepVoid(err, transactionID, product, transaction, carts, quantity)
 
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 		user := jwtAuthInfo.User
 		userID := user.ID
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		if productID = extras.ParseQueryToString(ctx, "product_id"); productID != "" {
 			if err = sqlx.ValidateUUID(productID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -152,7 +151,7 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 			return err
 		}
 
-		preloads := []string{"Categories", "Package", "Unit"}
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit", "Attachments"}
 		if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", productID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Unable to get product.", nil)
@@ -162,6 +161,12 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Product not found.", nil)
 		}
 
+		// quantities at any unit level, kept in base units
+		if quantity, err = schemas2.ToCartQuantity(cartBody, product); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid cart quantities.", err.Error())
+		}
+
 		if transactionID != "" {
 			if transaction, err = transactionRepository.SafeFirst("uuid = ? AND user_id = ? AND verified = FALSE", transactionID, userID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -196,24 +201,20 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 		err = DB.Transaction(func(tx *gorm.DB) error {
 			cartRepository := repositories2.NewCartRepository(tx)
 			transactionRepository := repositories2.NewTransactionRepository(tx)
+			interactionService := interactions.NewInteractionService(tx)
 
-			cart := schemas2.ToCartModelWithProductModel(cartBody, product)
+			cart := schemas2.ToCartModelWithProductModel(cartBody, product, quantity)
 
 			// set owner and transaction
 			cart.UserID = userID
 			cart.TransactionID = transaction.ID
 
-			unitTotal := product.UnitScale * cart.PackageTotal
-			unitTotal += cart.UnitExtra
+			unitTotal := cart.Quantity
 
 			// inject current product
 			cart.ProductID = product.ID
 			cart.Product = *product
 
-			qty := decimal.NewFromInt(int64(unitTotal))
-			pay := product.SalePrice.Mul(qty)
-			cart.SubTotal = pay
-
 			var statusText string
 			var check *models2.Cart
 
@@ -221,6 +222,22 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 				return err
 			}
 
+			// price effective when the line was added, scheduled price changes
+			// never reprice lines already in the cart
+			unitPrice := product.SalePrice
+			if check != nil {
+				if check.UnitPrice.Sign() > 0 {
+					unitPrice = check.UnitPrice
+				} else if check.Quantity > 0 {
+					unitPrice = check.SubTotal.Div(decimal.NewFromInt(int64(check.Quantity)))
+				}
+			}
+
+			qty := decimal.NewFromInt(int64(unitTotal))
+			pay := unitPrice.Mul(qty)
+			cart.UnitPrice = unitPrice
+			cart.SubTotal = pay
+
 			if check != nil {
 				// inject base model values
 				cart.ID = check.ID
@@ -271,13 +288,21 @@ func ProductCheckout(DB *gorm.DB) echo.HandlerFunc {
 				return err
 			}
 
+			// warnings of the whole basket, severe ones are acknowledged by a pharmacist
+			var warnings []interactions.InteractionWarning
+			if warnings, err = interactionService.CheckTransaction(transaction); err != nil {
+				return err
+			}
+
 			cartResult := schemas2.ToCartResult(cart)
 			transactionResult := schemas2.ToTransactionResult(transaction)
 			return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully %s cart.", statusText), &nokocore.MapAny{
-				"cart":        cartResult,
-				"transaction": transactionResult,
-				"unitTotal":   unitTotal,
-				"total":       total,
+				"cart":                    cartResult,
+				"transaction":             transactionResult,
+				"unitTotal":               unitTotal,
+				"total":                   total,
+				"interactions":            warnings,
+				"acknowledgementRequired": interactions.RequiresAcknowledgement(warnings),
 			})
 		})
 
@@ -294,13 +319,17 @@ func TransactionVerification(DB *gorm.DB) echo.HandlerFunc {
 	nokocore.KeepVoid(DB)
 
 	transactionRepository := repositories2.NewTransactionRepository(DB)
+	interactionService := interactions.NewInteractionService(DB)
+	locationService := locations.NewLocationService(DB)
 
 	return func(ctx echo.Context) error {
 		var err error
 		var transactionID string
 		var transaction *models2.Transaction
 		var carts []models2.Cart
-		nokocore.KeepVoid(err, transactionID, transaction, carts)
+		var warnings []interactions.InteractionWarning
+		var location *models2.Location
+		nokocore.KeepVoid(err, transactionID, transaction, carts, warnings, location)
 
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 		user := jwtAuthInfo.User
@@ -308,10 +337,6 @@ func TransactionVerification(DB *gorm.DB) echo.HandlerFunc {
 
 		nokocore.KeepVoid(transactionRepository, userID)
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		if transactionID = extras.ParseQueryToString(ctx, "transaction_id"); transactionID != "" {
 			if err = sqlx.ValidateUUID(transactionID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -347,6 +372,23 @@ func TransactionVerification(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Transaction not found.", nil)
 		}
 
+		if warnings, err = interactionService.CheckTransaction(transaction); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to check drug interactions.", nil)
+		}
+
+		if interactions.RequiresAcknowledgement(warnings) {
+			return extras.NewMessageBodyConflict(ctx, "Severe drug interactions must be acknowledged by a pharmacist.", &nokocore.MapAny{
+				"interactions": warnings,
+			})
+		}
+
+		// sold units are taken from the selling location only
+		if location, err = locationService.GetSelling(); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get selling location.", nil)
+		}
+
 		pay := decimal.RequireFromString(transactionBody.Pay)
 		exchange := pay.Sub(transaction.Total)
 		zero := decimal.NewFromInt(0)
@@ -356,9 +398,44 @@ func TransactionVerification(DB *gorm.DB) echo.HandlerFunc {
 		}
 
 		err = DB.Transaction(func(tx *gorm.DB) error {
+			cartRepository := repositories2.NewCartRepository(tx)
 			transactionRepository := repositories2.NewTransactionRepository(tx)
+			registerService := registers.NewRegisterService(tx)
+			costingService := costing.NewCostingService(tx)
+			locationService := locations.NewLocationService(tx)
+
+			// controlled products are written to the register when dispensed
+			carts, err = cartRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+				return tx.Preload("Product").Where("user_id = ? AND transaction_id = ? AND closed = FALSE", userID, transaction.ID), nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			for i, cart := range carts {
+				nokocore.KeepVoid(i)
+
+				if err = registerService.Dispense(&cart.Product, cart.Quantity, userID, transaction.UUID.String()); err != nil {
+					return err
+				}
+
+				// cost of goods is kept on the cart line when verified
+				var costOfGoods decimal.Decimal
+				if costOfGoods, err = costingService.Consume(&cart.Product, cart.Quantity, models2.CostEntryKindSale, transaction.UUID.String()); err != nil {
+					return err
+				}
+
+				if err = tx.Model(&models2.Cart{}).Where("id = ?", cart.ID).UpdateColumn("cost_of_goods", costOfGoods).Error; err != nil {
+					return err
+				}
+
+				if err = locationService.Adjust(&cart.Product, location.ID, -cart.Quantity); err != nil {
+					return err
+				}
+			}
 
-			stmt := DB.Model(&models2.Cart{}).Where("user_id = ? AND transaction_id = ? AND closed = FALSE", userID, transaction.ID).Update("closed", true)
+			stmt := tx.Model(&models2.Cart{}).Where("user_id = ? AND transaction_id = ? AND closed = FALSE", userID, transaction.ID).Update("closed", true)
 			if err = stmt.Error; err != nil {
 				return err
 			}
@@ -391,11 +468,74 @@ func TransactionVerification(DB *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+// AcknowledgeTransactionInteractions method, pharmacists acknowledge severe drug
+// interactions of the basket before it can be verified.
+func AcknowledgeTransactionInteractions(DB *gorm.DB) echo.HandlerFunc {
+	nokocore.KeepVoid(DB)
+
+	transactionRepository := repositories2.NewTransactionRepository(DB)
+	interactionService := interactions.NewInteractionService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var transactionID string
+		var transaction *models2.Transaction
+		var warnings []interactions.InteractionWarning
+		nokocore.KeepVoid(err, transactionID, transaction, warnings)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		transactionID = extras.ParseQueryToString(ctx, "transaction_id")
+		if err = sqlx.ValidateUUID(transactionID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'transaction_id'.", nil)
+		}
+
+		transactionAcknowledgeBody := new(schemas2.TransactionAcknowledgeBody)
+		if err = ctx.Bind(transactionAcknowledgeBody); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Unable to bind request body.", nil)
+		}
+
+		if err = ctx.Validate(transactionAcknowledgeBody); err != nil {
+			return err
+		}
+
+		// baskets of any officer
+		if transaction, err = transactionRepository.SafeFirst("uuid = ? AND verified = FALSE", transactionID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Unable to get transaction.", nil)
+		}
+
+		if transaction == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Transaction not found.", nil)
+		}
+
+		// every severe interaction keeps the acknowledging pharmacist
+		note := strings.TrimSpace(transactionAcknowledgeBody.Note)
+		if warnings, err = interactionService.Acknowledge(transaction, jwtAuthInfo.User.ID, note); err != nil {
+			if errors.Is(err, interactions.ErrNothingToAcknowledge) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "No severe drug interactions to acknowledge.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to acknowledge drug interactions.", nil)
+		}
+
+		transactionResult := schemas2.ToTransactionResult(transaction)
+		return extras.NewMessageBodyOk(ctx, "Successfully acknowledged drug interactions.", &nokocore.MapAny{
+			"transaction":  transactionResult,
+			"interactions": warnings,
+		})
+	}
+}
+
 func ShopController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
-	group.GET("/carts", GetAllCarts(DB))
-	group.POST("/product/checkout", ProductCheckout(DB))
-	group.POST("/transaction/verify", TransactionVerification(DB))
+	group.GET("/carts", GetAllCarts(DB), middlewares.RequirePermission(permissions.SaleRead))
+	group.POST("/product/checkout", ProductCheckout(DB), middlewares.RequirePermission(permissions.SaleWrite))
+	group.POST("/transaction/verify", TransactionVerification(DB), middlewares.RequirePermission(permissions.SaleWrite))
+	group.POST("/transaction/acknowledge", AcknowledgeTransactionInteractions(DB), middlewares.RequirePermission(permissions.SaleAcknowledge))
 
 	return group
 }
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/locations"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"strings"
+)
+
+var stockTransferPreloads = []string{"User", "FromLocation", "ToLocation", "Items.Product.Unit", "Items.Product.UnitLevels.Unit"}
+
+func GetAllStockTransfers(DB *gorm.DB) echo.HandlerFunc {
+
+	stockTransferRepository := repositories2.NewStockTransferRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockTransfers []models2.StockTransfer
+		nokocore.KeepVoid(err, stockTransfers)
+
+		status := strings.ToLower(extras.ParseQueryToString(ctx, "status"))
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		stockTransfers, err = stockTransferRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx
+			for i, preload := range stockTransferPreloads {
+				nokocore.KeepVoid(i)
+				stmt = stmt.Preload(preload)
+			}
+			if status != "" {
+				stmt = stmt.Where("status = ?", status)
+			}
+			stmt = stmt.Order("created_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock transfers.", nil)
+		}
+
+		stockTransferResults := schemas2.ToStockTransferResults(stockTransfers)
+		return extras.NewMessageBodyOk(ctx, "Successfully get stock transfers.", &nokocore.MapAny{
+			"stockTransfers": stockTransferResults,
+		})
+	}
+}
+
+func GetStockTransferById(DB *gorm.DB) echo.HandlerFunc {
+
+	stockTransferRepository := repositories2.NewStockTransferRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockTransferID string
+		var stockTransfer *models2.StockTransfer
+		nokocore.KeepVoid(err, stockTransferID, stockTransfer)
+
+		stockTransferID = ctx.Param("stockTransferId")
+		if err = sqlx.ValidateUUID(stockTransferID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_transfer_id'.", nil)
+		}
+
+		if stockTransfer, err = stockTransferRepository.SafePreFirst(stockTransferPreloads, "uuid = ?", stockTransferID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock transfer.", nil)
+		}
+
+		if stockTransfer == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock transfer not found.", nil)
+		}
+
+		stockTransferResult := schemas2.ToStockTransferResult(stockTransfer)
+		return extras.NewMessageBodyOk(ctx, "Successfully get stock transfer.", &nokocore.MapAny{
+			"stockTransfer": stockTransferResult,
+		})
+	}
+}
+
+// CreateStockTransfer method, a draft transfer, stock is moved when sent.
+func CreateStockTransfer(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	locationRepository := repositories2.NewLocationRepository(DB)
+	stockTransferRepository := repositories2.NewStockTransferRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var quantity int
+		var product *models2.Product
+		var fromLocation *models2.Location
+		var toLocation *models2.Location
+		var stockTransfer *models2.StockTransfer
+		nokocore.KeepVoid(err, quantity, product, fromLocation, toLocation, stockTransfer)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockTransferBody := new(schemas2.StockTransferBody)
+		if err = ctx.Bind(stockTransferBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(stockTransferBody); err != nil {
+			return err
+		}
+
+		if len(stockTransferBody.Items) == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Stock transfer items are required.", nil)
+		}
+
+		if stockTransferBody.FromLocationID == stockTransferBody.ToLocationID {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Stock transfer locations must be different.", nil)
+		}
+
+		if fromLocation, err = locationRepository.SafeFirst("uuid = ?", stockTransferBody.FromLocationID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+		}
+
+		if toLocation, err = locationRepository.SafeFirst("uuid = ?", stockTransferBody.ToLocationID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get location.", nil)
+		}
+
+		if fromLocation == nil || toLocation == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Location not found.", nil)
+		}
+
+		stockTransfer = &models2.StockTransfer{
+			UserID:         jwtAuthInfo.User.ID,
+			FromLocationID: fromLocation.ID,
+			ToLocationID:   toLocation.ID,
+			Status:         models2.StockTransferStatusDraft,
+			Note:           strings.TrimSpace(stockTransferBody.Note),
+		}
+
+		// only one item for each product
+		productIDs := make(map[uint]bool)
+		for i, stockTransferItemBody := range stockTransferBody.Items {
+			nokocore.KeepVoid(i)
+
+			if err = ctx.Validate(&stockTransferItemBody); err != nil {
+				return err
+			}
+
+			for j, unitQuantityBody := range stockTransferItemBody.Quantities {
+				nokocore.KeepVoid(j)
+
+				if err = ctx.Validate(&unitQuantityBody); err != nil {
+					return err
+				}
+			}
+
+			preloads := []string{"Unit", "UnitLevels.Unit"}
+			if product, err = productRepository.SafePreFirst(preloads, "uuid = ?", stockTransferItemBody.ProductID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+			}
+
+			if product == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+			}
+
+			if productIDs[product.ID] {
+				return extras.NewMessageBodyConflict(ctx, "Product is already in stock transfer.", nil)
+			}
+
+			// quantities at any unit level, kept in base units
+			if quantity, err = schemas2.ToUnitTotal(product, stockTransferItemBody.Quantities, stockTransferItemBody.PackageTotal, stockTransferItemBody.UnitExtra); err != nil {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid stock transfer quantities.", err.Error())
+			}
+
+			if quantity <= 0 {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Stock transfer quantity must be greater than zero.", nil)
+			}
+
+			productIDs[product.ID] = true
+			stockTransfer.Items = append(stockTransfer.Items, models2.StockTransferItem{
+				ProductID: product.ID,
+				Quantity:  quantity,
+			})
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			stockTransferRepository := repositories2.NewStockTransferRepository(tx)
+
+			// items are created one by one
+			stockTransferItems := stockTransfer.Items
+			stockTransfer.Items = nil
+			if err = stockTransferRepository.Create(stockTransfer); err != nil {
+				return err
+			}
+
+			for i := range stockTransferItems {
+				stockTransferItems[i].StockTransferID = stockTransfer.ID
+
+				if err = tx.Create(&stockTransferItems[i]).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create stock transfer.", nil)
+		}
+
+		if stockTransfer, err = stockTransferRepository.SafePreFirst(stockTransferPreloads, "id = ?", stockTransfer.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock transfer.", nil)
+		}
+
+		stockTransferResult := schemas2.ToStockTransferResult(stockTransfer)
+		return extras.NewMessageBodyOk(ctx, "Successfully create stock transfer.", &nokocore.MapAny{
+			"stockTransfer": stockTransferResult,
+		})
+	}
+}
+
+type stockTransferAction func(locationService locations.LocationServiceImpl, stockTransfer *models2.StockTransfer, userID uint) error
+
+// updateStockTransfer method, send, receive and cancel share the lookup and responses.
+func updateStockTransfer(DB *gorm.DB, name string, action stockTransferAction) echo.HandlerFunc {
+
+	stockTransferRepository := repositories2.NewStockTransferRepository(DB)
+	locationService := locations.NewLocationService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockTransferID string
+		var stockTransfer *models2.StockTransfer
+		nokocore.KeepVoid(err, stockTransferID, stockTransfer)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockTransferID = ctx.Param("stockTransferId")
+		if err = sqlx.ValidateUUID(stockTransferID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_transfer_id'.", nil)
+		}
+
+		if stockTransfer, err = stockTransferRepository.SafePreFirst(stockTransferPreloads, "uuid = ?", stockTransferID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock transfer.", nil)
+		}
+
+		if stockTransfer == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock transfer not found.", nil)
+		}
+
+		if err = action(locationService, stockTransfer, jwtAuthInfo.User.ID); err != nil {
+			switch {
+			case errors.Is(err, locations.ErrInvalidTransferStatus):
+				return extras.NewMessageBodyConflict(ctx, fmt.Sprintf("Stock transfer is already %s.", stockTransfer.Status), nil)
+
+			case errors.Is(err, locations.ErrInsufficientStock):
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Insufficient stock at the source location.", nil)
+
+			default:
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, fmt.Sprintf("Failed to %s stock transfer.", name), nil)
+			}
+		}
+
+		stockTransferResult := schemas2.ToStockTransferResult(stockTransfer)
+		return extras.NewMessageBodyOk(ctx, fmt.Sprintf("Successfully %s stock transfer.", name), &nokocore.MapAny{
+			"stockTransfer": stockTransferResult,
+		})
+	}
+}
+
+func SendStockTransfer(DB *gorm.DB) echo.HandlerFunc {
+	return updateStockTransfer(DB, "send", func(locationService locations.LocationServiceImpl, stockTransfer *models2.StockTransfer, userID uint) error {
+		return locationService.Send(stockTransfer)
+	})
+}
+
+func ReceiveStockTransfer(DB *gorm.DB) echo.HandlerFunc {
+	return updateStockTransfer(DB, "receive", func(locationService locations.LocationServiceImpl, stockTransfer *models2.StockTransfer, userID uint) error {
+		return locationService.Receive(stockTransfer, userID)
+	})
+}
+
+func CancelStockTransfer(DB *gorm.DB) echo.HandlerFunc {
+	return updateStockTransfer(DB, "cancel", func(locationService locations.LocationServiceImpl, stockTransfer *models2.StockTransfer, userID uint) error {
+		return locationService.Cancel(stockTransfer)
+	})
+}
+
+func StockTransferController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/stock-transfers", GetAllStockTransfers(DB), middlewares.RequirePermission(permissions.TransferRead))
+	group.POST("/stock-transfer", CreateStockTransfer(DB), middlewares.RequirePermission(permissions.TransferWrite))
+	group.GET("/stock-transfer/:stockTransferId", GetStockTransferById(DB), middlewares.RequirePermission(permissions.TransferRead))
+	group.POST("/stock-transfer/:stockTransferId/send", SendStockTransfer(DB), middlewares.RequirePermission(permissions.TransferWrite))
+	group.POST("/stock-transfer/:stockTransferId/receive", ReceiveStockTransfer(DB), middlewares.RequirePermission(permissions.TransferWrite))
+	group.POST("/stock-transfer/:stockTransferId/cancel", CancelStockTransfer(DB), middlewares.RequirePermission(permissions.TransferWrite))
+
+	return group
+}
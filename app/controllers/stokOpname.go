@@ -1,13 +1,22 @@
 package controllers
 
 import (
+	"bytes"
+	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
 	"nokowebapi/apis/utils"
 	"nokowebapi/console"
 	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	"pharma-cash-go/app/locations"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/opnames"
+	"pharma-cash-go/app/permissions"
+	"pharma-cash-go/app/reports"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
 	"strings"
@@ -15,27 +24,32 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateCheckpointOpnameCart(DB *gorm.DB) echo.HandlerFunc {
 
-	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationRepository := repositories2.NewLocationRepository(DB)
+	categoryRepository := repositories2.NewCategoryRepository(DB)
+	cycleCountRepository := repositories2.NewCycleCountRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
 	// productRepository := repositories2.NewProductRepository(DB)
 
 	return func(ctx echo.Context) error {
 		var err error
-		var stockOpname *models2.StockOpname
 		var stockOpnameNew *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		var cycleCount *models2.CycleCount
+		var productTotal int
 		// var products []*models2.Product
 		// var cartVerificationOpnames []*models2.CartVerificationOpname
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		nokocore.KeepVoid(err, stockOpname)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
+		nokocore.KeepVoid(err, productTotal)
 
 		// stockOpnameBody := new(schemas2.StockOpnameBody)
 
@@ -47,62 +61,130 @@ func CreateCheckpointOpnameCart(DB *gorm.DB) echo.HandlerFunc {
 		// 	return err
 		// }
 
-		preloads := []string{"User"}
-		if stockOpname, err = stokOpnameRepository.SafePreFirst(preloads, "is_verified = ?", false); err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
-		}
+		// counted cycle count list, its location is counted
+		if cycleCountID := extras.ParseQueryToString(ctx, "cycle_count_id"); cycleCountID != "" {
+			if err = sqlx.ValidateUUID(cycleCountID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'cycle_count_id'.", nil)
+			}
 
-		if stockOpname != nil {
-			stockOpnameResult := schemas2.ToStockOpnameResultCreate(stockOpname)
-			return extras.NewMessageBodyBadRequest(ctx, "Data has not been verified yet.", &nokocore.MapAny{
-				"stockOpname": stockOpnameResult,
-			})
+			if cycleCount, err = cycleCountRepository.SafePreFirst([]string{"Location", "Items"}, "uuid = ?", cycleCountID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get cycle count.", nil)
+			}
+
+			if cycleCount == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Cycle count not found.", nil)
+			}
+
+			if cycleCount.StockOpnameID != 0 {
+				return extras.NewMessageBodyConflict(ctx, "Cycle count is already started.", nil)
+			}
 		}
 
-		err = DB.Transaction(func(tx *gorm.DB) error {
+		// counted location, selling location by default
+		if cycleCount != nil {
+			location = &cycleCount.Location
 
-			// insert: to table stock_opnames
-			stockOpnameNew = &models2.StockOpname{
-				IsVerified: false,
-				UserID:     uint(jwtAuthInfo.User.ID),
+		} else if locationID := extras.ParseQueryToString(ctx, "location_id"); locationID != "" {
+			if err = sqlx.ValidateUUID(locationID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'location_id'.", nil)
 			}
-			if err = DB.Create(stockOpnameNew).Error; err != nil {
+
+			if location, err = locationRepository.SafeFirst("uuid = ?", locationID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
-				return errors.New("failed to create stock_opname data")
-			}
-
-			// // CREATE CART
-			// // select all: from table prodcuts
-			// if err = DB.Find(&products).Error; err != nil {
-			// 	console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			// 	return errors.New("failed to get all products data")
-
-			// }
-			// // prepared data
-			// for _, product := range products {
-			// 	cartVerificationOpnames = append(cartVerificationOpnames, &models2.CartVerificationOpname{
-			// 		UserID:     uint(jwtAuthInfo.User.ID),
-			// 		ProductID:  product.ID,
-			// 		IsMatch:    product.PackageTotal,
-			// 		AmountUnit: product.UnitAmount,
-			// 		UnitExtra:  product.UnitExtra,
-			// 		UnitTotal:  (product.PackageTotal * product.UnitAmount) + product.UnitExtra,
-			// 	})
-			// }
-
-			// // insert: to table cart_verification_opnames
-			// if err = DB.Create(&cartVerificationOpnames).Error; err != nil {
-			// 	console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			// 	return errors.New("failed to create cart_verification_opnames data")
-			// }
-
-			return nil
-		})
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+			}
 
-		if err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyUnprocessableEntity(ctx, "Failed to create checkpoint opname cart.", nil)
+			if location == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Location not found.", nil)
+			}
+
+		} else {
+			if location, err = locationService.GetSelling(); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get selling location.", nil)
+			}
+		}
+
+		scope := &opnames.OpnameScope{
+			LocationID:     location.ID,
+			StockedOnly:    extras.ParseQueryToBool(ctx, "stocked"),
+			NotCountedDays: extras.ParseQueryToInt(ctx, "not_counted_days"),
+			Class:          strings.ToLower(extras.ParseQueryToString(ctx, "class")),
+		}
+
+		if scope.NotCountedDays < 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'not_counted_days'.", nil)
+		}
+
+		if scope.Class != "" && !models2.IsAbcClass(scope.Class) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'class'.", nil)
+		}
+
+		// counted categories, every category by default
+		var categoryNames []string
+		for i, categoryName := range splitStockOpnameQuery(extras.ParseQueryToString(ctx, "category")) {
+			nokocore.KeepVoid(i)
+
+			categoryName = nokocore.ToPascalCase(categoryName)
+			if category, err = categoryRepository.SafeFirst("category_name = ?", categoryName); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+			}
+
+			if category == nil {
+				return extras.NewMessageBodyNotFound(ctx, "Category not found.", nil)
+			}
+
+			scope.CategoryIDs = append(scope.CategoryIDs, category.ID)
+			categoryNames = append(categoryNames, category.CategoryName)
+		}
+
+		// counted suppliers, every supplier by default
+		scope.Suppliers = splitStockOpnameQuery(extras.ParseQueryToString(ctx, "supplier"))
+
+		// the single category is kept for stock opnames of one category
+		var categoryID uint
+		if len(scope.CategoryIDs) == 1 {
+			categoryID = scope.CategoryIDs[0]
+		}
+
+		stockOpnameNew = &models2.StockOpname{
+			IsVerified:     false,
+			UserID:         uint(jwtAuthInfo.User.ID),
+			LocationID:     location.ID,
+			CategoryID:     categoryID,
+			Categories:     strings.Join(categoryNames, ","),
+			Suppliers:      strings.Join(scope.Suppliers, ","),
+			StockedOnly:    scope.StockedOnly,
+			NotCountedDays: scope.NotCountedDays,
+			Class:          scope.Class,
+			IsBlind:        extras.ParseQueryToBool(ctx, "blind"),
+			Status:         models2.StockOpnameStatusCounting,
+		}
+
+		if cycleCount != nil {
+			stockOpnameNew.CycleCountID = cycleCount.ID
+			for i, cycleCountItem := range cycleCount.Items {
+				nokocore.KeepVoid(i)
+				scope.ProductIDs = append(scope.ProductIDs, cycleCountItem.ProductID)
+			}
+		}
+
+		if productTotal, err = opnameService.Start(stockOpnameNew, scope, nokocore.GetTimeUtcNow()); err != nil {
+			switch {
+			case errors.Is(err, opnames.ErrEmptyScope):
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "There are no products in scope.", nil)
+			case errors.Is(err, opnames.ErrScopeOverlap):
+				return newScopeOverlapErrorBody(ctx, err, location)
+			case errors.Is(err, opnames.ErrCycleCountStarted):
+				return extras.NewMessageBodyConflict(ctx, "Cycle count is already started.", nil)
+			default:
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Failed to create checkpoint opname cart.", nil)
+			}
 		}
 
 		// Preload tabel User setelah data dibuat
@@ -113,19 +195,46 @@ func CreateCheckpointOpnameCart(DB *gorm.DB) echo.HandlerFunc {
 
 		stockOpnameResult := schemas2.ToStockOpnameResultCreate(stockOpnameNew)
 		return extras.NewMessageBodyOk(ctx, "Successfully create checkpoint opname cart.", &nokocore.MapAny{
-			"stockOpname": stockOpnameResult,
+			"stockOpname":  stockOpnameResult,
+			"location":     schemas2.ToLocationResult(location),
+			"category":     strings.Join(categoryNames, ","),
+			"scope":        schemas2.ToStockOpnameScope(stockOpnameNew),
+			"productTotal": productTotal,
 		})
 	}
 }
 
 func GetAllStockOpnames(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
 		var stockOpnamesResultGet []schemas2.StockOpnameResultGet
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		// system stock of the counted location
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		var stockOpnameID uint
+		if stockOpname != nil {
+			stockOpnameID = stockOpname.ID
 		}
 
 		query := `
@@ -136,12 +245,10 @@ func GetAllStockOpnames(DB *gorm.DB) echo.HandlerFunc {
 				p.brand,
 				pkg.uuid AS package_uuid,
 				pkg.package_type AS package_type,
-				p.package_total,
 				u.uuid AS unit_uuid,
     			u.unit_type AS unit_type,
 				p.unit_scale,
-				p.unit_extra,
-				(p.package_total * p.unit_scale) + p.unit_extra AS unit_total,
+				COALESCE(ps.stock, 0) AS unit_total,
 				COALESCE(cvo.is_match, TRUE) AS is_match,
 				COALESCE(cvo.uuid, NULL) AS cart_stock_opname_id,
 				COALESCE(cvo.not_match_reason, NULL) AS not_match_reason,
@@ -150,37 +257,369 @@ func GetAllStockOpnames(DB *gorm.DB) echo.HandlerFunc {
 			FROM
 				products p
 			LEFT JOIN
-				cart_verification_opnames cvo ON p.id = cvo.product_id
+				cart_verification_opnames cvo ON p.id = cvo.product_id AND cvo.stock_opname_id = ? AND cvo.deleted_at IS NULL
 			LEFT JOIN
 				packages pkg ON p.package_id = pkg.id
 			LEFT JOIN 
-    			units u ON p.unit_id = u.id;
+    			units u ON p.unit_id = u.id
+			LEFT JOIN
+				product_stocks ps ON p.id = ps.product_id AND ps.location_id = ? AND ps.deleted_at IS NULL
+			WHERE
+				` + stockOpnameProductScope + `;
 		`
 
-		if err = DB.Raw(query).Scan(&stockOpnamesResultGet).Error; err != nil {
+		if err = DB.Raw(query, stockOpnameID, location.ID, stockOpnameID, stockOpnameID).Scan(&stockOpnamesResultGet).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock_opnames.", err.Error())
 		}
 
+		for i := range stockOpnamesResultGet {
+			stockOpnameResultGet := &stockOpnamesResultGet[i]
+			stockOpnameResultGet.PackageTotal, stockOpnameResultGet.UnitExtra = models2.SplitQuantity(stockOpnameResultGet.UnitTotal, stockOpnameResultGet.UnitScale)
+		}
+
 		// stockOpnamesResult := schemas2.ToStockOpnamesResult(stockOpnames)
+		var stockOpnameResult *schemas2.StockOpnameResult
+		if stockOpname != nil {
+			result := schemas2.ToStockOpnameResult(stockOpname, location, category)
+			stockOpnameResult = &result
+		}
+
+		// counters of a blind count never see system quantities
+		var stockOpnamesResult any = stockOpnamesResultGet
+		if stockOpnameIsHidden(jwtAuthInfo, stockOpname) {
+			stockOpnamesResult = schemas2.ToStockOpnameResultGetBlinds(stockOpnamesResultGet)
+		}
+
 		return extras.NewMessageBodyOk(ctx, "Successfully get all stock_opnames.", &nokocore.MapAny{
-			"stockOpnames": stockOpnamesResultGet,
+			"stockOpnames": stockOpnamesResult,
+			"stockOpname":  stockOpnameResult,
+			"location":     schemas2.ToLocationResult(location),
 		})
 	}
 }
 
-func GetProductDetailForPopUpNotMatchVerification(DB *gorm.DB) echo.HandlerFunc {
+// GetAllStockOpnameCheckpoints method, open stock opnames by default, verified
+// ones with 'verified=true' and cancelled ones with 'cancelled=true'.
+func GetAllStockOpnameCheckpoints(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
-		var product *models2.Product
+		var location *models2.Location
+		var category *models2.Category
+		var stockOpnames []models2.StockOpname
+		nokocore.KeepVoid(err, location, category, stockOpnames)
+
+		verified := extras.ParseQueryToBool(ctx, "verified")
+		cancelled := extras.ParseQueryToBool(ctx, "cancelled")
+		status := extras.ParseQueryToString(ctx, "status")
+		if status != "" && !models2.IsStockOpnameStatus(status) {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'status'.", nil)
+		}
+
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		stockOpnames, err = stokOpnameRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Where("is_verified = ? AND is_cancelled = ?", verified, cancelled)
+			if status != "" {
+				stmt = stmt.Where("status = ?", status)
+			}
+			stmt = stmt.Order("created_at DESC, id DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock_opnames.", nil)
+		}
+
+		stockOpnameResults := make([]schemas2.StockOpnameResult, len(stockOpnames))
+		for i := range stockOpnames {
+			stockOpname := &stockOpnames[i]
+
+			if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+			}
+
+			if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+			}
+
+			stockOpnameResults[i] = schemas2.ToStockOpnameResult(stockOpname, location, category)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get stock_opnames.", &nokocore.MapAny{
+			"stockOpnames": stockOpnameResults,
+		})
+	}
+}
+
+// GetStockOpnameCheckpointById method, the stock opname with its not match entries.
+func GetStockOpnameCheckpointById(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	cartVerificationOpnameRepository := repositories2.NewCartVerificationOpnameRepository(DB)
+	stockOpnameStatusChangeRepository := repositories2.NewStockOpnameStatusChangeRepository(DB)
+	locationService := locations.NewLocationService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpnameID string
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		var cartVerificationOpnames []models2.CartVerificationOpname
+		var stockOpnameStatusChanges []models2.StockOpnameStatusChange
+		nokocore.KeepVoid(err, stockOpnameID, stockOpname, location, category, cartVerificationOpnames, stockOpnameStatusChanges)
+
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		productID := ctx.Param("productId")
+		stockOpnameID = ctx.Param("stockOpnameId")
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+		}
+
+		if stockOpname, err = stokOpnameRepository.SafePreFirst([]string{"User"}, "uuid = ?", stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		// entries are cleared once the stock opname is verified
+		cartVerificationOpnames, err = cartVerificationOpnameRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("User").Preload("Product.UnitLevels.Unit").Where("stock_opname_id = ?", stockOpname.ID)
+			return stmt.Order("id ASC"), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get cart_verification_opnames.", nil)
+		}
+
+		stockOpnameStatusChanges, err = stockOpnameStatusChangeRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			return tx.Preload("User").Where("stock_opname_id = ?", stockOpname.ID).Order("id ASC"), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock_opname_status_changes.", nil)
+		}
+
+		stockOpnameResult := schemas2.ToStockOpnameResult(stockOpname, location, category)
+		var cartVerificationOpnameResults any = schemas2.ToCartVerificationOpnameResults(cartVerificationOpnames)
+		if stockOpnameIsHidden(jwtAuthInfo, stockOpname) {
+			cartVerificationOpnameResults = schemas2.ToCartVerificationOpnameBlindResults(cartVerificationOpnames)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get stock_opname.", &nokocore.MapAny{
+			"stockOpname":   stockOpnameResult,
+			"entries":       cartVerificationOpnameResults,
+			"statusChanges": schemas2.ToStockOpnameStatusChangeResults(stockOpnameStatusChanges),
+		})
+	}
+}
+
+// CancelStockOpnameCheckpoint method, discards the pending entries of an open
+// stock opname, product quantities are kept as it is, submitted stock opnames
+// are cancelled by approvers only.
+func CancelStockOpnameCheckpoint(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpnameID string
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		nokocore.KeepVoid(err, stockOpnameID, stockOpname, location, category)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockOpnameID = ctx.Param("stockOpnameId")
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+		}
+
+		stockOpnameCancelBody := new(schemas2.StockOpnameCancelBody)
+		if err = ctx.Bind(stockOpnameCancelBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(stockOpnameCancelBody); err != nil {
+			return err
+		}
+
+		reason := strings.TrimSpace(stockOpnameCancelBody.Reason)
+		if reason == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Cancel reason is required.", nil)
+		}
+
+		if stockOpname, err = stokOpnameRepository.SafePreFirst([]string{"User"}, "uuid = ?", stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if stockOpname.IsVerified {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already verified.", nil)
+		}
+
+		if stockOpname.IsCancelled {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already cancelled.", nil)
+		}
+
+		// submitted entries are waiting for approval
+		if stockOpname.Status == models2.StockOpnameStatusSubmitted && !utils.HasPermission(jwtAuthInfo, permissions.OpnameApprove) {
+			return extras.NewMessageBodyUnauthorized(ctx, "Submitted stock opnames are cancelled by supervisors only.", nil)
+		}
+
+		if err = opnameService.Cancel(stockOpname, jwtAuthInfo.User.ID, reason); err != nil {
+			if errors.Is(err, opnames.ErrStatusChanged) {
+				return extras.NewMessageBodyConflict(ctx, "Stock opname is already verified or cancelled.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to cancel stock_opname.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		stockOpnameResult := schemas2.ToStockOpnameResult(stockOpname, location, category)
+		return extras.NewMessageBodyOk(ctx, "Successfully cancel stock_opname.", &nokocore.MapAny{
+			"stockOpname": stockOpnameResult,
+		})
+	}
+}
+
+// GetStockOpnameReport method, counted against system quantities of a verified
+// stock opname, as json or xlsx and pdf with 'format'.
+func GetStockOpnameReport(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpnameID string
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		var report *opnames.OpnameReport
+		nokocore.KeepVoid(err, stockOpnameID, stockOpname, location, category, report)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockOpnameID = ctx.Param("stockOpnameId")
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+		}
+
+		format := strings.ToLower(extras.ParseQueryToString(ctx, "format"))
+		if format == "" {
+			format = reports.FormatJson
+		}
+
+		writeTable, contentType, ok := reports.GetTableWriter(format)
+		if !ok && format != reports.FormatJson {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'format'.", nil)
+		}
+
+		if stockOpname, err = stokOpnameRepository.SafePreFirst([]string{"User"}, "uuid = ?", stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		// variances of blind counts are revealed to supervisors only
+		if stockOpnameIsHidden(jwtAuthInfo, stockOpname) {
+			return extras.NewMessageBodyUnauthorized(ctx, "Blind count variances are revealed to supervisors only.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		if report, err = opnameService.Report(stockOpname, location, category); err != nil {
+			if errors.Is(err, opnames.ErrNotVerified) {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Stock opname is not verified yet.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get stock opname report.", nil)
+		}
+
+		if format == reports.FormatJson {
+			stockOpnameResult := schemas2.ToStockOpnameResult(stockOpname, location, category)
+			return extras.NewMessageBodyOk(ctx, "Successfully get stock opname report.", &nokocore.MapAny{
+				"stockOpname": stockOpnameResult,
+				"rows":        report.Rows,
+				"total":       report.Total,
+			})
+		}
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+		buffer := new(bytes.Buffer)
+		if err = writeTable(report.ToTable(), buffer); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to write stock opname report.", nil)
 		}
 
+		fileName := fmt.Sprintf("stock-opname-%s.%s", stockOpname.CreatedAt.UTC().Format("2006-01-02"), format)
+		ctx.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		return ctx.Blob(http.StatusOK, contentType, buffer.Bytes())
+	}
+}
+
+func GetProductDetailForPopUpNotMatchVerification(DB *gorm.DB) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		var err error
+		var product *models2.Product
+
+		productID := ctx.Param("productId")
+
 		if err = DB.Preload("Package").Preload("Unit").First(&product, "UUID = ?", productID).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyBadRequest(ctx, "Unable to get product data.", err.Error())
@@ -194,9 +633,13 @@ func GetProductDetailForPopUpNotMatchVerification(DB *gorm.DB) echo.HandlerFunc
 }
 
 func NotMatchVerification(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
 		var product *models2.Product
+		var stockOpname *models2.StockOpname
 		var cartVerificationOpname *models2.CartVerificationOpname
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
@@ -204,14 +647,38 @@ func NotMatchVerification(DB *gorm.DB) echo.HandlerFunc {
 
 		cartVerificationOpnameRepository := repositories2.NewCartVerificationOpnameRepository(DB)
 
+		// entries belong to the open stock opname
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
 		// check: is productId exist
-		if err = DB.First(&product, "UUID = ?", productID).Error; err != nil {
+		if err = DB.Preload("Unit").Preload("UnitLevels.Unit").First(&product, "UUID = ?", productID).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyBadRequest(ctx, "Unable to get product data.", err.Error())
 		}
 
+		// check: is product counted by the stock opname
+		var count int64
+		if err = DB.Model(&models2.StockOpnameItem{}).Where("stock_opname_id = ? AND product_id = ?", stockOpname.ID, product.ID).Count(&count).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock opname items.", nil)
+		}
+
+		if count == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Product is not counted by the stock opname.", nil)
+		}
+
 		// check: is productId already registered
-		if err = DB.Preload("Product").First(&cartVerificationOpname, "product_id = ?", product.ID).Error; err == nil {
+		if err = DB.Preload("Product").First(&cartVerificationOpname, "product_id = ? AND stock_opname_id = ?", product.ID, stockOpname.ID).Error; err == nil {
 			console.Error(fmt.Sprintf("panic: %s", err))
 			return extras.NewMessageBodyBadRequest(ctx, "Already exists cart_verification_opnames data with productId.", err)
 		}
@@ -222,66 +689,514 @@ func NotMatchVerification(DB *gorm.DB) echo.HandlerFunc {
 			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
 		}
 
+		// real quantities at any unit level, kept in base units
+		realQuantity, err := schemas2.ToRealQuantity(cartVerificationOpnameBody, product)
+		if err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid real quantities.", err.Error())
+		}
+
+		realPackageTotal, realUnitExtra := models2.SplitQuantity(realQuantity, product.UnitScale)
+
+		// counters of a blind count can not tell a match, it is compared here
+		isMatch := false
+		if stockOpname.IsBlind {
+			var systemQuantity int
+			if systemQuantity, err = getStockOpnameSystemQuantity(DB, stockOpname, product.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get product stock.", nil)
+			}
+			isMatch = realQuantity == systemQuantity
+		}
+
 		// insert: to table cart_verification_opnames
 		if err = cartVerificationOpnameRepository.Create(&models2.CartVerificationOpname{
 			UserID:           uint(jwtAuthInfo.User.ID),
 			ProductID:        product.ID,
-			IsMatch:          false,
+			StockOpnameID:    stockOpname.ID,
+			IsMatch:          isMatch,
 			NotMatchReason:   cartVerificationOpnameBody.NotMatchReason,
-			RealPackageTotal: cartVerificationOpnameBody.RealPackageTotal,
-			RealUnitExtra:    cartVerificationOpnameBody.RealUnitExtra,
+			RealPackageTotal: realPackageTotal,
+			RealUnitExtra:    realUnitExtra,
+			RealQuantity:     realQuantity,
 			// RealUnitTotal:    (cartVerificationOpnameBody.RealPackageTotal * product.UnitAmount) + cartVerificationOpnameBody.RealUnitExtra,
 		}); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Failed to create cart_verification_opnames data.", err.Error())
 		}
 
-		// Preload tabel User setelah data dibuat
-		var newCartVerificationOpname *models2.CartVerificationOpname
-		if err := DB.Preload("User").Preload("Product").First(&newCartVerificationOpname, "product_id = ?", product.ID).Error; err != nil {
+		// Preload tabel User setelah data dibuat
+		var newCartVerificationOpname *models2.CartVerificationOpname
+		if err := DB.Preload("User").Preload("Product").Preload("Product.UnitLevels.Unit").First(&newCartVerificationOpname, "product_id = ? AND stock_opname_id = ?", product.ID, stockOpname.ID).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to load cart_verification_opnames data with related productId.", err.Error())
+		}
+
+		cartVerificationOpnameResult := toCartVerificationOpnameResult(jwtAuthInfo, stockOpname, newCartVerificationOpname)
+		return extras.NewMessageBodyOk(ctx, "Successfully create cart_verification_opnames data.", cartVerificationOpnameResult)
+	}
+}
+
+// CountStockOpnameProduct method, counted quantity of a product of the open
+// stock opname, the match is compared with the system quantity, counting again
+// replaces the entry.
+func CountStockOpnameProduct(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+	cartVerificationOpnameRepository := repositories2.NewCartVerificationOpnameRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		var stockOpname *models2.StockOpname
+		var cartVerificationOpname *models2.CartVerificationOpname
+		var realQuantity int
+		var systemQuantity int
+		nokocore.KeepVoid(err, productID, product, stockOpname, cartVerificationOpname, realQuantity, systemQuantity)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		cartVerificationOpnameBody := new(schemas2.CartVerificationOpnameBody)
+		if err = ctx.Bind(cartVerificationOpnameBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Unit", "UnitLevels.Unit"}, "uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		var count int64
+		if err = DB.Model(&models2.StockOpnameItem{}).Where("stock_opname_id = ? AND product_id = ?", stockOpname.ID, product.ID).Count(&count).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock opname items.", nil)
+		}
+
+		if count == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Product is not counted by the stock opname.", nil)
+		}
+
+		// real quantities at any unit level, kept in base units
+		if realQuantity, err = schemas2.ToRealQuantity(cartVerificationOpnameBody, product); err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid real quantities.", err.Error())
+		}
+
+		if systemQuantity, err = getStockOpnameSystemQuantity(DB, stockOpname, product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get product stock.", nil)
+		}
+
+		if cartVerificationOpname, err = cartVerificationOpnameRepository.SafeFirst("product_id = ? AND stock_opname_id = ?", product.ID, stockOpname.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get cart_verification_opnames.", nil)
+		}
+
+		if cartVerificationOpname == nil {
+			cartVerificationOpname = &models2.CartVerificationOpname{
+				ProductID:     product.ID,
+				StockOpnameID: stockOpname.ID,
+			}
+		}
+
+		cartVerificationOpname.UserID = jwtAuthInfo.User.ID
+		cartVerificationOpname.IsMatch = realQuantity == systemQuantity
+		cartVerificationOpname.NotMatchReason = cartVerificationOpnameBody.NotMatchReason
+		cartVerificationOpname.RealPackageTotal, cartVerificationOpname.RealUnitExtra = models2.SplitQuantity(realQuantity, product.UnitScale)
+		cartVerificationOpname.RealQuantity = realQuantity
+
+		if cartVerificationOpname.ID == 0 {
+			err = cartVerificationOpnameRepository.Create(cartVerificationOpname)
+		} else {
+			err = DB.Omit("User", "Product").Save(cartVerificationOpname).Error
+		}
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to save cart_verification_opnames data.", nil)
+		}
+
+		if err = DB.Preload("User").Preload("Product").Preload("Product.UnitLevels.Unit").First(&cartVerificationOpname, "id = ?", cartVerificationOpname.ID).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to load cart_verification_opnames data with related productId.", nil)
+		}
+
+		cartVerificationOpnameResult := toCartVerificationOpnameResult(jwtAuthInfo, stockOpname, cartVerificationOpname)
+		return extras.NewMessageBodyOk(ctx, "Successfully count product.", cartVerificationOpnameResult)
+	}
+}
+
+// ScanStockOpnameProduct method, barcode scans of the open stock opname, every
+// scan adds the quantity at the barcode level to the counted quantity.
+func ScanStockOpnameProduct(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	barcodeRepository := repositories2.NewBarcodeRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var barcode *models2.Barcode
+		var product *models2.Product
+		var stockOpname *models2.StockOpname
+		var cartVerificationOpname *models2.CartVerificationOpname
+		var systemQuantity int
+		nokocore.KeepVoid(err, barcode, product, stockOpname, cartVerificationOpname, systemQuantity)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		cartVerificationOpnameScanBody := new(schemas2.CartVerificationOpnameScanBody)
+		if err = ctx.Bind(cartVerificationOpnameScanBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(cartVerificationOpnameScanBody); err != nil {
+			return err
+		}
+
+		code := strings.TrimSpace(cartVerificationOpnameScanBody.Barcode)
+		if code == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Barcode is required.", nil)
+		}
+
+		quantity := cartVerificationOpnameScanBody.Quantity
+		if quantity < 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid scan quantity.", nil)
+		}
+
+		if quantity == 0 {
+			quantity = 1
+		}
+
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
+		// inactive barcodes are kept to reserve the code, but not for lookups
+		if barcode, err = barcodeRepository.SafeFirst("code = ? AND active = ?", code, true); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get barcode.", nil)
+		}
+
+		if barcode == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Barcode not found.", nil)
+		}
+
+		if product, err = productRepository.SafePreFirst([]string{"Package", "Unit", "UnitLevels.Unit"}, "id = ?", barcode.ProductID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		var count int64
+		if err = DB.Model(&models2.StockOpnameItem{}).Where("stock_opname_id = ? AND product_id = ?", stockOpname.ID, product.ID).Count(&count).Error; err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock opname items.", nil)
+		}
+
+		if count == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Product is not counted by the stock opname.", nil)
+		}
+
+		unitLevel := product.GetBarcodeUnitLevel(barcode.Level)
+		scanned := quantity * unitLevel.Factor
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+
+			// scans of several handhelds add up in one statement, the unique
+			// product entry of the stock opname is created by the first scan
+			if err = tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "stock_opname_id"}, {Name: "product_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"real_quantity": gorm.Expr("cart_verification_opnames.real_quantity + excluded.real_quantity"),
+					"user_id":       gorm.Expr("excluded.user_id"),
+					"updated_at":    gorm.Expr("excluded.updated_at"),
+				}),
+			}).Create(&models2.CartVerificationOpname{
+				UserID:        jwtAuthInfo.User.ID,
+				ProductID:     product.ID,
+				StockOpnameID: stockOpname.ID,
+				RealQuantity:  scanned,
+			}).Error; err != nil {
+				return err
+			}
+
+			// system quantity is read with the write lock held
+			if systemQuantity, err = getStockOpnameSystemQuantity(tx, stockOpname, product.ID); err != nil {
+				return err
+			}
+
+			if err = tx.Preload("User").Preload("Product").Preload("Product.UnitLevels.Unit").First(&cartVerificationOpname, "product_id = ? AND stock_opname_id = ?", product.ID, stockOpname.ID).Error; err != nil {
+				return err
+			}
+
+			cartVerificationOpname.IsMatch = cartVerificationOpname.RealQuantity == systemQuantity
+			cartVerificationOpname.RealPackageTotal, cartVerificationOpname.RealUnitExtra = models2.SplitQuantity(cartVerificationOpname.RealQuantity, product.UnitScale)
+			return tx.Model(&models2.CartVerificationOpname{}).Where("id = ?", cartVerificationOpname.ID).UpdateColumns(map[string]any{
+				"is_match":           cartVerificationOpname.IsMatch,
+				"real_package_total": cartVerificationOpname.RealPackageTotal,
+				"real_unit_extra":    cartVerificationOpname.RealUnitExtra,
+			}).Error
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to save cart_verification_opnames data.", nil)
+		}
+
+		cartVerificationOpnameResult := toCartVerificationOpnameResult(jwtAuthInfo, stockOpname, cartVerificationOpname)
+		return extras.NewMessageBodyOk(ctx, "Successfully scan product.", &nokocore.MapAny{
+			"barcode": schemas2.ToBarcodeResult(barcode, product.Barcode),
+			"scanned": scanned,
+			"total":   cartVerificationOpname.RealQuantity,
+			"entry":   cartVerificationOpnameResult,
+		})
+	}
+}
+
+// MergeStockOpnameCountSheet method, count sheets collected offline by a
+// device, sending the same batch again merges nothing twice.
+func MergeStockOpnameCountSheet(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	productRepository := repositories2.NewProductRepository(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpnameID string
+		var stockOpname *models2.StockOpname
+		var countSheetResults []opnames.CountSheetResult
+		nokocore.KeepVoid(err, stockOpnameID, stockOpname, countSheetResults)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockOpnameID = ctx.Param("stockOpnameId")
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+		}
+
+		countSheetBody := new(schemas2.CountSheetBody)
+		if err = ctx.Bind(countSheetBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(countSheetBody); err != nil {
+			return err
+		}
+
+		deviceID := strings.TrimSpace(countSheetBody.DeviceID)
+		if deviceID == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Device id is required.", nil)
+		}
+
+		if len(countSheetBody.Entries) == 0 {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Count sheet has no entries.", nil)
+		}
+
+		if len(countSheetBody.Entries) > opnames.CountSheetMaxEntries {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, fmt.Sprintf("Count sheet has more than %d entries.", opnames.CountSheetMaxEntries), nil)
+		}
+
+		if stockOpname, err = stokOpnameRepository.SafePreFirst([]string{"User"}, "uuid = ?", stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if stockOpname.IsVerified {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already verified.", nil)
+		}
+
+		if stockOpname.IsCancelled {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already cancelled.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
+		// invalid entries are answered without being kept
+		size := len(countSheetBody.Entries)
+		countEntryResults := make([]schemas2.CountEntryResult, size)
+		countSheetEntries := make([]opnames.CountSheetEntry, 0, size)
+		indexes := make([]int, 0, size)
+		products := make([]*models2.Product, size)
+		for i, countSheetEntryBody := range countSheetBody.Entries {
+			countEntryResults[i] = schemas2.CountEntryResult{
+				ClientID: countSheetEntryBody.ClientID,
+				Status:   models2.CountEntryStatusRejected,
+			}
+
+			if err = ctx.Validate(&countSheetEntryBody); err != nil {
+				countEntryResults[i].Message = "Invalid entry."
+				continue
+			}
+
+			var product *models2.Product
+			var realQuantity int
+			var systemQuantity int
+			var countedAt time.Time
+			if product, err = productRepository.SafePreFirst([]string{"Unit", "UnitLevels.Unit"}, "uuid = ?", countSheetEntryBody.ProductID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+			}
+
+			if product == nil {
+				countEntryResults[i].Message = "Product not found."
+				continue
+			}
+
+			countEntryResults[i].ProductID = product.UUID
+
+			var count int64
+			if err = DB.Model(&models2.StockOpnameItem{}).Where("stock_opname_id = ? AND product_id = ?", stockOpname.ID, product.ID).Count(&count).Error; err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock opname items.", nil)
+			}
+
+			if count == 0 {
+				countEntryResults[i].Message = "Product is not counted by the stock opname."
+				continue
+			}
+
+			realQuantity, err = schemas2.ToUnitTotal(product, countSheetEntryBody.RealQuantities, countSheetEntryBody.RealPackageTotal, countSheetEntryBody.RealUnitExtra)
+			if err != nil || realQuantity < 0 {
+				countEntryResults[i].Message = "Invalid real quantities."
+				continue
+			}
+
+			if countedAt, err = nokocore.ParseTimeUtcByStringISO8601(countSheetEntryBody.CountedAt); err != nil {
+				countEntryResults[i].Message = "Invalid counted at."
+				continue
+			}
+
+			if systemQuantity, err = getStockOpnameSystemQuantity(DB, stockOpname, product.ID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get product stock.", nil)
+			}
+
+			products[i] = product
+			indexes = append(indexes, i)
+			countSheetEntries = append(countSheetEntries, opnames.CountSheetEntry{
+				ClientID:       countSheetEntryBody.ClientID,
+				ProductID:      product.ID,
+				UnitScale:      product.UnitScale,
+				Quantity:       realQuantity,
+				SystemQuantity: systemQuantity,
+				NotMatchReason: strings.TrimSpace(countSheetEntryBody.NotMatchReason),
+				CountedAt:      countedAt,
+			})
+		}
+
+		if countSheetResults, err = opnameService.Merge(stockOpname, deviceID, countSheetEntries, jwtAuthInfo.User.ID); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Failed to load cart_verification_opnames data with related productId.", err.Error())
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to merge count sheet.", nil)
 		}
 
-		cartVerificationOpnameResult := schemas2.ToCartVerificationOpnameResult(newCartVerificationOpname)
-		return extras.NewMessageBodyOk(ctx, "Successfully create cart_verification_opnames data.", cartVerificationOpnameResult)
+		for i, countSheetResult := range countSheetResults {
+			index := indexes[i]
+			countEntryResult := schemas2.ToCountEntryResult(countSheetResult.CountEntry, products[index])
+			countEntryResult.Duplicate = countSheetResult.Duplicate
+			countEntryResult.CountedQuantity = countSheetResult.CountedQuantity
+			countEntryResult.ConflictDeviceID = countSheetResult.ConflictDeviceID
+			if countSheetResult.ConflictUser != nil {
+				countEntryResult.ConflictUserID = &countSheetResult.ConflictUser.UUID
+			}
+
+			countEntryResults[index] = countEntryResult
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully merge count sheet.", &nokocore.MapAny{
+			"entries": countEntryResults,
+		})
 	}
 }
 
 func GetNotMatchVerificationByCartVerificationOpnameId(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
+		var stockOpname *models2.StockOpname
 		var cartVerificationOpnames *models2.CartVerificationOpname
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 		cartVerificationOpnameId := ctx.Param("cartVerificationOpnameId")
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+		if err = sqlx.ValidateUUID(cartVerificationOpnameId); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'cart_verification_opname_id'.", nil)
 		}
 
-		if err = DB.Preload("User").Preload("Product").First(&cartVerificationOpnames, "uuid = ?", cartVerificationOpnameId).Error; err != nil {
+		if err = DB.Preload("User").Preload("Product").Preload("Product.UnitLevels.Unit").First(&cartVerificationOpnames, "uuid = ?", cartVerificationOpnameId).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyBadRequest(ctx, "Failed to load cart_verification_opnames data with related cartVerificationOpnameId.", err.Error())
 		}
 
-		cartVerificationOpnameResult := schemas2.ToCartVerificationOpnameResult(cartVerificationOpnames)
+		if stockOpname, err = stokOpnameRepository.First("id = ?", cartVerificationOpnames.StockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		cartVerificationOpnameResult := toCartVerificationOpnameResult(jwtAuthInfo, stockOpname, cartVerificationOpnames)
 		return extras.NewMessageBodyOk(ctx, "Successfully load cart_verification_opnames data.", cartVerificationOpnameResult)
 
 	}
 }
 
 func UpdateNotMatchVerificationByCartVerificationOpnameId(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
+		var stockOpname *models2.StockOpname
 		var cartVerificationOpnames *models2.CartVerificationOpname
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 		cartVerificationOpnameId := ctx.Param("cartVerificationOpnameId")
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+		if err = sqlx.ValidateUUID(cartVerificationOpnameId); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'cart_verification_opname_id'.", nil)
 		}
 
-		if err = DB.Preload("User").Preload("Product").First(&cartVerificationOpnames, "uuid = ?", cartVerificationOpnameId).Error; err != nil {
+		if err = DB.Preload("User").Preload("Product").Preload("Product.UnitLevels.Unit").First(&cartVerificationOpnames, "uuid = ?", cartVerificationOpnameId).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to load cart_verification_opnames data with related cartVerificationOpnameId.", err.Error())
 		}
@@ -291,29 +1206,72 @@ func UpdateNotMatchVerificationByCartVerificationOpnameId(DB *gorm.DB) echo.Hand
 			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
 		}
 
+		// real quantities at any unit level, kept in base units
+		realQuantity, err := schemas2.ToRealQuantity(cartVerificationOpnameBody, &cartVerificationOpnames.Product)
+		if err != nil {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid real quantities.", err.Error())
+		}
+
+		realPackageTotal, realUnitExtra := models2.SplitQuantity(realQuantity, cartVerificationOpnames.Product.UnitScale)
+
+		if stockOpname, err = stokOpnameRepository.First("id = ?", cartVerificationOpnames.StockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if stockOpname.IsVerified {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already verified.", nil)
+		}
+
+		if stockOpname.IsCancelled {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already cancelled.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
+		// counters of a blind count can not tell a match, it is compared here
+		if stockOpname.IsBlind {
+			var systemQuantity int
+			if systemQuantity, err = getStockOpnameSystemQuantity(DB, stockOpname, cartVerificationOpnames.ProductID); err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get product stock.", nil)
+			}
+			cartVerificationOpnames.IsMatch = realQuantity == systemQuantity
+		}
+
 		cartVerificationOpnames.NotMatchReason = cartVerificationOpnameBody.NotMatchReason
-		cartVerificationOpnames.RealPackageTotal = cartVerificationOpnameBody.RealPackageTotal
-		cartVerificationOpnames.RealUnitExtra = cartVerificationOpnameBody.RealUnitExtra
+		cartVerificationOpnames.RealPackageTotal = realPackageTotal
+		cartVerificationOpnames.RealUnitExtra = realUnitExtra
+		cartVerificationOpnames.RealQuantity = realQuantity
 
 		if err = DB.Save(&cartVerificationOpnames).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update cart_verification_opnames data with related cartVerificationOpnameId.", err.Error())
 		}
 
-		cartVerificationOpnameResult := schemas2.ToCartVerificationOpnameResult(cartVerificationOpnames)
+		cartVerificationOpnameResult := toCartVerificationOpnameResult(jwtAuthInfo, stockOpname, cartVerificationOpnames)
 		return extras.NewMessageBodyOk(ctx, "Successfully update cart_verification_opnames data with related cartVerificationOpnameId.", cartVerificationOpnameResult)
 	}
 }
 
 func DeleteCartVerificationOpnameByCartVerificationOpnameId(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
+		var stockOpname *models2.StockOpname
 		var cartVerificationOpnames *models2.CartVerificationOpname
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 		cartVerificationOpnameId := ctx.Param("cartVerificationOpnameId")
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+		if err = sqlx.ValidateUUID(cartVerificationOpnameId); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'cart_verification_opname_id'.", nil)
 		}
 
 		if err = DB.Preload("User").Preload("Product").First(&cartVerificationOpnames, "uuid = ?", cartVerificationOpnameId).Error; err != nil {
@@ -321,6 +1279,27 @@ func DeleteCartVerificationOpnameByCartVerificationOpnameId(DB *gorm.DB) echo.Ha
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to load cart_verification_opnames data with related cartVerificationOpnameId.", err.Error())
 		}
 
+		if stockOpname, err = stokOpnameRepository.First("id = ?", cartVerificationOpnames.StockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if stockOpname.IsVerified {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already verified.", nil)
+		}
+
+		if stockOpname.IsCancelled {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already cancelled.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is submitted for approval.", nil)
+		}
+
 		if err = DB.Unscoped().Delete(&cartVerificationOpnames).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Failed to delete cart_verification_opnames data with related cartVerificationOpnameId.", err.Error())
@@ -330,17 +1309,182 @@ func DeleteCartVerificationOpnameByCartVerificationOpnameId(DB *gorm.DB) echo.Ha
 	}
 }
 
+// SubmitStockOpname method, counters send the open stock opname for approval,
+// entries are kept as it is until it is approved or rejected.
+func SubmitStockOpname(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		nokocore.KeepVoid(err, stockOpname, location, category)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockOpnameStatusBody := new(schemas2.StockOpnameStatusBody)
+		if err = ctx.Bind(stockOpnameStatusBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(stockOpnameStatusBody); err != nil {
+			return err
+		}
+
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", nil)
+		}
+
+		if !stockOpnameIsEditable(stockOpname) {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already submitted.", nil)
+		}
+
+		// every product of a blind count has to be counted
+		if stockOpname.IsBlind {
+			var uncounted int64
+			stmt := DB.Model(&models2.StockOpnameItem{}).Where("stock_opname_id = ?", stockOpname.ID)
+			stmt = stmt.Where("product_id NOT IN (SELECT product_id FROM cart_verification_opnames WHERE stock_opname_id = ? AND deleted_at IS NULL)", stockOpname.ID)
+			if err = stmt.Count(&uncounted).Error; err != nil {
+				console.Error(fmt.Sprintf("panic: %s", err.Error()))
+				return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock opname items.", nil)
+			}
+
+			if uncounted > 0 {
+				return extras.NewMessageBodyUnprocessableEntity(ctx, "Every product of a blind count has to be counted.", &nokocore.MapAny{
+					"uncounted": uncounted,
+				})
+			}
+		}
+
+		if err = opnameService.Submit(stockOpname, jwtAuthInfo.User.ID, strings.TrimSpace(stockOpnameStatusBody.Comment)); err != nil {
+			if errors.Is(err, opnames.ErrStatusChanged) {
+				return extras.NewMessageBodyConflict(ctx, "Stock opname is already submitted.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to submit stock_opname.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		stockOpnameResult := schemas2.ToStockOpnameResult(stockOpname, location, category)
+		return extras.NewMessageBodyOk(ctx, "Successfully submit stock_opname.", &nokocore.MapAny{
+			"stockOpname": stockOpnameResult,
+		})
+	}
+}
+
+// RejectStockOpnameCheckpoint method, supervisors send the submitted stock
+// opname back to the counters with a comment.
+func RejectStockOpnameCheckpoint(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var stockOpnameID string
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
+		var category *models2.Category
+		nokocore.KeepVoid(err, stockOpnameID, stockOpname, location, category)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+
+		stockOpnameID = ctx.Param("stockOpnameId")
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+		}
+
+		stockOpnameStatusBody := new(schemas2.StockOpnameStatusBody)
+		if err = ctx.Bind(stockOpnameStatusBody); err != nil {
+			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
+		}
+
+		if err = ctx.Validate(stockOpnameStatusBody); err != nil {
+			return err
+		}
+
+		comment := strings.TrimSpace(stockOpnameStatusBody.Comment)
+		if comment == "" {
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Reject comment is required.", nil)
+		}
+
+		if stockOpname, err = stokOpnameRepository.SafePreFirst([]string{"User"}, "uuid = ?", stockOpnameID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Stock opname not found.", nil)
+		}
+
+		if stockOpname.IsCancelled {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname is already cancelled.", nil)
+		}
+
+		if stockOpname.Status != models2.StockOpnameStatusSubmitted {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname has to be submitted first.", nil)
+		}
+
+		if err = opnameService.Reject(stockOpname, jwtAuthInfo.User.ID, comment); err != nil {
+			if errors.Is(err, opnames.ErrStatusChanged) {
+				return extras.NewMessageBodyConflict(ctx, "Stock opname has to be submitted first.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to reject stock_opname.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
+		}
+
+		if category, err = getStockOpnameCategory(DB, stockOpname); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get category.", nil)
+		}
+
+		stockOpnameResult := schemas2.ToStockOpnameResult(stockOpname, location, category)
+		return extras.NewMessageBodyOk(ctx, "Successfully reject stock_opname.", &nokocore.MapAny{
+			"stockOpname": stockOpnameResult,
+		})
+	}
+}
+
 func VerifyStockOpname(DB *gorm.DB) echo.HandlerFunc {
+
+	stokOpnameRepository := repositories2.NewStockRepository(DB)
+	locationService := locations.NewLocationService(DB)
+	opnameService := opnames.NewOpnameService(DB)
+
 	return func(ctx echo.Context) error {
 		var err error
+		var stockOpname *models2.StockOpname
+		var location *models2.Location
 		var verificationOpnames []*models2.VerificationOpname
 		var stockOpnamesResultGetVerfies []schemas2.StockOpnameResultGetVerify
 		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
 
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
 		// // check: is table cart_verification_opname empty
 		// if err = DB.Find(&cartVerificationOpnames).Error; err != nil {
 		// 	console.Error(fmt.Sprintf("panic: %s", err.Error()))
@@ -352,29 +1496,42 @@ func VerifyStockOpname(DB *gorm.DB) echo.HandlerFunc {
 		// }
 
 		// check:is there no table stock_opname data with isVerified == false
-		var stockOpname models2.StockOpname
-		if err = DB.First(&stockOpname, "is_verified = ?", false).Error; err != nil {
+		if stockOpname, err = getOpenStockOpname(ctx, stokOpnameRepository); err != nil {
+			return newStockOpnameErrorBody(ctx, err)
+		}
+
+		if stockOpname == nil {
+			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", nil)
+		}
+
+		if stockOpname.Status != models2.StockOpnameStatusSubmitted {
+			return extras.NewMessageBodyConflict(ctx, "Stock opname has to be submitted first.", nil)
+		}
+
+		if location, err = getStockOpnameLocation(DB, locationService, stockOpname); err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyBadRequest(ctx, "There is no stock_opnames data, create checkpoint first.", err.Error())
+			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get location.", nil)
 		}
 
 		// get all
 		query := `
 			SELECT
+				p.id AS product_id,
 				p.uuid AS product_uuid,
 				p.barcode,
 				p.product_name,
 				p.brand,
-				p.package_total AS system_package_total,
+				p.stock AS product_stock,
 				p.unit_scale AS system_unit_scale,
-				p.unit_extra AS system_unit_extra,
-				(p.package_total * p.unit_scale) + p.unit_extra AS system_unit_total,
-				COALESCE(cvo.is_match, TRUE) AS is_match,
+				COALESCE(ps.stock, 0) AS system_unit_total,
+				cvo.id IS NOT NULL AS is_counted,
 				COALESCE(cvo.uuid, NULL) AS cart_stock_opname_id,
 				COALESCE(cvo.not_match_reason, NULL) AS not_match_reason,
 				COALESCE(cvo.real_package_total, NULL) AS real_package_total,
 				COALESCE(cvo.real_unit_extra, NULL) AS real_unit_extra,
-				(real_package_total * p.unit_scale) + real_unit_extra AS real_unit_total,
+				COALESCE(cvo.real_quantity, NULL) AS real_unit_total,
+				p.purchase_price,
+				p.sale_price,
 				p.created_at,
 				p.updated_at
 			FROM
@@ -382,14 +1539,36 @@ func VerifyStockOpname(DB *gorm.DB) echo.HandlerFunc {
 			LEFT JOIN
 				cart_verification_opnames cvo
 			ON
-				p.id = cvo.product_id;
+				p.id = cvo.product_id AND cvo.stock_opname_id = ? AND cvo.deleted_at IS NULL
+			LEFT JOIN
+				product_stocks ps
+			ON
+				p.id = ps.product_id AND ps.location_id = ? AND ps.deleted_at IS NULL
+			WHERE
+				` + stockOpnameProductScope + `;
 		`
 
-		if err = DB.Raw(query).Scan(&stockOpnamesResultGetVerfies).Error; err != nil {
+		if err = DB.Raw(query, stockOpname.ID, location.ID, stockOpname.ID, stockOpname.ID).Scan(&stockOpnamesResultGetVerfies).Error; err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stock_opnames.", err.Error())
 		}
 
+		for i := range stockOpnamesResultGetVerfies {
+			stockOpnamesResultGetVerify := &stockOpnamesResultGetVerfies[i]
+			stockOpnamesResultGetVerify.SystemPackageTotal, stockOpnamesResultGetVerify.SystemUnitExtra = models2.SplitQuantity(stockOpnamesResultGetVerify.SystemUnitTotal, stockOpnamesResultGetVerify.SystemUnitScale)
+
+			// matches saved at scan time are outdated by later stock changes,
+			// counted products are compared to the current system quantity
+			stockOpnamesResultGetVerify.IsMatch = true
+			if stockOpnamesResultGetVerify.IsCounted {
+				stockOpnamesResultGetVerify.IsMatch = stockOpnamesResultGetVerify.RealUnitTotal == stockOpnamesResultGetVerify.SystemUnitTotal
+			}
+
+			if stockOpnamesResultGetVerify.IsMatch {
+				stockOpnamesResultGetVerify.NotMatchReason = ""
+			}
+		}
+
 		// // CREATE CART
 		// // select all: from table prodcuts
 		// if err = DB.Find(&products).Error; err != nil {
@@ -397,85 +1576,41 @@ func VerifyStockOpname(DB *gorm.DB) echo.HandlerFunc {
 		// 	return errors.New("failed to get all products data")
 		// }
 
-		err = DB.Transaction(func(tx *gorm.DB) error {
+		var adjustments []opnames.OpnameAdjustment
+		for i, stockOpnamesResultGetVerify := range stockOpnamesResultGetVerfies {
+			nokocore.KeepVoid(i)
 
-			// prepared data
-			//
-			ids := []uuid.UUID{}
-			casesPackageTotal := "CASE uuid"
-			casesUnitExtra := "CASE uuid"
-			//
-			for _, stockOpnamesResultGetVerify := range stockOpnamesResultGetVerfies {
-				if !stockOpnamesResultGetVerify.IsMatch {
-					ids = append(ids, stockOpnamesResultGetVerify.ProductUUID)
-					casesPackageTotal += fmt.Sprintf(" WHEN '%s' THEN '%d'", stockOpnamesResultGetVerify.ProductUUID, stockOpnamesResultGetVerify.RealPackageTotal)
-					casesUnitExtra += fmt.Sprintf(" WHEN '%s' THEN '%d'", stockOpnamesResultGetVerify.ProductUUID, stockOpnamesResultGetVerify.RealUnitExtra)
-
-				}
-				verificationOpnames = append(verificationOpnames, &models2.VerificationOpname{
-					ProductID:          stockOpnamesResultGetVerify.ProductUUID,
-					StockOpnameID:      stockOpname.ID,
-					SystemPackageTotal: stockOpnamesResultGetVerify.SystemPackageTotal,
-					SystemUnitExtra:    stockOpnamesResultGetVerify.SystemUnitExtra,
-					SystemUnitTotal:    stockOpnamesResultGetVerify.SystemUnitTotal,
-					IsMatch:            stockOpnamesResultGetVerify.IsMatch,
-					NotMatchReason:     stockOpnamesResultGetVerify.NotMatchReason,
-					RealPackageTotal:   stockOpnamesResultGetVerify.RealPackageTotal,
-					RealUnitExtra:      stockOpnamesResultGetVerify.RealUnitExtra,
-					RealUnitTotal:      stockOpnamesResultGetVerify.RealUnitTotal,
-					UserID:             jwtAuthInfo.User.ID,
+			if !stockOpnamesResultGetVerify.IsMatch {
+				// product stock changes by the difference counted at the location
+				adjustments = append(adjustments, opnames.OpnameAdjustment{
+					ProductID: stockOpnamesResultGetVerify.ProductID,
+					Quantity:  stockOpnamesResultGetVerify.RealUnitTotal - stockOpnamesResultGetVerify.SystemUnitTotal,
+					Note:      stockOpnamesResultGetVerify.NotMatchReason,
 				})
-
-			}
-			casesPackageTotal += " END"
-			casesUnitExtra += " END"
-
-			var idList []string
-			for _, id := range ids {
-				idList = append(idList, fmt.Sprintf("'%s'", id))
 			}
-			idsString := strings.Join(idList, ",")
 
-			// insert: to table verification_opnames
-			if err = DB.Create(&verificationOpnames).Error; err != nil {
-				console.Error(fmt.Sprintf("panic: %s", err.Error()))
-				return errors.New("failed to create cart_verification_opnames data")
-			}
-
-			// bulk update: change the package_total and unit_extra from table products
-			updatedAt := time.Now().UTC().Format("2006-01-02 15:04:05.9999999-07:00")
-			rawQuery := fmt.Sprintf(`
-				UPDATE products
-				SET 
-					package_total = %s,
-					unit_extra = %s,
-					updated_at = '%s'
-				WHERE uuid IN (%s) 
-			`,
-				casesPackageTotal, casesUnitExtra, updatedAt, idsString)
-			fmt.Println("= rawQuery: ", rawQuery)
-
-			if err := DB.Exec(rawQuery).Error; err != nil {
-				console.Error(fmt.Sprintf("panic: %s", err.Error()))
-				return errors.New("failed to update product package_total and unit_extra data")
-			}
-
-			// empty: delete all data from table cart_verification_opnames
-			if err = DB.Unscoped().Where("1 = 1").Delete(&models2.CartVerificationOpname{}).Error; err != nil {
-				console.Error(fmt.Sprintf("panic: %s", err.Error()))
-				return errors.New("failed to delete all cart_verification_opnames data")
-			}
+			verificationOpnames = append(verificationOpnames, &models2.VerificationOpname{
+				ProductID:          stockOpnamesResultGetVerify.ProductUUID,
+				StockOpnameID:      stockOpname.ID,
+				SystemPackageTotal: stockOpnamesResultGetVerify.SystemPackageTotal,
+				SystemUnitExtra:    stockOpnamesResultGetVerify.SystemUnitExtra,
+				SystemUnitTotal:    stockOpnamesResultGetVerify.SystemUnitTotal,
+				IsMatch:            stockOpnamesResultGetVerify.IsMatch,
+				NotMatchReason:     stockOpnamesResultGetVerify.NotMatchReason,
+				RealPackageTotal:   stockOpnamesResultGetVerify.RealPackageTotal,
+				RealUnitExtra:      stockOpnamesResultGetVerify.RealUnitExtra,
+				RealUnitTotal:      stockOpnamesResultGetVerify.RealUnitTotal,
+				UserID:             jwtAuthInfo.User.ID,
+				PurchasePrice:      decimal.NewNullDecimal(stockOpnamesResultGetVerify.PurchasePrice),
+				SalePrice:          decimal.NewNullDecimal(stockOpnamesResultGetVerify.SalePrice),
+			})
+		}
 
-			// update: submited_at and is_verified from table stock_opname
-			if err = DB.Model(&stockOpname).Updates(map[string]interface{}{"submited_at": time.Now(), "is_verified": true}).Error; err != nil {
-				console.Error(fmt.Sprintf("panic: %s", err.Error()))
-				return errors.New("failed to update submited_at and is_verified from table stock_opname")
+		if err = opnameService.Verify(stockOpname, location.ID, verificationOpnames, adjustments, jwtAuthInfo.User.ID); err != nil {
+			if errors.Is(err, opnames.ErrStatusChanged) {
+				return extras.NewMessageBodyConflict(ctx, "Stock opname has to be submitted first.", nil)
 			}
 
-			return nil
-		})
-
-		if err != nil {
 			console.Error(fmt.Sprintf("panic: %s", err.Error()))
 			return extras.NewMessageBodyUnprocessableEntity(ctx, "Failed to verify all stock opname.", err.Error())
 		}
@@ -489,15 +1624,12 @@ func VerifyStockOpname(DB *gorm.DB) echo.HandlerFunc {
 func GetHistoryStockOpnameDates(DB *gorm.DB) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
 		var err error
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
 
 		type DateEntry struct {
 			StockOpnameId uuid.UUID `json:"stockOpnameId"`
 			Date          string    `json:"date"`
+			Status        string    `json:"status"` // open, verified or cancelled
+			CancelReason  string    `json:"cancelReason,omitempty"`
 		}
 
 		type YearlyData struct {
@@ -509,7 +1641,7 @@ func GetHistoryStockOpnameDates(DB *gorm.DB) echo.HandlerFunc {
 		year := ctx.QueryParam("year")
 
 		// Konstruksi kueri
-		query := DB.Table("stock_opnames").Select("strftime('%Y', created_at) AS year, uuid AS stock_opname_id, created_at AS date")
+		query := DB.Table("stock_opnames").Select("strftime('%Y', created_at) AS year, uuid AS stock_opname_id, created_at AS date, is_verified, is_cancelled, cancel_reason")
 		if year != "" {
 			query = query.Where("strftime('%Y', created_at) = ?", year)
 		}
@@ -518,6 +1650,9 @@ func GetHistoryStockOpnameDates(DB *gorm.DB) echo.HandlerFunc {
 			Year          string
 			StockOpnameId uuid.UUID
 			Date          string
+			IsVerified    bool
+			IsCancelled   bool
+			CancelReason  sql.NullString
 		}
 
 		if err = query.Find(&rawResults).Error; err != nil {
@@ -527,9 +1662,18 @@ func GetHistoryStockOpnameDates(DB *gorm.DB) echo.HandlerFunc {
 
 		groupedData := make(map[string][]DateEntry)
 		for _, row := range rawResults {
+			status := "open"
+			switch {
+			case row.IsCancelled:
+				status = "cancelled"
+			case row.IsVerified:
+				status = "verified"
+			}
 			groupedData[row.Year] = append(groupedData[row.Year], DateEntry{
 				StockOpnameId: row.StockOpnameId,
 				Date:          row.Date,
+				Status:        status,
+				CancelReason:  row.CancelReason.String,
 			})
 		}
 
@@ -545,20 +1689,197 @@ func GetHistoryStockOpnameDates(DB *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+// getStockOpnameLocation method, counted location of the stock opname, selling
+// location for stock opnames created before locations.
+func getStockOpnameLocation(DB *gorm.DB, locationService locations.LocationServiceImpl, stockOpname *models2.StockOpname) (*models2.Location, error) {
+	var err error
+	var location *models2.Location
+	nokocore.KeepVoid(err, location)
+
+	if stockOpname != nil && stockOpname.LocationID != 0 {
+		locationRepository := repositories2.NewLocationRepository(DB)
+		if location, err = locationRepository.First("id = ?", stockOpname.LocationID); err != nil {
+			return nil, err
+		}
+
+		if location != nil {
+			return location, nil
+		}
+	}
+
+	return locationService.GetSelling()
+}
+
+// stockOpnameIsHidden method, system quantities and variances of blind counts
+// are hidden from users who are not allowed to approve them.
+func stockOpnameIsHidden(jwtAuthInfo *extras.JwtAuthInfo, stockOpname *models2.StockOpname) bool {
+	if stockOpname == nil || !stockOpname.IsBlind {
+		return false
+	}
+
+	return !utils.HasPermission(jwtAuthInfo, permissions.OpnameApprove)
+}
+
+// stockOpnameIsEditable method, entries are changed while counting, or after
+// the stock opname is sent back to the counters.
+func stockOpnameIsEditable(stockOpname *models2.StockOpname) bool {
+	return stockOpname.Status == models2.StockOpnameStatusCounting || stockOpname.Status == models2.StockOpnameStatusRejected
+}
+
+// toCartVerificationOpnameResult method, entry result, blind results below supervisors.
+func toCartVerificationOpnameResult(jwtAuthInfo *extras.JwtAuthInfo, stockOpname *models2.StockOpname, cartVerificationOpname *models2.CartVerificationOpname) any {
+	if stockOpnameIsHidden(jwtAuthInfo, stockOpname) {
+		return schemas2.ToCartVerificationOpnameBlindResult(cartVerificationOpname)
+	}
+
+	return schemas2.ToCartVerificationOpnameResult(cartVerificationOpname)
+}
+
+// getStockOpnameSystemQuantity method, system quantity of the product at the
+// counted location, in base units.
+func getStockOpnameSystemQuantity(DB *gorm.DB, stockOpname *models2.StockOpname, productID uint) (int, error) {
+	var err error
+	var location *models2.Location
+	var quantity int
+	nokocore.KeepVoid(err, location, quantity)
+
+	// legacy stock opnames without location count the selling location
+	if location, err = getStockOpnameLocation(DB, locations.NewLocationService(DB), stockOpname); err != nil {
+		return 0, err
+	}
+
+	stmt := DB.Model(&models2.ProductStock{}).Select("COALESCE(SUM(stock), 0)")
+	if err = stmt.Where("product_id = ? AND location_id = ?", productID, location.ID).Scan(&quantity).Error; err != nil {
+		return 0, err
+	}
+
+	return quantity, nil
+}
+
+// splitStockOpnameQuery method, comma separated query values, blanks are skipped.
+func splitStockOpnameQuery(value string) []string {
+	var values []string
+	for i, temp := range strings.Split(value, ",") {
+		nokocore.KeepVoid(i)
+		if temp = strings.TrimSpace(temp); temp != "" {
+			values = append(values, temp)
+		}
+	}
+	return values
+}
+
+var errInvalidStockOpnameID = errors.New("invalid stock opname id")
+var errStockOpnameRequired = errors.New("stock opname is required")
+
+// stockOpnameProductScope, products counted by the stock opname, every product
+// when there is no stock opname.
+const stockOpnameProductScope = "(? = 0 OR p.id IN (SELECT soi.product_id FROM stock_opname_items soi WHERE soi.stock_opname_id = ? AND soi.deleted_at IS NULL))"
+
+// getOpenStockOpname method, open stock opname by 'stock_opname_id', the only
+// open stock opname when omitted.
+func getOpenStockOpname(ctx echo.Context, stokOpnameRepository repositories2.StockRepositoryImpl) (*models2.StockOpname, error) {
+	var err error
+	var stockOpnames []models2.StockOpname
+	nokocore.KeepVoid(err, stockOpnames)
+
+	preloads := []string{"User"}
+	if stockOpnameID := extras.ParseQueryToString(ctx, "stock_opname_id"); stockOpnameID != "" {
+		if err = sqlx.ValidateUUID(stockOpnameID); err != nil {
+			return nil, errInvalidStockOpnameID
+		}
+
+		return stokOpnameRepository.SafePreFirst(preloads, "uuid = ? AND is_verified = ? AND is_cancelled = ?", stockOpnameID, false, false)
+	}
+
+	if stockOpnames, err = stokOpnameRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Preload("User").Where("is_verified = ? AND is_cancelled = ?", false, false).Order("id ASC").Limit(2), nil
+	}); err != nil {
+		return nil, err
+	}
+
+	switch len(stockOpnames) {
+	case 0:
+		return nil, nil
+
+	case 1:
+		return &stockOpnames[0], nil
+
+	default:
+		return nil, errStockOpnameRequired
+	}
+}
+
+// newStockOpnameErrorBody method, responses for errors of open stock opname lookups.
+func newStockOpnameErrorBody(ctx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errInvalidStockOpnameID):
+		return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'stock_opname_id'.", nil)
+
+	case errors.Is(err, errStockOpnameRequired):
+		return extras.NewMessageBodyUnprocessableEntity(ctx, "Several stock opnames are open, parameter 'stock_opname_id' is required.", nil)
+
+	default:
+		console.Error(fmt.Sprintf("panic: %s", err.Error()))
+		return extras.NewMessageBodyInternalServerError(ctx, "Unable to get stcok_opname.", nil)
+	}
+}
+
+// newScopeOverlapErrorBody method, names the open stock opnames counting products of the scope.
+func newScopeOverlapErrorBody(ctx echo.Context, err error, location *models2.Location) error {
+	var scopeOverlapError *opnames.ScopeOverlapError
+	if !errors.As(err, &scopeOverlapError) {
+		return extras.NewMessageBodyConflict(ctx, "Products in scope are counted by an open stock opname.", nil)
+	}
+
+	stockOpnameIDs := make([]string, len(scopeOverlapError.StockOpnames))
+	stockOpnameResults := make([]schemas2.StockOpnameResult, len(scopeOverlapError.StockOpnames))
+	for i := range scopeOverlapError.StockOpnames {
+		stockOpname := &scopeOverlapError.StockOpnames[i]
+		stockOpnameIDs[i] = stockOpname.UUID.String()
+		stockOpnameResults[i] = schemas2.ToStockOpnameResult(stockOpname, location, nil)
+	}
+
+	message := fmt.Sprintf("%d products in scope are counted by open stock opname %s.", scopeOverlapError.ProductTotal, strings.Join(stockOpnameIDs, ", "))
+	return extras.NewMessageBodyConflict(ctx, message, &nokocore.MapAny{
+		"stockOpnames": stockOpnameResults,
+		"productTotal": scopeOverlapError.ProductTotal,
+	})
+}
+
+// getStockOpnameCategory method, counted category of the stock opname, nothing
+// when every category is counted.
+func getStockOpnameCategory(DB *gorm.DB, stockOpname *models2.StockOpname) (*models2.Category, error) {
+	if stockOpname != nil && stockOpname.CategoryID != 0 {
+		categoryRepository := repositories2.NewCategoryRepository(DB)
+		return categoryRepository.First("id = ?", stockOpname.CategoryID)
+	}
+
+	return nil, nil
+}
+
 func StokOpnameController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
 	// submenu verification
-	group.POST("/warehouse/checkpoint", CreateCheckpointOpnameCart(DB))
-	group.GET("/warehouse/cart", GetAllStockOpnames(DB))
-	group.GET("/warehouse/stock/:productId", GetProductDetailForPopUpNotMatchVerification(DB))
-	group.POST("/warehouse/cart/not-match/:productId", NotMatchVerification(DB))
-	group.GET("/warehouse/cart/not-match/:cartVerificationOpnameId", GetNotMatchVerificationByCartVerificationOpnameId(DB))
-	group.PUT("/warehouse/cart/not-match/:cartVerificationOpnameId", UpdateNotMatchVerificationByCartVerificationOpnameId(DB))
-	group.DELETE("/warehouse/cart/not-match/:cartVerificationOpnameId", DeleteCartVerificationOpnameByCartVerificationOpnameId(DB))
-	group.POST("/warehouse/cart/verify", VerifyStockOpname(DB))
+	group.POST("/warehouse/checkpoint", CreateCheckpointOpnameCart(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.GET("/warehouse/checkpoints", GetAllStockOpnameCheckpoints(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.GET("/warehouse/checkpoint/:stockOpnameId", GetStockOpnameCheckpointById(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.POST("/warehouse/checkpoint/:stockOpnameId/cancel", CancelStockOpnameCheckpoint(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.POST("/warehouse/checkpoint/:stockOpnameId/reject", RejectStockOpnameCheckpoint(DB), middlewares.RequirePermission(permissions.OpnameApprove))
+	group.POST("/warehouse/checkpoint/:stockOpnameId/count-sheets", MergeStockOpnameCountSheet(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.GET("/warehouse/checkpoint/:stockOpnameId/report", GetStockOpnameReport(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.GET("/warehouse/cart", GetAllStockOpnames(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.GET("/warehouse/stock/:productId", GetProductDetailForPopUpNotMatchVerification(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.POST("/warehouse/cart/not-match/:productId", NotMatchVerification(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.POST("/warehouse/cart/count/:productId", CountStockOpnameProduct(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.POST("/warehouse/cart/scan", ScanStockOpnameProduct(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.GET("/warehouse/cart/not-match/:cartVerificationOpnameId", GetNotMatchVerificationByCartVerificationOpnameId(DB), middlewares.RequirePermission(permissions.OpnameRead))
+	group.PUT("/warehouse/cart/not-match/:cartVerificationOpnameId", UpdateNotMatchVerificationByCartVerificationOpnameId(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.DELETE("/warehouse/cart/not-match/:cartVerificationOpnameId", DeleteCartVerificationOpnameByCartVerificationOpnameId(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.POST("/warehouse/cart/submit", SubmitStockOpname(DB), middlewares.RequirePermission(permissions.OpnameCount))
+	group.POST("/warehouse/cart/verify", VerifyStockOpname(DB), middlewares.RequirePermission(permissions.OpnameApprove))
 
 	// submenu history
-	group.GET("/warehouse/history/dates", GetHistoryStockOpnameDates(DB))
+	group.GET("/warehouse/history/dates", GetHistoryStockOpnameDates(DB), middlewares.RequirePermission(permissions.OpnameRead))
 
 	return group
 }
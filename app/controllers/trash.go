@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/apis/schemas"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"pharma-cash-go/app/trash"
+)
+
+func GetAllDeletedProducts(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var products []models2.Product
+		nokocore.KeepVoid(err, products)
+
+		// latest deleted first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		products, err = productRepository.ManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Categories").Preload("Package").Preload("Unit").Preload("UnitLevels.Unit")
+			stmt = stmt.Where("deleted_at IS NOT NULL").Order("deleted_at DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get deleted products.", nil)
+		}
+
+		size := len(products)
+		productResults := make([]schemas2.ProductResult, size)
+		for i, product := range products {
+			nokocore.KeepVoid(i)
+			productResults[i] = schemas2.ToProductResult(&product)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get deleted products.", &nokocore.MapAny{
+			"products":  productResults,
+			"retention": trash.GetRetention().String(),
+		})
+	}
+}
+
+func RestoreProduct(DB *gorm.DB) echo.HandlerFunc {
+
+	productRepository := repositories2.NewProductRepository(DB)
+	trashService := trash.NewTrashService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var productID string
+		var product *models2.Product
+		nokocore.KeepVoid(err, productID, product)
+
+		productID = ctx.Param("productId")
+		if err = sqlx.ValidateUUID(productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'product_id'.", nil)
+		}
+
+		if product, err = productRepository.First("uuid = ?", productID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		if product == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Product not found.", nil)
+		}
+
+		if err = trashService.Restore(&models2.Product{}, product.ID); err != nil {
+			if errors.Is(err, trash.ErrNotDeleted) {
+				return extras.NewMessageBodyConflict(ctx, "Product is not deleted.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to restore product.", nil)
+		}
+
+		preloads := []string{"Categories", "Package", "Unit", "UnitLevels.Unit"}
+		if product, err = productRepository.SafePreFirst(preloads, "id = ?", product.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get product.", nil)
+		}
+
+		productResult := schemas2.ToProductResult(product)
+		return extras.NewMessageBodyOk(ctx, "Successfully restore product.", &nokocore.MapAny{
+			"product": productResult,
+		})
+	}
+}
+
+func GetAllDeletedUsers(DB *gorm.DB) echo.HandlerFunc {
+
+	userRepository := repositories.NewUserRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var users []models.User
+		nokocore.KeepVoid(err, users)
+
+		// latest deleted first
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		users, err = userRepository.ManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Roles").Where("deleted_at IS NOT NULL").Order("deleted_at DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get deleted users.", nil)
+		}
+
+		size := len(users)
+		userResults := make([]schemas.UserResult, size)
+		for i, user := range users {
+			nokocore.KeepVoid(i)
+			userResults[i] = schemas.ToUserResult(&user)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get deleted users.", &nokocore.MapAny{
+			"users":     userResults,
+			"retention": trash.GetRetention().String(),
+		})
+	}
+}
+
+func RestoreUser(DB *gorm.DB) echo.HandlerFunc {
+
+	userRepository := repositories.NewUserRepository(DB)
+	trashService := trash.NewTrashService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var userID string
+		var user *models.User
+		nokocore.KeepVoid(err, userID, user)
+
+		userID = ctx.Param("userId")
+		if err = sqlx.ValidateUUID(userID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'user_id'.", nil)
+		}
+
+		preloads := []string{"Roles"}
+		if user, err = userRepository.PreFirst(preloads, "uuid = ?", userID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get user.", nil)
+		}
+
+		if user == nil {
+			return extras.NewMessageBodyNotFound(ctx, "User not found.", nil)
+		}
+
+		if err = trashService.RestoreUser(user); err != nil {
+			if errors.Is(err, trash.ErrNotDeleted) {
+				return extras.NewMessageBodyConflict(ctx, "User is not deleted.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to restore user.", nil)
+		}
+
+		userResult := schemas.ToUserResult(user)
+		return extras.NewMessageBodyOk(ctx, "Successfully restore user.", &nokocore.MapAny{
+			"user": userResult,
+		})
+	}
+}
+
+func GetAllDeletedEmployees(DB *gorm.DB) echo.HandlerFunc {
+
+	employeeRepository := repositories2.NewEmployeeRepository(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var employees []models2.Employee
+		nokocore.KeepVoid(err, employees)
+
+		// deleted users are preloaded too
+		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
+		employees, err = employeeRepository.ManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Preload("Shift").Preload("User", func(tx *gorm.DB) *gorm.DB {
+				return tx.Unscoped()
+			}).Preload("User.Roles")
+			stmt = stmt.Where("deleted_at IS NOT NULL").Order("deleted_at DESC")
+			return stmt.Offset(pagination.Offset).Limit(pagination.Limit), nil
+		})
+
+		if err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get deleted employees.", nil)
+		}
+
+		size := len(employees)
+		employeeResults := make([]schemas2.EmployeeResult, size)
+		for i, employee := range employees {
+			nokocore.KeepVoid(i)
+			employeeResults[i] = schemas2.ToEmployeeResult(&employee)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully get deleted employees.", &nokocore.MapAny{
+			"employees": employeeResults,
+			"retention": trash.GetRetention().String(),
+		})
+	}
+}
+
+func RestoreEmployee(DB *gorm.DB) echo.HandlerFunc {
+
+	employeeRepository := repositories2.NewEmployeeRepository(DB)
+	trashService := trash.NewTrashService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var employeeID string
+		var employee *models2.Employee
+		nokocore.KeepVoid(err, employeeID, employee)
+
+		employeeID = ctx.Param("employeeId")
+		if err = sqlx.ValidateUUID(employeeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyUnprocessableEntity(ctx, "Invalid parameter 'employee_id'.", nil)
+		}
+
+		if employee, err = employeeRepository.First("uuid = ?", employeeID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get employee.", nil)
+		}
+
+		if employee == nil {
+			return extras.NewMessageBodyNotFound(ctx, "Employee not found.", nil)
+		}
+
+		if err = trashService.RestoreEmployee(employee); err != nil {
+			if errors.Is(err, trash.ErrNotDeleted) {
+				return extras.NewMessageBodyConflict(ctx, "Employee is not deleted.", nil)
+			}
+
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to restore employee.", nil)
+		}
+
+		preloads := []string{"Shift", "User", "User.Roles"}
+		if employee, err = employeeRepository.SafePreFirst(preloads, "id = ?", employee.ID); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get employee.", nil)
+		}
+
+		employeeResult := schemas2.ToEmployeeResult(employee)
+		return extras.NewMessageBodyOk(ctx, "Successfully restore employee.", &nokocore.MapAny{
+			"employee": employeeResult,
+		})
+	}
+}
+
+func PurgeTrash(DB *gorm.DB) echo.HandlerFunc {
+
+	trashService := trash.NewTrashService(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		var result *trash.PurgeResult
+		nokocore.KeepVoid(err, result)
+
+		// only rows deleted before the retention period
+		before := nokocore.GetTimeUtcNow().Add(-trash.GetRetention())
+		if result, err = trashService.Purge(before); err != nil {
+			console.Error(fmt.Sprintf("panic: %s", err.Error()))
+			return extras.NewMessageBodyInternalServerError(ctx, "Failed to purge trash.", nil)
+		}
+
+		return extras.NewMessageBodyOk(ctx, "Successfully purge trash.", &nokocore.MapAny{
+			"purged": result,
+			"before": nokocore.ToTimeUtcStringISO8601(before),
+		})
+	}
+}
+
+func TrashController(group *echo.Group, DB *gorm.DB) *echo.Group {
+
+	group.GET("/trash/products", GetAllDeletedProducts(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.POST("/trash/product/:productId/restore", RestoreProduct(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.GET("/trash/users", GetAllDeletedUsers(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.POST("/trash/user/:userId/restore", RestoreUser(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.GET("/trash/employees", GetAllDeletedEmployees(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.POST("/trash/employee/:employeeId/restore", RestoreEmployee(DB), middlewares.RequirePermission(permissions.TrashManage))
+	group.POST("/trash/purge", PurgeTrash(DB), middlewares.RequirePermission(permissions.TrashManage))
+
+	return group
+}
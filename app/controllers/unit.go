@@ -1,103 +1,75 @@
 package controllers
 
 import (
-	"fmt"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
-	"nokowebapi/apis/extras"
-	"nokowebapi/apis/utils"
-	"nokowebapi/console"
-	"nokowebapi/nokocore"
+	"nokowebapi/apis/middlewares"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
 	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/permissions"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
 )
 
-func CreateUnit(DB *gorm.DB) echo.HandlerFunc {
-
-	unitRepository := repositories2.NewUnitRepository(DB)
-
-	return func(ctx echo.Context) error {
-		var err error
-		var unit *models2.Unit
-		nokocore.KeepVoid(err, unit)
-
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-
-		if !utils.RoleIsAdmin(jwtAuthInfo) && !utils.RoleIs(jwtAuthInfo, nokocore.RoleOfficer) {
-			return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
-		}
-
-		unitBody := new(schemas2.UnitBody)
-
-		if err = ctx.Bind(unitBody); err != nil {
-			return extras.NewMessageBodyBadRequest(ctx, "Invalid request body.", err)
-		}
-
-		if err = ctx.Validate(unitBody); err != nil {
-			return err
-		}
-
-		// normalized text
-		unitBody.UnitType = nokocore.ToTitleCase(unitBody.UnitType)
-
-		unitType := unitBody.UnitType
-		if unit, err = unitRepository.SafeFirst("unit_type = ?", unitType); err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Unable to get unit.", nil)
-		}
-
-		if unit != nil {
-			unitResult := schemas2.ToUnitResult(unit)
-			return extras.NewMessageBodyOk(ctx, "Unit already exists.", &nokocore.MapAny{
-				"unit": unitResult,
-			})
-		}
-
-		unit = schemas2.ToUnitModel(unitBody)
-		if err = unitRepository.Create(unit); err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Failed to create unit.", nil)
-		}
+var unitLookupController = &LookupController[models2.Unit, schemas2.UnitBody, schemas2.UnitResult]{
+	Name:            "unit",
+	Column:          "unit_type",
+	ProductColumn:   "unit_id",
+	ProductQuery:    "unit_id = @id OR id IN (SELECT product_id FROM product_units WHERE unit_id = @id)",
+	ProductPreloads: []string{"Package", "Unit", "UnitLevels.Unit"},
+
+	// a product with levels of both units would have two levels of the same unit
+	MergeQuery: "id IN (SELECT product_id FROM product_units WHERE unit_id = @id) AND id IN (SELECT product_id FROM product_units WHERE unit_id = @target)",
+
+	NewRepository: func(DB *gorm.DB) repositories.BaseRepositoryImpl[models2.Unit] {
+		return repositories2.NewUnitRepository(DB)
+	},
+	GetBaseModel: func(unit *models2.Unit) *models.BaseModel {
+		return &unit.BaseModel
+	},
+	GetType: func(unitBody *schemas2.UnitBody) string {
+		return unitBody.UnitType
+	},
+	SetType: func(unit *models2.Unit, unitType string) {
+		unit.UnitType = unitType
+	},
+	ToModel:  schemas2.ToUnitModel,
+	ToResult: schemas2.ToUnitResult,
+
+	// product unit levels follow the merged unit
+	OnMerge: func(tx *gorm.DB, unit *models2.Unit, target *models2.Unit) error {
+		return tx.Model(&models2.ProductUnit{}).Where("unit_id = ?", unit.ID).Update("unit_id", target.ID).Error
+	},
+}
 
-		unitResult := schemas2.ToUnitResult(unit)
-		return extras.NewMessageBodyOk(ctx, "Successfully create unit.", &nokocore.MapAny{
-			"unit": unitResult,
-		})
-	}
+func CreateUnit(DB *gorm.DB) echo.HandlerFunc {
+	return unitLookupController.Create(DB)
 }
 
 func GetAllUnits(DB *gorm.DB) echo.HandlerFunc {
+	return unitLookupController.GetAll(DB)
+}
 
-	return func(ctx echo.Context) error {
-		var err error
-		nokocore.KeepVoid(err)
-
-		pagination := extras.NewURLQueryPaginationFromEchoContext(ctx)
-
-		var units []models2.Unit
-		tx := DB.Offset(pagination.Offset).Limit(pagination.Limit).Find(&units)
-		if err = tx.Error; err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Failed to get units.", nil)
-		}
+func UpdateUnit(DB *gorm.DB) echo.HandlerFunc {
+	return unitLookupController.Update(DB)
+}
 
-		var unitResults []schemas2.UnitResult
-		for i, unit := range units {
-			nokocore.KeepVoid(i)
-			unitResults = append(unitResults, schemas2.ToUnitResult(&unit))
-		}
+func DeleteUnit(DB *gorm.DB) echo.HandlerFunc {
+	return unitLookupController.Delete(DB)
+}
 
-		return extras.NewMessageBodyOk(ctx, "Successfully get units.", &nokocore.MapAny{
-			"units": unitResults,
-		})
-	}
+func MergeUnit(DB *gorm.DB) echo.HandlerFunc {
+	return unitLookupController.Merge(DB)
 }
 
 func UnitController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
-	group.GET("/units", GetAllUnits(DB))
-	group.POST("/unit", CreateUnit(DB))
+	group.GET("/units", GetAllUnits(DB), middlewares.RequirePermission(permissions.ProductRead))
+	group.POST("/unit", CreateUnit(DB), middlewares.RequirePermission(permissions.UnitWrite))
+	group.PUT("/unit/:unitId", UpdateUnit(DB), middlewares.RequirePermission(permissions.UnitWrite))
+	group.DELETE("/unit/:unitId", DeleteUnit(DB), middlewares.RequirePermission(permissions.UnitWrite))
+	group.POST("/unit/:unitId/merge", MergeUnit(DB), middlewares.RequirePermission(permissions.UnitWrite))
 
 	return group
 }
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"net/http/httptest"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/models"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"testing"
+)
+
+func TestMergeUnit(t *testing.T) {
+	var err error
+	var DB *gorm.DB
+	nokocore.KeepVoid(err, DB)
+
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	if DB, err = gorm.Open(sqlite.Open(dsn), config); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []any{
+		&models2.Category{},
+		&models2.Package{},
+		&models2.Unit{},
+		&models2.Product{},
+		&models2.Barcode{},
+		&models2.ProductUnit{},
+	}
+
+	if err = DB.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	box := &models2.Package{BaseModel: models.BaseModel{UUID: nokocore.NewUUID()}, PackageType: "Box"}
+	tablet := &models2.Unit{BaseModel: models.BaseModel{UUID: nokocore.NewUUID()}, UnitType: "Tablet"}
+	tab := &models2.Unit{BaseModel: models.BaseModel{UUID: nokocore.NewUUID()}, UnitType: "Tab"}
+	for i, model := range []any{box, tablet, tab} {
+		nokocore.KeepVoid(i)
+		if err = DB.Create(model).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both products have tablet as the base unit
+	products := []models2.Product{
+		{Barcode: "8990001", ProductName: "Paracetamol 500 mg", PackageID: box.ID, UnitID: tablet.ID, UnitScale: 1},
+		{Barcode: "8990002", ProductName: "Amoxicillin 500 mg", PackageID: box.ID, UnitID: tablet.ID, UnitScale: 1},
+	}
+
+	for i := range products {
+		products[i].UUID = nokocore.NewUUID()
+		if err = DB.Create(&products[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first product has tab as an upper level
+	level := &models2.ProductUnit{
+		BaseModel: models.BaseModel{UUID: nokocore.NewUUID()},
+		ProductID: products[0].ID,
+		UnitID:    tab.ID,
+		Level:     1,
+		Factor:    10,
+	}
+	if err = DB.Create(level).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Validator = sqlx.NewValidator()
+	e.HTTPErrorHandler = extras.EchoHTTPErrorHandler()
+	e.POST("/unit/:unitId/merge", MergeUnit(DB))
+
+	merge := func() (int, nokocore.MapAny) {
+		var buf bytes.Buffer
+		nokocore.NoErr(json.NewEncoder(&buf).Encode(nokocore.MapAny{"targetId": tab.UUID.String()}))
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/unit/%s/merge", tablet.UUID), &buf)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		data := nokocore.MapAny{}
+		if messageBody := (nokocore.MapAny{}); json.Unmarshal(rec.Body.Bytes(), &messageBody) == nil {
+			if temp, ok := messageBody["data"].(map[string]any); ok {
+				data = temp
+			}
+		}
+
+		return rec.Code, data
+	}
+
+	countLevels := func(unitID uint) int64 {
+		var count int64
+		nokocore.NoErr(DB.Model(&models2.ProductUnit{}).Where("unit_id = ?", unitID).Count(&count).Error)
+		return count
+	}
+
+	// the first product would end up with two tab levels
+	code, data := merge()
+	if code != http.StatusConflict {
+		t.Errorf("merge status should be 409, got %d", code)
+	}
+
+	if conflicts, ok := data["products"].([]any); !ok || len(conflicts) != 1 {
+		t.Errorf("merge should list the first product, got %v", data["products"])
+	}
+
+	if count := countLevels(tablet.ID); count != 2 {
+		t.Errorf("failed merge should keep 2 tablet levels, got %d", count)
+	}
+
+	// without the conflict every level follows the merge
+	if err = DB.Unscoped().Delete(level).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if code, data = merge(); code != http.StatusOK {
+		t.Errorf("merge status should be 200, got %d", code)
+	}
+
+	if count := countLevels(tablet.ID); count != 0 {
+		t.Errorf("merge should move every tablet level, got %d", count)
+	}
+
+	if count := countLevels(tab.ID); count != 2 {
+		t.Errorf("merge should move 2 levels to tab, got %d", count)
+	}
+
+	var unit models2.Unit
+	if err = DB.Unscoped().Where("id = ?", tablet.ID).Find(&unit).Error; err != nil || unit.ID != 0 {
+		t.Errorf("merged unit should be removed")
+	}
+}
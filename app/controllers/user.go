@@ -10,9 +10,7 @@ import (
 	"nokowebapi/apis/schemas"
 	"nokowebapi/apis/utils"
 	"nokowebapi/console"
-	"nokowebapi/globals"
 	"nokowebapi/nokocore"
-	"nokowebapi/sqlx"
 	models2 "pharma-cash-go/app/models"
 	repositories2 "pharma-cash-go/app/repositories"
 	schemas2 "pharma-cash-go/app/schemas"
@@ -53,6 +51,24 @@ func GetProfile(DB *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+func GetPermissions(DB *gorm.DB) echo.HandlerFunc {
+	nokocore.KeepVoid(DB)
+
+	return func(ctx echo.Context) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+		roles := jwtAuthInfo.GetRoles()
+		permissions := utils.GetUserPermissions(jwtAuthInfo)
+
+		return extras.NewMessageBodyOk(ctx, "Successfully retrieved.", &nokocore.MapAny{
+			"roles":       roles,
+			"permissions": permissions,
+		})
+	}
+}
+
 func GetAllSessions(DB *gorm.DB) echo.HandlerFunc {
 	nokocore.KeepVoid(DB)
 
@@ -111,61 +127,6 @@ func SetLogout(DB *gorm.DB) echo.HandlerFunc {
 	}
 }
 
-func GetRefreshToken(DB *gorm.DB) echo.HandlerFunc {
-	nokocore.KeepVoid(DB)
-
-	jwtConfig := globals.GetJwtConfig()
-	signingMethod := jwtConfig.GetSigningMethod()
-	expiresIn := jwtConfig.GetExpiresIn()
-
-	sessionRepository := repositories.NewSessionRepository(DB)
-
-	return func(ctx echo.Context) error {
-		var err error
-		nokocore.KeepVoid(err)
-
-		jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
-		session := jwtAuthInfo.Session
-		user := jwtAuthInfo.User
-
-		// get user roles
-		roles := utils.ToUserRolesArrayString(user.Roles)
-
-		sessionID := session.UUID
-		timeUtcNow := nokocore.GetTimeUtcNow()
-		expires := timeUtcNow.Add(expiresIn)
-
-		jwtClaimsDataAccess := nokocore.NewEmptyJwtClaimsDataAccess()
-		jwtClaimsDataAccess.SetSubject("NokoWebApiToken")
-		jwtClaimsDataAccess.SetIssuer(jwtConfig.Issuer)
-		jwtClaimsDataAccess.SetAudience(jwtConfig.Audience)
-		jwtClaimsDataAccess.SetIssuedAt(timeUtcNow)
-		jwtClaimsDataAccess.SetExpiresAt(expires)
-		jwtClaimsDataAccess.SetUser(user.Username)
-		jwtClaimsDataAccess.SetSessionID(sessionID.String())
-		jwtClaimsDataAccess.SetRoles(roles)
-		jwtClaimsDataAccess.SetAdmin(user.Admin)
-		jwtClaimsDataAccess.SetLevel(user.Level)
-
-		jwtClaims := nokocore.ToJwtClaims(jwtClaimsDataAccess, signingMethod)
-		jwtToken := nokocore.GenerateJwtToken(jwtClaims, jwtConfig.SecretKey)
-
-		// don't let gorm update the user
-		session.User = models.User{}
-
-		// update the session
-		session.RefreshTokenID = sqlx.NewString(jwtClaimsDataAccess.GetIdentity())
-		if err = sessionRepository.SafeUpdate(session, "uuid = ?", session.UUID); err != nil {
-			console.Error(fmt.Sprintf("panic: %s", err.Error()))
-			return extras.NewMessageBodyInternalServerError(ctx, "Failed to update session.", nil)
-		}
-
-		return extras.NewMessageBodyOk(ctx, "Successfully refresh token.", &nokocore.MapAny{
-			"accessToken": jwtToken,
-		})
-	}
-}
-
 func DeleteOwnUser(DB *gorm.DB) echo.HandlerFunc {
 	nokocore.KeepVoid(DB)
 
@@ -229,9 +190,9 @@ func DeleteOwnUser(DB *gorm.DB) echo.HandlerFunc {
 func UserController(group *echo.Group, DB *gorm.DB) *echo.Group {
 
 	group.GET("/profile", GetProfile(DB))
+	group.GET("/permissions", GetPermissions(DB))
 	group.GET("/sessions", GetAllSessions(DB))
 	group.POST("/logout", SetLogout(DB))
-	group.GET("/refresh-token", GetRefreshToken(DB))
 	group.DELETE("/me", DeleteOwnUser(DB))
 
 	return group
@@ -0,0 +1,9 @@
+package costing
+
+type Config struct {
+	Method string `mapstructure:"method" json:"method" yaml:"method"`
+}
+
+func (Config) GetNameType() string {
+	return "Costing"
+}
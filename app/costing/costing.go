@@ -0,0 +1,428 @@
+package costing
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	"strings"
+	"time"
+)
+
+// GetMethod method, costing method from 'costing' config, fifo by default.
+func GetMethod() string {
+	config := globals.GetConfigGlobals[Config]()
+	if method := strings.ToLower(config.Method); models2.IsCostMethod(method) {
+		return method
+	}
+	return models2.CostMethodFIFO
+}
+
+type ValuationRow struct {
+	ProductID   uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	ProductName string          `mapstructure:"product_name" json:"productName"`
+	UnitType    string          `mapstructure:"unit_type" json:"unitType"`
+	Quantity    int             `mapstructure:"quantity" json:"quantity"`
+	UnitCost    decimal.Decimal `mapstructure:"unit_cost" json:"unitCost"`
+	Value       decimal.Decimal `mapstructure:"value" json:"value"`
+}
+
+type Valuation struct {
+	AsOf   time.Time       `mapstructure:"as_of" json:"asOf"`
+	Method string          `mapstructure:"method" json:"method"`
+	Rows   []ValuationRow  `mapstructure:"rows" json:"rows"`
+	Total  decimal.Decimal `mapstructure:"total" json:"total"`
+}
+
+type CostOfGoodsRow struct {
+	ProductID   uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	ProductName string          `mapstructure:"product_name" json:"productName"`
+	UnitType    string          `mapstructure:"unit_type" json:"unitType"`
+	Quantity    int             `mapstructure:"quantity" json:"quantity"`
+	CostOfGoods decimal.Decimal `mapstructure:"cost_of_goods" json:"costOfGoods"`
+}
+
+type CostOfGoods struct {
+	From  time.Time        `mapstructure:"from" json:"from"`
+	To    time.Time        `mapstructure:"to" json:"to"`
+	Rows  []CostOfGoodsRow `mapstructure:"rows" json:"rows"`
+	Total decimal.Decimal  `mapstructure:"total" json:"total"`
+}
+
+type CostingServiceImpl interface {
+	Receive(product *models2.Product, goodsReceiptID uint, quantity int, unitCost decimal.Decimal, reference string) error
+	Consume(product *models2.Product, quantity int, kind string, reference string) (decimal.Decimal, error)
+	Sync(product *models2.Product, reference string) error
+	GetUnitCost(product *models2.Product) (decimal.Decimal, error)
+	SyncProducts() error
+	Valuation(asOf time.Time) (*Valuation, error)
+	CostOfGoodsSold(from time.Time, to time.Time) (*CostOfGoods, error)
+}
+
+type CostingService struct {
+	DB     *gorm.DB
+	Method string
+}
+
+func NewCostingService(DB *gorm.DB) CostingServiceImpl {
+	return &CostingService{
+		DB:     DB,
+		Method: GetMethod(),
+	}
+}
+
+// getBalance method, latest ledger entry of the product, empty if none.
+func (c *CostingService) getBalance(productID uint) (*models2.CostEntry, error) {
+	var err error
+	var costEntries []models2.CostEntry
+	nokocore.KeepVoid(err, costEntries)
+
+	costEntryRepository := repositories2.NewCostEntryRepository(c.DB)
+	costEntries, err = costEntryRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Where("product_id = ?", productID).Order("id DESC").Limit(1), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(costEntries) == 0 {
+		return &models2.CostEntry{
+			ProductID:    productID,
+			BalanceValue: decimal.Zero,
+		}, nil
+	}
+
+	return &costEntries[0], nil
+}
+
+func (c *CostingService) record(balance *models2.CostEntry, kind string, quantity int, totalCost decimal.Decimal, reference string) error {
+	unitCost := decimal.Zero
+	if quantity != 0 {
+		unitCost = totalCost.Div(decimal.NewFromInt(int64(quantity))).Abs().Round(2)
+	}
+
+	balanceQuantity := balance.BalanceQuantity + quantity
+	balanceValue := balance.BalanceValue.Add(totalCost)
+
+	// no residue from rounding when the stock runs out
+	if balanceQuantity == 0 {
+		balanceValue = decimal.Zero
+	}
+
+	costEntryRepository := repositories2.NewCostEntryRepository(c.DB)
+	return costEntryRepository.Create(&models2.CostEntry{
+		ProductID:       balance.ProductID,
+		Kind:            kind,
+		Method:          c.Method,
+		Quantity:        quantity,
+		UnitCost:        unitCost,
+		TotalCost:       totalCost,
+		BalanceQuantity: balanceQuantity,
+		BalanceValue:    balanceValue,
+		Reference:       reference,
+		OccurredAt:      nokocore.GetTimeUtcNow(),
+	})
+}
+
+// Receive method, adds a cost layer and a ledger entry, goods receipt id is
+// empty for opening stock and adjustments.
+func (c *CostingService) Receive(product *models2.Product, goodsReceiptID uint, quantity int, unitCost decimal.Decimal, reference string) error {
+	var err error
+	var balance *models2.CostEntry
+	nokocore.KeepVoid(err, balance)
+
+	if quantity <= 0 {
+		return nil
+	}
+
+	if balance, err = c.getBalance(product.ID); err != nil {
+		return err
+	}
+
+	costLayerRepository := repositories2.NewCostLayerRepository(c.DB)
+	if err = costLayerRepository.Create(&models2.CostLayer{
+		ProductID:      product.ID,
+		GoodsReceiptID: goodsReceiptID,
+		Quantity:       quantity,
+		Remaining:      quantity,
+		UnitCost:       unitCost,
+		ReceivedAt:     nokocore.GetTimeUtcNow(),
+	}); err != nil {
+		return err
+	}
+
+	kind := models2.CostEntryKindReceipt
+	if goodsReceiptID == 0 {
+		kind = models2.CostEntryKindAdjustment
+		if balance.ID == 0 {
+			kind = models2.CostEntryKindOpening
+		}
+	}
+
+	totalCost := unitCost.Mul(decimal.NewFromInt(int64(quantity)))
+	return c.record(balance, kind, quantity, totalCost, reference)
+}
+
+// Consume method, takes units from the oldest cost layers and returns their cost,
+// by the moving average the cost is the average of the balance instead,
+// units missing from the layers are costed by the latest unit cost.
+func (c *CostingService) Consume(product *models2.Product, quantity int, kind string, reference string) (decimal.Decimal, error) {
+	var err error
+	var balance *models2.CostEntry
+	var costLayers []models2.CostLayer
+	nokocore.KeepVoid(err, balance, costLayers)
+
+	if quantity <= 0 {
+		return decimal.Zero, nil
+	}
+
+	if balance, err = c.getBalance(product.ID); err != nil {
+		return decimal.Zero, err
+	}
+
+	costLayerRepository := repositories2.NewCostLayerRepository(c.DB)
+	costLayers, err = costLayerRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Where("product_id = ? AND remaining > 0", product.ID).Order("received_at ASC, id ASC"), nil
+	})
+
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	fallback := product.PurchasePrice
+	cost := decimal.Zero
+	remaining := quantity
+	for i, costLayer := range costLayers {
+		nokocore.KeepVoid(i)
+
+		if remaining == 0 {
+			break
+		}
+
+		taken := min(remaining, costLayer.Remaining)
+		cost = cost.Add(costLayer.UnitCost.Mul(decimal.NewFromInt(int64(taken))))
+		fallback = costLayer.UnitCost
+		remaining -= taken
+
+		stmt := c.DB.Model(&models2.CostLayer{}).Where("id = ?", costLayer.ID)
+		if err = stmt.UpdateColumn("remaining", costLayer.Remaining-taken).Error; err != nil {
+			return decimal.Zero, err
+		}
+	}
+
+	// oversold, no layers left
+	cost = cost.Add(fallback.Mul(decimal.NewFromInt(int64(remaining))))
+
+	if c.Method == models2.CostMethodMovingAverage && balance.BalanceQuantity > 0 {
+		if quantity == balance.BalanceQuantity {
+			cost = balance.BalanceValue
+		} else {
+			average := balance.BalanceValue.Div(decimal.NewFromInt(int64(balance.BalanceQuantity)))
+			cost = average.Mul(decimal.NewFromInt(int64(quantity))).Round(2)
+		}
+	}
+
+	if err = c.record(balance, kind, -quantity, cost.Neg(), reference); err != nil {
+		return decimal.Zero, err
+	}
+
+	return cost, nil
+}
+
+// Sync method, adjusts the ledger to the product stock, added units are
+// costed by the purchase price, used when stock is set directly.
+func (c *CostingService) Sync(product *models2.Product, reference string) error {
+	var err error
+	var balance *models2.CostEntry
+	nokocore.KeepVoid(err, balance)
+
+	if balance, err = c.getBalance(product.ID); err != nil {
+		return err
+	}
+
+	quantity := product.Stock - balance.BalanceQuantity
+	switch {
+	case quantity > 0:
+		return c.Receive(product, 0, quantity, product.PurchasePrice, reference)
+	case quantity < 0:
+		_, err = c.Consume(product, -quantity, models2.CostEntryKindAdjustment, reference)
+		return err
+	default:
+		return nil
+	}
+}
+
+// GetUnitCost method, average cost of the stock on hand, the purchase price
+// when there is no stock on hand.
+func (c *CostingService) GetUnitCost(product *models2.Product) (decimal.Decimal, error) {
+	var err error
+	var balance *models2.CostEntry
+	nokocore.KeepVoid(err, balance)
+
+	if balance, err = c.getBalance(product.ID); err != nil {
+		return decimal.Zero, err
+	}
+
+	if balance.BalanceQuantity <= 0 {
+		return product.PurchasePrice, nil
+	}
+
+	return balance.BalanceValue.Div(decimal.NewFromInt(int64(balance.BalanceQuantity))).Round(2), nil
+}
+
+// SyncProducts method, opening stock of products created before cost layers.
+func (c *CostingService) SyncProducts() error {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	productRepository := repositories2.NewProductRepository(c.DB)
+	products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Where("stock > 0 AND id NOT IN (?)", c.DB.Model(&models2.CostEntry{}).Select("product_id")), nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if err = c.Sync(&product, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Valuation method, quantity and value of every product by the last ledger entry before as of.
+func (c *CostingService) Valuation(asOf time.Time) (*Valuation, error) {
+	var err error
+	var costEntries []models2.CostEntry
+	nokocore.KeepVoid(err, costEntries)
+
+	asOf = asOf.UTC()
+
+	costEntryRepository := repositories2.NewCostEntryRepository(c.DB)
+	costEntries, err = costEntryRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		latest := c.DB.Model(&models2.CostEntry{}).Select("MAX(id)").Where("occurred_at < ? AND deleted_at IS NULL", asOf).Group("product_id")
+		stmt := tx.Preload("Product", func(tx *gorm.DB) *gorm.DB {
+			return tx.Unscoped()
+		}).Preload("Product.Unit")
+		return stmt.Where("id IN (?)", latest).Order("product_id ASC"), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &Valuation{
+		AsOf:   asOf,
+		Method: c.Method,
+		Rows:   make([]ValuationRow, 0, len(costEntries)),
+		Total:  decimal.Zero,
+	}
+
+	for i, costEntry := range costEntries {
+		nokocore.KeepVoid(i)
+
+		if costEntry.BalanceQuantity == 0 && costEntry.BalanceValue.IsZero() {
+			continue
+		}
+
+		unitCost := decimal.Zero
+		if costEntry.BalanceQuantity != 0 {
+			unitCost = costEntry.BalanceValue.Div(decimal.NewFromInt(int64(costEntry.BalanceQuantity))).Round(2)
+		}
+
+		valuation.Rows = append(valuation.Rows, ValuationRow{
+			ProductID:   costEntry.Product.UUID,
+			ProductName: costEntry.Product.ProductName,
+			UnitType:    costEntry.Product.Unit.UnitType,
+			Quantity:    costEntry.BalanceQuantity,
+			UnitCost:    unitCost,
+			Value:       costEntry.BalanceValue,
+		})
+
+		valuation.Total = valuation.Total.Add(costEntry.BalanceValue)
+	}
+
+	return valuation, nil
+}
+
+type costOfGoodsSum struct {
+	ProductID uint
+	Quantity  int
+	TotalCost decimal.Decimal
+}
+
+// CostOfGoodsSold method, cost of sold units between from and to per product.
+func (c *CostingService) CostOfGoodsSold(from time.Time, to time.Time) (*CostOfGoods, error) {
+	var err error
+	var sums []costOfGoodsSum
+	var products []models2.Product
+	nokocore.KeepVoid(err, sums, products)
+
+	from = from.UTC()
+	to = to.UTC()
+
+	if !from.Before(to) {
+		return nil, errors.New("invalid period")
+	}
+
+	stmt := c.DB.Model(&models2.CostEntry{}).Select("product_id, -SUM(quantity) AS quantity, -SUM(total_cost) AS total_cost")
+	stmt = stmt.Where("kind = ? AND occurred_at >= ? AND occurred_at < ? AND deleted_at IS NULL", models2.CostEntryKindSale, from, to)
+	if err = stmt.Group("product_id").Order("product_id ASC").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]uint, len(sums))
+	for i, sum := range sums {
+		productIDs[i] = sum.ProductID
+	}
+
+	productRepository := repositories2.NewProductRepository(c.DB)
+	products, err = productRepository.ManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Preload("Unit").Where("id IN ?", productIDs), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	productMap := make(map[uint]models2.Product, len(products))
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+		productMap[product.ID] = product
+	}
+
+	costOfGoods := &CostOfGoods{
+		From:  from,
+		To:    to,
+		Rows:  make([]CostOfGoodsRow, 0, len(sums)),
+		Total: decimal.Zero,
+	}
+
+	for i, sum := range sums {
+		nokocore.KeepVoid(i)
+
+		product := productMap[sum.ProductID]
+		costOfGoods.Rows = append(costOfGoods.Rows, CostOfGoodsRow{
+			ProductID:   product.UUID,
+			ProductName: product.ProductName,
+			UnitType:    product.Unit.UnitType,
+			Quantity:    sum.Quantity,
+			CostOfGoods: sum.TotalCost,
+		})
+
+		costOfGoods.Total = costOfGoods.Total.Add(sum.TotalCost)
+	}
+
+	return costOfGoods, nil
+}
@@ -0,0 +1,337 @@
+package expiry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/costing"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/registers"
+	repositories2 "pharma-cash-go/app/repositories"
+	"time"
+)
+
+// Windows, days left before expiry, stock is flagged once per window.
+var Windows = []int{30, 60, 90}
+
+var ErrNothingExpired = errors.New("no expired stock")
+
+type ExpiryRow struct {
+	ProductID   uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	ProductName string          `mapstructure:"product_name" json:"productName"`
+	Barcode     string          `mapstructure:"barcode" json:"barcode"`
+	UnitType    string          `mapstructure:"unit_type" json:"unitType"`
+	Expires     string          `mapstructure:"expires" json:"expires"`
+	DaysLeft    int             `mapstructure:"days_left" json:"daysLeft"`
+	WindowDays  int             `mapstructure:"window_days" json:"windowDays"`
+	Quantity    int             `mapstructure:"quantity" json:"quantity"`
+	UnitCost    decimal.Decimal `mapstructure:"unit_cost" json:"unitCost"`
+	Value       decimal.Decimal `mapstructure:"value" json:"value"`
+}
+
+type ExpirySummary struct {
+	WindowDays int             `mapstructure:"window_days" json:"windowDays"`
+	Products   int             `mapstructure:"products" json:"products"`
+	Quantity   int             `mapstructure:"quantity" json:"quantity"`
+	Value      decimal.Decimal `mapstructure:"value" json:"value"`
+}
+
+type ExpiryReport struct {
+	Date    string          `mapstructure:"date" json:"date"`
+	Days    int             `mapstructure:"days" json:"days"`
+	Rows    []ExpiryRow     `mapstructure:"rows" json:"rows"`
+	Summary []ExpirySummary `mapstructure:"summary" json:"summary"`
+	Total   decimal.Decimal `mapstructure:"total" json:"total"`
+}
+
+// GetDaysLeft method, negative when expired.
+func GetDaysLeft(expires time.Time, today time.Time) int {
+	expires = time.Date(expires.Year(), expires.Month(), expires.Day(), 0, 0, 0, 0, time.UTC)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	return int(expires.Sub(today).Hours() / 24)
+}
+
+// GetWindowDays method, smallest window of the days left, zero when expired,
+// minus one when outside every window.
+func GetWindowDays(daysLeft int) int {
+	if daysLeft < 0 {
+		return 0
+	}
+
+	for i, window := range Windows {
+		nokocore.KeepVoid(i)
+
+		if daysLeft <= window {
+			return window
+		}
+	}
+
+	return -1
+}
+
+type ExpiryServiceImpl interface {
+	Report(today time.Time, days int) (*ExpiryReport, error)
+	Quarantine(productIDs []uint, userID uint, note string, today time.Time) (*models2.WriteOff, error)
+	Flag(today time.Time) (int, error)
+}
+
+type ExpiryService struct {
+	DB *gorm.DB
+}
+
+func NewExpiryService(DB *gorm.DB) ExpiryServiceImpl {
+	return &ExpiryService{
+		DB: DB,
+	}
+}
+
+// Report method, stocked products expired or expiring within the given days,
+// valued at cost, soonest first.
+func (e *ExpiryService) Report(today time.Time, days int) (*ExpiryReport, error) {
+	var err error
+	var unitCost decimal.Decimal
+	var products []models2.Product
+	nokocore.KeepVoid(err, unitCost, products)
+
+	today = today.UTC()
+	until := today.AddDate(0, 0, days).Format(nokocore.DateOnlyFormat)
+
+	productRepository := repositories2.NewProductRepository(e.DB)
+	products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Preload("Unit").Where("stock > 0 AND expires <= ?", until).Order("expires ASC, id ASC"), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ExpiryReport{
+		Date:    today.Format(nokocore.DateOnlyFormat),
+		Days:    days,
+		Rows:    make([]ExpiryRow, 0, len(products)),
+		Summary: make([]ExpirySummary, 0, len(Windows)+1),
+		Total:   decimal.Zero,
+	}
+
+	summaries := make(map[int]*ExpirySummary)
+	for i, window := range append([]int{0}, Windows...) {
+		nokocore.KeepVoid(i)
+
+		report.Summary = append(report.Summary, ExpirySummary{
+			WindowDays: window,
+			Value:      decimal.Zero,
+		})
+	}
+
+	for i := range report.Summary {
+		summaries[report.Summary[i].WindowDays] = &report.Summary[i]
+	}
+
+	costingService := costing.NewCostingService(e.DB)
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if unitCost, err = costingService.GetUnitCost(&product); err != nil {
+			return nil, err
+		}
+
+		daysLeft := GetDaysLeft(product.Expires.Time, today)
+		window := GetWindowDays(daysLeft)
+		value := unitCost.Mul(decimal.NewFromInt(int64(product.Stock)))
+
+		report.Rows = append(report.Rows, ExpiryRow{
+			ProductID:   product.UUID,
+			ProductName: product.ProductName,
+			Barcode:     product.Barcode,
+			UnitType:    product.Unit.UnitType,
+			Expires:     product.Expires.Format(nokocore.DateOnlyFormat),
+			DaysLeft:    daysLeft,
+			WindowDays:  window,
+			Quantity:    product.Stock,
+			UnitCost:    unitCost,
+			Value:       value,
+		})
+
+		// requested days past the last window are left out of the summary
+		if summary, ok := summaries[window]; ok {
+			summary.Products += 1
+			summary.Quantity += product.Stock
+			summary.Value = summary.Value.Add(value)
+		}
+
+		report.Total = report.Total.Add(value)
+	}
+
+	return report, nil
+}
+
+// Quarantine method, moves expired stock of the given products, every expired
+// product when empty, out of the sellable quantity into a write-off document.
+func (e *ExpiryService) Quarantine(productIDs []uint, userID uint, note string, today time.Time) (*models2.WriteOff, error) {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	// expired before today, the expiry date itself is still sellable
+	before := today.UTC().Format(nokocore.DateOnlyFormat)
+
+	writeOff := &models2.WriteOff{
+		UserID:    userID,
+		Reason:    models2.WriteOffReasonExpired,
+		Note:      note,
+		TotalCost: decimal.Zero,
+	}
+
+	err = e.DB.Transaction(func(tx *gorm.DB) error {
+		productRepository := repositories2.NewProductRepository(tx)
+		writeOffRepository := repositories2.NewWriteOffRepository(tx)
+		costingService := costing.NewCostingService(tx)
+		registerService := registers.NewRegisterService(tx)
+
+		products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+			stmt := tx.Where("stock > 0 AND expires < ?", before)
+			if len(productIDs) > 0 {
+				stmt = stmt.Where("id IN ?", productIDs)
+			}
+			return stmt.Order("expires ASC, id ASC"), nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if len(products) == 0 {
+			return ErrNothingExpired
+		}
+
+		if err = writeOffRepository.Create(writeOff); err != nil {
+			return err
+		}
+
+		reference := writeOff.UUID.String()
+		for i, product := range products {
+			nokocore.KeepVoid(i)
+
+			var cost decimal.Decimal
+			quantity := product.Stock
+			if cost, err = costingService.Consume(&product, quantity, models2.CostEntryKindWriteOff, reference); err != nil {
+				return err
+			}
+
+			if err = registerService.Adjust(&product, -quantity, userID, reference, "expired stock written off"); err != nil {
+				return err
+			}
+
+			writeOffItem := models2.WriteOffItem{
+				WriteOffID: writeOff.ID,
+				ProductID:  product.ID,
+				Quantity:   quantity,
+				Expires:    product.Expires,
+				TotalCost:  cost,
+			}
+
+			if err = tx.Create(&writeOffItem).Error; err != nil {
+				return err
+			}
+
+			// unit levels and categories are kept, no save hooks
+			product.SetStock(0)
+			stmt := tx.Model(&models2.Product{}).Where("id = ?", product.ID)
+			if err = stmt.UpdateColumns(map[string]any{
+				"stock":         product.Stock,
+				"package_total": product.PackageTotal,
+				"unit_extra":    product.UnitExtra,
+			}).Error; err != nil {
+				return err
+			}
+
+			// expired stock is taken from every location
+			if err = tx.Model(&models2.ProductStock{}).Where("product_id = ?", product.ID).UpdateColumn("stock", 0).Error; err != nil {
+				return err
+			}
+
+			writeOffItem.Product = product
+			writeOff.Items = append(writeOff.Items, writeOffItem)
+			writeOff.TotalCost = writeOff.TotalCost.Add(cost)
+		}
+
+		return tx.Model(&models2.WriteOff{}).Where("id = ?", writeOff.ID).UpdateColumn("total_cost", writeOff.TotalCost).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return writeOff, nil
+}
+
+// Flag method, flags stock entering an expiry window since the last run,
+// returns the count of new alerts.
+func (e *ExpiryService) Flag(today time.Time) (int, error) {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	today = today.UTC()
+	until := today.AddDate(0, 0, Windows[len(Windows)-1]).Format(nokocore.DateOnlyFormat)
+
+	productRepository := repositories2.NewProductRepository(e.DB)
+	products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Where("stock > 0 AND expires <= ?", until).Order("expires ASC, id ASC"), nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		var count int64
+		window := GetWindowDays(GetDaysLeft(product.Expires.Time, today))
+		stmt := e.DB.Model(&models2.ExpiryAlert{}).Where("product_id = ? AND expires = ? AND window_days = ?", product.ID, product.Expires, window)
+		if err = stmt.Count(&count).Error; err != nil {
+			return flagged, err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if err = e.DB.Create(&models2.ExpiryAlert{
+			ProductID:  product.ID,
+			Expires:    product.Expires,
+			WindowDays: window,
+			Quantity:   product.Stock,
+			FlaggedAt:  nokocore.GetTimeUtcNow(),
+		}).Error; err != nil {
+			return flagged, err
+		}
+
+		flagged += 1
+	}
+
+	return flagged, nil
+}
+
+// FlagJob method, scheduler job flagging newly expiring stock.
+func FlagJob(DB *gorm.DB, now time.Time) error {
+	var err error
+	var flagged int
+	nokocore.KeepVoid(err, flagged)
+
+	if flagged, err = NewExpiryService(DB).Flag(now); err != nil {
+		return err
+	}
+
+	if flagged > 0 {
+		console.Warn(fmt.Sprintf("%d product(s) has been flagged as expiring.", flagged))
+	}
+
+	return nil
+}
@@ -28,6 +28,7 @@ func ProductFactory(DB *gorm.DB) []any {
 			UnitID:           1,
 			UnitScale:        1,
 			UnitExtra:        0,
+			Stock:            1,
 			Categories: []models2.Category{
 				{
 					CategoryName: "Yamaha Guitar",
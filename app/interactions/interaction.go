@@ -0,0 +1,438 @@
+package interactions
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const MaxImportSize = 4 << 20
+
+var ErrInvalidSeverity = errors.New("invalid interaction severity")
+var ErrSameIngredient = errors.New("ingredient cannot interact with itself")
+var ErrNothingToAcknowledge = errors.New("no severe drug interactions to acknowledge")
+
+type InteractionWarning struct {
+	InteractionID    uuid.UUID `mapstructure:"interaction_id" json:"interactionId"`
+	Severity         string    `mapstructure:"severity" json:"severity"`
+	Note             string    `mapstructure:"note" json:"note"`
+	Ingredient       string    `mapstructure:"ingredient" json:"ingredient"`
+	InteractsWith    string    `mapstructure:"interacts_with" json:"interactsWith"`
+	ProductID        uuid.UUID `mapstructure:"product_id" json:"productId"`
+	ProductName      string    `mapstructure:"product_name" json:"productName"`
+	OtherProductID   uuid.UUID `mapstructure:"other_product_id" json:"otherProductId"`
+	OtherProductName string    `mapstructure:"other_product_name" json:"otherProductName"`
+	Acknowledged     bool      `mapstructure:"acknowledged" json:"acknowledged"`
+	AcknowledgedBy   string    `mapstructure:"acknowledged_by" json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt   string    `mapstructure:"acknowledged_at" json:"acknowledgedAt,omitempty"`
+	AcknowledgeNote  string    `mapstructure:"acknowledge_note" json:"acknowledgeNote,omitempty"`
+}
+
+type ImportResult struct {
+	Created int      `mapstructure:"created" json:"created"`
+	Updated int      `mapstructure:"updated" json:"updated"`
+	Skipped int      `mapstructure:"skipped" json:"skipped"`
+	Errors  []string `mapstructure:"errors" json:"errors"`
+}
+
+type InteractionServiceImpl interface {
+	Save(ingredientName string, interactsWithName string, severity string, note string) (*models2.DrugInteraction, bool, error)
+	Import(reader io.Reader) (*ImportResult, error)
+	Check(productIDs []uint) ([]InteractionWarning, error)
+	CheckTransaction(transaction *models2.Transaction) ([]InteractionWarning, error)
+	Acknowledge(transaction *models2.Transaction, userID uint, note string) ([]InteractionWarning, error)
+}
+
+type InteractionService struct {
+	DB *gorm.DB
+}
+
+func NewInteractionService(DB *gorm.DB) InteractionServiceImpl {
+	return &InteractionService{
+		DB: DB,
+	}
+}
+
+func (i *InteractionService) getIngredient(ingredientName string) (*models2.ActiveIngredient, error) {
+	var err error
+	var activeIngredient *models2.ActiveIngredient
+	nokocore.KeepVoid(err, activeIngredient)
+
+	activeIngredientRepository := repositories2.NewActiveIngredientRepository(i.DB)
+
+	ingredientName = nokocore.ToTitleCase(strings.TrimSpace(ingredientName))
+	if activeIngredient, err = activeIngredientRepository.SafeFirst("ingredient_name = ?", ingredientName); err != nil {
+		return nil, err
+	}
+
+	// can be automatic build
+	if activeIngredient == nil {
+		activeIngredient = &models2.ActiveIngredient{
+			IngredientName: ingredientName,
+		}
+
+		if err = activeIngredientRepository.Create(activeIngredient); err != nil {
+			return nil, err
+		}
+	}
+
+	return activeIngredient, nil
+}
+
+// Save method, creates or updates the interaction of both ingredients,
+// unknown ingredient names are created, returns true if created.
+func (i *InteractionService) Save(ingredientName string, interactsWithName string, severity string, note string) (*models2.DrugInteraction, bool, error) {
+	var err error
+	var ingredient *models2.ActiveIngredient
+	var interactsWith *models2.ActiveIngredient
+	var drugInteraction *models2.DrugInteraction
+	nokocore.KeepVoid(err, ingredient, interactsWith, drugInteraction)
+
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if !models2.IsInteractionSeverity(severity) {
+		return nil, false, ErrInvalidSeverity
+	}
+
+	if ingredient, err = i.getIngredient(ingredientName); err != nil {
+		return nil, false, err
+	}
+
+	if interactsWith, err = i.getIngredient(interactsWithName); err != nil {
+		return nil, false, err
+	}
+
+	if ingredient.ID == interactsWith.ID {
+		return nil, false, ErrSameIngredient
+	}
+
+	pair := new(models2.DrugInteraction)
+	pair.SetPair(ingredient, interactsWith)
+
+	drugInteractionRepository := repositories2.NewDrugInteractionRepository(i.DB)
+	if drugInteraction, err = drugInteractionRepository.SafeFirst("ingredient_id = ? AND interacts_with_id = ?", pair.IngredientID, pair.InteractsWithID); err != nil {
+		return nil, false, err
+	}
+
+	if drugInteraction != nil {
+		drugInteraction.Severity = severity
+		drugInteraction.Note = strings.TrimSpace(note)
+		if err = drugInteractionRepository.SafeUpdate(drugInteraction, "id = ?", drugInteraction.ID); err != nil {
+			return nil, false, err
+		}
+
+		drugInteraction.Ingredient = pair.Ingredient
+		drugInteraction.InteractsWith = pair.InteractsWith
+		return drugInteraction, false, nil
+	}
+
+	drugInteraction = &models2.DrugInteraction{
+		IngredientID:    pair.IngredientID,
+		InteractsWithID: pair.InteractsWithID,
+		Severity:        severity,
+		Note:            strings.TrimSpace(note),
+	}
+
+	if err = drugInteractionRepository.Create(drugInteraction); err != nil {
+		return nil, false, err
+	}
+
+	drugInteraction.Ingredient = pair.Ingredient
+	drugInteraction.InteractsWith = pair.InteractsWith
+	return drugInteraction, true, nil
+}
+
+// Import method, reads 'ingredient, interacts with, severity, note' rows,
+// an optional header is skipped, invalid rows are reported and skipped.
+func (i *InteractionService) Import(reader io.Reader) (*ImportResult, error) {
+	var err error
+	var records [][]string
+	var created bool
+	nokocore.KeepVoid(err, records, created)
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	if records, err = csvReader.ReadAll(); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		Errors: []string{},
+	}
+
+	for j, record := range records {
+		line := j + 1
+
+		if len(record) < 3 {
+			if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+				continue
+			}
+
+			result.Skipped += 1
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: expected at least 3 columns", line))
+			continue
+		}
+
+		severity := strings.ToLower(strings.TrimSpace(record[2]))
+
+		// header
+		if j == 0 && severity == "severity" {
+			continue
+		}
+
+		var note string
+		if len(record) > 3 {
+			note = record[3]
+		}
+
+		ingredientName := strings.TrimSpace(record[0])
+		interactsWithName := strings.TrimSpace(record[1])
+		if ingredientName == "" || interactsWithName == "" {
+			result.Skipped += 1
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: ingredient names are required", line))
+			continue
+		}
+
+		if _, created, err = i.Save(ingredientName, interactsWithName, severity, note); err != nil {
+			if errors.Is(err, ErrInvalidSeverity) || errors.Is(err, ErrSameIngredient) {
+				result.Skipped += 1
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: %s", line, err.Error()))
+				continue
+			}
+
+			return nil, err
+		}
+
+		if created {
+			result.Created += 1
+		} else {
+			result.Updated += 1
+		}
+	}
+
+	return result, nil
+}
+
+// Check method, interactions between active ingredients of different products,
+// most severe first.
+func (i *InteractionService) Check(productIDs []uint) ([]InteractionWarning, error) {
+	var err error
+	var products []models2.Product
+	var drugInteractions []models2.DrugInteraction
+	nokocore.KeepVoid(err, products, drugInteractions)
+
+	warnings := make([]InteractionWarning, 0)
+	if len(productIDs) < 2 {
+		return warnings, nil
+	}
+
+	productRepository := repositories2.NewProductRepository(i.DB)
+	products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Preload("Ingredients").Where("id IN ?", productIDs).Order("id ASC"), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// products by their active ingredients
+	ingredientProducts := make(map[uint][]*models2.Product)
+	ingredientIDs := make([]uint, 0)
+	for j := range products {
+		product := &products[j]
+		for k, productIngredient := range product.Ingredients {
+			nokocore.KeepVoid(k)
+
+			ingredientID := productIngredient.ActiveIngredientID
+			if !slices.Contains(ingredientIDs, ingredientID) {
+				ingredientIDs = append(ingredientIDs, ingredientID)
+			}
+
+			ingredientProducts[ingredientID] = append(ingredientProducts[ingredientID], product)
+		}
+	}
+
+	if len(ingredientIDs) < 2 {
+		return warnings, nil
+	}
+
+	drugInteractionRepository := repositories2.NewDrugInteractionRepository(i.DB)
+	drugInteractions, err = drugInteractionRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		stmt := tx.Preload("Ingredient").Preload("InteractsWith")
+		return stmt.Where("ingredient_id IN ? AND interacts_with_id IN ?", ingredientIDs, ingredientIDs), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for j, drugInteraction := range drugInteractions {
+		nokocore.KeepVoid(j)
+
+		for k, product := range ingredientProducts[drugInteraction.IngredientID] {
+			nokocore.KeepVoid(k)
+
+			for l, otherProduct := range ingredientProducts[drugInteraction.InteractsWithID] {
+				nokocore.KeepVoid(l)
+
+				// combination products are intended
+				if product.ID == otherProduct.ID {
+					continue
+				}
+
+				warnings = append(warnings, InteractionWarning{
+					InteractionID:    drugInteraction.UUID,
+					Severity:         drugInteraction.Severity,
+					Note:             drugInteraction.Note,
+					Ingredient:       drugInteraction.Ingredient.IngredientName,
+					InteractsWith:    drugInteraction.InteractsWith.IngredientName,
+					ProductID:        product.UUID,
+					ProductName:      product.ProductName,
+					OtherProductID:   otherProduct.UUID,
+					OtherProductName: otherProduct.ProductName,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(j, k int) bool {
+		return models2.GetInteractionSeverityLevel(warnings[j].Severity) > models2.GetInteractionSeverityLevel(warnings[k].Severity)
+	})
+
+	return warnings, nil
+}
+
+// CheckTransaction method, interactions of open carts in the transaction,
+// acknowledged interactions are flagged with the acknowledging pharmacist.
+func (i *InteractionService) CheckTransaction(transaction *models2.Transaction) ([]InteractionWarning, error) {
+	var err error
+	var productIDs []uint
+	var warnings []InteractionWarning
+	nokocore.KeepVoid(err, productIDs, warnings)
+
+	stmt := i.DB.Model(&models2.Cart{}).Where("transaction_id = ? AND closed = FALSE AND deleted_at IS NULL", transaction.ID)
+	if err = stmt.Distinct().Pluck("product_id", &productIDs).Error; err != nil {
+		return nil, err
+	}
+
+	if warnings, err = i.Check(productIDs); err != nil {
+		return nil, err
+	}
+
+	var acknowledgements []models2.InteractionAcknowledgement
+	if err = i.DB.Preload("Interaction").Preload("User").Find(&acknowledgements, "transaction_id = ?", transaction.ID).Error; err != nil {
+		return nil, err
+	}
+
+	acknowledged := make(map[uuid.UUID]*models2.InteractionAcknowledgement, len(acknowledgements))
+	for j := range acknowledgements {
+		acknowledged[acknowledgements[j].Interaction.UUID] = &acknowledgements[j]
+	}
+
+	for j, warning := range warnings {
+		if acknowledgement, ok := acknowledged[warning.InteractionID]; ok {
+			warnings[j].Acknowledged = true
+			warnings[j].AcknowledgedBy = acknowledgement.User.Username
+			warnings[j].AcknowledgedAt = nokocore.ToTimeUtcStringISO8601(acknowledgement.UpdatedAt)
+			warnings[j].AcknowledgeNote = acknowledgement.Note
+		}
+	}
+
+	return warnings, nil
+}
+
+// Acknowledge method, the pharmacist acknowledges every severe interaction of the basket,
+// interactions added to the basket later are acknowledged again.
+func (i *InteractionService) Acknowledge(transaction *models2.Transaction, userID uint, note string) ([]InteractionWarning, error) {
+	var err error
+	var warnings []InteractionWarning
+	var interactionIDs []uint
+	nokocore.KeepVoid(err, warnings, interactionIDs)
+
+	if warnings, err = i.CheckTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	severe := GetSevereInteractions(warnings)
+	if len(severe) == 0 {
+		return nil, ErrNothingToAcknowledge
+	}
+
+	if err = i.DB.Model(&models2.DrugInteraction{}).Where("uuid IN ?", severe).Pluck("id", &interactionIDs).Error; err != nil {
+		return nil, err
+	}
+
+	timeUtcNow := nokocore.GetTimeUtcNow()
+	err = i.DB.Transaction(func(tx *gorm.DB) error {
+		for j, interactionID := range interactionIDs {
+			nokocore.KeepVoid(j)
+
+			if err = tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "transaction_id"}, {Name: "interaction_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"user_id":    gorm.Expr("excluded.user_id"),
+					"note":       gorm.Expr("excluded.note"),
+					"updated_at": gorm.Expr("excluded.updated_at"),
+				}),
+			}).Create(&models2.InteractionAcknowledgement{
+				TransactionID: transaction.ID,
+				InteractionID: interactionID,
+				UserID:        userID,
+				Note:          note,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		transaction.AcknowledgedBy = userID
+		transaction.AcknowledgedAt = sql.NullTime{Time: timeUtcNow, Valid: true}
+		transaction.AcknowledgeNote = note
+		return tx.Model(transaction).Select("acknowledged_by", "acknowledged_at", "acknowledge_note").Updates(transaction).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return i.CheckTransaction(transaction)
+}
+
+// GetSevereInteractions method, unique severe interaction ids of the warnings.
+func GetSevereInteractions(warnings []InteractionWarning) []string {
+	interactionIDs := make([]string, 0)
+	for i, warning := range warnings {
+		nokocore.KeepVoid(i)
+
+		interactionID := warning.InteractionID.String()
+		if warning.Severity == models2.InteractionSeveritySevere && !slices.Contains(interactionIDs, interactionID) {
+			interactionIDs = append(interactionIDs, interactionID)
+		}
+	}
+
+	return interactionIDs
+}
+
+// RequiresAcknowledgement method, severe interactions not yet acknowledged by a pharmacist.
+func RequiresAcknowledgement(warnings []InteractionWarning) bool {
+	for i, warning := range warnings {
+		nokocore.KeepVoid(i)
+
+		if warning.Severity == models2.InteractionSeveritySevere && !warning.Acknowledged {
+			return true
+		}
+	}
+
+	return false
+}
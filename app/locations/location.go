@@ -0,0 +1,352 @@
+package locations
+
+import (
+	"database/sql"
+	"errors"
+	"gorm.io/gorm"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+)
+
+var ErrSellingLocationNotFound = errors.New("selling location not found")
+var ErrInvalidTransferStatus = errors.New("invalid stock transfer status")
+var ErrInsufficientStock = errors.New("insufficient stock at location")
+
+type LocationServiceImpl interface {
+	SyncLocations() error
+	GetSelling() (*models2.Location, error)
+	GetReceiving() (*models2.Location, error)
+	GetStock(productID uint, locationID uint) (int, error)
+	GetInTransit(productID uint) (int, error)
+	Move(productID uint, locationID uint, quantity int) error
+	Adjust(product *models2.Product, locationID uint, quantity int) error
+	Sync(product *models2.Product) error
+	SyncProducts() error
+	Send(stockTransfer *models2.StockTransfer) error
+	Receive(stockTransfer *models2.StockTransfer, userID uint) error
+	Cancel(stockTransfer *models2.StockTransfer) error
+}
+
+type LocationService struct {
+	DB *gorm.DB
+}
+
+func NewLocationService(DB *gorm.DB) LocationServiceImpl {
+	return &LocationService{
+		DB: DB,
+	}
+}
+
+// SyncLocations method, front shelf and back warehouse are created when there
+// are no locations yet.
+func (l *LocationService) SyncLocations() error {
+	var err error
+	var count int64
+	nokocore.KeepVoid(err, count)
+
+	if err = l.DB.Model(&models2.Location{}).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	locationRepository := repositories2.NewLocationRepository(l.DB)
+	for i, location := range []models2.Location{
+		{
+			Code:         models2.LocationCodeFront,
+			LocationName: "Front Shelf",
+			Selling:      true,
+		},
+		{
+			Code:         models2.LocationCodeWarehouse,
+			LocationName: "Back Warehouse",
+			Receiving:    true,
+		},
+	} {
+		nokocore.KeepVoid(i)
+
+		if err = locationRepository.Create(&location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *LocationService) GetSelling() (*models2.Location, error) {
+	var err error
+	var location *models2.Location
+	nokocore.KeepVoid(err, location)
+
+	locationRepository := repositories2.NewLocationRepository(l.DB)
+	if location, err = locationRepository.SafeFirst("selling = ?", true); err != nil {
+		return nil, err
+	}
+
+	if location == nil {
+		return nil, ErrSellingLocationNotFound
+	}
+
+	return location, nil
+}
+
+// GetReceiving method, the selling location when there is no receiving location.
+func (l *LocationService) GetReceiving() (*models2.Location, error) {
+	var err error
+	var location *models2.Location
+	nokocore.KeepVoid(err, location)
+
+	locationRepository := repositories2.NewLocationRepository(l.DB)
+	if location, err = locationRepository.SafeFirst("receiving = ?", true); err != nil {
+		return nil, err
+	}
+
+	if location == nil {
+		return l.GetSelling()
+	}
+
+	return location, nil
+}
+
+func (l *LocationService) GetStock(productID uint, locationID uint) (int, error) {
+	var err error
+	var productStock *models2.ProductStock
+	nokocore.KeepVoid(err, productStock)
+
+	productStockRepository := repositories2.NewProductStockRepository(l.DB)
+	if productStock, err = productStockRepository.SafeFirst("product_id = ? AND location_id = ?", productID, locationID); err != nil {
+		return 0, err
+	}
+
+	if productStock == nil {
+		return 0, nil
+	}
+
+	return productStock.Stock, nil
+}
+
+func (l *LocationService) GetInTransit(productID uint) (int, error) {
+	var err error
+	var quantity int
+	nokocore.KeepVoid(err, quantity)
+
+	stmt := l.DB.Model(&models2.StockTransferItem{}).Select("COALESCE(SUM(stock_transfer_items.quantity), 0)")
+	stmt = stmt.Joins("JOIN stock_transfers ON stock_transfers.id = stock_transfer_items.stock_transfer_id")
+	stmt = stmt.Where("stock_transfer_items.product_id = ? AND stock_transfers.status = ?", productID, models2.StockTransferStatusSent)
+	if err = stmt.Where("stock_transfers.deleted_at IS NULL AND stock_transfer_items.deleted_at IS NULL").Scan(&quantity).Error; err != nil {
+		return 0, err
+	}
+
+	return quantity, nil
+}
+
+// Move method, changes the quantity at a location only, product stock is kept.
+func (l *LocationService) Move(productID uint, locationID uint, quantity int) error {
+	var err error
+	var productStock *models2.ProductStock
+	nokocore.KeepVoid(err, productStock)
+
+	productStockRepository := repositories2.NewProductStockRepository(l.DB)
+	if productStock, err = productStockRepository.SafeFirst("product_id = ? AND location_id = ?", productID, locationID); err != nil {
+		return err
+	}
+
+	if productStock == nil {
+		return productStockRepository.Create(&models2.ProductStock{
+			ProductID:  productID,
+			LocationID: locationID,
+			Stock:      quantity,
+		})
+	}
+
+	stmt := l.DB.Model(&models2.ProductStock{}).Where("id = ?", productStock.ID)
+	return stmt.UpdateColumn("stock", gorm.Expr("stock + ?", quantity)).Error
+}
+
+// Adjust method, changes the quantity at a location and the product stock,
+// package total and unit extra of the product are kept in sync.
+func (l *LocationService) Adjust(product *models2.Product, locationID uint, quantity int) error {
+	var err error
+	var stock int
+	nokocore.KeepVoid(err, stock)
+
+	if quantity == 0 {
+		return nil
+	}
+
+	if err = l.Move(product.ID, locationID, quantity); err != nil {
+		return err
+	}
+
+	// current stock, the same product may be adjusted more than once
+	if err = l.DB.Model(&models2.Product{}).Select("stock").Where("id = ?", product.ID).Scan(&stock).Error; err != nil {
+		return err
+	}
+
+	product.SetStock(stock + quantity)
+	return l.DB.Model(&models2.Product{}).Where("id = ?", product.ID).UpdateColumns(map[string]any{
+		"stock":         product.Stock,
+		"package_total": product.PackageTotal,
+		"unit_extra":    product.UnitExtra,
+	}).Error
+}
+
+// Sync method, stock set directly on the product is put on the selling location.
+func (l *LocationService) Sync(product *models2.Product) error {
+	var err error
+	var stock int
+	var inTransit int
+	var location *models2.Location
+	nokocore.KeepVoid(err, stock, inTransit, location)
+
+	stmt := l.DB.Model(&models2.ProductStock{}).Select("COALESCE(SUM(stock), 0)")
+	if err = stmt.Where("product_id = ? AND deleted_at IS NULL", product.ID).Scan(&stock).Error; err != nil {
+		return err
+	}
+
+	if inTransit, err = l.GetInTransit(product.ID); err != nil {
+		return err
+	}
+
+	quantity := product.Stock - stock - inTransit
+	if quantity == 0 {
+		return nil
+	}
+
+	if location, err = l.GetSelling(); err != nil {
+		return err
+	}
+
+	return l.Move(product.ID, location.ID, quantity)
+}
+
+// SyncProducts method, stock of products without locations is put on the selling location.
+func (l *LocationService) SyncProducts() error {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	productRepository := repositories2.NewProductRepository(l.DB)
+	products, err = productRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		return tx.Where("stock <> 0 AND id NOT IN (?)", l.DB.Model(&models2.ProductStock{}).Select("product_id")), nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if err = l.Sync(&product); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Send method, items leave the source location, product stock is kept while in transit.
+func (l *LocationService) Send(stockTransfer *models2.StockTransfer) error {
+	if stockTransfer.Status != models2.StockTransferStatusDraft {
+		return ErrInvalidTransferStatus
+	}
+
+	return l.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		var stock int
+		nokocore.KeepVoid(err, stock)
+
+		locationService := NewLocationService(tx)
+		for i, stockTransferItem := range stockTransfer.Items {
+			nokocore.KeepVoid(i)
+
+			if stock, err = locationService.GetStock(stockTransferItem.ProductID, stockTransfer.FromLocationID); err != nil {
+				return err
+			}
+
+			if stock < stockTransferItem.Quantity {
+				return ErrInsufficientStock
+			}
+
+			if err = locationService.Move(stockTransferItem.ProductID, stockTransfer.FromLocationID, -stockTransferItem.Quantity); err != nil {
+				return err
+			}
+		}
+
+		stockTransfer.Status = models2.StockTransferStatusSent
+		stockTransfer.SentAt = sql.NullTime{Time: nokocore.GetTimeUtcNow(), Valid: true}
+		return tx.Model(&models2.StockTransfer{}).Where("id = ?", stockTransfer.ID).UpdateColumns(map[string]any{
+			"status":     stockTransfer.Status,
+			"sent_at":    stockTransfer.SentAt,
+			"updated_at": nokocore.GetTimeUtcNow(),
+		}).Error
+	})
+}
+
+// Receive method, items in transit arrive at the destination location.
+func (l *LocationService) Receive(stockTransfer *models2.StockTransfer, userID uint) error {
+	if stockTransfer.Status != models2.StockTransferStatusSent {
+		return ErrInvalidTransferStatus
+	}
+
+	return l.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		locationService := NewLocationService(tx)
+		for i, stockTransferItem := range stockTransfer.Items {
+			nokocore.KeepVoid(i)
+
+			if err = locationService.Move(stockTransferItem.ProductID, stockTransfer.ToLocationID, stockTransferItem.Quantity); err != nil {
+				return err
+			}
+		}
+
+		stockTransfer.Status = models2.StockTransferStatusReceived
+		stockTransfer.ReceivedAt = sql.NullTime{Time: nokocore.GetTimeUtcNow(), Valid: true}
+		stockTransfer.ReceivedBy = userID
+		return tx.Model(&models2.StockTransfer{}).Where("id = ?", stockTransfer.ID).UpdateColumns(map[string]any{
+			"status":      stockTransfer.Status,
+			"received_at": stockTransfer.ReceivedAt,
+			"received_by": stockTransfer.ReceivedBy,
+			"updated_at":  nokocore.GetTimeUtcNow(),
+		}).Error
+	})
+}
+
+// Cancel method, sent items are returned to the source location.
+func (l *LocationService) Cancel(stockTransfer *models2.StockTransfer) error {
+	switch stockTransfer.Status {
+	case models2.StockTransferStatusDraft, models2.StockTransferStatusSent:
+	default:
+		return ErrInvalidTransferStatus
+	}
+
+	return l.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		locationService := NewLocationService(tx)
+		if stockTransfer.Status == models2.StockTransferStatusSent {
+			for i, stockTransferItem := range stockTransfer.Items {
+				nokocore.KeepVoid(i)
+
+				if err = locationService.Move(stockTransferItem.ProductID, stockTransfer.FromLocationID, stockTransferItem.Quantity); err != nil {
+					return err
+				}
+			}
+		}
+
+		stockTransfer.Status = models2.StockTransferStatusCancelled
+		stockTransfer.CancelledAt = sql.NullTime{Time: nokocore.GetTimeUtcNow(), Valid: true}
+		return tx.Model(&models2.StockTransfer{}).Where("id = ?", stockTransfer.ID).UpdateColumns(map[string]any{
+			"status":       stockTransfer.Status,
+			"cancelled_at": stockTransfer.CancelledAt,
+			"updated_at":   nokocore.GetTimeUtcNow(),
+		}).Error
+	})
+}
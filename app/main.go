@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/net/http2"
@@ -124,12 +125,34 @@ func Main(args []string) nokocore.ExitCode {
 
 	/// END FACTORIES
 
+	// START SESSIONS
+
+	if err = globals.GetJwtConfig().Validate(); err != nil {
+		console.Warn(fmt.Sprintf("%s, defaults are used", err.Error()))
+	}
+
+	// END SESSIONS
+
+	// START PERMISSIONS
+
+	Permissions(DB)
+
+	// END PERMISSIONS
+
 	// START CONTROLLERS
 
 	Controllers(group, DB)
 
 	// END CONTROLLERS
 
+	// START SCHEDULERS
+
+	scheduler := Schedulers(DB)
+	scheduler.Start(context.Background())
+	defer scheduler.Stop()
+
+	// END SCHEDULERS
+
 	h2s := &http2.Server{
 		MaxConcurrentStreams: 100,
 		MaxReadFrameSize:     16384,
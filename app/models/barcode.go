@@ -2,12 +2,52 @@ package models
 
 import "nokowebapi/apis/models"
 
+const (
+	BarcodeLevelUnit    = "unit"
+	BarcodeLevelStrip   = "strip"
+	BarcodeLevelPackage = "package"
+)
+
 type Barcode struct {
 	models.BaseModel
-	Code   string `db:"code" gorm:"not null;" mapstructure:"code" json:"code"`
-	Closed bool   `db:"closed" gorm:"not null;" mapstructure:"closed" json:"closed"`
+	ProductID uint    `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Code      string  `db:"code" gorm:"unique;index;not null;" mapstructure:"code" json:"code"`
+	Level     string  `db:"level" gorm:"index;not null;" mapstructure:"level" json:"level"`
+	Active    bool    `db:"active" gorm:"index;not null;" mapstructure:"active" json:"active"`
+	Product   Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
 }
 
 func (Barcode) TableName() string {
 	return "barcodes"
 }
+
+func IsBarcodeLevel(level string) bool {
+	switch level {
+	case BarcodeLevelUnit, BarcodeLevelStrip, BarcodeLevelPackage:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetBarcodeUnitLevel method, unit level counted by a single scan of a barcode
+// at the level, strips are the level below the package when there is one.
+func (p *Product) GetBarcodeUnitLevel(level string) ProductUnit {
+	levels := p.GetUnitLevels()
+	size := len(levels)
+
+	switch level {
+	case BarcodeLevelPackage:
+		return levels[size-1]
+
+	case BarcodeLevelStrip:
+		if size > 2 {
+			return levels[size-2]
+		}
+
+		return levels[0]
+
+	default:
+		return levels[0]
+	}
+}
@@ -12,8 +12,11 @@ type Cart struct {
 	TransactionID uint            `db:"transaction_id" gorm:"index;null;" mapstructure:"transaction_id" json:"transactionId"`
 	PackageTotal  int             `db:"package_total" gorm:"not null;" mapstructure:"package_total" json:"packageTotal"`
 	UnitExtra     int             `db:"unit_extra" gorm:"not null;" mapstructure:"unit_extra" json:"unitExtra"`
+	Quantity      int             `db:"quantity" gorm:"not null;default:0;" mapstructure:"quantity" json:"quantity"`      // base units
+	UnitPrice     decimal.Decimal `db:"unit_price" gorm:"not null;default:0;" mapstructure:"unit_price" json:"unitPrice"` // per base unit, effective when added
 	SubTotal      decimal.Decimal `db:"sub_total" gorm:"not null;" mapstructure:"sub_total" json:"subTotal"`
 	Closed        bool            `db:"closed" gorm:"not null;" mapstructure:"closed" json:"closed"`
+	CostOfGoods   decimal.Decimal `db:"cost_of_goods" gorm:"not null;default:0;" mapstructure:"cost_of_goods" json:"costOfGoods"` // set when verified
 
 	User        models.User `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
 	Product     Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
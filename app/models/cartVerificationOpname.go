@@ -6,11 +6,13 @@ import (
 
 type CartVerificationOpname struct {
 	models.BaseModel
-	ProductID        uint   `db:"product_id" gorm:"index" mapstructure:"product_id" json:"productId"`
+	ProductID        uint   `db:"product_id" gorm:"uniqueIndex:idx_cart_verification_opnames_product;index" mapstructure:"product_id" json:"productId"`
+	StockOpnameID    uint   `db:"stock_opname_id" gorm:"uniqueIndex:idx_cart_verification_opnames_product,priority:1;index;null;" mapstructure:"stock_opname_id" json:"stockOpnameId"`
 	IsMatch          bool   `db:"is_match" gorm:"index" mapstructure:"is_match" json:"isMatch"`
 	NotMatchReason   string `db:"not_match_reason" gorm:"index;not null;" mapstructure:"not_match_reason" json:"notMatchReason"`
 	RealPackageTotal int    `db:"real_package_total" gorm:"index;not null;" mapstructure:"real_package_total" json:"realPackageTotal"`
 	RealUnitExtra    int    `db:"real_unit_extra" gorm:"index;not null;" mapstructure:"real_unit_extra" json:"realUnitExtra"`
+	RealQuantity     int    `db:"real_quantity" gorm:"index;not null;default:0;" mapstructure:"real_quantity" json:"realQuantity"` // base units
 	UserID           uint   `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
 
 	User    models.User `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+	"time"
+)
+
+const (
+	RegisterKindReceipt    = "receipt"
+	RegisterKindDispense   = "dispense"
+	RegisterKindAdjustment = "adjustment"
+)
+
+type ControlledRegister struct {
+	models.BaseModel
+	ProductID  uint      `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	UserID     uint      `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Kind       string    `db:"kind" gorm:"index;not null;" mapstructure:"kind" json:"kind"`
+	DrugClass  string    `db:"drug_class" gorm:"index;not null;" mapstructure:"drug_class" json:"drugClass"`
+	Quantity   int       `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // signed base units
+	Reference  string    `db:"reference" gorm:"index;null;" mapstructure:"reference" json:"reference"`
+	Note       string    `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+	RecordedAt time.Time `db:"recorded_at" gorm:"index;not null;" mapstructure:"recorded_at" json:"recordedAt"`
+
+	User    models.User `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	Product Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (ControlledRegister) TableName() string {
+	return "controlled_registers"
+}
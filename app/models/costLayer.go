@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+	"time"
+)
+
+const (
+	CostMethodFIFO          = "fifo"
+	CostMethodMovingAverage = "moving_average"
+)
+
+const (
+	CostEntryKindOpening    = "opening"
+	CostEntryKindReceipt    = "receipt"
+	CostEntryKindSale       = "sale"
+	CostEntryKindAdjustment = "adjustment"
+	CostEntryKindWriteOff   = "write_off"
+)
+
+// CostLayer, received units of a product at the same unit cost, consumed oldest first.
+type CostLayer struct {
+	models.BaseModel
+	ProductID      uint            `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	GoodsReceiptID uint            `db:"goods_receipt_id" gorm:"index;null;" mapstructure:"goods_receipt_id" json:"goodsReceiptId"` // empty for opening stock
+	Quantity       int             `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"`                         // base units
+	Remaining      int             `db:"remaining" gorm:"index;not null;" mapstructure:"remaining" json:"remaining"`                // base units
+	UnitCost       decimal.Decimal `db:"unit_cost" gorm:"not null;" mapstructure:"unit_cost" json:"unitCost"`
+	ReceivedAt     time.Time       `db:"received_at" gorm:"index;not null;" mapstructure:"received_at" json:"receivedAt"`
+
+	Product Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (CostLayer) TableName() string {
+	return "cost_layers"
+}
+
+// CostEntry, inventory ledger of a product, every entry keeps the balance after it,
+// so the stock value as of any date is the balance of the last entry before it.
+type CostEntry struct {
+	models.BaseModel
+	ProductID       uint            `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Kind            string          `db:"kind" gorm:"index;not null;" mapstructure:"kind" json:"kind"`
+	Method          string          `db:"method" gorm:"not null;" mapstructure:"method" json:"method"`
+	Quantity        int             `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // signed base units
+	UnitCost        decimal.Decimal `db:"unit_cost" gorm:"not null;" mapstructure:"unit_cost" json:"unitCost"`
+	TotalCost       decimal.Decimal `db:"total_cost" gorm:"not null;" mapstructure:"total_cost" json:"totalCost"` // signed
+	BalanceQuantity int             `db:"balance_quantity" gorm:"not null;" mapstructure:"balance_quantity" json:"balanceQuantity"`
+	BalanceValue    decimal.Decimal `db:"balance_value" gorm:"not null;" mapstructure:"balance_value" json:"balanceValue"`
+	Reference       string          `db:"reference" gorm:"index;null;" mapstructure:"reference" json:"reference"`
+	OccurredAt      time.Time       `db:"occurred_at" gorm:"index;not null;" mapstructure:"occurred_at" json:"occurredAt"`
+
+	Product Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (CostEntry) TableName() string {
+	return "cost_entries"
+}
+
+func IsCostMethod(method string) bool {
+	switch method {
+	case CostMethodFIFO, CostMethodMovingAverage:
+		return true
+	default:
+		return false
+	}
+}
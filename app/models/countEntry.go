@@ -0,0 +1,37 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+	"time"
+)
+
+// rejected entries are answered but never kept, the client may send them again
+const (
+	CountEntryStatusApplied  = "applied"
+	CountEntryStatusStale    = "stale"
+	CountEntryStatusConflict = "conflict"
+	CountEntryStatusRejected = "rejected"
+)
+
+// CountEntry model, count of a product collected offline by a device, client
+// ids are kept to merge batches sent more than once.
+type CountEntry struct {
+	models.BaseModel
+	StockOpnameID  uint      `db:"stock_opname_id" gorm:"uniqueIndex:idx_count_entries_client;not null;" mapstructure:"stock_opname_id" json:"stockOpnameId"`
+	ClientID       string    `db:"client_id" gorm:"uniqueIndex:idx_count_entries_client;not null;" mapstructure:"client_id" json:"clientId"`
+	DeviceID       string    `db:"device_id" gorm:"index;not null;" mapstructure:"device_id" json:"deviceId"`
+	ProductID      uint      `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	UserID         uint      `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Quantity       int       `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+	NotMatchReason string    `db:"not_match_reason" gorm:"null;" mapstructure:"not_match_reason" json:"notMatchReason"`
+	CountedAt      time.Time `db:"counted_at" gorm:"index;not null;" mapstructure:"counted_at" json:"countedAt"`
+	Status         string    `db:"status" gorm:"index;not null;" mapstructure:"status" json:"status"`
+
+	StockOpname StockOpname `db:"-" gorm:"foreignKey:StockOpnameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"stock_opname" json:"stockOpname"`
+	Product     Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+	User        models.User `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+}
+
+func (CountEntry) TableName() string {
+	return "count_entries"
+}
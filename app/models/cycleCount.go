@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"nokowebapi/apis/models"
+	"nokowebapi/sqlx"
+)
+
+const (
+	AbcClassA = "a"
+	AbcClassB = "b"
+	AbcClassC = "c"
+)
+
+func IsAbcClass(class string) bool {
+	switch class {
+	case AbcClassA, AbcClassB, AbcClassC:
+		return true
+	default:
+		return false
+	}
+}
+
+// CycleCount model, daily list of products proposed for counting at a location.
+type CycleCount struct {
+	models.BaseModel
+	LocationID    uint          `db:"location_id" gorm:"uniqueIndex:idx_cycle_counts_day;not null;" mapstructure:"location_id" json:"locationId"`
+	ProposedOn    sqlx.DateOnly `db:"proposed_on" gorm:"uniqueIndex:idx_cycle_counts_day;not null;" mapstructure:"proposed_on" json:"proposedOn"`
+	StockOpnameID uint          `db:"stock_opname_id" gorm:"index;null;" mapstructure:"stock_opname_id" json:"stockOpnameId"` // started stock opname
+
+	Items    []CycleCountItem `db:"-" gorm:"foreignKey:CycleCountID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"items" json:"items"`
+	Location Location         `db:"-" gorm:"foreignKey:LocationID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"location" json:"location"`
+}
+
+func (CycleCount) TableName() string {
+	return "cycle_counts"
+}
+
+type CycleCountItem struct {
+	models.BaseModel
+	CycleCountID  uint         `db:"cycle_count_id" gorm:"index;not null;" mapstructure:"cycle_count_id" json:"cycleCountId"`
+	ProductID     uint         `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Class         string       `db:"class" gorm:"not null;" mapstructure:"class" json:"class"`
+	LastCountedAt sql.NullTime `db:"last_counted_at" gorm:"null;" mapstructure:"last_counted_at" json:"lastCountedAt"` // never counted when empty
+
+	Product Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (CycleCountItem) TableName() string {
+	return "cycle_count_items"
+}
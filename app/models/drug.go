@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+)
+
+const (
+	DrugClassOTC          = "otc"
+	DrugClassHard         = "hard"
+	DrugClassNarcotic     = "narcotic"
+	DrugClassPsychotropic = "psychotropic"
+	DrugClassPrecursor    = "precursor"
+)
+
+type ActiveIngredient struct {
+	models.BaseModel
+	IngredientName string `db:"ingredient_name" gorm:"unique;index;not null;" mapstructure:"ingredient_name" json:"ingredientName"`
+	Description    string `db:"description" gorm:"null;" mapstructure:"description" json:"description"`
+}
+
+func (ActiveIngredient) TableName() string {
+	return "active_ingredients"
+}
+
+type ProductIngredient struct {
+	models.BaseModel
+	ProductID          uint             `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	ActiveIngredientID uint             `db:"active_ingredient_id" gorm:"index;not null;" mapstructure:"active_ingredient_id" json:"activeIngredientId"`
+	Strength           decimal.Decimal  `db:"strength" gorm:"not null;" mapstructure:"strength" json:"strength"`
+	StrengthUnit       string           `db:"strength_unit" gorm:"not null;" mapstructure:"strength_unit" json:"strengthUnit"` // mg, ml, mcg
+	Product            Product          `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+	ActiveIngredient   ActiveIngredient `db:"-" gorm:"foreignKey:ActiveIngredientID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"active_ingredient" json:"activeIngredient"`
+}
+
+func (ProductIngredient) TableName() string {
+	return "product_ingredients"
+}
+
+func IsDrugClass(drugClass string) bool {
+	switch drugClass {
+	case DrugClassOTC, DrugClassHard, DrugClassNarcotic, DrugClassPsychotropic, DrugClassPrecursor:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsControlledDrugClass method, sales and receipts of controlled drugs are written to the register.
+func IsControlledDrugClass(drugClass string) bool {
+	switch drugClass {
+	case DrugClassNarcotic, DrugClassPsychotropic:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+)
+
+const (
+	InteractionSeverityMinor    = "minor"
+	InteractionSeverityModerate = "moderate"
+	InteractionSeveritySevere   = "severe"
+)
+
+// DrugInteraction, interacting pair of active ingredients, the lower ingredient id
+// is always kept first, so every pair is stored once.
+type DrugInteraction struct {
+	models.BaseModel
+	IngredientID    uint   `db:"ingredient_id" gorm:"uniqueIndex:idx_drug_interactions_pair;not null;" mapstructure:"ingredient_id" json:"ingredientId"`
+	InteractsWithID uint   `db:"interacts_with_id" gorm:"uniqueIndex:idx_drug_interactions_pair;index;not null;" mapstructure:"interacts_with_id" json:"interactsWithId"`
+	Severity        string `db:"severity" gorm:"index;not null;" mapstructure:"severity" json:"severity"`
+	Note            string `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+
+	Ingredient    ActiveIngredient `db:"-" gorm:"foreignKey:IngredientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"ingredient" json:"ingredient"`
+	InteractsWith ActiveIngredient `db:"-" gorm:"foreignKey:InteractsWithID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"interacts_with" json:"interactsWith"`
+}
+
+func (DrugInteraction) TableName() string {
+	return "drug_interactions"
+}
+
+func (d *DrugInteraction) SetPair(ingredient *ActiveIngredient, interactsWith *ActiveIngredient) {
+	if interactsWith.ID < ingredient.ID {
+		ingredient, interactsWith = interactsWith, ingredient
+	}
+
+	d.IngredientID = ingredient.ID
+	d.InteractsWithID = interactsWith.ID
+	d.Ingredient = *ingredient
+	d.InteractsWith = *interactsWith
+}
+
+func IsInteractionSeverity(severity string) bool {
+	switch severity {
+	case InteractionSeverityMinor, InteractionSeverityModerate, InteractionSeveritySevere:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetInteractionSeverityLevel method, higher is more severe, unknown severities are zero.
+func GetInteractionSeverityLevel(severity string) int {
+	switch severity {
+	case InteractionSeverityMinor:
+		return 1
+	case InteractionSeverityModerate:
+		return 2
+	case InteractionSeveritySevere:
+		return 3
+	default:
+		return 0
+	}
+}
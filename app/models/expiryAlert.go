@@ -0,0 +1,24 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+	"nokowebapi/sqlx"
+	"time"
+)
+
+// ExpiryAlert model, stock flagged once when it enters an expiry window,
+// window is the days left, zero when expired.
+type ExpiryAlert struct {
+	models.BaseModel
+	ProductID  uint          `db:"product_id" gorm:"uniqueIndex:idx_expiry_alerts_window;not null;" mapstructure:"product_id" json:"productId"`
+	Expires    sqlx.DateOnly `db:"expires" gorm:"uniqueIndex:idx_expiry_alerts_window;not null;" mapstructure:"expires" json:"expires"`
+	WindowDays int           `db:"window_days" gorm:"uniqueIndex:idx_expiry_alerts_window;not null;" mapstructure:"window_days" json:"windowDays"`
+	Quantity   int           `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+	FlaggedAt  time.Time     `db:"flagged_at" gorm:"index;not null;" mapstructure:"flagged_at" json:"flaggedAt"`
+
+	Product Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (ExpiryAlert) TableName() string {
+	return "expiry_alerts"
+}
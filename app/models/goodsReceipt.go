@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+)
+
+type GoodsReceipt struct {
+	models.BaseModel
+	ProductID  uint            `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	UserID     uint            `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Quantity   int             `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+	UnitCost   decimal.Decimal `db:"unit_cost" gorm:"not null;" mapstructure:"unit_cost" json:"unitCost"`
+	Supplier   string          `db:"supplier" gorm:"index;null;" mapstructure:"supplier" json:"supplier"`
+	Reference  string          `db:"reference" gorm:"index;null;" mapstructure:"reference" json:"reference"`
+	LocationID uint            `db:"location_id" gorm:"index;null;" mapstructure:"location_id" json:"locationId"` // receiving location
+
+	User    models.User `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	Product Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (GoodsReceipt) TableName() string {
+	return "goods_receipts"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+)
+
+// InteractionAcknowledgement, pharmacist who acknowledged a severe drug interaction
+// of the basket, interactions acknowledged again keep the latest pharmacist.
+type InteractionAcknowledgement struct {
+	models.BaseModel
+	TransactionID uint   `db:"transaction_id" gorm:"uniqueIndex:idx_interaction_acknowledgements_pair;not null;" mapstructure:"transaction_id" json:"transactionId"`
+	InteractionID uint   `db:"interaction_id" gorm:"uniqueIndex:idx_interaction_acknowledgements_pair;index;not null;" mapstructure:"interaction_id" json:"interactionId"`
+	UserID        uint   `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Note          string `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+
+	Transaction Transaction     `db:"-" gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"transaction" json:"transaction"`
+	Interaction DrugInteraction `db:"-" gorm:"foreignKey:InteractionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"interaction" json:"interaction"`
+	User        models.User     `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+}
+
+func (InteractionAcknowledgement) TableName() string {
+	return "interaction_acknowledgements"
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+)
+
+const (
+	LocationCodeFront     = "front"
+	LocationCodeWarehouse = "warehouse"
+)
+
+// Location model, stock is kept per location, checkout deducts from the selling
+// location only, goods are received into the receiving location by default.
+type Location struct {
+	models.BaseModel
+	Code         string `db:"code" gorm:"unique;index;not null;" mapstructure:"code" json:"code"`
+	LocationName string `db:"location_name" gorm:"index;not null;" mapstructure:"location_name" json:"locationName"`
+	Description  string `db:"description" gorm:"null;" mapstructure:"description" json:"description"`
+	Selling      bool   `db:"selling" gorm:"index;not null;default:false;" mapstructure:"selling" json:"selling"`
+	Receiving    bool   `db:"receiving" gorm:"index;not null;default:false;" mapstructure:"receiving" json:"receiving"`
+}
+
+func (Location) TableName() string {
+	return "locations"
+}
+
+// ProductStock model, quantity of a product at a location, product stock is
+// the sum of every location plus the quantity in transit.
+type ProductStock struct {
+	models.BaseModel
+	ProductID  uint `db:"product_id" gorm:"uniqueIndex:idx_product_stocks_location;not null;" mapstructure:"product_id" json:"productId"`
+	LocationID uint `db:"location_id" gorm:"uniqueIndex:idx_product_stocks_location;index;not null;" mapstructure:"location_id" json:"locationId"`
+	Stock      int  `db:"stock" gorm:"not null;default:0;" mapstructure:"stock" json:"stock"` // base units
+
+	Product  Product  `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+	Location Location `db:"-" gorm:"foreignKey:LocationID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"location" json:"location"`
+}
+
+func (ProductStock) TableName() string {
+	return "product_stocks"
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+	"time"
+)
+
+const (
+	PriceChangeStatusScheduled = "scheduled"
+	PriceChangeStatusApplied   = "applied"
+	PriceChangeStatusCancelled = "cancelled"
+	PriceChangeStatusReverted  = "reverted"
+)
+
+type PriceChange struct {
+	models.BaseModel
+	UserID      uint         `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Name        string       `db:"name" gorm:"not null;" mapstructure:"name" json:"name"`
+	Description string       `db:"description" gorm:"null;" mapstructure:"description" json:"description"`
+	EffectiveAt time.Time    `db:"effective_at" gorm:"index;not null;" mapstructure:"effective_at" json:"effectiveAt"`
+	Status      string       `db:"status" gorm:"index;not null;default:'scheduled';" mapstructure:"status" json:"status"`
+	AppliedAt   sql.NullTime `db:"applied_at" gorm:"null;" mapstructure:"applied_at" json:"appliedAt"`
+	CancelledAt sql.NullTime `db:"cancelled_at" gorm:"null;" mapstructure:"cancelled_at" json:"cancelledAt"`
+	RevertedAt  sql.NullTime `db:"reverted_at" gorm:"null;" mapstructure:"reverted_at" json:"revertedAt"`
+
+	User  models.User       `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	Items []PriceChangeItem `db:"-" gorm:"foreignKey:PriceChangeID;" mapstructure:"items" json:"items"`
+}
+
+func (PriceChange) TableName() string {
+	return "price_changes"
+}
+
+// PriceChangeItem model, empty pricing rule and null prices keep the product
+// values, previous values are stored when the change is applied.
+type PriceChangeItem struct {
+	models.BaseModel
+	PriceChangeID         uint                `db:"price_change_id" gorm:"index;not null;" mapstructure:"price_change_id" json:"priceChangeId"`
+	ProductID             uint                `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	PricingRule           string              `db:"pricing_rule" gorm:"null;" mapstructure:"pricing_rule" json:"pricingRule"`
+	PurchasePrice         decimal.NullDecimal `db:"purchase_price" gorm:"null;" mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice             decimal.NullDecimal `db:"sale_price" gorm:"null;" mapstructure:"sale_price" json:"salePrice"`
+	PreviousPricingRule   string              `db:"previous_pricing_rule" gorm:"null;" mapstructure:"previous_pricing_rule" json:"previousPricingRule"`
+	PreviousPurchasePrice decimal.NullDecimal `db:"previous_purchase_price" gorm:"null;" mapstructure:"previous_purchase_price" json:"previousPurchasePrice"`
+	PreviousSalePrice     decimal.NullDecimal `db:"previous_sale_price" gorm:"null;" mapstructure:"previous_sale_price" json:"previousSalePrice"`
+
+	PriceChange PriceChange `db:"-" gorm:"foreignKey:PriceChangeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"price_change" json:"priceChange"`
+	Product     Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (PriceChangeItem) TableName() string {
+	return "price_change_items"
+}
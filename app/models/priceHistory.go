@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+)
+
+const (
+	PricingRuleCostPlus = "cost_plus"
+	PricingRuleFixed    = "fixed"
+)
+
+type PriceHistory struct {
+	models.BaseModel
+	ProductID             uint            `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	UserID                uint            `db:"user_id" gorm:"index;null;" mapstructure:"user_id" json:"userId"`
+	Source                string          `db:"source" gorm:"index;not null;" mapstructure:"source" json:"source"`
+	PricingRule           string          `db:"pricing_rule" gorm:"not null;" mapstructure:"pricing_rule" json:"pricingRule"`
+	PurchasePrice         decimal.Decimal `db:"purchase_price" gorm:"not null;" mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice             decimal.Decimal `db:"sale_price" gorm:"not null;" mapstructure:"sale_price" json:"salePrice"`
+	PreviousPurchasePrice decimal.Decimal `db:"previous_purchase_price" gorm:"not null;" mapstructure:"previous_purchase_price" json:"previousPurchasePrice"`
+	PreviousSalePrice     decimal.Decimal `db:"previous_sale_price" gorm:"not null;" mapstructure:"previous_sale_price" json:"previousSalePrice"`
+	Product               Product         `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (PriceHistory) TableName() string {
+	return "price_histories"
+}
+
+func IsPricingRule(rule string) bool {
+	switch rule {
+	case PricingRuleCostPlus, PricingRuleFixed:
+		return true
+	default:
+		return false
+	}
+}
@@ -34,15 +34,27 @@ type Product struct {
 	SupplierDiscount float64         `db:"supplier_discount" gorm:"index;not null;" mapstructure:"supplier_discount" json:"supplierDiscount"`
 	VAT              float64         `db:"vat" gorm:"index;not null;" mapstructure:"vat" json:"vat"`
 	ProfitMargin     float64         `db:"profit_margin" gorm:"index;not null;" mapstructure:"profit_margin" json:"profitMargin"`
+	PricingRule      string          `db:"pricing_rule" gorm:"index;not null;default:'cost_plus';" mapstructure:"pricing_rule" json:"pricingRule"`
+	PriceRounding    decimal.Decimal `db:"price_rounding" gorm:"not null;default:0;" mapstructure:"price_rounding" json:"priceRounding"`
 	PackageID        uint            `db:"package_id" gorm:"index;not null;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"package_id" json:"packageId"`
 	PackageTotal     int             `db:"package_total" gorm:"index;not null;" mapstructure:"package_total" json:"packageTotal"`
 	UnitID           uint            `db:"unit_id" gorm:"index;not null;" mapstructure:"unit_id" json:"unitId"`
 	UnitScale        int             `db:"unit_scale" gorm:"index;not null;" mapstructure:"unit_scale" json:"unitScale"`
 	UnitExtra        int             `db:"unit_extra" gorm:"index;not null;" mapstructure:"unit_extra" json:"unitExtra"`
-
-	Categories []Category `db:"-" gorm:"many2many:product_categories;" mapstructure:"categories" json:"categories"`
-	Package    Package    `db:"-" gorm:"foreignKey:PackageID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"package" json:"package"`
-	Unit       Unit       `db:"-" gorm:"foreignKey:UnitID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"unit" json:"unit"`
+	Stock            int             `db:"stock" gorm:"index;not null;default:0;" mapstructure:"stock" json:"stock"` // base units
+	DrugClass        string          `db:"drug_class" gorm:"index;not null;default:'otc';" mapstructure:"drug_class" json:"drugClass"`
+	DosageForm       string          `db:"dosage_form" gorm:"index;null;" mapstructure:"dosage_form" json:"dosageForm"`
+	Route            string          `db:"route" gorm:"null;" mapstructure:"route" json:"route"`
+	Manufacturer     string          `db:"manufacturer" gorm:"index;null;" mapstructure:"manufacturer" json:"manufacturer"`
+	AuthorizationNo  string          `db:"authorization_no" gorm:"index;null;" mapstructure:"authorization_no" json:"authorizationNo"` // marketing authorisation number
+
+	Categories  []Category          `db:"-" gorm:"many2many:product_categories;" mapstructure:"categories" json:"categories"`
+	Barcodes    []Barcode           `db:"-" gorm:"foreignKey:ProductID;" mapstructure:"barcodes" json:"barcodes"`
+	Attachments []ProductAttachment `db:"-" gorm:"foreignKey:ProductID;" mapstructure:"attachments" json:"attachments"`
+	UnitLevels  []ProductUnit       `db:"-" gorm:"foreignKey:ProductID;" mapstructure:"unit_levels" json:"unitLevels"`
+	Ingredients []ProductIngredient `db:"-" gorm:"foreignKey:ProductID;" mapstructure:"ingredients" json:"ingredients"`
+	Package     Package             `db:"-" gorm:"foreignKey:PackageID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"package" json:"package"`
+	Unit        Unit                `db:"-" gorm:"foreignKey:UnitID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"unit" json:"unit"`
 }
 
 func (Product) TableName() string {
@@ -133,6 +145,64 @@ func (p *Product) CategoriesAppend(DB *gorm.DB, names ...string) error {
 	return p.CreateCategories(DB)
 }
 
+func (p *Product) SyncBarcode(DB *gorm.DB) error {
+	var err error
+	var check Barcode
+	nokocore.KeepVoid(err, check)
+
+	// pseudo product
+	if p.ID == 0 || p.Barcode == "" {
+		return nil
+	}
+
+	// searching, codes are unique across all products
+	tx := DB.Unscoped().Where("code = ?", p.Barcode).Find(&check)
+	if err = tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if check.ID != 0 {
+		if check.ProductID != p.ID {
+			return errors.New("barcode already used by another product")
+		}
+
+		// primary barcode always active
+		if !check.Active || check.DeletedAt.Valid {
+			tx = DB.Unscoped().Model(&check).Updates(map[string]any{
+				"active":     true,
+				"deleted_at": nil,
+			})
+			if err = tx.Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// create new, previous codes are kept, old stocks may still carry them
+	barcode := Barcode{
+		BaseModel: models.BaseModel{
+			UUID: nokocore.NewUUID(),
+		},
+		ProductID: p.ID,
+		Code:      p.Barcode,
+		Level:     BarcodeLevelUnit,
+		Active:    true,
+	}
+	tx = DB.Create(&barcode)
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	// check rows affected
+	if tx.RowsAffected < 1 {
+		return errors.New("no rows affected")
+	}
+
+	return nil
+}
+
 func (p *Product) BeforeSave(DB *gorm.DB) (err error) {
 	nokocore.KeepVoid(DB)
 
@@ -145,5 +215,23 @@ func (p *Product) BeforeSave(DB *gorm.DB) (err error) {
 		return err
 	}
 
+	// stock is the source of truth, package total and unit extra follow it
+	p.SetStock(p.Stock)
+
+	return nil
+}
+
+func (p *Product) AfterSave(DB *gorm.DB) (err error) {
+
+	// mirror primary barcode into product barcodes
+	if err = p.SyncBarcode(DB); err != nil {
+		return err
+	}
+
+	// keep base and package unit levels in sync
+	if err = p.SyncUnitLevels(DB); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,25 @@
+package models
+
+import "nokowebapi/apis/models"
+
+const (
+	AttachmentKindImage    = "image"
+	AttachmentKindDocument = "document"
+)
+
+type ProductAttachment struct {
+	models.BaseModel
+	ProductID    uint    `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Kind         string  `db:"kind" gorm:"index;not null;" mapstructure:"kind" json:"kind"`
+	FileName     string  `db:"file_name" gorm:"not null;" mapstructure:"file_name" json:"fileName"`
+	ContentType  string  `db:"content_type" gorm:"not null;" mapstructure:"content_type" json:"contentType"`
+	Size         int64   `db:"size" gorm:"not null;" mapstructure:"size" json:"size"`
+	StorageKey   string  `db:"storage_key" gorm:"unique;not null;" mapstructure:"storage_key" json:"storageKey"`
+	ThumbnailKey string  `db:"thumbnail_key" gorm:"null;" mapstructure:"thumbnail_key" json:"thumbnailKey"`
+	IsPrimary    bool    `db:"is_primary" gorm:"index;not null;" mapstructure:"is_primary" json:"isPrimary"`
+	Product      Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (ProductAttachment) TableName() string {
+	return "product_attachments"
+}
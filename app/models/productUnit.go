@@ -0,0 +1,301 @@
+package models
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"nokowebapi/apis/models"
+	"nokowebapi/nokocore"
+	"slices"
+	"strings"
+)
+
+// ProductUnit, packaging level of product, level 0 is the base unit,
+// every level contains factor base units, e.g. tablet 1, strip 10, box 100.
+type ProductUnit struct {
+	models.BaseModel
+	ProductID uint    `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	UnitID    uint    `db:"unit_id" gorm:"index;not null;" mapstructure:"unit_id" json:"unitId"`
+	Level     int     `db:"level" gorm:"index;not null;" mapstructure:"level" json:"level"`
+	Factor    int     `db:"factor" gorm:"not null;" mapstructure:"factor" json:"factor"`
+	Product   Product `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+	Unit      Unit    `db:"-" gorm:"foreignKey:UnitID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"unit" json:"unit"`
+}
+
+func (ProductUnit) TableName() string {
+	return "product_units"
+}
+
+type UnitQuantity struct {
+	Unit     Unit `mapstructure:"unit" json:"unit"`
+	Level    int  `mapstructure:"level" json:"level"`
+	Quantity int  `mapstructure:"quantity" json:"quantity"`
+}
+
+// SplitQuantity method, splits base units into packages and extra units.
+func SplitQuantity(quantity int, scale int) (packageTotal int, unitExtra int) {
+	if scale < 1 {
+		scale = 1
+	}
+
+	return quantity / scale, quantity % scale
+}
+
+// CheckUnitLevels method, base level has factor 1, every upper level contains
+// a whole number of the level below it.
+func CheckUnitLevels(levels []ProductUnit) error {
+	if len(levels) == 0 {
+		return errors.New("product has no unit levels")
+	}
+
+	if levels[0].Factor != 1 {
+		return errors.New("base unit must have factor 1")
+	}
+
+	for i := 1; i < len(levels); i++ {
+		below, level := levels[i-1], levels[i]
+		if level.Factor <= below.Factor || level.Factor%below.Factor != 0 {
+			return errors.New("unit factor must be a multiple of the unit below it")
+		}
+
+		for j := 0; j < i; j++ {
+			if levels[j].UnitID == level.UnitID {
+				return errors.New("unit is used by more than one level")
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetUnitLevels method, ordered from the base unit, products without unit
+// levels are derived from package and unit scale.
+func (p *Product) GetUnitLevels() []ProductUnit {
+	if len(p.UnitLevels) > 0 {
+		levels := slices.Clone(p.UnitLevels)
+		slices.SortFunc(levels, func(a, b ProductUnit) int {
+			return a.Level - b.Level
+		})
+		return levels
+	}
+
+	levels := []ProductUnit{
+		{
+			ProductID: p.ID,
+			UnitID:    p.UnitID,
+			Unit:      p.Unit,
+			Level:     0,
+			Factor:    1,
+		},
+	}
+
+	if p.UnitScale > 1 {
+		levels = append(levels, ProductUnit{
+			ProductID: p.ID,
+			Unit:      Unit{UnitType: p.Package.PackageType},
+			Level:     1,
+			Factor:    p.UnitScale,
+		})
+	}
+
+	return levels
+}
+
+// GetUnitLevel method, finds unit level by unit uuid or unit type,
+// empty unit means the base unit.
+func (p *Product) GetUnitLevel(unitID uuid.UUID, unitType string) (*ProductUnit, error) {
+	levels := p.GetUnitLevels()
+
+	if unitID == uuid.Nil && unitType == "" {
+		return &levels[0], nil
+	}
+
+	for i, level := range levels {
+		nokocore.KeepVoid(i)
+
+		if unitID != uuid.Nil && level.Unit.UUID == unitID {
+			return &level, nil
+		}
+
+		if unitID == uuid.Nil && strings.EqualFold(level.Unit.UnitType, unitType) {
+			return &level, nil
+		}
+	}
+
+	return nil, errors.New("unit is not a level of the product")
+}
+
+// ToUnitQuantities method, breaks base units down from the top level.
+func (p *Product) ToUnitQuantities(quantity int) []UnitQuantity {
+	levels := p.GetUnitLevels()
+	size := len(levels)
+
+	quantities := make([]UnitQuantity, size)
+	for i := size - 1; i >= 0; i-- {
+		level := levels[i]
+		quantities[size-1-i] = UnitQuantity{
+			Unit:     level.Unit,
+			Level:    level.Level,
+			Quantity: quantity / level.Factor,
+		}
+		quantity %= level.Factor
+	}
+
+	return quantities
+}
+
+// SetStock method, stock is kept in base units, package total and
+// unit extra follow the unit scale.
+func (p *Product) SetStock(stock int) {
+	p.Stock = stock
+	p.PackageTotal, p.UnitExtra = SplitQuantity(stock, p.UnitScale)
+}
+
+// CheckUnitScale method, unit scale is the factor of the top level.
+func (p *Product) CheckUnitScale(scale int) error {
+	return checkUnitScale(p.GetUnitLevels(), scale)
+}
+
+func checkUnitScale(levels []ProductUnit, scale int) error {
+	size := len(levels)
+
+	// top level gets removed or added
+	if size < 3 {
+		return nil
+	}
+
+	below := levels[size-2]
+	if scale <= below.Factor || scale%below.Factor != 0 {
+		return errors.New("unit scale must be a multiple of the unit below the package")
+	}
+
+	return nil
+}
+
+func (p *Product) getPackageUnit(DB *gorm.DB) (*Unit, error) {
+	var err error
+	var packageModel Package
+	var unit Unit
+	nokocore.KeepVoid(err, packageModel, unit)
+
+	if err = DB.Where("id = ?", p.PackageID).Find(&packageModel).Error; err != nil {
+		return nil, err
+	}
+
+	unitType := nokocore.ToTitleCase(packageModel.PackageType)
+	if unitType == "" {
+		return nil, errors.New("package not found")
+	}
+
+	// unit types are unique, includes deleted units
+	if err = DB.Unscoped().Where("unit_type = ?", unitType).Find(&unit).Error; err != nil {
+		return nil, err
+	}
+
+	if unit.ID != 0 {
+		if unit.DeletedAt.Valid {
+			if err = DB.Unscoped().Model(&unit).Update("deleted_at", nil).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		return &unit, nil
+	}
+
+	unit = Unit{
+		BaseModel: models.BaseModel{
+			UUID: nokocore.NewUUID(),
+		},
+		UnitType: unitType,
+	}
+	if err = DB.Create(&unit).Error; err != nil {
+		return nil, err
+	}
+
+	return &unit, nil
+}
+
+func (p *Product) createUnitLevel(DB *gorm.DB, unitID uint, level int, factor int) error {
+	productUnit := ProductUnit{
+		BaseModel: models.BaseModel{
+			UUID: nokocore.NewUUID(),
+		},
+		ProductID: p.ID,
+		UnitID:    unitID,
+		Level:     level,
+		Factor:    factor,
+	}
+
+	tx := DB.Create(&productUnit)
+	if err := tx.Error; err != nil {
+		return err
+	}
+
+	// check rows affected
+	if tx.RowsAffected < 1 {
+		return errors.New("no rows affected")
+	}
+
+	return nil
+}
+
+// SyncUnitLevels method, base level follows the product unit and
+// the top level follows the package and unit scale.
+func (p *Product) SyncUnitLevels(DB *gorm.DB) error {
+	var err error
+	var levels []ProductUnit
+	var unit *Unit
+	nokocore.KeepVoid(err, levels, unit)
+
+	// pseudo product
+	if p.ID == 0 || p.UnitID == 0 {
+		return nil
+	}
+
+	if err = DB.Where("product_id = ?", p.ID).Order("level ASC").Find(&levels).Error; err != nil {
+		return err
+	}
+
+	if len(levels) == 0 {
+		if err = p.createUnitLevel(DB, p.UnitID, 0, 1); err != nil {
+			return err
+		}
+
+		levels = append(levels, ProductUnit{UnitID: p.UnitID, Level: 0, Factor: 1})
+	}
+
+	base := levels[0]
+	if base.UnitID != p.UnitID {
+		if err = DB.Model(&base).Update("unit_id", p.UnitID).Error; err != nil {
+			return err
+		}
+	}
+
+	size := len(levels)
+	top := levels[size-1]
+	switch {
+	case size == 1 && p.UnitScale > 1:
+		if unit, err = p.getPackageUnit(DB); err != nil {
+			return err
+		}
+
+		// package and unit share the same unit type
+		if unit.ID == p.UnitID {
+			return nil
+		}
+
+		return p.createUnitLevel(DB, unit.ID, 1, p.UnitScale)
+
+	case size == 2 && p.UnitScale <= 1:
+		return DB.Unscoped().Delete(&top).Error
+
+	case size > 1 && top.Factor != p.UnitScale:
+		if err = checkUnitScale(levels, p.UnitScale); err != nil {
+			return err
+		}
+
+		return DB.Model(&top).Update("factor", p.UnitScale).Error
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"nokowebapi/apis/models"
+)
+
+const (
+	StockMovementKindOpname = "opname"
+)
+
+// StockMovement model, signed quantity changes of product stock at a location,
+// reference is the uuid of the source document.
+type StockMovement struct {
+	models.BaseModel
+	ProductID   uint   `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	LocationID  uint   `db:"location_id" gorm:"index;not null;" mapstructure:"location_id" json:"locationId"`
+	UserID      uint   `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Kind        string `db:"kind" gorm:"index;not null;" mapstructure:"kind" json:"kind"`
+	Quantity    int    `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+	StockBefore int    `db:"stock_before" gorm:"not null;" mapstructure:"stock_before" json:"stockBefore"`
+	StockAfter  int    `db:"stock_after" gorm:"not null;" mapstructure:"stock_after" json:"stockAfter"`
+	Reference   string `db:"reference" gorm:"index;null;" mapstructure:"reference" json:"reference"`
+	Note        string `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+
+	Product  Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+	Location Location    `db:"-" gorm:"foreignKey:LocationID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"location" json:"location"`
+	User     models.User `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+}
+
+func (StockMovement) TableName() string {
+	return "stock_movements"
+}
@@ -1,19 +1,95 @@
 package models
 
 import (
+	"database/sql"
 	"nokowebapi/apis/models"
 	"nokowebapi/sqlx"
 )
 
+// counters submit, supervisors approve the adjustments or send it back to the counters
+const (
+	StockOpnameStatusCounting  = "counting"
+	StockOpnameStatusSubmitted = "submitted"
+	StockOpnameStatusApproved  = "approved"
+	StockOpnameStatusRejected  = "rejected"
+)
+
+// StockOpnameStatusCancelled, cancelled stock opnames keep their status, the
+// cancellation is recorded by status changes only.
+const StockOpnameStatusCancelled = "cancelled"
+
+func IsStockOpnameStatus(status string) bool {
+	switch status {
+	case StockOpnameStatusCounting, StockOpnameStatusSubmitted, StockOpnameStatusApproved, StockOpnameStatusRejected:
+		return true
+
+	default:
+		return false
+	}
+}
+
 type StockOpname struct {
 	models.BaseModel
 	UserID     uint              `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	LocationID uint              `db:"location_id" gorm:"index;null;" mapstructure:"location_id" json:"locationId"` // selling location when empty
+	CategoryID uint              `db:"category_id" gorm:"index;null;" mapstructure:"category_id" json:"categoryId"` // every category when empty
 	SubmitedAt sqlx.NullDateOnly `db:"submited_at" gorm:"index;null;" mapstructure:"submited_at" json:"submitedAt"`
 	IsVerified bool              `db:"is_verified" gorm:"index;not null;" mapstructure:"is_verified" json:"isVerified"`
 
+	// cancelled stock opnames keep their history, product quantities are never changed
+	IsCancelled  bool         `db:"is_cancelled" gorm:"index;not null;default:false;" mapstructure:"is_cancelled" json:"isCancelled"`
+	CancelledAt  sql.NullTime `db:"cancelled_at" gorm:"null;" mapstructure:"cancelled_at" json:"cancelledAt"`
+	CancelledBy  uint         `db:"cancelled_by" gorm:"index;null;" mapstructure:"cancelled_by" json:"cancelledBy"`
+	CancelReason string       `db:"cancel_reason" gorm:"null;" mapstructure:"cancel_reason" json:"cancelReason"`
+
+	// cycle count scope, counted products are kept in stock opname items
+	Categories     string `db:"categories" gorm:"null;" mapstructure:"categories" json:"categories"` // comma separated names
+	Suppliers      string `db:"suppliers" gorm:"null;" mapstructure:"suppliers" json:"suppliers"`    // comma separated names
+	StockedOnly    bool   `db:"stocked_only" gorm:"not null;default:false;" mapstructure:"stocked_only" json:"stockedOnly"`
+	NotCountedDays int    `db:"not_counted_days" gorm:"not null;default:0;" mapstructure:"not_counted_days" json:"notCountedDays"`
+	Class          string `db:"class" gorm:"null;" mapstructure:"class" json:"class"` // abc class
+	CycleCountID   uint   `db:"cycle_count_id" gorm:"index;null;" mapstructure:"cycle_count_id" json:"cycleCountId"`
+
+	// blind counts hide system quantities below supervisors, every product is counted
+	IsBlind bool `db:"is_blind" gorm:"not null;default:false;" mapstructure:"is_blind" json:"isBlind"`
+
+	// entries are changed while counting or rejected only
+	Status string `db:"status" gorm:"index;not null;default:'counting';" mapstructure:"status" json:"status"`
+
 	User models.User `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
 }
 
 func (StockOpname) TableName() string {
 	return "stock_opnames"
 }
+
+// StockOpnameItem model, product counted by the stock opname.
+type StockOpnameItem struct {
+	models.BaseModel
+	StockOpnameID uint `db:"stock_opname_id" gorm:"uniqueIndex:idx_stock_opname_items_product;not null;" mapstructure:"stock_opname_id" json:"stockOpnameId"`
+	ProductID     uint `db:"product_id" gorm:"uniqueIndex:idx_stock_opname_items_product;index;not null;" mapstructure:"product_id" json:"productId"`
+
+	StockOpname StockOpname `db:"-" gorm:"foreignKey:StockOpnameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"stock_opname" json:"stockOpname"`
+	Product     Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (StockOpnameItem) TableName() string {
+	return "stock_opname_items"
+}
+
+// StockOpnameStatusChange model, approval history of the stock opname.
+type StockOpnameStatusChange struct {
+	models.BaseModel
+	StockOpnameID uint   `db:"stock_opname_id" gorm:"index;not null;" mapstructure:"stock_opname_id" json:"stockOpnameId"`
+	UserID        uint   `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	FromStatus    string `db:"from_status" gorm:"not null;" mapstructure:"from_status" json:"fromStatus"`
+	ToStatus      string `db:"to_status" gorm:"index;not null;" mapstructure:"to_status" json:"toStatus"`
+	Comment       string `db:"comment" gorm:"null;" mapstructure:"comment" json:"comment"`
+
+	StockOpname StockOpname `db:"-" gorm:"foreignKey:StockOpnameID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"stock_opname" json:"stockOpname"`
+	User        models.User `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+}
+
+func (StockOpnameStatusChange) TableName() string {
+	return "stock_opname_status_changes"
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"nokowebapi/apis/models"
+)
+
+const (
+	StockTransferStatusDraft     = "draft"
+	StockTransferStatusSent      = "sent"
+	StockTransferStatusReceived  = "received"
+	StockTransferStatusCancelled = "cancelled"
+)
+
+// StockTransfer model, sent items leave the source location and stay in transit
+// until received at the destination location.
+type StockTransfer struct {
+	models.BaseModel
+	UserID         uint         `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	FromLocationID uint         `db:"from_location_id" gorm:"index;not null;" mapstructure:"from_location_id" json:"fromLocationId"`
+	ToLocationID   uint         `db:"to_location_id" gorm:"index;not null;" mapstructure:"to_location_id" json:"toLocationId"`
+	Status         string       `db:"status" gorm:"index;not null;default:'draft';" mapstructure:"status" json:"status"`
+	Note           string       `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+	SentAt         sql.NullTime `db:"sent_at" gorm:"null;" mapstructure:"sent_at" json:"sentAt"`
+	ReceivedAt     sql.NullTime `db:"received_at" gorm:"null;" mapstructure:"received_at" json:"receivedAt"`
+	ReceivedBy     uint         `db:"received_by" gorm:"index;null;" mapstructure:"received_by" json:"receivedBy"`
+	CancelledAt    sql.NullTime `db:"cancelled_at" gorm:"null;" mapstructure:"cancelled_at" json:"cancelledAt"`
+
+	User         models.User         `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	FromLocation Location            `db:"-" gorm:"foreignKey:FromLocationID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"from_location" json:"fromLocation"`
+	ToLocation   Location            `db:"-" gorm:"foreignKey:ToLocationID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" mapstructure:"to_location" json:"toLocation"`
+	Items        []StockTransferItem `db:"-" gorm:"foreignKey:StockTransferID;" mapstructure:"items" json:"items"`
+}
+
+func (StockTransfer) TableName() string {
+	return "stock_transfers"
+}
+
+type StockTransferItem struct {
+	models.BaseModel
+	StockTransferID uint `db:"stock_transfer_id" gorm:"index;not null;" mapstructure:"stock_transfer_id" json:"stockTransferId"`
+	ProductID       uint `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Quantity        int  `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+
+	StockTransfer StockTransfer `db:"-" gorm:"foreignKey:StockTransferID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"stock_transfer" json:"stockTransfer"`
+	Product       Product       `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (StockTransferItem) TableName() string {
+	return "stock_transfer_items"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"github.com/shopspring/decimal"
 	"nokowebapi/apis/models"
 )
@@ -13,6 +14,13 @@ type Transaction struct {
 	Exchange decimal.Decimal `db:"exchange" gorm:"not null;" mapstructure:"exchange" json:"exchange"`
 	Verified bool            `db:"verified" gorm:"not null;" mapstructure:"verified" json:"verified"`
 
-	Carts []Cart      `db:"-" gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"carts" json:"carts"`
-	User  models.User `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	// latest pharmacist acknowledgement of severe drug interactions in the basket,
+	// every acknowledged interaction is kept in interaction acknowledgements
+	AcknowledgedBy  uint         `db:"acknowledged_by" gorm:"index;null;" mapstructure:"acknowledged_by" json:"acknowledgedBy"`
+	AcknowledgedAt  sql.NullTime `db:"acknowledged_at" gorm:"null;" mapstructure:"acknowledged_at" json:"acknowledgedAt"`
+	AcknowledgeNote string       `db:"acknowledge_note" gorm:"null;" mapstructure:"acknowledge_note" json:"acknowledgeNote"`
+
+	Carts                       []Cart                       `db:"-" gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"carts" json:"carts"`
+	InteractionAcknowledgements []InteractionAcknowledgement `db:"-" gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"interaction_acknowledgements" json:"interactionAcknowledgements"`
+	User                        models.User                  `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
 }
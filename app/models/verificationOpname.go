@@ -4,6 +4,7 @@ import (
 	"nokowebapi/apis/models"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type VerificationOpname struct {
@@ -20,6 +21,10 @@ type VerificationOpname struct {
 	RealUnitTotal      int       `db:"real_unit_total" gorm:"index;not null;" mapstructure:"real_unit_total" json:"realUnitTotal"`
 	UserID             uint      `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
 
+	// product prices when verified, values the report of the stock opname later
+	PurchasePrice decimal.NullDecimal `db:"purchase_price" gorm:"null;" mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice     decimal.NullDecimal `db:"sale_price" gorm:"null;" mapstructure:"sale_price" json:"salePrice"`
+
 	Product     Product     `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
 	User        models.User `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
 	StockOpname StockOpname `db:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"stock_opname" json:"stockOpname"`
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/apis/models"
+	"nokowebapi/sqlx"
+)
+
+const (
+	WriteOffReasonExpired = "expired"
+)
+
+// WriteOff model, quarantined stock taken out of the sellable quantity.
+type WriteOff struct {
+	models.BaseModel
+	UserID    uint            `db:"user_id" gorm:"index;not null;" mapstructure:"user_id" json:"userId"`
+	Reason    string          `db:"reason" gorm:"index;not null;default:'expired';" mapstructure:"reason" json:"reason"`
+	Note      string          `db:"note" gorm:"null;" mapstructure:"note" json:"note"`
+	TotalCost decimal.Decimal `db:"total_cost" gorm:"not null;default:0;" mapstructure:"total_cost" json:"totalCost"`
+
+	User  models.User    `db:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"user" json:"user"`
+	Items []WriteOffItem `db:"-" gorm:"foreignKey:WriteOffID;" mapstructure:"items" json:"items"`
+}
+
+func (WriteOff) TableName() string {
+	return "write_offs"
+}
+
+type WriteOffItem struct {
+	models.BaseModel
+	WriteOffID uint            `db:"write_off_id" gorm:"index;not null;" mapstructure:"write_off_id" json:"writeOffId"`
+	ProductID  uint            `db:"product_id" gorm:"index;not null;" mapstructure:"product_id" json:"productId"`
+	Quantity   int             `db:"quantity" gorm:"not null;" mapstructure:"quantity" json:"quantity"` // base units
+	Expires    sqlx.DateOnly   `db:"expires" gorm:"not null;" mapstructure:"expires" json:"expires"`
+	TotalCost  decimal.Decimal `db:"total_cost" gorm:"not null;" mapstructure:"total_cost" json:"totalCost"`
+
+	WriteOff WriteOff `db:"-" gorm:"foreignKey:WriteOffID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"write_off" json:"writeOff"`
+	Product  Product  `db:"-" gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" mapstructure:"product" json:"product"`
+}
+
+func (WriteOffItem) TableName() string {
+	return "write_off_items"
+}
@@ -0,0 +1,104 @@
+package opnames
+
+import (
+	"database/sql"
+	"errors"
+	"gorm.io/gorm"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+var ErrStatusChanged = errors.New("stock opname status is changed")
+
+// Submit method, counted entries are sent for approval, rejected stock
+// opnames are submitted again.
+func (o *OpnameService) Submit(stockOpname *models2.StockOpname, userID uint, comment string) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		return changeStockOpnameStatus(tx, stockOpname, models2.StockOpnameStatusSubmitted, userID, comment, models2.StockOpnameStatusCounting, models2.StockOpnameStatusRejected)
+	})
+}
+
+// Reject method, sends the submitted stock opname back to the counters.
+func (o *OpnameService) Reject(stockOpname *models2.StockOpname, userID uint, comment string) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		return changeStockOpnameStatus(tx, stockOpname, models2.StockOpnameStatusRejected, userID, comment, models2.StockOpnameStatusSubmitted)
+	})
+}
+
+// Cancel method, pending entries are removed and the cancellation is recorded
+// with its reason, fails when the stock opname is verified or cancelled first.
+func (o *OpnameService) Cancel(stockOpname *models2.StockOpname, userID uint, reason string) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		// pending entries are removed, verification opnames are never created
+		if err = tx.Unscoped().Where("stock_opname_id = ?", stockOpname.ID).Delete(&models2.CartVerificationOpname{}).Error; err != nil {
+			return err
+		}
+
+		now := nokocore.GetTimeUtcNow()
+		stmt := tx.Model(&models2.StockOpname{}).Where("id = ? AND is_verified = ? AND is_cancelled = ?", stockOpname.ID, false, false)
+		if stmt = stmt.UpdateColumns(map[string]any{
+			"is_cancelled":  true,
+			"cancelled_at":  sql.NullTime{Time: now, Valid: true},
+			"cancelled_by":  userID,
+			"cancel_reason": reason,
+			"updated_at":    now,
+		}); stmt.Error != nil {
+			return stmt.Error
+		}
+
+		if stmt.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+
+		if err = tx.Create(&models2.StockOpnameStatusChange{
+			StockOpnameID: stockOpname.ID,
+			UserID:        userID,
+			FromStatus:    stockOpname.Status,
+			ToStatus:      models2.StockOpnameStatusCancelled,
+			Comment:       reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		stockOpname.IsCancelled = true
+		stockOpname.CancelledAt = sql.NullTime{Time: now, Valid: true}
+		stockOpname.CancelledBy = userID
+		stockOpname.CancelReason = reason
+		return nil
+	})
+}
+
+// changeStockOpnameStatus method, moves the stock opname from one of the given
+// statuses and records the change, fails when another request changed it first.
+func changeStockOpnameStatus(tx *gorm.DB, stockOpname *models2.StockOpname, status string, userID uint, comment string, from ...string) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	stmt := tx.Model(&models2.StockOpname{}).Where("id = ? AND status IN ?", stockOpname.ID, from)
+	if stmt = stmt.UpdateColumns(map[string]any{
+		"status":     status,
+		"updated_at": nokocore.GetTimeUtcNow(),
+	}); stmt.Error != nil {
+		return stmt.Error
+	}
+
+	if stmt.RowsAffected == 0 {
+		return ErrStatusChanged
+	}
+
+	if err = tx.Create(&models2.StockOpnameStatusChange{
+		StockOpnameID: stockOpname.ID,
+		UserID:        userID,
+		FromStatus:    stockOpname.Status,
+		ToStatus:      status,
+		Comment:       comment,
+	}).Error; err != nil {
+		return err
+	}
+
+	stockOpname.Status = status
+	return nil
+}
@@ -0,0 +1,180 @@
+package opnames
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/models"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"slices"
+	"time"
+)
+
+// CountSheetMaxEntries, entries merged by a single batch.
+const CountSheetMaxEntries = 500
+
+// CountSheetDeviceOnline, conflict device of counts entered online.
+const CountSheetDeviceOnline = "online"
+
+// CountSheetEntry, count of a product collected offline, quantities are kept
+// in base units.
+type CountSheetEntry struct {
+	ClientID       string
+	ProductID      uint
+	UnitScale      int
+	Quantity       int
+	SystemQuantity int
+	NotMatchReason string
+	CountedAt      time.Time
+}
+
+// CountSheetResult, kept entry of the batch, duplicates are entries of an
+// earlier batch with the same client id. Conflicts are answered with the device
+// and the user of the count kept by the stock opname.
+type CountSheetResult struct {
+	CountEntry       *models2.CountEntry
+	Duplicate        bool
+	CountedQuantity  int // counted quantity of the product after the merge
+	ConflictDeviceID string
+	ConflictUser     *models.User
+}
+
+// Merge method, merges count sheets of a device into the stock opname, entries
+// are applied in counted order. Later counts of the same device replace earlier
+// ones, different counts of another device are kept as conflicts.
+func (o *OpnameService) Merge(stockOpname *models2.StockOpname, deviceID string, countSheetEntries []CountSheetEntry, userID uint) ([]CountSheetResult, error) {
+	var err error
+	nokocore.KeepVoid(err)
+
+	size := len(countSheetEntries)
+	countSheetResults := make([]CountSheetResult, size)
+
+	indexes := make([]int, size)
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return countSheetEntries[a].CountedAt.Compare(countSheetEntries[b].CountedAt)
+	})
+
+	err = o.DB.Transaction(func(tx *gorm.DB) error {
+		for i, index := range indexes {
+			nokocore.KeepVoid(i)
+
+			if countSheetResults[index], err = mergeCountSheetEntry(tx, stockOpname, deviceID, &countSheetEntries[index], userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return countSheetResults, nil
+}
+
+func mergeCountSheetEntry(tx *gorm.DB, stockOpname *models2.StockOpname, deviceID string, countSheetEntry *CountSheetEntry, userID uint) (CountSheetResult, error) {
+	var err error
+	var countEntry models2.CountEntry
+	var lastCountEntry models2.CountEntry
+	var cartVerificationOpname models2.CartVerificationOpname
+	nokocore.KeepVoid(err, countEntry, lastCountEntry, cartVerificationOpname)
+
+	if err = tx.Where("product_id = ? AND stock_opname_id = ?", countSheetEntry.ProductID, stockOpname.ID).Limit(1).Find(&cartVerificationOpname).Error; err != nil {
+		return CountSheetResult{}, err
+	}
+
+	// sent again, answered as it was merged first
+	if err = tx.Where("stock_opname_id = ? AND client_id = ?", stockOpname.ID, countSheetEntry.ClientID).Limit(1).Find(&countEntry).Error; err != nil {
+		return CountSheetResult{}, err
+	}
+
+	if countEntry.ID != 0 {
+		return CountSheetResult{
+			CountEntry:      &countEntry,
+			Duplicate:       true,
+			CountedQuantity: cartVerificationOpname.RealQuantity,
+		}, nil
+	}
+
+	stmt := tx.Where("stock_opname_id = ? AND product_id = ? AND status = ?", stockOpname.ID, countSheetEntry.ProductID, models2.CountEntryStatusApplied)
+	if err = stmt.Order("counted_at DESC, id DESC").Limit(1).Find(&lastCountEntry).Error; err != nil {
+		return CountSheetResult{}, err
+	}
+
+	status := models2.CountEntryStatusApplied
+	var conflictDeviceID string
+	var conflictUser *models.User
+	switch {
+	case lastCountEntry.ID != 0 && lastCountEntry.DeviceID == deviceID:
+		if countSheetEntry.CountedAt.Before(lastCountEntry.CountedAt) {
+			status = models2.CountEntryStatusStale
+		}
+
+	case cartVerificationOpname.ID != 0 && cartVerificationOpname.RealQuantity != countSheetEntry.Quantity:
+		status = models2.CountEntryStatusConflict
+
+		// counts entered online have no count entry, or replaced the count of the device
+		conflictDeviceID = CountSheetDeviceOnline
+		conflictUserID := cartVerificationOpname.UserID
+		if lastCountEntry.ID != 0 && lastCountEntry.DeviceID != "" && lastCountEntry.Quantity == cartVerificationOpname.RealQuantity {
+			conflictDeviceID = lastCountEntry.DeviceID
+			conflictUserID = lastCountEntry.UserID
+		}
+
+		conflictUser = new(models.User)
+		if err = tx.Select("id", "uuid", "username").Limit(1).Find(conflictUser, "id = ?", conflictUserID).Error; err != nil {
+			return CountSheetResult{}, err
+		}
+
+		if conflictUser.ID == 0 {
+			conflictUser = nil
+		}
+	}
+
+	countEntry = models2.CountEntry{
+		StockOpnameID:  stockOpname.ID,
+		ClientID:       countSheetEntry.ClientID,
+		DeviceID:       deviceID,
+		ProductID:      countSheetEntry.ProductID,
+		UserID:         userID,
+		Quantity:       countSheetEntry.Quantity,
+		NotMatchReason: countSheetEntry.NotMatchReason,
+		CountedAt:      countSheetEntry.CountedAt,
+		Status:         status,
+	}
+
+	if err = tx.Create(&countEntry).Error; err != nil {
+		return CountSheetResult{}, err
+	}
+
+	if status == models2.CountEntryStatusApplied {
+		cartVerificationOpname.UserID = userID
+		cartVerificationOpname.ProductID = countSheetEntry.ProductID
+		cartVerificationOpname.StockOpnameID = stockOpname.ID
+		cartVerificationOpname.IsMatch = countSheetEntry.Quantity == countSheetEntry.SystemQuantity
+		cartVerificationOpname.NotMatchReason = countSheetEntry.NotMatchReason
+		cartVerificationOpname.RealPackageTotal, cartVerificationOpname.RealUnitExtra = models2.SplitQuantity(countSheetEntry.Quantity, countSheetEntry.UnitScale)
+		cartVerificationOpname.RealQuantity = countSheetEntry.Quantity
+
+		if cartVerificationOpname.ID == 0 {
+			err = tx.Create(&cartVerificationOpname).Error
+		} else {
+			err = tx.Omit("User", "Product").Save(&cartVerificationOpname).Error
+		}
+
+		if err != nil {
+			return CountSheetResult{}, err
+		}
+	}
+
+	return CountSheetResult{
+		CountEntry:       &countEntry,
+		CountedQuantity:  cartVerificationOpname.RealQuantity,
+		ConflictDeviceID: conflictDeviceID,
+		ConflictUser:     conflictUser,
+	}, nil
+}
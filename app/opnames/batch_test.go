@@ -0,0 +1,198 @@
+package opnames
+
+import (
+	"fmt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"testing"
+	"time"
+)
+
+type batchTest struct {
+	DB          *gorm.DB
+	Service     *OpnameService
+	StockOpname *models2.StockOpname
+	Users       []models.User
+}
+
+func newBatchTest(t *testing.T) *batchTest {
+	var err error
+	var DB *gorm.DB
+	nokocore.KeepVoid(err, DB)
+
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	if DB, err = gorm.Open(sqlite.Open(dsn), config); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []any{
+		&models.User{},
+		&models2.StockOpname{},
+		&models2.CountEntry{},
+		&models2.CartVerificationOpname{},
+	}
+
+	if err = DB.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	users := []models.User{
+		{Username: "officer", Password: "Officer@1234"},
+		{Username: "supervisor", Password: "Supervisor@1234"},
+	}
+
+	// repositories fill base model fields before create
+	userRepository := repositories.NewUserRepository(DB)
+	for i := range users {
+		if err = userRepository.Create(&users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stockOpname := &models2.StockOpname{
+		UserID: users[0].ID,
+	}
+
+	if err = DB.Create(stockOpname).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return &batchTest{
+		DB:          DB,
+		Service:     &OpnameService{DB: DB},
+		StockOpname: stockOpname,
+		Users:       users,
+	}
+}
+
+func (b *batchTest) merge(t *testing.T, deviceID string, userID uint, countSheetEntries ...CountSheetEntry) []CountSheetResult {
+	countSheetResults, err := b.Service.Merge(b.StockOpname, deviceID, countSheetEntries, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return countSheetResults
+}
+
+func TestMergeReplay(t *testing.T) {
+	b := newBatchTest(t)
+	countedAt := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	countSheetEntry := CountSheetEntry{
+		ClientID:  nokocore.NewUUID().String(),
+		ProductID: 1,
+		UnitScale: 10,
+		Quantity:  12,
+		CountedAt: countedAt,
+	}
+
+	first := b.merge(t, "device-a", b.Users[0].ID, countSheetEntry)[0]
+	if first.Duplicate || first.CountEntry.Status != models2.CountEntryStatusApplied {
+		t.Errorf("first merge should be applied, got %s (duplicate %t)", first.CountEntry.Status, first.Duplicate)
+		return
+	}
+
+	// the same batch is sent again, nothing is changed
+	for i := 0; i < 2; i++ {
+		replay := b.merge(t, "device-a", b.Users[0].ID, countSheetEntry)[0]
+		if !replay.Duplicate || replay.CountEntry.ID != first.CountEntry.ID || replay.CountedQuantity != 12 {
+			t.Errorf("replay should answer the first count entry, got %+v", replay)
+			return
+		}
+	}
+
+	var count int64
+	if err := b.DB.Model(&models2.CountEntry{}).Count(&count).Error; err != nil {
+		t.Error(err)
+		return
+	}
+
+	if count != 1 {
+		t.Errorf("count entries should be 1, got %d", count)
+		return
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	b := newBatchTest(t)
+	countedAt := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	b.merge(t, "device-a", b.Users[0].ID, CountSheetEntry{
+		ClientID:  nokocore.NewUUID().String(),
+		ProductID: 1,
+		UnitScale: 10,
+		Quantity:  12,
+		CountedAt: countedAt,
+	})
+
+	// another device counts a different quantity
+	conflict := b.merge(t, "device-b", b.Users[1].ID, CountSheetEntry{
+		ClientID:  nokocore.NewUUID().String(),
+		ProductID: 1,
+		UnitScale: 10,
+		Quantity:  15,
+		CountedAt: countedAt.Add(time.Minute),
+	})[0]
+
+	if conflict.CountEntry.Status != models2.CountEntryStatusConflict || conflict.CountedQuantity != 12 {
+		t.Errorf("different count should be a conflict keeping 12, got %s keeping %d", conflict.CountEntry.Status, conflict.CountedQuantity)
+		return
+	}
+
+	if conflict.ConflictDeviceID != "device-a" || conflict.ConflictUser == nil || conflict.ConflictUser.ID != b.Users[0].ID {
+		t.Errorf("conflict should be reported against device-a of officer, got %q", conflict.ConflictDeviceID)
+		return
+	}
+
+	// counts entered online replace the count of the device
+	if err := b.DB.Model(&models2.CartVerificationOpname{}).Where("product_id = ?", 1).Updates(map[string]any{"real_quantity": 13, "user_id": b.Users[1].ID}).Error; err != nil {
+		t.Error(err)
+		return
+	}
+
+	conflict = b.merge(t, "device-a", b.Users[0].ID, CountSheetEntry{
+		ClientID:  nokocore.NewUUID().String(),
+		ProductID: 1,
+		UnitScale: 10,
+		Quantity:  14,
+		CountedAt: countedAt.Add(2 * time.Minute),
+	})[0]
+
+	// the same device replaces its own count
+	if conflict.CountEntry.Status != models2.CountEntryStatusApplied || conflict.CountedQuantity != 14 {
+		t.Errorf("later count of the same device should be applied, got %s", conflict.CountEntry.Status)
+		return
+	}
+
+	if err := b.DB.Model(&models2.CartVerificationOpname{}).Where("product_id = ?", 1).Updates(map[string]any{"real_quantity": 13, "user_id": b.Users[1].ID}).Error; err != nil {
+		t.Error(err)
+		return
+	}
+
+	conflict = b.merge(t, "device-b", b.Users[1].ID, CountSheetEntry{
+		ClientID:  nokocore.NewUUID().String(),
+		ProductID: 1,
+		UnitScale: 10,
+		Quantity:  16,
+		CountedAt: countedAt.Add(3 * time.Minute),
+	})[0]
+
+	if conflict.CountEntry.Status != models2.CountEntryStatusConflict {
+		t.Errorf("count against an online count should be a conflict, got %s", conflict.CountEntry.Status)
+		return
+	}
+
+	if conflict.ConflictDeviceID != CountSheetDeviceOnline || conflict.ConflictUser == nil || conflict.ConflictUser.ID != b.Users[1].ID {
+		t.Errorf("conflict should be reported against the online count of supervisor, got %q", conflict.ConflictDeviceID)
+		return
+	}
+}
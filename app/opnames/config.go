@@ -0,0 +1,13 @@
+package opnames
+
+type Config struct {
+	Size       int    `mapstructure:"size" json:"size" yaml:"size"`
+	AbcPeriod  string `mapstructure:"abc_period" json:"abcPeriod" yaml:"abc_period"`
+	ClassADays int    `mapstructure:"class_a_days" json:"classADays" yaml:"class_a_days"`
+	ClassBDays int    `mapstructure:"class_b_days" json:"classBDays" yaml:"class_b_days"`
+	ClassCDays int    `mapstructure:"class_c_days" json:"classCDays" yaml:"class_c_days"`
+}
+
+func (Config) GetNameType() string {
+	return "CycleCount"
+}
@@ -0,0 +1,209 @@
+package opnames
+
+import (
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"nokowebapi/sqlx"
+	models2 "pharma-cash-go/app/models"
+	"sort"
+	"time"
+)
+
+const DefaultCycleSize = 20
+const DefaultAbcPeriod = 90 * 24 * time.Hour
+
+// default days between counts of each abc class
+var defaultClassDays = map[string]int{
+	models2.AbcClassA: 30,
+	models2.AbcClassB: 60,
+	models2.AbcClassC: 90,
+}
+
+// GetConfig method, 'cycle_count' config with defaults.
+func GetConfig() *Config {
+	config := globals.GetConfigGlobals[Config]()
+	if config.Size <= 0 {
+		config.Size = DefaultCycleSize
+	}
+	if config.ClassADays <= 0 {
+		config.ClassADays = defaultClassDays[models2.AbcClassA]
+	}
+	if config.ClassBDays <= 0 {
+		config.ClassBDays = defaultClassDays[models2.AbcClassB]
+	}
+	if config.ClassCDays <= 0 {
+		config.ClassCDays = defaultClassDays[models2.AbcClassC]
+	}
+	return config
+}
+
+// GetAbcPeriod method, sales period of abc classes from 'cycle_count' config.
+func GetAbcPeriod() time.Duration {
+	config := globals.GetConfigGlobals[Config]()
+	if period, err := time.ParseDuration(config.AbcPeriod); err == nil && period > 0 {
+		return period
+	}
+	return DefaultAbcPeriod
+}
+
+// GetClassDays method, days between counts of products of the abc class.
+func (c *Config) GetClassDays(class string) int {
+	switch class {
+	case models2.AbcClassA:
+		return c.ClassADays
+	case models2.AbcClassB:
+		return c.ClassBDays
+	default:
+		return c.ClassCDays
+	}
+}
+
+type cycleCandidate struct {
+	ProductID     uint
+	Class         string
+	LastCountedAt sql.NullTime
+}
+
+// Propose method, daily cycle count list of each location, products stocked
+// at the location are due by their abc class, class a and the longest not
+// counted come first, products of open stock opnames are left out.
+func (o *OpnameService) Propose(now time.Time) (int, error) {
+	var err error
+	var locations []models2.Location
+	var classes map[uint]string
+	nokocore.KeepVoid(err, locations, classes)
+
+	config := GetConfig()
+	proposedOn := sqlx.DateOnly{Time: now.UTC().Truncate(24 * time.Hour)}
+
+	if err = o.DB.Order("id ASC").Find(&locations).Error; err != nil {
+		return 0, err
+	}
+
+	if classes, err = o.Classify(now.Add(-GetAbcPeriod())); err != nil {
+		return 0, err
+	}
+
+	proposed := 0
+	for i := range locations {
+		var count int64
+		var productIDs []uint
+		var countedAt map[uint]time.Time
+		nokocore.KeepVoid(count, productIDs, countedAt)
+
+		location := &locations[i]
+		stmt := o.DB.Model(&models2.CycleCount{}).Where("location_id = ? AND proposed_on = ?", location.ID, proposedOn)
+		if err = stmt.Count(&count).Error; err != nil {
+			return proposed, err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		stmt = o.DB.Model(&models2.Product{})
+		stmt = stmt.Where("id IN (SELECT product_id FROM product_stocks WHERE location_id = ? AND stock <> 0 AND deleted_at IS NULL)", location.ID)
+		stmt = stmt.Where("id NOT IN (SELECT soi.product_id FROM stock_opname_items soi JOIN stock_opnames so ON so.id = soi.stock_opname_id WHERE so.location_id = ? AND so.is_verified = ? AND so.is_cancelled = ? AND so.deleted_at IS NULL AND soi.deleted_at IS NULL)", location.ID, false, false)
+		if err = stmt.Order("id ASC").Pluck("id", &productIDs).Error; err != nil {
+			return proposed, err
+		}
+
+		if countedAt, err = o.LastCounted(location.ID); err != nil {
+			return proposed, err
+		}
+
+		var candidates []cycleCandidate
+		for j, productID := range productIDs {
+			nokocore.KeepVoid(j)
+
+			class, ok := classes[productID]
+			if !ok {
+				class = models2.AbcClassC
+			}
+
+			candidate := cycleCandidate{
+				ProductID: productID,
+				Class:     class,
+			}
+
+			if counted, ok := countedAt[productID]; ok {
+				if counted.After(now.AddDate(0, 0, -config.GetClassDays(class))) {
+					continue
+				}
+				candidate.LastCountedAt = sql.NullTime{Time: counted, Valid: true}
+			}
+
+			candidates = append(candidates, candidate)
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].Class != candidates[b].Class {
+				return candidates[a].Class < candidates[b].Class
+			}
+			if candidates[a].LastCountedAt.Valid != candidates[b].LastCountedAt.Valid {
+				return !candidates[a].LastCountedAt.Valid
+			}
+			return candidates[a].LastCountedAt.Time.Before(candidates[b].LastCountedAt.Time)
+		})
+
+		if len(candidates) > config.Size {
+			candidates = candidates[:config.Size]
+		}
+
+		err = o.DB.Transaction(func(tx *gorm.DB) error {
+			cycleCount := &models2.CycleCount{
+				LocationID: location.ID,
+				ProposedOn: proposedOn,
+			}
+
+			if err = tx.Create(cycleCount).Error; err != nil {
+				return err
+			}
+
+			cycleCountItems := make([]models2.CycleCountItem, len(candidates))
+			for j, candidate := range candidates {
+				cycleCountItems[j] = models2.CycleCountItem{
+					CycleCountID:  cycleCount.ID,
+					ProductID:     candidate.ProductID,
+					Class:         candidate.Class,
+					LastCountedAt: candidate.LastCountedAt,
+				}
+			}
+
+			return tx.CreateInBatches(&cycleCountItems, AdjustBatchSize).Error
+		})
+
+		if err != nil {
+			return proposed, err
+		}
+
+		proposed += 1
+	}
+
+	return proposed, nil
+}
+
+// CycleCountJob method, scheduler job proposing daily cycle count lists.
+func CycleCountJob(DB *gorm.DB, now time.Time) error {
+	var err error
+	var proposed int
+	nokocore.KeepVoid(err, proposed)
+
+	if proposed, err = NewOpnameService(DB).Propose(now); err != nil {
+		return err
+	}
+
+	if proposed > 0 {
+		console.Info(fmt.Sprintf("%d cycle count list(s) has been proposed.", proposed))
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package opnames
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/costing"
+	"pharma-cash-go/app/locations"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/registers"
+	"strings"
+	"time"
+)
+
+// AdjustBatchSize, products updated by a single statement, seven bound
+// parameters are used for each product.
+const AdjustBatchSize = 100
+
+var ErrNotVerified = errors.New("stock opname is not verified")
+
+// OpnameAdjustment, counted minus system quantity of a product at the counted location.
+type OpnameAdjustment struct {
+	ProductID uint
+	Quantity  int
+	Note      string
+}
+
+type OpnameReportRow struct {
+	ProductUUID     uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	Barcode         string          `mapstructure:"barcode" json:"barcode"`
+	ProductName     string          `mapstructure:"product_name" json:"productName"`
+	Brand           string          `mapstructure:"brand" json:"brand"`
+	UnitType        string          `mapstructure:"unit_type" json:"unitType"`
+	SystemQuantity  int             `mapstructure:"system_quantity" json:"systemQuantity"`
+	CountedQuantity int             `mapstructure:"counted_quantity" json:"countedQuantity"`
+	Difference      int             `mapstructure:"difference" json:"difference"` // counted minus system
+	PurchasePrice   decimal.Decimal `mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice       decimal.Decimal `mapstructure:"sale_price" json:"salePrice"`
+	PurchaseValue   decimal.Decimal `mapstructure:"purchase_value" json:"purchaseValue"`
+	SaleValue       decimal.Decimal `mapstructure:"sale_value" json:"saleValue"`
+	IsMatch         bool            `mapstructure:"is_match" json:"isMatch"`
+	NotMatchReason  string          `mapstructure:"not_match_reason" json:"notMatchReason"`
+}
+
+type OpnameReportTotal struct {
+	Lines           int             `mapstructure:"lines" json:"lines"`
+	Mismatches      int             `mapstructure:"mismatches" json:"mismatches"`
+	SystemQuantity  int             `mapstructure:"system_quantity" json:"systemQuantity"`
+	CountedQuantity int             `mapstructure:"counted_quantity" json:"countedQuantity"`
+	Difference      int             `mapstructure:"difference" json:"difference"`
+	PurchaseValue   decimal.Decimal `mapstructure:"purchase_value" json:"purchaseValue"`
+	SaleValue       decimal.Decimal `mapstructure:"sale_value" json:"saleValue"`
+}
+
+type OpnameReport struct {
+	StockOpname *models2.StockOpname `mapstructure:"-" json:"-"`
+	Location    *models2.Location    `mapstructure:"-" json:"-"`
+	Category    *models2.Category    `mapstructure:"-" json:"-"`
+	Rows        []OpnameReportRow    `mapstructure:"rows" json:"rows"`
+	Total       OpnameReportTotal    `mapstructure:"total" json:"total"`
+}
+
+type OpnameServiceImpl interface {
+	Verify(stockOpname *models2.StockOpname, locationID uint, verificationOpnames []*models2.VerificationOpname, adjustments []OpnameAdjustment, userID uint) error
+	Submit(stockOpname *models2.StockOpname, userID uint, comment string) error
+	Reject(stockOpname *models2.StockOpname, userID uint, comment string) error
+	Cancel(stockOpname *models2.StockOpname, userID uint, reason string) error
+	Merge(stockOpname *models2.StockOpname, deviceID string, countSheetEntries []CountSheetEntry, userID uint) ([]CountSheetResult, error)
+	Adjust(stockOpname *models2.StockOpname, locationID uint, adjustments []OpnameAdjustment, userID uint) error
+	Report(stockOpname *models2.StockOpname, location *models2.Location, category *models2.Category) (*OpnameReport, error)
+	Classify(since time.Time) (map[uint]string, error)
+	LastCounted(locationID uint) (map[uint]time.Time, error)
+	ScopeProducts(scope *OpnameScope, now time.Time) ([]uint, error)
+	Start(stockOpname *models2.StockOpname, scope *OpnameScope, now time.Time) (int, error)
+	Propose(now time.Time) (int, error)
+	SyncStockOpnames() error
+}
+
+type OpnameService struct {
+	DB *gorm.DB
+}
+
+func NewOpnameService(DB *gorm.DB) OpnameServiceImpl {
+	return &OpnameService{
+		DB: DB,
+	}
+}
+
+// Verify method, approves the submitted stock opname, writes verification lines,
+// applies adjustments, clears pending entries and marks the stock opname verified
+// in a single transaction.
+func (o *OpnameService) Verify(stockOpname *models2.StockOpname, locationID uint, verificationOpnames []*models2.VerificationOpname, adjustments []OpnameAdjustment, userID uint) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		if err = changeStockOpnameStatus(tx, stockOpname, models2.StockOpnameStatusApproved, userID, "", models2.StockOpnameStatusSubmitted); err != nil {
+			return err
+		}
+
+		if len(verificationOpnames) > 0 {
+			if err = tx.CreateInBatches(verificationOpnames, AdjustBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		opnameService := NewOpnameService(tx)
+		if err = opnameService.Adjust(stockOpname, locationID, adjustments, userID); err != nil {
+			return err
+		}
+
+		if err = tx.Unscoped().Where("stock_opname_id = ?", stockOpname.ID).Delete(&models2.CartVerificationOpname{}).Error; err != nil {
+			return err
+		}
+
+		stockOpname.IsVerified = true
+		return tx.Model(&models2.StockOpname{}).Where("id = ?", stockOpname.ID).Updates(map[string]any{
+			"submited_at": nokocore.GetTimeUtcNow(),
+			"is_verified": stockOpname.IsVerified,
+		}).Error
+	})
+}
+
+// Adjust method, changes product stock by the counted differences in batches,
+// every change is recorded as a stock movement referencing the stock opname,
+// must be called in a transaction.
+func (o *OpnameService) Adjust(stockOpname *models2.StockOpname, locationID uint, adjustments []OpnameAdjustment, userID uint) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	locationService := locations.NewLocationService(o.DB)
+	costingService := costing.NewCostingService(o.DB)
+	registerService := registers.NewRegisterService(o.DB)
+	reference := stockOpname.UUID.String()
+
+	for start := 0; start < len(adjustments); start += AdjustBatchSize {
+		var products []models2.Product
+		nokocore.KeepVoid(products)
+
+		end := min(start+AdjustBatchSize, len(adjustments))
+		batch := adjustments[start:end]
+
+		productIDs := make([]uint, 0, len(batch))
+		for i, adjustment := range batch {
+			nokocore.KeepVoid(i)
+			if adjustment.Quantity != 0 {
+				productIDs = append(productIDs, adjustment.ProductID)
+			}
+		}
+
+		if len(productIDs) == 0 {
+			continue
+		}
+
+		// deleted products are counted too
+		if err = o.DB.Unscoped().Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			return err
+		}
+
+		productMap := make(map[uint]*models2.Product, len(products))
+		for i := range products {
+			productMap[products[i].ID] = &products[i]
+		}
+
+		var cases [3]strings.Builder
+		var args [3][]any
+		var ids []any
+		var stockMovements []models2.StockMovement
+		for i, adjustment := range batch {
+			nokocore.KeepVoid(i)
+
+			product, ok := productMap[adjustment.ProductID]
+			if !ok || adjustment.Quantity == 0 {
+				continue
+			}
+
+			stockBefore := product.Stock
+			product.SetStock(stockBefore + adjustment.Quantity)
+
+			values := []int{product.PackageTotal, product.UnitExtra, product.Stock}
+			for j, value := range values {
+				cases[j].WriteString(" WHEN ? THEN ?")
+				args[j] = append(args[j], product.ID, value)
+			}
+			ids = append(ids, product.ID)
+
+			stockMovements = append(stockMovements, models2.StockMovement{
+				ProductID:   product.ID,
+				LocationID:  locationID,
+				UserID:      userID,
+				Kind:        models2.StockMovementKindOpname,
+				Quantity:    adjustment.Quantity,
+				StockBefore: stockBefore,
+				StockAfter:  product.Stock,
+				Reference:   reference,
+				Note:        adjustment.Note,
+			})
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		query := fmt.Sprintf("UPDATE products SET package_total = CASE id%s END, unit_extra = CASE id%s END, stock = CASE id%s END, updated_at = ? WHERE id IN ?", cases[0].String(), cases[1].String(), cases[2].String())
+		values := append(append(append(args[0], args[1]...), args[2]...), nokocore.GetTimeUtcNow(), ids)
+		if err = o.DB.Exec(query, values...).Error; err != nil {
+			return err
+		}
+
+		if err = o.DB.CreateInBatches(&stockMovements, AdjustBatchSize).Error; err != nil {
+			return err
+		}
+
+		for i, stockMovement := range stockMovements {
+			nokocore.KeepVoid(i)
+
+			product := productMap[stockMovement.ProductID]
+			if err = locationService.Move(product.ID, locationID, stockMovement.Quantity); err != nil {
+				return err
+			}
+
+			if err = costingService.Sync(product, reference); err != nil {
+				return err
+			}
+
+			if err = registerService.Adjust(product, stockMovement.Quantity, userID, reference, stockMovement.Note); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type opnameLine struct {
+	ProductUUID     uuid.UUID
+	Barcode         string
+	ProductName     string
+	Brand           string
+	UnitType        sql.NullString
+	PurchasePrice   decimal.NullDecimal
+	SalePrice       decimal.NullDecimal
+	SystemUnitTotal int
+	RealUnitTotal   int
+	IsMatch         bool
+	NotMatchReason  string
+}
+
+// Report method, every verified line of the stock opname, differences are valued
+// at purchase and sale prices of the products when the stock opname was verified.
+func (o *OpnameService) Report(stockOpname *models2.StockOpname, location *models2.Location, category *models2.Category) (*OpnameReport, error) {
+	var err error
+	var lines []opnameLine
+	nokocore.KeepVoid(err, lines)
+
+	if stockOpname == nil || !stockOpname.IsVerified {
+		return nil, ErrNotVerified
+	}
+
+	// verification opnames keep product uuids, deleted products are reported too,
+	// lines verified before prices were kept fall back to current prices
+	stmt := o.DB.Table("verification_opnames vo").Select(`
+		p.uuid AS product_uuid,
+		p.barcode,
+		p.product_name,
+		p.brand,
+		u.unit_type,
+		COALESCE(vo.purchase_price, p.purchase_price) AS purchase_price,
+		COALESCE(vo.sale_price, p.sale_price) AS sale_price,
+		vo.system_unit_total,
+		vo.real_unit_total,
+		vo.is_match,
+		vo.not_match_reason
+	`)
+	stmt = stmt.Joins("JOIN products p ON p.uuid = vo.product_id").Joins("LEFT JOIN units u ON u.id = p.unit_id")
+	stmt = stmt.Where("vo.stock_opname_id = ? AND vo.deleted_at IS NULL", stockOpname.ID)
+	if err = stmt.Order("p.product_name ASC, vo.id ASC").Scan(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	report := &OpnameReport{
+		StockOpname: stockOpname,
+		Location:    location,
+		Category:    category,
+		Rows:        make([]OpnameReportRow, 0, len(lines)),
+		Total: OpnameReportTotal{
+			PurchaseValue: decimal.Zero,
+			SaleValue:     decimal.Zero,
+		},
+	}
+
+	for i, line := range lines {
+		nokocore.KeepVoid(i)
+
+		// real quantities are only written for not match lines
+		counted := line.SystemUnitTotal
+		if !line.IsMatch {
+			counted = line.RealUnitTotal
+		}
+
+		difference := counted - line.SystemUnitTotal
+		purchasePrice := line.PurchasePrice.Decimal
+		salePrice := line.SalePrice.Decimal
+		row := OpnameReportRow{
+			ProductUUID:     line.ProductUUID,
+			Barcode:         line.Barcode,
+			ProductName:     line.ProductName,
+			Brand:           line.Brand,
+			UnitType:        line.UnitType.String,
+			SystemQuantity:  line.SystemUnitTotal,
+			CountedQuantity: counted,
+			Difference:      difference,
+			PurchasePrice:   purchasePrice,
+			SalePrice:       salePrice,
+			PurchaseValue:   purchasePrice.Mul(decimal.NewFromInt(int64(difference))),
+			SaleValue:       salePrice.Mul(decimal.NewFromInt(int64(difference))),
+			IsMatch:         line.IsMatch,
+			NotMatchReason:  line.NotMatchReason,
+		}
+
+		report.Rows = append(report.Rows, row)
+		report.Total.Lines += 1
+		if !row.IsMatch {
+			report.Total.Mismatches += 1
+		}
+		report.Total.SystemQuantity += row.SystemQuantity
+		report.Total.CountedQuantity += row.CountedQuantity
+		report.Total.Difference += row.Difference
+		report.Total.PurchaseValue = report.Total.PurchaseValue.Add(row.PurchaseValue)
+		report.Total.SaleValue = report.Total.SaleValue.Add(row.SaleValue)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package opnames
+
+import (
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/reports"
+)
+
+var reportHeaders = []string{
+	"No",
+	"Barcode",
+	"Product",
+	"Brand",
+	"Unit",
+	"System",
+	"Counted",
+	"Difference",
+	"Purchase Price",
+	"Purchase Value",
+	"Sale Price",
+	"Sale Value",
+	"Reason",
+}
+
+var reportWidths = []float64{5, 14, 26, 14, 8, 9, 9, 12, 18, 18, 16, 16, 22}
+
+// ToTable method, the report as a table of the reports package.
+func (o *OpnameReport) ToTable() *reports.Table {
+	location := "-"
+	if o.Location != nil {
+		location = o.Location.LocationName
+	}
+
+	category := "All"
+	if o.Category != nil {
+		category = o.Category.CategoryName
+	}
+
+	table := &reports.Table{
+		Title: "Stock Opname Variance Report",
+		Sheet: "Stock Opname",
+		Lines: [][2]string{
+			{"Stock Opname", o.StockOpname.UUID.String()},
+			{"Location", location},
+			{"Category", category},
+			{"Started At", nokocore.ToTimeUtcStringISO8601(o.StockOpname.CreatedAt)},
+			{"Verified At", nokocore.ToTimeUtcStringISO8601(o.StockOpname.UpdatedAt)}, // last update of verified stock opnames
+			{"Generated At", nokocore.ToTimeUtcStringISO8601(nokocore.GetTimeUtcNow())},
+		},
+		Headers: reportHeaders,
+		Widths:  reportWidths,
+		Rows:    make([][]any, 0, len(o.Rows)),
+	}
+
+	for i, row := range o.Rows {
+		table.Rows = append(table.Rows, []any{
+			i + 1,
+			row.Barcode,
+			row.ProductName,
+			row.Brand,
+			row.UnitType,
+			row.SystemQuantity,
+			row.CountedQuantity,
+			row.Difference,
+			row.PurchasePrice,
+			row.PurchaseValue,
+			row.SalePrice,
+			row.SaleValue,
+			row.NotMatchReason,
+		})
+	}
+
+	table.Totals = []any{
+		"",
+		"Total",
+		"",
+		"",
+		"",
+		o.Total.SystemQuantity,
+		o.Total.CountedQuantity,
+		o.Total.Difference,
+		"",
+		o.Total.PurchaseValue,
+		"",
+		o.Total.SaleValue,
+		"",
+	}
+
+	return table
+}
@@ -0,0 +1,367 @@
+package opnames
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/locations"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+	"time"
+)
+
+var ErrEmptyScope = errors.New("no products in scope")
+var ErrScopeOverlap = errors.New("products in scope are counted by an open stock opname")
+var ErrCycleCountStarted = errors.New("cycle count is already started")
+
+// ScopeOverlapError, open stock opnames at the location already counting products of the scope.
+type ScopeOverlapError struct {
+	StockOpnames []models2.StockOpname
+	ProductTotal int
+}
+
+func (s *ScopeOverlapError) Error() string {
+	return fmt.Sprintf("%d %s", s.ProductTotal, ErrScopeOverlap.Error())
+}
+
+func (s *ScopeOverlapError) Unwrap() error {
+	return ErrScopeOverlap
+}
+
+// abc classes by the share of sold cost, the remaining products are class c
+var abcClassShareA = decimal.NewFromFloat(0.80)
+var abcClassShareB = decimal.NewFromFloat(0.95)
+
+// OpnameScope, filters of the products counted at a location, filters are
+// combined, every product when empty.
+type OpnameScope struct {
+	LocationID     uint
+	CategoryIDs    []uint
+	Suppliers      []string
+	StockedOnly    bool // products stocked at the location
+	NotCountedDays int
+	Class          string
+	ProductIDs     []uint // cycle count list
+}
+
+// Classify method, abc classes of products sold since, by cost of sold units,
+// unsold products are class c and left out.
+func (o *OpnameService) Classify(since time.Time) (map[uint]string, error) {
+	var err error
+	var rows []struct {
+		ProductID uint
+		Value     decimal.Decimal
+	}
+	nokocore.KeepVoid(err, rows)
+
+	stmt := o.DB.Model(&models2.CostEntry{}).Select("product_id, -SUM(total_cost) AS value")
+	stmt = stmt.Where("kind = ? AND occurred_at >= ?", models2.CostEntryKindSale, since)
+	if err = stmt.Group("product_id").Order("value DESC, product_id ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	total := decimal.Zero
+	for i, row := range rows {
+		nokocore.KeepVoid(i)
+		if row.Value.IsPositive() {
+			total = total.Add(row.Value)
+		}
+	}
+
+	classes := make(map[uint]string, len(rows))
+	if !total.IsPositive() {
+		return classes, nil
+	}
+
+	// class by the share sold before the product, the best seller is always class a
+	cumulative := decimal.Zero
+	for i, row := range rows {
+		nokocore.KeepVoid(i)
+		if !row.Value.IsPositive() {
+			break
+		}
+
+		share := cumulative.Div(total)
+		switch {
+		case share.LessThan(abcClassShareA):
+			classes[row.ProductID] = models2.AbcClassA
+		case share.LessThan(abcClassShareB):
+			classes[row.ProductID] = models2.AbcClassB
+		default:
+			classes[row.ProductID] = models2.AbcClassC
+		}
+
+		cumulative = cumulative.Add(row.Value)
+	}
+
+	return classes, nil
+}
+
+// LastCounted method, when products were last counted by a verified stock
+// opname at the location, never counted products are left out.
+func (o *OpnameService) LastCounted(locationID uint) (map[uint]time.Time, error) {
+	var err error
+	var rows []struct {
+		ProductID uint
+		CountedAt time.Time
+	}
+	nokocore.KeepVoid(err, rows)
+
+	// verification lines keep the product uuid
+	query := `
+		SELECT
+			p.id AS product_id,
+			vo.created_at AS counted_at
+		FROM
+			verification_opnames vo
+		JOIN
+			products p ON p.uuid = vo.product_id
+		WHERE
+			vo.id IN (
+				SELECT MAX(vo2.id)
+				FROM verification_opnames vo2
+				JOIN stock_opnames so ON so.id = vo2.stock_opname_id
+				WHERE so.location_id = ? AND so.is_verified = ? AND vo2.deleted_at IS NULL
+				GROUP BY vo2.product_id
+			);
+	`
+
+	if err = o.DB.Raw(query, locationID, true).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	countedAt := make(map[uint]time.Time, len(rows))
+	for i, row := range rows {
+		nokocore.KeepVoid(i)
+		countedAt[row.ProductID] = row.CountedAt
+	}
+
+	return countedAt, nil
+}
+
+// ScopeProducts method, ids of the products matching the scope.
+func (o *OpnameService) ScopeProducts(scope *OpnameScope, now time.Time) ([]uint, error) {
+	var err error
+	var productIDs []uint
+	nokocore.KeepVoid(err, productIDs)
+
+	stmt := o.DB.Model(&models2.Product{})
+	if len(scope.ProductIDs) > 0 {
+		stmt = stmt.Where("id IN ?", scope.ProductIDs)
+	}
+
+	if len(scope.CategoryIDs) > 0 {
+		stmt = stmt.Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", scope.CategoryIDs)
+	}
+
+	if len(scope.Suppliers) > 0 {
+		suppliers := make([]string, len(scope.Suppliers))
+		for i, supplier := range scope.Suppliers {
+			suppliers[i] = strings.ToLower(strings.TrimSpace(supplier))
+		}
+		stmt = stmt.Where("LOWER(supplier) IN ?", suppliers)
+	}
+
+	if scope.StockedOnly {
+		stmt = stmt.Where("id IN (SELECT product_id FROM product_stocks WHERE location_id = ? AND stock <> 0 AND deleted_at IS NULL)", scope.LocationID)
+	}
+
+	if scope.NotCountedDays > 0 {
+		since := now.AddDate(0, 0, -scope.NotCountedDays)
+		stmt = stmt.Where("uuid NOT IN (SELECT vo.product_id FROM verification_opnames vo JOIN stock_opnames so ON so.id = vo.stock_opname_id WHERE so.location_id = ? AND so.is_verified = ? AND vo.deleted_at IS NULL AND vo.created_at >= ?)", scope.LocationID, true, since)
+	}
+
+	if scope.Class != "" {
+		var classes map[uint]string
+		if classes, err = o.Classify(now.Add(-GetAbcPeriod())); err != nil {
+			return nil, err
+		}
+
+		// class c are products sold least and unsold products, not in classes a and b
+		var classIDs []uint
+		for productID, class := range classes {
+			if (scope.Class == models2.AbcClassC) != (class == scope.Class) {
+				classIDs = append(classIDs, productID)
+			}
+		}
+
+		switch {
+		case scope.Class == models2.AbcClassC:
+			if len(classIDs) > 0 {
+				stmt = stmt.Where("id NOT IN ?", classIDs)
+			}
+		case len(classIDs) == 0:
+			return nil, nil
+		default:
+			stmt = stmt.Where("id IN ?", classIDs)
+		}
+	}
+
+	if err = stmt.Order("id ASC").Pluck("id", &productIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return productIDs, nil
+}
+
+// Start method, creates the stock opname with the products in scope, products
+// can not be counted by two open stock opnames at the same location, returns
+// the number of counted products.
+func (o *OpnameService) Start(stockOpname *models2.StockOpname, scope *OpnameScope, now time.Time) (int, error) {
+	var err error
+	var productIDs []uint
+	nokocore.KeepVoid(err, productIDs)
+
+	if productIDs, err = o.ScopeProducts(scope, now); err != nil {
+		return 0, err
+	}
+
+	if len(productIDs) == 0 {
+		return 0, ErrEmptyScope
+	}
+
+	err = o.DB.Transaction(func(tx *gorm.DB) error {
+		var overlaps []struct {
+			StockOpnameID uint
+			ProductTotal  int
+		}
+		nokocore.KeepVoid(overlaps)
+
+		if err = tx.Create(stockOpname).Error; err != nil {
+			return err
+		}
+
+		if err = createStockOpnameItems(tx, stockOpname, productIDs); err != nil {
+			return err
+		}
+
+		// a cycle count list is counted once
+		if stockOpname.CycleCountID != 0 {
+			stmt := tx.Model(&models2.CycleCount{}).Where("id = ? AND COALESCE(stock_opname_id, 0) = 0", stockOpname.CycleCountID)
+			if stmt = stmt.UpdateColumn("stock_opname_id", stockOpname.ID); stmt.Error != nil {
+				return stmt.Error
+			}
+
+			if stmt.RowsAffected == 0 {
+				return ErrCycleCountStarted
+			}
+		}
+
+		query := `
+			SELECT
+				so.id AS stock_opname_id,
+				COUNT(*) AS product_total
+			FROM
+				stock_opname_items soi
+			JOIN
+				stock_opname_items other ON other.product_id = soi.product_id AND other.stock_opname_id <> soi.stock_opname_id AND other.deleted_at IS NULL
+			JOIN
+				stock_opnames so ON so.id = other.stock_opname_id AND so.deleted_at IS NULL
+			WHERE
+				soi.stock_opname_id = ? AND so.location_id = ? AND so.is_verified = ? AND so.is_cancelled = ?
+			GROUP BY
+				so.id
+			ORDER BY
+				so.id ASC;
+		`
+
+		if err = tx.Raw(query, stockOpname.ID, stockOpname.LocationID, false, false).Scan(&overlaps).Error; err != nil {
+			return err
+		}
+
+		if len(overlaps) > 0 {
+			scopeOverlapError := &ScopeOverlapError{}
+			stockOpnameIDs := make([]uint, len(overlaps))
+			for i, overlap := range overlaps {
+				stockOpnameIDs[i] = overlap.StockOpnameID
+				scopeOverlapError.ProductTotal += overlap.ProductTotal
+			}
+
+			// overlapping stock opnames are reported, the new one is rolled back
+			if err = tx.Preload("User").Order("id ASC").Find(&scopeOverlapError.StockOpnames, "id IN ?", stockOpnameIDs).Error; err != nil {
+				return err
+			}
+
+			return scopeOverlapError
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(productIDs), nil
+}
+
+func createStockOpnameItems(tx *gorm.DB, stockOpname *models2.StockOpname, productIDs []uint) error {
+	stockOpnameItems := make([]models2.StockOpnameItem, len(productIDs))
+	for i, productID := range productIDs {
+		stockOpnameItems[i] = models2.StockOpnameItem{
+			StockOpnameID: stockOpname.ID,
+			ProductID:     productID,
+		}
+	}
+
+	return tx.CreateInBatches(&stockOpnameItems, AdjustBatchSize).Error
+}
+
+// SyncStockOpnames method, stock opnames created before locations count the
+// selling location, verified ones are approved, open stock opnames created
+// before scopes count every product of their category.
+func (o *OpnameService) SyncStockOpnames() error {
+	var err error
+	var location *models2.Location
+	var stockOpnames []models2.StockOpname
+	nokocore.KeepVoid(err, location, stockOpnames)
+
+	if location, err = locations.NewLocationService(o.DB).GetSelling(); err != nil {
+		return err
+	}
+
+	stmt := o.DB.Unscoped().Model(&models2.StockOpname{}).Where("location_id IS NULL OR location_id = 0")
+	if err = stmt.UpdateColumn("location_id", location.ID).Error; err != nil {
+		return err
+	}
+
+	// verified before approvals were recorded
+	stmt = o.DB.Unscoped().Model(&models2.StockOpname{}).Where("is_verified = ? AND status <> ?", true, models2.StockOpnameStatusApproved)
+	if err = stmt.UpdateColumn("status", models2.StockOpnameStatusApproved).Error; err != nil {
+		return err
+	}
+
+	stmt = o.DB.Where("is_verified = ? AND is_cancelled = ?", false, false)
+	stmt = stmt.Where("id NOT IN (SELECT stock_opname_id FROM stock_opname_items)")
+	if err = stmt.Find(&stockOpnames).Error; err != nil {
+		return err
+	}
+
+	for i := range stockOpnames {
+		var productIDs []uint
+		stockOpname := &stockOpnames[i]
+
+		scope := &OpnameScope{
+			LocationID: stockOpname.LocationID,
+		}
+		if stockOpname.CategoryID != 0 {
+			scope.CategoryIDs = []uint{stockOpname.CategoryID}
+		}
+
+		if productIDs, err = o.ScopeProducts(scope, nokocore.GetTimeUtcNow()); err != nil {
+			return err
+		}
+
+		if len(productIDs) == 0 {
+			continue
+		}
+
+		if err = createStockOpnameItems(o.DB, stockOpname, productIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package permissions
+
+import "nokowebapi/nokocore"
+
+const (
+	UserManage       = "user.manage"
+	TrashManage      = "trash.manage"
+	PolicyManage     = "policy.manage"
+	ProductRead      = "product.read"
+	ProductWrite     = "product.write"
+	UnitWrite        = "unit.write"
+	PackageWrite     = "package.write"
+	ReceiptRead      = "receipt.read"
+	ReceiptWrite     = "receipt.write"
+	PriceRead        = "price.read"
+	PriceWrite       = "price.write"
+	InteractionRead  = "interaction.read"
+	InteractionWrite = "interaction.write"
+	SaleRead         = "sale.read"
+	SaleWrite        = "sale.write"
+	SaleAcknowledge  = "sale.acknowledge"
+	RegisterRead     = "register.read"
+	InventoryRead    = "inventory.read"
+	ExpiryRead       = "expiry.read"
+	ExpiryQuarantine = "expiry.quarantine"
+	LocationRead     = "location.read"
+	LocationWrite    = "location.write"
+	TransferRead     = "transfer.read"
+	TransferWrite    = "transfer.write"
+	OpnameRead       = "opname.read"
+	OpnameCount      = "opname.count"
+	OpnameApprove    = "opname.approve"
+	OpnamePlan       = "opname.plan"
+)
+
+// Grant method, default roles of the permissions, the 'permissions' config replaces them by name,
+// the roles assigned in database replace both.
+func Grant(policy nokocore.PolicyImpl) {
+	policy.Grant(UserManage, nokocore.RoleAdmin)
+	policy.Grant(TrashManage, nokocore.RoleAdmin)
+	policy.Grant(PolicyManage, nokocore.RoleAdmin)
+	policy.Grant(ProductRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor, nokocore.RolePharmacist)
+	policy.Grant(ProductWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(UnitWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(PackageWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(ReceiptRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor)
+	policy.Grant(ReceiptWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(PriceRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor)
+	policy.Grant(PriceWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(InteractionRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor, nokocore.RolePharmacist)
+	policy.Grant(InteractionWrite, nokocore.RoleAdmin, nokocore.RoleSupervisor)
+	policy.Grant(SaleRead, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(SaleWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(RegisterRead, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(InventoryRead, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(ExpiryRead, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(ExpiryQuarantine, nokocore.RoleAdmin, nokocore.RoleSupervisor)
+	policy.Grant(LocationRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor)
+	policy.Grant(LocationWrite, nokocore.RoleAdmin)
+	policy.Grant(TransferRead, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(TransferWrite, nokocore.RoleAdmin, nokocore.RoleOfficer)
+	policy.Grant(OpnameRead, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor)
+	policy.Grant(OpnameCount, nokocore.RoleAdmin, nokocore.RoleOfficer, nokocore.RoleSupervisor)
+	policy.Grant(OpnameApprove, nokocore.RoleAdmin, nokocore.RoleSupervisor)
+	policy.Grant(OpnamePlan, nokocore.RoleAdmin)
+
+	// severe drug interactions are acknowledged by pharmacists only
+	policy.Grant(SaleAcknowledge, nokocore.RolePharmacist)
+}
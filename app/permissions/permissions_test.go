@@ -0,0 +1,60 @@
+package permissions
+
+import (
+	"nokowebapi/nokocore"
+	"slices"
+	"testing"
+)
+
+func TestPolicyRoles(t *testing.T) {
+	policy := nokocore.NewPolicy()
+	Grant(policy)
+
+	// config replaces the defaults, empty roles deny everyone
+	policy.Apply(nokocore.Permissions{
+		{Name: ExpiryQuarantine, Roles: []string{"Admin", "Officer"}},
+		{Name: TrashManage, Roles: []string{}},
+	})
+
+	// database replaces both
+	policy.Assign(OpnameApprove, []string{"Admin"})
+	policy.Assign(ExpiryQuarantine, []string{"Supervisor"})
+	policy.Unassign(ExpiryQuarantine)
+
+	for i, test := range []struct {
+		name    string
+		roles   []string
+		allowed bool
+	}{
+		{ProductWrite, []string{"Officer"}, true},
+		{ProductWrite, []string{"Supervisor"}, false},
+		{SaleAcknowledge, []string{"Pharmacist"}, true},
+		{SaleAcknowledge, []string{"Admin"}, false},
+		{ExpiryQuarantine, []string{"Officer"}, true},
+		{ExpiryQuarantine, []string{"Supervisor"}, false},
+		{TrashManage, []string{"Admin"}, false},
+		{OpnameApprove, []string{"Admin"}, true},
+		{OpnameApprove, []string{"Supervisor"}, false},
+		{"unknown.permission", []string{"Admin"}, false},
+		{" Product.Read ", []string{"Pharmacist"}, true},
+	} {
+		nokocore.KeepVoid(i)
+
+		if allowed := policy.Allows(test.roles, test.name); allowed != test.allowed {
+			t.Errorf("Allows(%v, %q) = %t, want %t", test.roles, test.name, allowed, test.allowed)
+		}
+	}
+
+	if !policy.IsAssigned(OpnameApprove) || policy.IsAssigned(ExpiryQuarantine) {
+		t.Errorf("IsAssigned(%q) and IsAssigned(%q) should be true and false", OpnameApprove, ExpiryQuarantine)
+	}
+
+	if policy.IsGranted("unknown.permission") || policy.GetRoles("unknown.permission") != nil {
+		t.Errorf("unknown permission should not be granted to any roles")
+	}
+
+	permissions := policy.GetPermissions([]string{"Pharmacist"})
+	if !slices.Equal(permissions, []string{InteractionRead, ProductRead, SaleAcknowledge}) {
+		t.Errorf("GetPermissions(Pharmacist) = %v", permissions)
+	}
+}
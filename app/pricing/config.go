@@ -0,0 +1,10 @@
+package pricing
+
+type Config struct {
+	Rounding     string `mapstructure:"rounding" json:"rounding" yaml:"rounding"`
+	RoundingMode string `mapstructure:"rounding_mode" json:"roundingMode" yaml:"rounding_mode"`
+}
+
+func (Config) GetNameType() string {
+	return "Pricing"
+}
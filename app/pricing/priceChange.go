@@ -0,0 +1,255 @@
+package pricing
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	"time"
+)
+
+var ErrPriceChangeNotScheduled = errors.New("price change is not scheduled")
+var ErrPriceChangeNotApplied = errors.New("price change is not applied")
+
+type PriceChangeServiceImpl interface {
+	Preview(priceChange *models2.PriceChange) ([]models2.Product, error)
+	ApplyChange(priceChange *models2.PriceChange) error
+	RevertChange(priceChange *models2.PriceChange, userID uint) error
+	ApplyDue(now time.Time) (int, error)
+}
+
+type PriceChangeService struct {
+	DB *gorm.DB
+}
+
+func NewPriceChangeService(DB *gorm.DB) PriceChangeServiceImpl {
+	return &PriceChangeService{
+		DB: DB,
+	}
+}
+
+// Preview method, products with the price change applied without saving,
+// ordered by price change items, missing products are left empty.
+func (p *PriceChangeService) Preview(priceChange *models2.PriceChange) ([]models2.Product, error) {
+	var err error
+	var product *models2.Product
+	nokocore.KeepVoid(err, product)
+
+	productRepository := repositories2.NewProductRepository(p.DB)
+	pricingService := NewPricingService(p.DB)
+
+	products := make([]models2.Product, len(priceChange.Items))
+	for i, priceChangeItem := range priceChange.Items {
+		if product, err = productRepository.SafeFirst("id = ?", priceChangeItem.ProductID); err != nil {
+			return nil, err
+		}
+
+		if product == nil {
+			continue
+		}
+
+		products[i] = *pricingService.Change(product, &priceChangeItem)
+	}
+
+	return products, nil
+}
+
+// ApplyChange method, the price change is claimed before products are updated,
+// so the scheduler and a request never apply it twice.
+func (p *PriceChangeService) ApplyChange(priceChange *models2.PriceChange) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	now := nokocore.GetTimeUtcNow()
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var product *models2.Product
+		var priceChangeItems []models2.PriceChangeItem
+		nokocore.KeepVoid(product, priceChangeItems)
+
+		productRepository := repositories2.NewProductRepository(tx)
+		priceChangeItemRepository := repositories2.NewPriceChangeItemRepository(tx)
+		pricingService := NewPricingService(tx)
+
+		stmt := tx.Model(&models2.PriceChange{}).Where("id = ? AND status = ?", priceChange.ID, models2.PriceChangeStatusScheduled)
+		stmt = stmt.Updates(map[string]any{
+			"status":     models2.PriceChangeStatusApplied,
+			"applied_at": now,
+		})
+
+		if err = stmt.Error; err != nil {
+			return err
+		}
+
+		if stmt.RowsAffected == 0 {
+			return ErrPriceChangeNotScheduled
+		}
+
+		if priceChangeItems, err = priceChangeItemRepository.SafeMany(0, -1, "price_change_id = ?", priceChange.ID); err != nil {
+			return err
+		}
+
+		for i, priceChangeItem := range priceChangeItems {
+			nokocore.KeepVoid(i)
+
+			// categories are cleared before save
+			if product, err = productRepository.SafePreFirst([]string{"Categories"}, "id = ?", priceChangeItem.ProductID); err != nil {
+				return err
+			}
+
+			if product == nil {
+				continue
+			}
+
+			previous := *product
+			priceChangeItem.PreviousPricingRule = product.PricingRule
+			priceChangeItem.PreviousPurchasePrice = decimal.NewNullDecimal(product.PurchasePrice)
+			priceChangeItem.PreviousSalePrice = decimal.NewNullDecimal(product.SalePrice)
+
+			pricingService.Change(product, &priceChangeItem)
+			product.UnitLevels = nil
+			if err = productRepository.SafeUpdate(product, "id = ?", product.ID); err != nil {
+				return err
+			}
+
+			if err = pricingService.Record(product, &previous, SourceSchedule, priceChange.UserID); err != nil {
+				return err
+			}
+
+			if err = priceChangeItemRepository.SafeUpdate(&priceChangeItem, "id = ?", priceChangeItem.ID); err != nil {
+				return err
+			}
+		}
+
+		priceChange.Status = models2.PriceChangeStatusApplied
+		priceChange.AppliedAt = sql.NullTime{Time: now, Valid: true}
+		return nil
+	})
+}
+
+// RevertChange method, restores the prices stored when the price change was applied.
+func (p *PriceChangeService) RevertChange(priceChange *models2.PriceChange, userID uint) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	now := nokocore.GetTimeUtcNow()
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var product *models2.Product
+		var priceChangeItems []models2.PriceChangeItem
+		nokocore.KeepVoid(product, priceChangeItems)
+
+		productRepository := repositories2.NewProductRepository(tx)
+		priceChangeItemRepository := repositories2.NewPriceChangeItemRepository(tx)
+		pricingService := NewPricingService(tx)
+
+		stmt := tx.Model(&models2.PriceChange{}).Where("id = ? AND status = ?", priceChange.ID, models2.PriceChangeStatusApplied)
+		stmt = stmt.Updates(map[string]any{
+			"status":      models2.PriceChangeStatusReverted,
+			"reverted_at": now,
+		})
+
+		if err = stmt.Error; err != nil {
+			return err
+		}
+
+		if stmt.RowsAffected == 0 {
+			return ErrPriceChangeNotApplied
+		}
+
+		if priceChangeItems, err = priceChangeItemRepository.SafeMany(0, -1, "price_change_id = ?", priceChange.ID); err != nil {
+			return err
+		}
+
+		for i, priceChangeItem := range priceChangeItems {
+			nokocore.KeepVoid(i)
+
+			// products missing when applied are skipped
+			if !priceChangeItem.PreviousSalePrice.Valid {
+				continue
+			}
+
+			if product, err = productRepository.SafePreFirst([]string{"Categories"}, "id = ?", priceChangeItem.ProductID); err != nil {
+				return err
+			}
+
+			if product == nil {
+				continue
+			}
+
+			previous := *product
+			product.PricingRule = priceChangeItem.PreviousPricingRule
+			product.PurchasePrice = priceChangeItem.PreviousPurchasePrice.Decimal
+			product.SalePrice = priceChangeItem.PreviousSalePrice.Decimal
+
+			product.UnitLevels = nil
+			if err = productRepository.SafeUpdate(product, "id = ?", product.ID); err != nil {
+				return err
+			}
+
+			if err = pricingService.Record(product, &previous, SourceRevert, userID); err != nil {
+				return err
+			}
+		}
+
+		priceChange.Status = models2.PriceChangeStatusReverted
+		priceChange.RevertedAt = sql.NullTime{Time: now, Valid: true}
+		return nil
+	})
+}
+
+// ApplyDue method, applies scheduled price changes effective at or before now,
+// oldest first, and returns how many were applied.
+func (p *PriceChangeService) ApplyDue(now time.Time) (int, error) {
+	var err error
+	var priceChanges []models2.PriceChange
+	nokocore.KeepVoid(err, priceChanges)
+
+	priceChangeRepository := repositories2.NewPriceChangeRepository(p.DB)
+	priceChanges, err = priceChangeRepository.SafeManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		stmt := tx.Where("status = ? AND effective_at <= ?", models2.PriceChangeStatusScheduled, now.UTC())
+		return stmt.Order("effective_at ASC, id ASC"), nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for i, priceChange := range priceChanges {
+		nokocore.KeepVoid(i)
+
+		if err = p.ApplyChange(&priceChange); err != nil {
+			// cancelled in the meantime
+			if errors.Is(err, ErrPriceChangeNotScheduled) {
+				continue
+			}
+
+			return applied, err
+		}
+
+		applied += 1
+	}
+
+	return applied, nil
+}
+
+// PriceChangeJob method, scheduler job applying due price changes.
+func PriceChangeJob(DB *gorm.DB, now time.Time) error {
+	var err error
+	var applied int
+	nokocore.KeepVoid(err, applied)
+
+	if applied, err = NewPriceChangeService(DB).ApplyDue(now); err != nil {
+		return err
+	}
+
+	if applied > 0 {
+		console.Info(fmt.Sprintf("%d price change(s) has been applied.", applied))
+	}
+
+	return nil
+}
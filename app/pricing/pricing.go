@@ -0,0 +1,159 @@
+package pricing
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+)
+
+const (
+	RoundUp      = "up"
+	RoundDown    = "down"
+	RoundNearest = "nearest"
+)
+
+const (
+	SourceCreate   = "create"
+	SourceUpdate   = "update"
+	SourceReceipt  = "receipt"
+	SourceSchedule = "schedule"
+	SourceRevert   = "revert"
+)
+
+func GetConfig() *Config {
+	config := globals.GetConfigGlobals[Config]()
+	if config.RoundingMode == "" {
+		config.RoundingMode = RoundUp
+	}
+	return config
+}
+
+// Round method, rounds price to a multiple of step, e.g. 12.340 to 12.500 by 500.
+func Round(price decimal.Decimal, step decimal.Decimal, mode string) decimal.Decimal {
+	if step.Sign() <= 0 {
+		return price
+	}
+
+	quotient := price.Div(step)
+	switch mode {
+	case RoundDown:
+		quotient = quotient.Floor()
+	case RoundNearest:
+		quotient = quotient.Round(0)
+	default:
+		quotient = quotient.Ceil()
+	}
+
+	return quotient.Mul(step)
+}
+
+// CostPlus method, sale price from purchase price with profit margin and tax.
+func CostPlus(product *models2.Product) decimal.Decimal {
+	margin := product.PurchasePrice.Mul(decimal.NewFromFloat(product.ProfitMargin))
+	tax := product.PurchasePrice.Mul(decimal.NewFromFloat(product.VAT))
+	return product.PurchasePrice.Add(margin).Add(tax)
+}
+
+type PricingServiceImpl interface {
+	SalePrice(product *models2.Product) decimal.Decimal
+	Apply(product *models2.Product) *models2.Product
+	Change(product *models2.Product, priceChangeItem *models2.PriceChangeItem) *models2.Product
+	Record(product *models2.Product, previous *models2.Product, source string, userID uint) error
+}
+
+type PricingService struct {
+	DB     *gorm.DB
+	Config *Config
+}
+
+func NewPricingService(DB *gorm.DB) PricingServiceImpl {
+	return &PricingService{
+		DB:     DB,
+		Config: GetConfig(),
+	}
+}
+
+// SalePrice method, fixed prices are kept as it is, cost plus prices are
+// rounded by the product rounding or the default rounding.
+func (p *PricingService) SalePrice(product *models2.Product) decimal.Decimal {
+	if product.PricingRule == models2.PricingRuleFixed {
+		return product.SalePrice
+	}
+
+	step := product.PriceRounding
+	if step.Sign() <= 0 {
+		if rounding, err := decimal.NewFromString(p.Config.Rounding); err == nil {
+			step = rounding
+		}
+	}
+
+	return Round(CostPlus(product), step, p.Config.RoundingMode)
+}
+
+func (p *PricingService) Apply(product *models2.Product) *models2.Product {
+	if product.PricingRule == "" {
+		product.PricingRule = models2.PricingRuleCostPlus
+	}
+
+	product.SalePrice = p.SalePrice(product)
+	return product
+}
+
+// Change method, applies a scheduled price change item to the product, a sale
+// price without pricing rule is a fixed price.
+func (p *PricingService) Change(product *models2.Product, priceChangeItem *models2.PriceChangeItem) *models2.Product {
+	if priceChangeItem.PurchasePrice.Valid {
+		product.PurchasePrice = priceChangeItem.PurchasePrice.Decimal
+	}
+
+	if priceChangeItem.SalePrice.Valid {
+		product.PricingRule = models2.PricingRuleFixed
+		product.SalePrice = priceChangeItem.SalePrice.Decimal
+	}
+
+	if priceChangeItem.PricingRule != "" {
+		product.PricingRule = priceChangeItem.PricingRule
+	}
+
+	return p.Apply(product)
+}
+
+// Record method, stores a price history row if purchase or sale price changed,
+// previous product is nil for new products.
+func (p *PricingService) Record(product *models2.Product, previous *models2.Product, source string, userID uint) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	if product == nil || product.ID == 0 {
+		return errors.New("product not found")
+	}
+
+	priceHistory := &models2.PriceHistory{
+		ProductID:     product.ID,
+		UserID:        userID,
+		Source:        source,
+		PricingRule:   product.PricingRule,
+		PurchasePrice: product.PurchasePrice,
+		SalePrice:     product.SalePrice,
+	}
+
+	if previous != nil {
+		if previous.PurchasePrice.Equal(product.PurchasePrice) && previous.SalePrice.Equal(product.SalePrice) {
+			return nil
+		}
+
+		priceHistory.PreviousPurchasePrice = previous.PurchasePrice
+		priceHistory.PreviousSalePrice = previous.SalePrice
+	}
+
+	priceHistoryRepository := repositories2.NewPriceHistoryRepository(p.DB)
+	if err = priceHistoryRepository.Create(priceHistory); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package pricing
+
+import (
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	for i, test := range []struct {
+		price string
+		step  string
+		mode  string
+		want  string
+	}{
+		{"12340", "500", RoundUp, "12500"},
+		{"12340", "500", RoundDown, "12000"},
+		{"12340", "500", RoundNearest, "12500"},
+		{"12240", "500", RoundNearest, "12000"},
+		{"12250", "500", RoundNearest, "12500"},
+		{"12500", "500", RoundUp, "12500"},
+		{"12500", "500", RoundDown, "12500"},
+		{"12340", "500", "", "12500"},
+		{"12340", "500", "unknown", "12500"},
+		{"12340.25", "0.5", RoundUp, "12340.5"},
+		{"12340", "0", RoundUp, "12340"},
+		{"12340", "-500", RoundDown, "12340"},
+		{"0", "500", RoundUp, "0"},
+		{"1", "500", RoundUp, "500"},
+		{"1", "500", RoundDown, "0"},
+	} {
+		nokocore.KeepVoid(i)
+
+		price := decimal.RequireFromString(test.price)
+		step := decimal.RequireFromString(test.step)
+		want := decimal.RequireFromString(test.want)
+
+		if got := Round(price, step, test.mode); !got.Equal(want) {
+			t.Errorf("Round(%s, %s, %q) = %s, want %s", test.price, test.step, test.mode, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package registers
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	schemas2 "pharma-cash-go/app/schemas"
+	"time"
+)
+
+type RegisterReportRow struct {
+	ProductID       uint   `mapstructure:"-" json:"-"`
+	ProductUUID     string `mapstructure:"product_id" json:"productId"`
+	ProductName     string `mapstructure:"product_name" json:"productName"`
+	Substance       string `mapstructure:"substance" json:"substance"`
+	DrugClass       string `mapstructure:"drug_class" json:"drugClass"`
+	DosageForm      string `mapstructure:"dosage_form" json:"dosageForm"`
+	AuthorizationNo string `mapstructure:"authorization_no" json:"authorizationNo"`
+	UnitType        string `mapstructure:"unit_type" json:"unitType"`
+	Opening         int    `mapstructure:"opening" json:"opening"`
+	Received        int    `mapstructure:"received" json:"received"`
+	Dispensed       int    `mapstructure:"dispensed" json:"dispensed"`
+	Adjusted        int    `mapstructure:"adjusted" json:"adjusted"`
+	Closing         int    `mapstructure:"closing" json:"closing"`
+}
+
+type RegisterReport struct {
+	From time.Time           `mapstructure:"from" json:"from"`
+	To   time.Time           `mapstructure:"to" json:"to"`
+	Rows []RegisterReportRow `mapstructure:"rows" json:"rows"`
+}
+
+type RegisterServiceImpl interface {
+	Record(product *models2.Product, kind string, quantity int, userID uint, reference string, note string) error
+	Receipt(product *models2.Product, quantity int, userID uint, reference string) error
+	Dispense(product *models2.Product, quantity int, userID uint, reference string) error
+	Adjust(product *models2.Product, quantity int, userID uint, reference string, note string) error
+	Balance(product *models2.Product) (int, error)
+	Sync(product *models2.Product, userID uint, note string) error
+	Report(from time.Time, to time.Time) (*RegisterReport, error)
+}
+
+type RegisterService struct {
+	DB *gorm.DB
+}
+
+func NewRegisterService(DB *gorm.DB) RegisterServiceImpl {
+	return &RegisterService{
+		DB: DB,
+	}
+}
+
+// Record method, writes a register entry of controlled products only,
+// quantity is signed, received units are positive and dispensed units negative.
+func (r *RegisterService) Record(product *models2.Product, kind string, quantity int, userID uint, reference string, note string) error {
+	if product == nil || quantity == 0 || !models2.IsControlledDrugClass(product.DrugClass) {
+		return nil
+	}
+
+	controlledRegisterRepository := repositories2.NewControlledRegisterRepository(r.DB)
+	return controlledRegisterRepository.Create(&models2.ControlledRegister{
+		ProductID:  product.ID,
+		UserID:     userID,
+		Kind:       kind,
+		DrugClass:  product.DrugClass,
+		Quantity:   quantity,
+		Reference:  reference,
+		Note:       note,
+		RecordedAt: nokocore.GetTimeUtcNow(),
+	})
+}
+
+func (r *RegisterService) Receipt(product *models2.Product, quantity int, userID uint, reference string) error {
+	return r.Record(product, models2.RegisterKindReceipt, quantity, userID, reference, "")
+}
+
+func (r *RegisterService) Dispense(product *models2.Product, quantity int, userID uint, reference string) error {
+	return r.Record(product, models2.RegisterKindDispense, -quantity, userID, reference, "")
+}
+
+func (r *RegisterService) Adjust(product *models2.Product, quantity int, userID uint, reference string, note string) error {
+	return r.Record(product, models2.RegisterKindAdjustment, quantity, userID, reference, note)
+}
+
+// Balance method, sum of all register entries of the product.
+func (r *RegisterService) Balance(product *models2.Product) (int, error) {
+	var err error
+	var balance int
+	nokocore.KeepVoid(err, balance)
+
+	stmt := r.DB.Model(&models2.ControlledRegister{}).Select("COALESCE(SUM(quantity), 0)")
+	if err = stmt.Where("product_id = ?", product.ID).Scan(&balance).Error; err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// Sync method, adjusts the register to the product stock, used when stock
+// is set directly or the product becomes controlled.
+func (r *RegisterService) Sync(product *models2.Product, userID uint, note string) error {
+	var err error
+	var balance int
+	nokocore.KeepVoid(err, balance)
+
+	if product == nil || !models2.IsControlledDrugClass(product.DrugClass) {
+		return nil
+	}
+
+	if balance, err = r.Balance(product); err != nil {
+		return err
+	}
+
+	return r.Adjust(product, product.Stock-balance, userID, "", note)
+}
+
+type registerSum struct {
+	ProductID uint
+	Kind      string
+	Quantity  int
+}
+
+// Report method, quantities per controlled product between from and to,
+// opening quantities are the sum of entries recorded before from.
+func (r *RegisterService) Report(from time.Time, to time.Time) (*RegisterReport, error) {
+	var err error
+	var openings []registerSum
+	var movements []registerSum
+	var products []models2.Product
+	nokocore.KeepVoid(err, openings, movements, products)
+
+	from = from.UTC()
+	to = to.UTC()
+
+	stmt := r.DB.Model(&models2.ControlledRegister{}).Select("product_id, SUM(quantity) AS quantity")
+	if err = stmt.Where("recorded_at < ?", from).Group("product_id").Scan(&openings).Error; err != nil {
+		return nil, err
+	}
+
+	stmt = r.DB.Model(&models2.ControlledRegister{}).Select("product_id, kind, SUM(quantity) AS quantity")
+	if err = stmt.Where("recorded_at >= ? AND recorded_at < ?", from, to).Group("product_id, kind").Scan(&movements).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make(map[uint]*RegisterReportRow)
+	getRow := func(productID uint) *RegisterReportRow {
+		if row, ok := rows[productID]; ok {
+			return row
+		}
+		row := &RegisterReportRow{ProductID: productID}
+		rows[productID] = row
+		return row
+	}
+
+	for i, opening := range openings {
+		nokocore.KeepVoid(i)
+		getRow(opening.ProductID).Opening = opening.Quantity
+	}
+
+	for i, movement := range movements {
+		nokocore.KeepVoid(i)
+		row := getRow(movement.ProductID)
+		switch movement.Kind {
+		case models2.RegisterKindReceipt:
+			row.Received += movement.Quantity
+		case models2.RegisterKindDispense:
+			row.Dispensed -= movement.Quantity
+		default:
+			row.Adjusted += movement.Quantity
+		}
+	}
+
+	// controlled products without entries are reported too, deleted products
+	// only while they have entries
+	productRepository := repositories2.NewProductRepository(r.DB)
+	products, err = productRepository.ManyHook(func(tx *gorm.DB) (*gorm.DB, error) {
+		controlled := []string{models2.DrugClassNarcotic, models2.DrugClassPsychotropic}
+		stmt := tx.Preload("Unit").Preload("Ingredients.ActiveIngredient")
+		stmt = stmt.Where("(drug_class IN ? AND deleted_at IS NULL) OR id IN (?)", controlled, r.DB.Model(&models2.ControlledRegister{}).Select("product_id"))
+		return stmt.Order("drug_class ASC, product_name ASC, id ASC"), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RegisterReport{
+		From: from,
+		To:   to,
+		Rows: make([]RegisterReportRow, 0, len(products)),
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		row := getRow(product.ID)
+		row.ProductUUID = product.UUID.String()
+		row.ProductName = product.ProductName
+		row.Substance = schemas2.ToSubstance(&product)
+		row.DrugClass = product.DrugClass
+		row.DosageForm = product.DosageForm
+		row.AuthorizationNo = product.AuthorizationNo
+		row.UnitType = product.Unit.UnitType
+		row.Closing = row.Opening + row.Received - row.Dispensed + row.Adjusted
+		report.Rows = append(report.Rows, *row)
+	}
+
+	return report, nil
+}
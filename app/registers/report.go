@@ -0,0 +1,61 @@
+package registers
+
+import (
+	"fmt"
+	"nokowebapi/nokocore"
+	"pharma-cash-go/app/reports"
+)
+
+var reportHeaders = []string{
+	"No",
+	"Product",
+	"Substance",
+	"Drug Class",
+	"Dosage Form",
+	"Authorization No",
+	"Unit",
+	"Opening",
+	"Received",
+	"Dispensed",
+	"Adjusted",
+	"Closing",
+}
+
+var reportWidths = []float64{6, 28, 36, 14, 16, 20, 10, 12, 12, 12, 12, 12}
+
+// ToTable method, the report as a table of the reports package for regulatory submission.
+func (r *RegisterReport) ToTable() *reports.Table {
+	// last day of the period, to is exclusive
+	period := fmt.Sprintf("%s - %s", r.From.Format("02 January 2006"), r.To.AddDate(0, 0, -1).Format("02 January 2006"))
+
+	table := &reports.Table{
+		Title: "Controlled Substance Report",
+		Sheet: "Register",
+		Lines: [][2]string{
+			{"Period", period},
+			{"Generated At", nokocore.ToTimeUtcStringISO8601(nokocore.GetTimeUtcNow())},
+		},
+		Headers: reportHeaders,
+		Widths:  reportWidths,
+		Rows:    make([][]any, 0, len(r.Rows)),
+	}
+
+	for i, row := range r.Rows {
+		table.Rows = append(table.Rows, []any{
+			i + 1,
+			row.ProductName,
+			row.Substance,
+			row.DrugClass,
+			row.DosageForm,
+			row.AuthorizationNo,
+			row.UnitType,
+			row.Opening,
+			row.Received,
+			row.Dispensed,
+			row.Adjusted,
+			row.Closing,
+		})
+	}
+
+	return table
+}
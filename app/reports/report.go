@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"reporting/reporting"
+	"reporting/reporting/pdf"
+)
+
+const (
+	FormatJson = "json"
+	FormatXlsx = "xlsx"
+	FormatPdf  = "pdf"
+)
+
+const (
+	ContentTypeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypePdf  = "application/pdf"
+)
+
+// Table, a titled report with label and value lines above a single table,
+// written as xlsx or pdf.
+type Table struct {
+	Title   string
+	Sheet   string
+	Lines   [][2]string
+	Headers []string
+	Widths  []float64 // xlsx column widths, pdf columns are scaled by them
+	Rows    [][]any
+	Totals  []any // written below the rows when not empty
+}
+
+type TableWriter func(table *Table, writer io.Writer) error
+
+// GetTableWriter method, writer of the format with its content type and file extension.
+func GetTableWriter(format string) (TableWriter, string, bool) {
+	switch format {
+	case FormatXlsx:
+		return WriteXlsx, ContentTypeXlsx, true
+
+	case FormatPdf:
+		return WritePdf, ContentTypePdf, true
+
+	default:
+		return nil, "", false
+	}
+}
+
+// WritePdf method, writes the table with the pdf config of the reporting package.
+func WritePdf(table *Table, writer io.Writer) error {
+	config := globals.GetConfigGlobals[reporting.Config]()
+	return NewPdfWriter(&config.Pdf)(table, writer)
+}
+
+// NewPdfWriter method, pages are printed over the first landscape template of the config.
+func NewPdfWriter(config *pdf.Config) TableWriter {
+	return func(table *Table, writer io.Writer) error {
+		var err error
+		var doc pdf.DocPdfImpl
+		nokocore.KeepVoid(err, doc)
+
+		templateId := -1
+		for i, template := range config.Templates {
+			if pdf.IsTemplateLayoutLandscape(template) {
+				templateId = i
+				break
+			}
+		}
+
+		if doc, err = pdf.OpenDocPdf(templateId, config); err != nil {
+			return err
+		}
+
+		if err = doc.TablePrint(ToTablePdf(table)); err != nil {
+			return err
+		}
+
+		return doc.Write(writer)
+	}
+}
+
+// ToTablePdf method, columns holding numbers are aligned to the right.
+func ToTablePdf(table *Table) *pdf.TablePdf {
+	size := len(table.Headers)
+	tablePdf := &pdf.TablePdf{
+		Title:  table.Title,
+		Lines:  table.Lines,
+		Cols:   make([]pdf.TableColPdf, size),
+		Rows:   make([][]string, len(table.Rows)),
+		Totals: ToCellStrings(table.Totals),
+	}
+
+	for i, header := range table.Headers {
+		width := 10.0
+		if i < len(table.Widths) {
+			width = table.Widths[i]
+		}
+
+		align := "left"
+		for j, row := range table.Rows {
+			nokocore.KeepVoid(j)
+			if i < len(row) && IsNumber(row[i]) {
+				align = "right"
+				break
+			}
+		}
+
+		tablePdf.Cols[i] = pdf.TableColPdf{
+			Header: header,
+			Width:  width,
+			Align:  align,
+		}
+	}
+
+	for i, row := range table.Rows {
+		tablePdf.Rows[i] = ToCellStrings(row)
+	}
+
+	return tablePdf
+}
+
+func IsNumber(value any) bool {
+	switch value.(type) {
+	case int, int64, uint, float64, decimal.Decimal:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func ToCellStrings(values []any) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = ToCellString(value)
+	}
+
+	return cells
+}
+
+// ToCellString method, cell values as written on pdf pages.
+func ToCellString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+
+	case string:
+		return value
+
+	case decimal.Decimal:
+		return value.StringFixed(2)
+
+	case bool:
+		if value {
+			return "Yes"
+		}
+		return "No"
+
+	default:
+		return fmt.Sprint(value)
+	}
+}
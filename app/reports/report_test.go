@@ -0,0 +1,142 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+	"nokowebapi/nokocore"
+	"regexp"
+	"reporting/reporting/pdf"
+	"strconv"
+	"testing"
+)
+
+func newTestTable(size int) *Table {
+	table := &Table{
+		Title: "Stock Report",
+		Sheet: "Stock",
+		Lines: [][2]string{
+			{"Location", "Main Store"},
+			{"Generated At", "2026-10-19T00:00:00Z"},
+		},
+		Headers: []string{"No", "Product", "Quantity", "Value"},
+		Widths:  []float64{6, 28, 12, 16},
+	}
+
+	for i := 0; i < size; i++ {
+		table.Rows = append(table.Rows, []any{i + 1, fmt.Sprintf("Paracetamol %d", i+1), 2, decimal.RequireFromString("1500.50")})
+	}
+
+	table.Totals = []any{"", "Total", size * 2, decimal.RequireFromString("1500.50").Mul(decimal.NewFromInt(int64(size)))}
+	return table
+}
+
+func TestWriteXlsx(t *testing.T) {
+	var err error
+	var file *excelize.File
+	nokocore.KeepVoid(err, file)
+
+	table := newTestTable(3)
+	buffer := new(bytes.Buffer)
+	if err = WriteXlsx(table, buffer); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if file, err = excelize.OpenReader(buffer); err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+
+	// title, lines, a blank row, headers, rows and totals
+	for i, test := range []struct {
+		cell string
+		want string
+	}{
+		{"A1", "Stock Report"},
+		{"A2", "Location"},
+		{"C2", "Main Store"},
+		{"A3", "Generated At"},
+		{"A5", "No"},
+		{"D5", "Value"},
+		{"A6", "1"},
+		{"B6", "Paracetamol 1"},
+		{"B8", "Paracetamol 3"},
+		{"C8", "2"},
+		{"D8", "1500.5"},
+		{"A9", ""},
+		{"B9", "Total"},
+		{"C9", "6"},
+		{"D9", "4501.5"},
+		{"B10", ""},
+	} {
+		nokocore.KeepVoid(i)
+
+		got := nokocore.Unwrap(file.GetCellValue("Stock", test.cell))
+		if got != test.want {
+			t.Errorf("cell %s = %q, want %q", test.cell, got, test.want)
+		}
+	}
+
+	rowStyle := nokocore.Unwrap(file.GetCellStyle("Stock", "B8"))
+	totalStyle := nokocore.Unwrap(file.GetCellStyle("Stock", "B9"))
+	if rowStyle == totalStyle {
+		t.Errorf("totals row has the style of the rows")
+	}
+}
+
+func TestWritePdf(t *testing.T) {
+	config := &pdf.Config{
+		Assets: "../../assets",
+		Templates: pdf.Templates{
+			{PageFile: "templates/template1.pdf", PageLayout: "portrait", PageSize: "A4"},
+			{PageFile: "templates/template2.pdf", PageLayout: "landscape", PageSize: "A4"},
+		},
+		FontFamily: "Liberation Serif",
+		FontType:   "Regular",
+		FontSize:   12,
+	}
+
+	pageCount := regexp.MustCompile(`/Type /Pages[^>]*/Count (\d+)`)
+	pageObject := regexp.MustCompile(`/Type /Page\s`)
+
+	for i, test := range []struct {
+		rows  int
+		pages int
+	}{
+		{0, 1},
+		{1, 1},
+		{21, 1},
+		{22, 2},
+		{70, 3},
+		{73, 3},
+		{74, 4},
+	} {
+		nokocore.KeepVoid(i)
+
+		buffer := new(bytes.Buffer)
+		if err := NewPdfWriter(config)(newTestTable(test.rows), buffer); err != nil {
+			t.Error(err)
+			return
+		}
+
+		data := buffer.Bytes()
+		if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.Contains(data[len(data)-16:], []byte("%%EOF")) {
+			t.Errorf("%d rows, not a pdf document", test.rows)
+			continue
+		}
+
+		matches := pageCount.FindSubmatch(data)
+		if matches == nil {
+			t.Errorf("%d rows, no page tree", test.rows)
+			continue
+		}
+
+		count := nokocore.Unwrap(strconv.Atoi(string(matches[1])))
+		if count != test.pages || len(pageObject.FindAll(data, -1)) != test.pages {
+			t.Errorf("%d rows, %d pages, want %d", test.rows, count, test.pages)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package reports
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"nokowebapi/nokocore"
+)
+
+// WriteXlsx method, writes the table as a single sheet workbook.
+func WriteXlsx(table *Table, writer io.Writer) error {
+	var err error
+	var titleStyle int
+	var headerStyle int
+	var cellStyle int
+	var totalStyle int
+	nokocore.KeepVoid(err, titleStyle, headerStyle, cellStyle, totalStyle)
+
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheet := table.Sheet
+	if sheet == "" {
+		sheet = "Report"
+	}
+
+	if err = file.SetSheetName(file.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	border := []excelize.Border{
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+
+	if titleStyle, err = file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Family: "Arial", Size: 14, Bold: true},
+	}); err != nil {
+		return err
+	}
+
+	if headerStyle, err = file.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border:    border,
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D9D9D9"}, Pattern: 1},
+		Font:      &excelize.Font{Family: "Arial", Size: 10, Bold: true},
+	}); err != nil {
+		return err
+	}
+
+	if cellStyle, err = file.NewStyle(&excelize.Style{
+		Border: border,
+		Font:   &excelize.Font{Family: "Arial", Size: 10},
+	}); err != nil {
+		return err
+	}
+
+	if totalStyle, err = file.NewStyle(&excelize.Style{
+		Border: border,
+		Font:   &excelize.Font{Family: "Arial", Size: 10, Bold: true},
+	}); err != nil {
+		return err
+	}
+
+	if err = file.SetCellValue(sheet, "A1", table.Title); err != nil {
+		return err
+	}
+
+	if err = file.SetCellStyle(sheet, "A1", "A1", titleStyle); err != nil {
+		return err
+	}
+
+	for i, line := range table.Lines {
+		j := i + 2
+		if err = file.SetCellValue(sheet, fmt.Sprintf("A%d", j), line[0]); err != nil {
+			return err
+		}
+
+		if err = file.SetCellValue(sheet, fmt.Sprintf("C%d", j), line[1]); err != nil {
+			return err
+		}
+	}
+
+	for i, width := range table.Widths {
+		column := nokocore.Unwrap(excelize.ColumnNumberToName(i + 1))
+		if err = file.SetColWidth(sheet, column, column, width); err != nil {
+			return err
+		}
+	}
+
+	headerRow := len(table.Lines) + 3
+	if err = file.SetSheetRow(sheet, fmt.Sprintf("A%d", headerRow), &table.Headers); err != nil {
+		return err
+	}
+
+	lastColumn := nokocore.Unwrap(excelize.ColumnNumberToName(len(table.Headers)))
+	if err = file.SetCellStyle(sheet, fmt.Sprintf("A%d", headerRow), fmt.Sprintf("%s%d", lastColumn, headerRow), headerStyle); err != nil {
+		return err
+	}
+
+	rows := table.Rows
+	if len(table.Totals) > 0 {
+		rows = append(rows[:len(rows):len(rows)], table.Totals)
+	}
+
+	for i, row := range rows {
+		values := make([]any, len(row))
+		for k, value := range row {
+			// decimals are written as numbers
+			if value, ok := value.(decimal.Decimal); ok {
+				values[k] = value.InexactFloat64()
+				continue
+			}
+			values[k] = value
+		}
+
+		j := headerRow + i + 1
+		if err = file.SetSheetRow(sheet, fmt.Sprintf("A%d", j), &values); err != nil {
+			return err
+		}
+
+		style := cellStyle
+		if len(table.Totals) > 0 && i == len(rows)-1 {
+			style = totalStyle
+		}
+
+		if err = file.SetCellStyle(sheet, fmt.Sprintf("A%d", j), fmt.Sprintf("%s%d", lastColumn, j), style); err != nil {
+			return err
+		}
+	}
+
+	return file.Write(writer)
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ActiveIngredientRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ActiveIngredient]
+}
+
+type ActiveIngredientRepository struct {
+	repositories.BaseRepositoryImpl[models2.ActiveIngredient]
+}
+
+func NewActiveIngredientRepository(DB *gorm.DB) ActiveIngredientRepositoryImpl {
+	return &ActiveIngredientRepository{
+		repositories.NewBaseRepository[models2.ActiveIngredient](DB),
+	}
+}
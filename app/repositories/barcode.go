@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type BarcodeRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.Barcode]
+	SyncProductBarcodes() error
+}
+
+type BarcodeRepository struct {
+	repositories.BaseRepositoryImpl[models2.Barcode]
+	DB *gorm.DB
+}
+
+func NewBarcodeRepository(DB *gorm.DB) BarcodeRepositoryImpl {
+	return &BarcodeRepository{
+		BaseRepositoryImpl: repositories.NewBaseRepository[models2.Barcode](DB),
+		DB:                 DB,
+	}
+}
+
+// SyncProductBarcodes method, mirrors primary barcodes of products
+// created before product barcodes table into it.
+func (b *BarcodeRepository) SyncProductBarcodes() error {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	tx := b.DB.Unscoped().Where("barcode NOT IN (SELECT code FROM barcodes)").Find(&products)
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if err = product.SyncBarcode(b.DB); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"nokowebapi/apis/repositories"
+	"nokowebapi/nokocore"
 	models2 "pharma-cash-go/app/models"
 
 	"gorm.io/gorm"
@@ -9,14 +10,37 @@ import (
 
 type CartVerificationOpnameRepositoryImpl interface {
 	repositories.BaseRepositoryImpl[models2.CartVerificationOpname]
+	SyncStockOpnames() error
 }
 
 type CartVerificationOpnameRepository struct {
 	repositories.BaseRepositoryImpl[models2.CartVerificationOpname]
+	DB *gorm.DB
 }
 
 func NewCartVerificationOpnameRepository(DB *gorm.DB) CartVerificationOpnameRepositoryImpl {
 	return &CartVerificationOpnameRepository{
-		repositories.NewBaseRepository[models2.CartVerificationOpname](DB),
+		BaseRepositoryImpl: repositories.NewBaseRepository[models2.CartVerificationOpname](DB),
+		DB:                 DB,
 	}
 }
+
+// SyncStockOpnames method, entries created before stock opname sessions
+// belong to the oldest open stock opname.
+func (c *CartVerificationOpnameRepository) SyncStockOpnames() error {
+	var err error
+	var stockOpname models2.StockOpname
+	nokocore.KeepVoid(err, stockOpname)
+
+	tx := c.DB.Where("is_verified = ? AND is_cancelled = ?", false, false).Order("id ASC").Limit(1).Find(&stockOpname)
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	if tx.RowsAffected == 0 {
+		return nil
+	}
+
+	stmt := c.DB.Unscoped().Model(&models2.CartVerificationOpname{})
+	return stmt.Where("stock_opname_id IS NULL OR stock_opname_id = 0").UpdateColumn("stock_opname_id", stockOpname.ID).Error
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CategoryRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.Category]
+}
+
+type CategoryRepository struct {
+	repositories.BaseRepositoryImpl[models2.Category]
+}
+
+func NewCategoryRepository(DB *gorm.DB) CategoryRepositoryImpl {
+	return &CategoryRepository{
+		repositories.NewBaseRepository[models2.Category](DB),
+	}
+}
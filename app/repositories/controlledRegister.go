@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ControlledRegisterRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ControlledRegister]
+}
+
+type ControlledRegisterRepository struct {
+	repositories.BaseRepositoryImpl[models2.ControlledRegister]
+}
+
+func NewControlledRegisterRepository(DB *gorm.DB) ControlledRegisterRepositoryImpl {
+	return &ControlledRegisterRepository{
+		repositories.NewBaseRepository[models2.ControlledRegister](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CostEntryRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.CostEntry]
+}
+
+type CostEntryRepository struct {
+	repositories.BaseRepositoryImpl[models2.CostEntry]
+}
+
+func NewCostEntryRepository(DB *gorm.DB) CostEntryRepositoryImpl {
+	return &CostEntryRepository{
+		repositories.NewBaseRepository[models2.CostEntry](DB),
+	}
+}
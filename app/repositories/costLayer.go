@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CostLayerRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.CostLayer]
+}
+
+type CostLayerRepository struct {
+	repositories.BaseRepositoryImpl[models2.CostLayer]
+}
+
+func NewCostLayerRepository(DB *gorm.DB) CostLayerRepositoryImpl {
+	return &CostLayerRepository{
+		repositories.NewBaseRepository[models2.CostLayer](DB),
+	}
+}
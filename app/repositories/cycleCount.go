@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CycleCountRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.CycleCount]
+}
+
+type CycleCountRepository struct {
+	repositories.BaseRepositoryImpl[models2.CycleCount]
+}
+
+func NewCycleCountRepository(DB *gorm.DB) CycleCountRepositoryImpl {
+	return &CycleCountRepository{
+		repositories.NewBaseRepository[models2.CycleCount](DB),
+	}
+}
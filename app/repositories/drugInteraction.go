@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type DrugInteractionRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.DrugInteraction]
+}
+
+type DrugInteractionRepository struct {
+	repositories.BaseRepositoryImpl[models2.DrugInteraction]
+}
+
+func NewDrugInteractionRepository(DB *gorm.DB) DrugInteractionRepositoryImpl {
+	return &DrugInteractionRepository{
+		repositories.NewBaseRepository[models2.DrugInteraction](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ExpiryAlertRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ExpiryAlert]
+}
+
+type ExpiryAlertRepository struct {
+	repositories.BaseRepositoryImpl[models2.ExpiryAlert]
+}
+
+func NewExpiryAlertRepository(DB *gorm.DB) ExpiryAlertRepositoryImpl {
+	return &ExpiryAlertRepository{
+		repositories.NewBaseRepository[models2.ExpiryAlert](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type GoodsReceiptRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.GoodsReceipt]
+}
+
+type GoodsReceiptRepository struct {
+	repositories.BaseRepositoryImpl[models2.GoodsReceipt]
+}
+
+func NewGoodsReceiptRepository(DB *gorm.DB) GoodsReceiptRepositoryImpl {
+	return &GoodsReceiptRepository{
+		repositories.NewBaseRepository[models2.GoodsReceipt](DB),
+	}
+}
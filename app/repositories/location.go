@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type LocationRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.Location]
+}
+
+type LocationRepository struct {
+	repositories.BaseRepositoryImpl[models2.Location]
+}
+
+func NewLocationRepository(DB *gorm.DB) LocationRepositoryImpl {
+	return &LocationRepository{
+		repositories.NewBaseRepository[models2.Location](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type PriceChangeRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.PriceChange]
+}
+
+type PriceChangeRepository struct {
+	repositories.BaseRepositoryImpl[models2.PriceChange]
+}
+
+func NewPriceChangeRepository(DB *gorm.DB) PriceChangeRepositoryImpl {
+	return &PriceChangeRepository{
+		repositories.NewBaseRepository[models2.PriceChange](DB),
+	}
+}
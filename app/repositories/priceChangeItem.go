@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type PriceChangeItemRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.PriceChangeItem]
+}
+
+type PriceChangeItemRepository struct {
+	repositories.BaseRepositoryImpl[models2.PriceChangeItem]
+}
+
+func NewPriceChangeItemRepository(DB *gorm.DB) PriceChangeItemRepositoryImpl {
+	return &PriceChangeItemRepository{
+		repositories.NewBaseRepository[models2.PriceChangeItem](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type PriceHistoryRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.PriceHistory]
+}
+
+type PriceHistoryRepository struct {
+	repositories.BaseRepositoryImpl[models2.PriceHistory]
+}
+
+func NewPriceHistoryRepository(DB *gorm.DB) PriceHistoryRepositoryImpl {
+	return &PriceHistoryRepository{
+		repositories.NewBaseRepository[models2.PriceHistory](DB),
+	}
+}
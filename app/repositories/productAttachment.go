@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ProductAttachmentRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ProductAttachment]
+}
+
+type ProductAttachmentRepository struct {
+	repositories.BaseRepositoryImpl[models2.ProductAttachment]
+}
+
+func NewProductAttachmentRepository(DB *gorm.DB) ProductAttachmentRepositoryImpl {
+	return &ProductAttachmentRepository{
+		repositories.NewBaseRepository[models2.ProductAttachment](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ProductIngredientRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ProductIngredient]
+}
+
+type ProductIngredientRepository struct {
+	repositories.BaseRepositoryImpl[models2.ProductIngredient]
+}
+
+func NewProductIngredientRepository(DB *gorm.DB) ProductIngredientRepositoryImpl {
+	return &ProductIngredientRepository{
+		repositories.NewBaseRepository[models2.ProductIngredient](DB),
+	}
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"nokowebapi/console"
+	"nokowebapi/nokocore"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// productSearchAvailable, sqlite must be built with the 'sqlite_fts5' tag
+var productSearchAvailable atomic.Bool
+
+type ProductSearchRow struct {
+	ProductID uint    `db:"product_id" mapstructure:"product_id" json:"productId"`
+	Score     float64 `db:"score" mapstructure:"score" json:"score"`
+}
+
+type ProductSearchRepositoryImpl interface {
+	Migrate() error
+	Available() bool
+	Search(keywords string, offset int, limit int) ([]ProductSearchRow, error)
+}
+
+type ProductSearchRepository struct {
+	DB *gorm.DB
+}
+
+func NewProductSearchRepository(DB *gorm.DB) ProductSearchRepositoryImpl {
+	return &ProductSearchRepository{
+		DB: DB,
+	}
+}
+
+func (p *ProductSearchRepository) Migrate() error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	// external content table, products stay the source of truth
+	queries := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS products_fts USING fts5(
+			barcode, brand, product_name, supplier,
+			content='products', content_rowid='id', tokenize='trigram'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_ai AFTER INSERT ON products BEGIN
+			INSERT INTO products_fts(rowid, barcode, brand, product_name, supplier)
+			VALUES (new.id, new.barcode, new.brand, new.product_name, new.supplier);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_ad AFTER DELETE ON products BEGIN
+			INSERT INTO products_fts(products_fts, rowid, barcode, brand, product_name, supplier)
+			VALUES ('delete', old.id, old.barcode, old.brand, old.product_name, old.supplier);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_au AFTER UPDATE ON products BEGIN
+			INSERT INTO products_fts(products_fts, rowid, barcode, brand, product_name, supplier)
+			VALUES ('delete', old.id, old.barcode, old.brand, old.product_name, old.supplier);
+			INSERT INTO products_fts(rowid, barcode, brand, product_name, supplier)
+			VALUES (new.id, new.barcode, new.brand, new.product_name, new.supplier);
+		END`,
+
+		// auto migrations may recreate the products table, keep the index in sync
+		`INSERT INTO products_fts(products_fts) VALUES ('rebuild')`,
+	}
+
+	productSearchAvailable.Store(false)
+
+	for i, query := range queries {
+		nokocore.KeepVoid(i)
+
+		if err = p.DB.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to migrate products full-text search, %w", err)
+		}
+	}
+
+	productSearchAvailable.Store(true)
+	return nil
+}
+
+func (p *ProductSearchRepository) Available() bool {
+	return productSearchAvailable.Load()
+}
+
+func (p *ProductSearchRepository) Search(keywords string, offset int, limit int) ([]ProductSearchRow, error) {
+	var err error
+	var rows []ProductSearchRow
+	nokocore.KeepVoid(err, rows)
+
+	if !p.Available() {
+		return nil, errors.New("products full-text search is not available")
+	}
+
+	match := ToProductSearchMatch(keywords)
+	if match == "" {
+		return nil, nil
+	}
+
+	// bm25 is lower for better matches, columns weights follow the table columns
+	query := `
+		SELECT
+			p.id AS product_id,
+			-bm25(products_fts, 10.0, 5.0, 5.0, 1.0) AS score
+		FROM
+			products_fts
+		JOIN
+			products p ON p.id = products_fts.rowid
+		WHERE
+			products_fts MATCH ? AND p.deleted_at IS NULL
+		ORDER BY
+			score DESC
+		LIMIT ? OFFSET ?
+	`
+
+	if limit == 0 {
+		limit = -1
+	}
+
+	if err = p.DB.Raw(query, match, limit, offset).Scan(&rows).Error; err != nil {
+		console.Error(fmt.Sprintf("panic: %s", err.Error()))
+		return nil, errors.New("failed to search products")
+	}
+
+	return rows, nil
+}
+
+// ToProductSearchMatch method, makes fts5 match expression, every keyword is expanded
+// into its trigrams, any of them matches the keyword to tolerate typos. Whole keywords
+// are kept as phrases, bm25 ranks products matching them or more trigrams first.
+func ToProductSearchMatch(keywords string) string {
+	var groups []string
+	var keys []string
+	for i, keyword := range strings.Fields(strings.ToLower(keywords)) {
+		nokocore.KeepVoid(i)
+
+		// trigram tokenizer is unable to match less than 3 characters
+		runes := []rune(keyword)
+		if len(runes) < 3 || slices.Contains(keys, keyword) {
+			continue
+		}
+
+		keys = append(keys, keyword)
+		terms := []string{quoteProductSearchTerm(keyword)}
+		for j := 0; j+3 <= len(runes); j++ {
+			if term := quoteProductSearchTerm(string(runes[j : j+3])); !slices.Contains(terms, term) {
+				terms = append(terms, term)
+			}
+		}
+
+		groups = append(groups, fmt.Sprintf("(%s)", strings.Join(terms, " OR ")))
+	}
+
+	return strings.Join(groups, " AND ")
+}
+
+func quoteProductSearchTerm(term string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(term, "\"", "\"\""))
+}
@@ -0,0 +1,80 @@
+//go:build sqlite_fts5
+
+package repositories
+
+import (
+	"fmt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"testing"
+)
+
+func TestProductSearchTypos(t *testing.T) {
+	var err error
+	var DB *gorm.DB
+	nokocore.KeepVoid(err, DB)
+
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	if DB, err = gorm.Open(sqlite.Open(dsn), config); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []any{
+		&models2.Category{},
+		&models2.Product{},
+		&models2.Barcode{},
+		&models2.ProductUnit{},
+	}
+
+	if err = DB.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+
+	productSearchRepository := NewProductSearchRepository(DB)
+	if err = productSearchRepository.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	products := []models2.Product{
+		{Barcode: "8990001", Brand: "Sanmol", ProductName: "Paracetamol 500 mg", Supplier: "Sanbe"},
+		{Barcode: "8990002", Brand: "Amoxsan", ProductName: "Amoxicillin 500 mg", Supplier: "Sanbe"},
+		{Barcode: "8990003", Brand: "Promag", ProductName: "Antasida Doen", Supplier: "Kalbe"},
+	}
+
+	for i := range products {
+		products[i].UUID = nokocore.NewUUID()
+		if err = DB.Create(&products[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, test := range []struct {
+		keywords string
+		want     uint
+	}{
+		{"paracetamol", products[0].ID},
+		{"paracetmol", products[0].ID},
+		{"parasetamol 500", products[0].ID},
+		{"amoxcillin", products[1].ID},
+		{"antacida", products[2].ID},
+	} {
+		nokocore.KeepVoid(i)
+
+		rows, err := productSearchRepository.Search(test.keywords, 0, 10)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(rows) == 0 || rows[0].ProductID != test.want {
+			t.Errorf("Search(%q) should rank product %d first, got %+v", test.keywords, test.want, rows)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ProductStockRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ProductStock]
+}
+
+type ProductStockRepository struct {
+	repositories.BaseRepositoryImpl[models2.ProductStock]
+}
+
+func NewProductStockRepository(DB *gorm.DB) ProductStockRepositoryImpl {
+	return &ProductStockRepository{
+		repositories.NewBaseRepository[models2.ProductStock](DB),
+	}
+}
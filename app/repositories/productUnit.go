@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ProductUnitRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.ProductUnit]
+	SyncProductUnits() error
+}
+
+type ProductUnitRepository struct {
+	repositories.BaseRepositoryImpl[models2.ProductUnit]
+	DB *gorm.DB
+}
+
+func NewProductUnitRepository(DB *gorm.DB) ProductUnitRepositoryImpl {
+	return &ProductUnitRepository{
+		BaseRepositoryImpl: repositories.NewBaseRepository[models2.ProductUnit](DB),
+		DB:                 DB,
+	}
+}
+
+// SyncProductUnits method, stock of products created before unit levels is
+// moved into base units, then base and package unit levels are created.
+func (p *ProductUnitRepository) SyncProductUnits() error {
+	var err error
+	var products []models2.Product
+	nokocore.KeepVoid(err, products)
+
+	tx := p.DB.Exec("UPDATE products SET stock = package_total * unit_scale + unit_extra WHERE stock = 0")
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	tx = p.DB.Exec("UPDATE carts SET quantity = package_total * COALESCE((SELECT unit_scale FROM products WHERE products.id = carts.product_id), 1) + unit_extra WHERE quantity = 0")
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	tx = p.DB.Exec("UPDATE cart_verification_opnames SET real_quantity = real_package_total * COALESCE((SELECT unit_scale FROM products WHERE products.id = cart_verification_opnames.product_id), 1) + real_unit_extra WHERE real_quantity = 0")
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	tx = p.DB.Unscoped().Where("id NOT IN (SELECT product_id FROM product_units)").Find(&products)
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if err = product.SyncUnitLevels(p.DB); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type StockMovementRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.StockMovement]
+}
+
+type StockMovementRepository struct {
+	repositories.BaseRepositoryImpl[models2.StockMovement]
+}
+
+func NewStockMovementRepository(DB *gorm.DB) StockMovementRepositoryImpl {
+	return &StockMovementRepository{
+		repositories.NewBaseRepository[models2.StockMovement](DB),
+	}
+}
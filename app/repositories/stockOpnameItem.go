@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type StockOpnameItemRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.StockOpnameItem]
+}
+
+type StockOpnameItemRepository struct {
+	repositories.BaseRepositoryImpl[models2.StockOpnameItem]
+}
+
+func NewStockOpnameItemRepository(DB *gorm.DB) StockOpnameItemRepositoryImpl {
+	return &StockOpnameItemRepository{
+		repositories.NewBaseRepository[models2.StockOpnameItem](DB),
+	}
+}
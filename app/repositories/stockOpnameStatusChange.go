@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type StockOpnameStatusChangeRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.StockOpnameStatusChange]
+}
+
+type StockOpnameStatusChangeRepository struct {
+	repositories.BaseRepositoryImpl[models2.StockOpnameStatusChange]
+}
+
+func NewStockOpnameStatusChangeRepository(DB *gorm.DB) StockOpnameStatusChangeRepositoryImpl {
+	return &StockOpnameStatusChangeRepository{
+		repositories.NewBaseRepository[models2.StockOpnameStatusChange](DB),
+	}
+}
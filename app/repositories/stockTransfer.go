@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type StockTransferRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.StockTransfer]
+}
+
+type StockTransferRepository struct {
+	repositories.BaseRepositoryImpl[models2.StockTransfer]
+}
+
+func NewStockTransferRepository(DB *gorm.DB) StockTransferRepositoryImpl {
+	return &StockTransferRepository{
+		repositories.NewBaseRepository[models2.StockTransfer](DB),
+	}
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/repositories"
+	models2 "pharma-cash-go/app/models"
+)
+
+type WriteOffRepositoryImpl interface {
+	repositories.BaseRepositoryImpl[models2.WriteOff]
+}
+
+type WriteOffRepository struct {
+	repositories.BaseRepositoryImpl[models2.WriteOff]
+}
+
+func NewWriteOffRepository(DB *gorm.DB) WriteOffRepositoryImpl {
+	return &WriteOffRepository{
+		repositories.NewBaseRepository[models2.WriteOff](DB),
+	}
+}
@@ -0,0 +1,9 @@
+package schedulers
+
+type Config struct {
+	Interval string `mapstructure:"interval" json:"interval" yaml:"interval"`
+}
+
+func (Config) GetNameType() string {
+	return "Scheduler"
+}
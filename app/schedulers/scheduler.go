@@ -0,0 +1,139 @@
+package schedulers
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"sync"
+	"time"
+)
+
+const DefaultInterval = time.Minute
+
+type JobHandler func(DB *gorm.DB, now time.Time) error
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Handler  JobHandler
+}
+
+type SchedulerImpl interface {
+	Add(name string, interval time.Duration, handler JobHandler)
+	Run(name string) error
+	Start(ctx context.Context)
+	Stop()
+}
+
+type Scheduler struct {
+	DB     *gorm.DB
+	Jobs   []Job
+	locker sync.Mutex
+	group  sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewScheduler(DB *gorm.DB) SchedulerImpl {
+	return &Scheduler{
+		DB: DB,
+	}
+}
+
+// GetInterval method, default job interval from 'scheduler' config.
+func GetInterval() time.Duration {
+	config := globals.GetConfigGlobals[Config]()
+	if interval, err := time.ParseDuration(config.Interval); err == nil && interval > 0 {
+		return interval
+	}
+	return DefaultInterval
+}
+
+// Add method, zero interval uses the default interval.
+func (s *Scheduler) Add(name string, interval time.Duration, handler JobHandler) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	if interval <= 0 {
+		interval = GetInterval()
+	}
+
+	s.Jobs = append(s.Jobs, Job{
+		Name:     name,
+		Interval: interval,
+		Handler:  handler,
+	})
+}
+
+// Run method, runs a job once by name.
+func (s *Scheduler) Run(name string) error {
+	s.locker.Lock()
+	jobs := s.Jobs
+	s.locker.Unlock()
+
+	for i, job := range jobs {
+		nokocore.KeepVoid(i)
+
+		if job.Name == name {
+			return job.Handler(s.DB, nokocore.GetTimeUtcNow())
+		}
+	}
+
+	return fmt.Errorf("job '%s' not found", name)
+}
+
+// Start method, every job runs once at start, then on its interval until stopped.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for i, job := range s.Jobs {
+		nokocore.KeepVoid(i)
+
+		s.group.Add(1)
+		go func(job Job) {
+			defer s.group.Done()
+
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+
+			for {
+				s.handle(job)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	s.locker.Lock()
+	cancel := s.cancel
+	s.locker.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	s.group.Wait()
+}
+
+// handle method, failing jobs are logged and retried on the next tick.
+func (s *Scheduler) handle(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			console.Error(fmt.Sprintf("panic: job '%s', %v", job.Name, r))
+		}
+	}()
+
+	if err := job.Handler(s.DB, nokocore.GetTimeUtcNow()); err != nil {
+		console.Error(fmt.Sprintf("panic: job '%s', %s", job.Name, err.Error()))
+	}
+}
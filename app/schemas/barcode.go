@@ -0,0 +1,73 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+)
+
+type BarcodeBody struct {
+	Code   string `mapstructure:"code" json:"code" form:"code" validate:"ascii"`
+	Level  string `mapstructure:"level" json:"level" form:"level" validate:"omitempty,oneof=unit strip package"`
+	Active *bool  `mapstructure:"active" json:"active" form:"active" validate:"omitempty"`
+}
+
+func ToBarcodeModel(barcode *BarcodeBody) *models2.Barcode {
+	if barcode != nil {
+		level := strings.ToLower(barcode.Level)
+		if level == "" {
+			level = models2.BarcodeLevelUnit
+		}
+		active := true
+		if barcode.Active != nil {
+			active = *barcode.Active
+		}
+		return &models2.Barcode{
+			Code:   strings.TrimSpace(barcode.Code),
+			Level:  level,
+			Active: active,
+		}
+	}
+
+	return nil
+}
+
+type BarcodeResult struct {
+	UUID      uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	Code      string    `mapstructure:"code" json:"code"`
+	Level     string    `mapstructure:"level" json:"level"`
+	Active    bool      `mapstructure:"active" json:"active"`
+	Primary   bool      `mapstructure:"primary" json:"primary"`
+	CreatedAt string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt string    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToBarcodeResult(barcode *models2.Barcode, primary string) BarcodeResult {
+	if barcode != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(barcode.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(barcode.UpdatedAt)
+		return BarcodeResult{
+			UUID:      barcode.UUID,
+			Code:      barcode.Code,
+			Level:     barcode.Level,
+			Active:    barcode.Active,
+			Primary:   barcode.Code == primary,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	return BarcodeResult{}
+}
+
+func ToBarcodeResults(barcodes []models2.Barcode, primary string) []BarcodeResult {
+	size := len(barcodes)
+	barcodeResults := make([]BarcodeResult, size)
+	for i, barcode := range barcodes {
+		nokocore.KeepVoid(i)
+		barcodeResults[i] = ToBarcodeResult(&barcode, primary)
+	}
+
+	return barcodeResults
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/shopspring/decimal"
 	"nokowebapi/nokocore"
 	models2 "pharma-cash-go/app/models"
-	utils2 "pharma-cash-go/app/utils"
 )
 
 type CartBody struct {
@@ -14,6 +13,9 @@ type CartBody struct {
 	TransactionID uuid.UUID `mapstructure:"transaction_id" json:"transactionId" form:"transaction_id" validate:"uuid,omitempty"`
 	PackageTotal  int       `mapstructure:"package_total" json:"packageTotal" form:"package_total" validate:"number,omitempty"`
 	UnitExtra     int       `mapstructure:"unit_extra" json:"unitExtra" form:"unit_extra" validate:"number,omitempty"`
+
+	// quantities at any unit level, replaces package total and unit extra
+	Quantities []UnitQuantityBody `mapstructure:"quantities" json:"quantities" form:"quantities" validate:"omitempty"`
 }
 
 func ToCartModel(cart *CartBody) *models2.Cart {
@@ -27,19 +29,24 @@ func ToCartModel(cart *CartBody) *models2.Cart {
 	return nil
 }
 
-func ToCartModelWithProductModel(cart *CartBody, product *models2.Product) *models2.Cart {
+// ToCartQuantity method, normalises cart quantities into base units.
+func ToCartQuantity(cart *CartBody, product *models2.Product) (int, error) {
 	if cart != nil && product != nil {
-		packageTotal := cart.PackageTotal
-		unitExtra := cart.UnitExtra
+		return ToUnitTotal(product, cart.Quantities, cart.PackageTotal, cart.UnitExtra)
+	}
 
-		extra, div := utils2.Modulo(unitExtra, product.UnitScale)
-		packageTotal += div
-		unitExtra = extra
+	return 0, nil
+}
+
+func ToCartModelWithProductModel(cart *CartBody, product *models2.Product, quantity int) *models2.Cart {
+	if cart != nil && product != nil {
+		packageTotal, unitExtra := models2.SplitQuantity(quantity, product.UnitScale)
 
 		return &models2.Cart{
 			ProductID:    product.ID,
 			PackageTotal: packageTotal,
 			UnitExtra:    unitExtra,
+			Quantity:     quantity,
 			Closed:       false,
 		}
 	}
@@ -48,16 +55,20 @@ func ToCartModelWithProductModel(cart *CartBody, product *models2.Product) *mode
 }
 
 type CartResult struct {
-	UUID         uuid.UUID       `mapstructure:"uuid" json:"uuid"`
-	ProductID    uuid.UUID       `mapstructure:"product_id" json:"productId"`
-	Product      ProductResult   `mapstructure:"product" json:"product"`
-	PackageTotal int             `mapstructure:"package_total" json:"packageTotal"`
-	UnitExtra    int             `mapstructure:"unit_extra" json:"unitExtra"`
-	SubTotal     decimal.Decimal `mapstructure:"sub_total" json:"subTotal"`
-	Closed       bool            `mapstructure:"closed" json:"closed"`
-	CreatedAt    string          `mapstructure:"created_at" json:"createdAt"`
-	UpdatedAt    string          `mapstructure:"updated_at" json:"updatedAt"`
-	DeletedAt    string          `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+	UUID         uuid.UUID            `mapstructure:"uuid" json:"uuid"`
+	ProductID    uuid.UUID            `mapstructure:"product_id" json:"productId"`
+	Product      ProductResult        `mapstructure:"product" json:"product"`
+	PackageTotal int                  `mapstructure:"package_total" json:"packageTotal"`
+	UnitExtra    int                  `mapstructure:"unit_extra" json:"unitExtra"`
+	Quantity     int                  `mapstructure:"quantity" json:"quantity"`
+	Units        []UnitQuantityResult `mapstructure:"units" json:"units"`
+	UnitPrice    decimal.Decimal      `mapstructure:"unit_price" json:"unitPrice"`
+	SubTotal     decimal.Decimal      `mapstructure:"sub_total" json:"subTotal"`
+	CostOfGoods  decimal.Decimal      `mapstructure:"cost_of_goods" json:"costOfGoods"`
+	Closed       bool                 `mapstructure:"closed" json:"closed"`
+	CreatedAt    string               `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt    string               `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt    string               `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
 }
 
 func ToCartResult(cart *models2.Cart) CartResult {
@@ -74,7 +85,11 @@ func ToCartResult(cart *models2.Cart) CartResult {
 			Product:      ToProductResult(&cart.Product),
 			PackageTotal: cart.PackageTotal,
 			UnitExtra:    cart.UnitExtra,
+			Quantity:     cart.Quantity,
+			Units:        ToUnitQuantityResults(cart.Product.ToUnitQuantities(cart.Quantity)),
+			UnitPrice:    cart.UnitPrice,
 			SubTotal:     cart.SubTotal,
+			CostOfGoods:  cart.CostOfGoods,
 			Closed:       cart.Closed,
 			CreatedAt:    createdAt,
 			UpdatedAt:    updatedAt,
@@ -0,0 +1,44 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CostLayerResult struct {
+	UUID       uuid.UUID       `mapstructure:"uuid" json:"uuid"`
+	ProductID  uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	Quantity   int             `mapstructure:"quantity" json:"quantity"`
+	Remaining  int             `mapstructure:"remaining" json:"remaining"`
+	UnitCost   decimal.Decimal `mapstructure:"unit_cost" json:"unitCost"`
+	ReceivedAt string          `mapstructure:"received_at" json:"receivedAt"`
+}
+
+func ToCostLayerResult(costLayer *models2.CostLayer) CostLayerResult {
+	if costLayer != nil {
+		receivedAt := nokocore.ToTimeUtcStringISO8601(costLayer.ReceivedAt)
+		return CostLayerResult{
+			UUID:       costLayer.UUID,
+			ProductID:  costLayer.Product.UUID,
+			Quantity:   costLayer.Quantity,
+			Remaining:  costLayer.Remaining,
+			UnitCost:   costLayer.UnitCost,
+			ReceivedAt: receivedAt,
+		}
+	}
+
+	return CostLayerResult{}
+}
+
+func ToCostLayerResults(costLayers []models2.CostLayer) []CostLayerResult {
+	size := len(costLayers)
+	costLayerResults := make([]CostLayerResult, size)
+	for i, costLayer := range costLayers {
+		nokocore.KeepVoid(i)
+		costLayerResults[i] = ToCostLayerResult(&costLayer)
+	}
+
+	return costLayerResults
+}
@@ -0,0 +1,52 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CountSheetEntryBody struct {
+	ClientID         string `mapstructure:"client_id" json:"clientId" form:"client_id" validate:"uuid"`
+	ProductID        string `mapstructure:"product_id" json:"productId" form:"product_id" validate:"uuid"`
+	NotMatchReason   string `mapstructure:"not_match_reason" json:"notMatchReason" form:"not_match_reason" validate:"ascii,omitempty"`
+	RealPackageTotal int    `mapstructure:"real_package_total" json:"realPackageTotal" form:"real_package_total" validate:"number,omitempty"`
+	RealUnitExtra    int    `mapstructure:"real_unit_extra" json:"realUnitExtra" form:"real_unit_extra" validate:"number,omitempty"`
+	CountedAt        string `mapstructure:"counted_at" json:"countedAt" form:"counted_at" validate:"datetimeISO"`
+
+	// real quantities at any unit level, replaces real package total and real unit extra
+	RealQuantities []UnitQuantityBody `mapstructure:"real_quantities" json:"realQuantities" form:"real_quantities" validate:"omitempty"`
+}
+
+type CountSheetBody struct {
+	DeviceID string                `mapstructure:"device_id" json:"deviceId" form:"device_id" validate:"ascii"`
+	Entries  []CountSheetEntryBody `mapstructure:"entries" json:"entries" form:"entries"`
+}
+
+type CountEntryResult struct {
+	ClientID         string     `mapstructure:"client_id" json:"clientId"`
+	ProductID        uuid.UUID  `mapstructure:"product_id" json:"productId"`
+	Status           string     `mapstructure:"status" json:"status"`
+	Duplicate        bool       `mapstructure:"duplicate" json:"duplicate"`
+	Quantity         int        `mapstructure:"quantity" json:"quantity"`
+	CountedQuantity  int        `mapstructure:"counted_quantity" json:"countedQuantity"`
+	ConflictDeviceID string     `mapstructure:"conflict_device_id" json:"conflictDeviceId,omitempty"`
+	ConflictUserID   *uuid.UUID `mapstructure:"conflict_user_id" json:"conflictUserId,omitempty"`
+	CountedAt        string     `mapstructure:"counted_at" json:"countedAt,omitempty"`
+	Message          string     `mapstructure:"message" json:"message,omitempty"`
+}
+
+func ToCountEntryResult(countEntry *models2.CountEntry, product *models2.Product) CountEntryResult {
+	if countEntry != nil && product != nil {
+		countedAt := nokocore.ToTimeUtcStringISO8601(countEntry.CountedAt)
+		return CountEntryResult{
+			ClientID:  countEntry.ClientID,
+			ProductID: product.UUID,
+			Status:    countEntry.Status,
+			Quantity:  countEntry.Quantity,
+			CountedAt: countedAt,
+		}
+	}
+
+	return CountEntryResult{}
+}
@@ -0,0 +1,81 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type CycleCountItemResult struct {
+	ProductID     uuid.UUID `mapstructure:"product_id" json:"productId"`
+	Barcode       string    `mapstructure:"barcode" json:"barcode"`
+	ProductName   string    `mapstructure:"product_name" json:"productName"`
+	Brand         string    `mapstructure:"brand" json:"brand"`
+	Class         string    `mapstructure:"class" json:"class"`
+	LastCountedAt string    `mapstructure:"last_counted_at" json:"lastCountedAt,omitempty"`
+}
+
+func ToCycleCountItemResult(cycleCountItem *models2.CycleCountItem) CycleCountItemResult {
+	if cycleCountItem != nil {
+		var lastCountedAt string
+		if cycleCountItem.LastCountedAt.Valid {
+			lastCountedAt = nokocore.ToTimeUtcStringISO8601(cycleCountItem.LastCountedAt.Time)
+		}
+		return CycleCountItemResult{
+			ProductID:     cycleCountItem.Product.UUID,
+			Barcode:       cycleCountItem.Product.Barcode,
+			ProductName:   cycleCountItem.Product.ProductName,
+			Brand:         cycleCountItem.Product.Brand,
+			Class:         cycleCountItem.Class,
+			LastCountedAt: lastCountedAt,
+		}
+	}
+
+	return CycleCountItemResult{}
+}
+
+type CycleCountResult struct {
+	UUID       uuid.UUID              `mapstructure:"uuid" json:"uuid"`
+	Location   LocationResult         `mapstructure:"location" json:"location"`
+	ProposedOn string                 `mapstructure:"proposed_on" json:"proposedOn"`
+	IsStarted  bool                   `mapstructure:"is_started" json:"isStarted"`
+	ItemTotal  int                    `mapstructure:"item_total" json:"itemTotal"`
+	Items      []CycleCountItemResult `mapstructure:"items" json:"items"`
+	CreatedAt  string                 `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt  string                 `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToCycleCountResult(cycleCount *models2.CycleCount) CycleCountResult {
+	if cycleCount != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(cycleCount.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(cycleCount.UpdatedAt)
+		size := len(cycleCount.Items)
+		items := make([]CycleCountItemResult, size)
+		for i, cycleCountItem := range cycleCount.Items {
+			items[i] = ToCycleCountItemResult(&cycleCountItem)
+		}
+		return CycleCountResult{
+			UUID:       cycleCount.UUID,
+			Location:   ToLocationResult(&cycleCount.Location),
+			ProposedOn: cycleCount.ProposedOn.Format(nokocore.DateOnlyFormat),
+			IsStarted:  cycleCount.StockOpnameID != 0,
+			ItemTotal:  size,
+			Items:      items,
+			CreatedAt:  createdAt,
+			UpdatedAt:  updatedAt,
+		}
+	}
+
+	return CycleCountResult{}
+}
+
+func ToCycleCountResults(cycleCounts []models2.CycleCount) []CycleCountResult {
+	size := len(cycleCounts)
+	cycleCountResults := make([]CycleCountResult, size)
+	for i, cycleCount := range cycleCounts {
+		nokocore.KeepVoid(i)
+		cycleCountResults[i] = ToCycleCountResult(&cycleCount)
+	}
+
+	return cycleCountResults
+}
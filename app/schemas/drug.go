@@ -0,0 +1,235 @@
+package schemas
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+)
+
+type ActiveIngredientBody struct {
+	IngredientName string `mapstructure:"ingredient_name" json:"ingredientName" form:"ingredient_name"`
+	Description    string `mapstructure:"description" json:"description" form:"description" validate:"omitempty"`
+}
+
+func ToActiveIngredientModel(activeIngredient *ActiveIngredientBody) *models2.ActiveIngredient {
+	if activeIngredient != nil {
+		return &models2.ActiveIngredient{
+			IngredientName: nokocore.ToTitleCase(strings.TrimSpace(activeIngredient.IngredientName)),
+			Description:    strings.TrimSpace(activeIngredient.Description),
+		}
+	}
+
+	return nil
+}
+
+type ActiveIngredientResult struct {
+	UUID           uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	IngredientName string    `mapstructure:"ingredient_name" json:"ingredientName"`
+	Description    string    `mapstructure:"description" json:"description"`
+	CreatedAt      string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt      string    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToActiveIngredientResult(activeIngredient *models2.ActiveIngredient) ActiveIngredientResult {
+	if activeIngredient != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(activeIngredient.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(activeIngredient.UpdatedAt)
+		return ActiveIngredientResult{
+			UUID:           activeIngredient.UUID,
+			IngredientName: activeIngredient.IngredientName,
+			Description:    activeIngredient.Description,
+			CreatedAt:      createdAt,
+			UpdatedAt:      updatedAt,
+		}
+	}
+
+	return ActiveIngredientResult{}
+}
+
+func ToActiveIngredientResults(activeIngredients []models2.ActiveIngredient) []ActiveIngredientResult {
+	size := len(activeIngredients)
+	activeIngredientResults := make([]ActiveIngredientResult, size)
+	for i, activeIngredient := range activeIngredients {
+		nokocore.KeepVoid(i)
+		activeIngredientResults[i] = ToActiveIngredientResult(&activeIngredient)
+	}
+
+	return activeIngredientResults
+}
+
+type ProductIngredientBody struct {
+	IngredientID   string `mapstructure:"ingredient_id" json:"ingredientId" form:"ingredient_id" validate:"uuid,omitempty"`
+	IngredientName string `mapstructure:"ingredient_name" json:"ingredientName" form:"ingredient_name" validate:"omitempty"`
+	Strength       string `mapstructure:"strength" json:"strength" form:"strength" validate:"decimal"`
+	StrengthUnit   string `mapstructure:"strength_unit" json:"strengthUnit" form:"strength_unit" validate:"ascii"`
+}
+
+type ProductIngredientsBody struct {
+	Ingredients []ProductIngredientBody `mapstructure:"ingredients" json:"ingredients" form:"ingredients"`
+}
+
+func ToProductIngredientModel(productIngredient *ProductIngredientBody, product *models2.Product, activeIngredient *models2.ActiveIngredient) *models2.ProductIngredient {
+	if productIngredient != nil && product != nil && activeIngredient != nil {
+		return &models2.ProductIngredient{
+			ProductID:          product.ID,
+			ActiveIngredientID: activeIngredient.ID,
+			Strength:           decimal.RequireFromString(productIngredient.Strength),
+			StrengthUnit:       strings.ToLower(strings.TrimSpace(productIngredient.StrengthUnit)),
+		}
+	}
+
+	return nil
+}
+
+type ProductIngredientResult struct {
+	UUID           uuid.UUID       `mapstructure:"uuid" json:"uuid"`
+	IngredientID   uuid.UUID       `mapstructure:"ingredient_id" json:"ingredientId"`
+	IngredientName string          `mapstructure:"ingredient_name" json:"ingredientName"`
+	Strength       decimal.Decimal `mapstructure:"strength" json:"strength"`
+	StrengthUnit   string          `mapstructure:"strength_unit" json:"strengthUnit"`
+}
+
+func ToProductIngredientResult(productIngredient *models2.ProductIngredient) ProductIngredientResult {
+	if productIngredient != nil {
+		return ProductIngredientResult{
+			UUID:           productIngredient.UUID,
+			IngredientID:   productIngredient.ActiveIngredient.UUID,
+			IngredientName: productIngredient.ActiveIngredient.IngredientName,
+			Strength:       productIngredient.Strength,
+			StrengthUnit:   productIngredient.StrengthUnit,
+		}
+	}
+
+	return ProductIngredientResult{}
+}
+
+func ToProductIngredientResults(productIngredients []models2.ProductIngredient) []ProductIngredientResult {
+	size := len(productIngredients)
+	productIngredientResults := make([]ProductIngredientResult, size)
+	for i, productIngredient := range productIngredients {
+		nokocore.KeepVoid(i)
+		productIngredientResults[i] = ToProductIngredientResult(&productIngredient)
+	}
+
+	return productIngredientResults
+}
+
+// ToSubstance method, active ingredients with strength, e.g. 'Codeine 10 mg + Paracetamol 500 mg',
+// falls back to the product name.
+func ToSubstance(product *models2.Product) string {
+	if product != nil {
+		substances := make([]string, 0, len(product.Ingredients))
+		for i, productIngredient := range product.Ingredients {
+			nokocore.KeepVoid(i)
+			substance := fmt.Sprintf("%s %s %s", productIngredient.ActiveIngredient.IngredientName, productIngredient.Strength.String(), productIngredient.StrengthUnit)
+			substances = append(substances, strings.TrimSpace(substance))
+		}
+		if len(substances) > 0 {
+			return strings.Join(substances, " + ")
+		}
+		return product.ProductName
+	}
+
+	return ""
+}
+
+type ControlledRegisterResult struct {
+	UUID        uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	ProductID   uuid.UUID `mapstructure:"product_id" json:"productId"`
+	ProductName string    `mapstructure:"product_name" json:"productName"`
+	DrugClass   string    `mapstructure:"drug_class" json:"drugClass"`
+	Kind        string    `mapstructure:"kind" json:"kind"`
+	Quantity    int       `mapstructure:"quantity" json:"quantity"`
+	UnitType    string    `mapstructure:"unit_type" json:"unitType"`
+	Reference   string    `mapstructure:"reference" json:"reference"`
+	Note        string    `mapstructure:"note" json:"note"`
+	RecordedBy  uuid.UUID `mapstructure:"recorded_by" json:"recordedBy"`
+	Username    string    `mapstructure:"username" json:"username"`
+	RecordedAt  string    `mapstructure:"recorded_at" json:"recordedAt"`
+}
+
+func ToControlledRegisterResult(controlledRegister *models2.ControlledRegister) ControlledRegisterResult {
+	if controlledRegister != nil {
+		recordedAt := nokocore.ToTimeUtcStringISO8601(controlledRegister.RecordedAt)
+		return ControlledRegisterResult{
+			UUID:        controlledRegister.UUID,
+			ProductID:   controlledRegister.Product.UUID,
+			ProductName: controlledRegister.Product.ProductName,
+			DrugClass:   controlledRegister.DrugClass,
+			Kind:        controlledRegister.Kind,
+			Quantity:    controlledRegister.Quantity,
+			UnitType:    controlledRegister.Product.Unit.UnitType,
+			Reference:   controlledRegister.Reference,
+			Note:        controlledRegister.Note,
+			RecordedBy:  controlledRegister.User.UUID,
+			Username:    controlledRegister.User.Username,
+			RecordedAt:  recordedAt,
+		}
+	}
+
+	return ControlledRegisterResult{}
+}
+
+func ToControlledRegisterResults(controlledRegisters []models2.ControlledRegister) []ControlledRegisterResult {
+	size := len(controlledRegisters)
+	controlledRegisterResults := make([]ControlledRegisterResult, size)
+	for i, controlledRegister := range controlledRegisters {
+		nokocore.KeepVoid(i)
+		controlledRegisterResults[i] = ToControlledRegisterResult(&controlledRegister)
+	}
+
+	return controlledRegisterResults
+}
+
+type DrugInteractionBody struct {
+	Ingredient    string `mapstructure:"ingredient" json:"ingredient" form:"ingredient"`
+	InteractsWith string `mapstructure:"interacts_with" json:"interactsWith" form:"interacts_with"`
+	Severity      string `mapstructure:"severity" json:"severity" form:"severity" validate:"ascii"`
+	Note          string `mapstructure:"note" json:"note" form:"note" validate:"omitempty"`
+}
+
+type DrugInteractionResult struct {
+	UUID            uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	IngredientID    uuid.UUID `mapstructure:"ingredient_id" json:"ingredientId"`
+	Ingredient      string    `mapstructure:"ingredient" json:"ingredient"`
+	InteractsWithID uuid.UUID `mapstructure:"interacts_with_id" json:"interactsWithId"`
+	InteractsWith   string    `mapstructure:"interacts_with" json:"interactsWith"`
+	Severity        string    `mapstructure:"severity" json:"severity"`
+	Note            string    `mapstructure:"note" json:"note"`
+	CreatedAt       string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt       string    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToDrugInteractionResult(drugInteraction *models2.DrugInteraction) DrugInteractionResult {
+	if drugInteraction != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(drugInteraction.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(drugInteraction.UpdatedAt)
+		return DrugInteractionResult{
+			UUID:            drugInteraction.UUID,
+			IngredientID:    drugInteraction.Ingredient.UUID,
+			Ingredient:      drugInteraction.Ingredient.IngredientName,
+			InteractsWithID: drugInteraction.InteractsWith.UUID,
+			InteractsWith:   drugInteraction.InteractsWith.IngredientName,
+			Severity:        drugInteraction.Severity,
+			Note:            drugInteraction.Note,
+			CreatedAt:       createdAt,
+			UpdatedAt:       updatedAt,
+		}
+	}
+
+	return DrugInteractionResult{}
+}
+
+func ToDrugInteractionResults(drugInteractions []models2.DrugInteraction) []DrugInteractionResult {
+	size := len(drugInteractions)
+	drugInteractionResults := make([]DrugInteractionResult, size)
+	for i, drugInteraction := range drugInteractions {
+		nokocore.KeepVoid(i)
+		drugInteractionResults[i] = ToDrugInteractionResult(&drugInteraction)
+	}
+
+	return drugInteractionResults
+}
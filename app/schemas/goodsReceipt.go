@@ -0,0 +1,91 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+)
+
+type GoodsReceiptBody struct {
+	PurchasePrice string             `mapstructure:"purchase_price" json:"purchasePrice" form:"purchase_price" validate:"decimal,omitempty"`
+	PackageTotal  int                `mapstructure:"package_total" json:"packageTotal" form:"package_total" validate:"number,omitempty"`
+	UnitExtra     int                `mapstructure:"unit_extra" json:"unitExtra" form:"unit_extra" validate:"number,omitempty"`
+	Quantities    []UnitQuantityBody `mapstructure:"quantities" json:"quantities" form:"quantities" validate:"omitempty"`
+	Supplier      string             `mapstructure:"supplier" json:"supplier" form:"supplier" validate:"ascii,omitempty"`
+	Reference     string             `mapstructure:"reference" json:"reference" form:"reference" validate:"ascii,omitempty"`
+	LocationID    string             `mapstructure:"location_id" json:"locationId" form:"location_id" validate:"uuid,omitempty"` // receiving location by default
+}
+
+func ToGoodsReceiptModel(goodsReceipt *GoodsReceiptBody, product *models2.Product) *models2.GoodsReceipt {
+	if goodsReceipt != nil && product != nil {
+		unitCost := product.PurchasePrice
+		if goodsReceipt.PurchasePrice != "" {
+			unitCost = decimal.RequireFromString(goodsReceipt.PurchasePrice)
+		}
+		supplier := strings.TrimSpace(goodsReceipt.Supplier)
+		if supplier == "" {
+			supplier = product.Supplier
+		}
+		return &models2.GoodsReceipt{
+			ProductID: product.ID,
+			UnitCost:  unitCost,
+			Supplier:  supplier,
+			Reference: strings.TrimSpace(goodsReceipt.Reference),
+		}
+	}
+
+	return nil
+}
+
+type GoodsReceiptResult struct {
+	UUID       uuid.UUID            `mapstructure:"uuid" json:"uuid"`
+	ProductID  uuid.UUID            `mapstructure:"product_id" json:"productId"`
+	Quantity   int                  `mapstructure:"quantity" json:"quantity"`
+	Units      []UnitQuantityResult `mapstructure:"units" json:"units"`
+	UnitCost   decimal.Decimal      `mapstructure:"unit_cost" json:"unitCost"`
+	Supplier   string               `mapstructure:"supplier" json:"supplier"`
+	Reference  string               `mapstructure:"reference" json:"reference"`
+	ReceivedBy uuid.UUID            `mapstructure:"received_by" json:"receivedBy"`
+	CreatedAt  string               `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt  string               `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt  string               `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+func ToGoodsReceiptResult(goodsReceipt *models2.GoodsReceipt) GoodsReceiptResult {
+	if goodsReceipt != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(goodsReceipt.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(goodsReceipt.UpdatedAt)
+		var deletedAt string
+		if goodsReceipt.DeletedAt.Valid {
+			deletedAt = nokocore.ToTimeUtcStringISO8601(goodsReceipt.DeletedAt.Time)
+		}
+		return GoodsReceiptResult{
+			UUID:       goodsReceipt.UUID,
+			ProductID:  goodsReceipt.Product.UUID,
+			Quantity:   goodsReceipt.Quantity,
+			Units:      ToUnitQuantityResults(goodsReceipt.Product.ToUnitQuantities(goodsReceipt.Quantity)),
+			UnitCost:   goodsReceipt.UnitCost,
+			Supplier:   goodsReceipt.Supplier,
+			Reference:  goodsReceipt.Reference,
+			ReceivedBy: goodsReceipt.User.UUID,
+			CreatedAt:  createdAt,
+			UpdatedAt:  updatedAt,
+			DeletedAt:  deletedAt,
+		}
+	}
+
+	return GoodsReceiptResult{}
+}
+
+func ToGoodsReceiptResults(goodsReceipts []models2.GoodsReceipt) []GoodsReceiptResult {
+	size := len(goodsReceipts)
+	goodsReceiptResults := make([]GoodsReceiptResult, size)
+	for i, goodsReceipt := range goodsReceipts {
+		nokocore.KeepVoid(i)
+		goodsReceiptResults[i] = ToGoodsReceiptResult(&goodsReceipt)
+	}
+
+	return goodsReceiptResults
+}
@@ -0,0 +1,150 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+)
+
+type LocationBody struct {
+	Code         string `mapstructure:"code" json:"code" form:"code" validate:"ascii"`
+	LocationName string `mapstructure:"location_name" json:"locationName" form:"location_name"`
+	Description  string `mapstructure:"description" json:"description" form:"description" validate:"omitempty"`
+	Selling      bool   `mapstructure:"selling" json:"selling" form:"selling" validate:"omitempty"`
+	Receiving    bool   `mapstructure:"receiving" json:"receiving" form:"receiving" validate:"omitempty"`
+}
+
+func ToLocationModel(location *LocationBody) *models2.Location {
+	if location != nil {
+		return &models2.Location{
+			Code:         strings.ToLower(strings.TrimSpace(location.Code)),
+			LocationName: strings.TrimSpace(location.LocationName),
+			Description:  strings.TrimSpace(location.Description),
+			Selling:      location.Selling,
+			Receiving:    location.Receiving,
+		}
+	}
+
+	return nil
+}
+
+type LocationResult struct {
+	UUID         uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	Code         string    `mapstructure:"code" json:"code"`
+	LocationName string    `mapstructure:"location_name" json:"locationName"`
+	Description  string    `mapstructure:"description" json:"description"`
+	Selling      bool      `mapstructure:"selling" json:"selling"`
+	Receiving    bool      `mapstructure:"receiving" json:"receiving"`
+	CreatedAt    string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt    string    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToLocationResult(location *models2.Location) LocationResult {
+	if location != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(location.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(location.UpdatedAt)
+		return LocationResult{
+			UUID:         location.UUID,
+			Code:         location.Code,
+			LocationName: location.LocationName,
+			Description:  location.Description,
+			Selling:      location.Selling,
+			Receiving:    location.Receiving,
+			CreatedAt:    createdAt,
+			UpdatedAt:    updatedAt,
+		}
+	}
+
+	return LocationResult{}
+}
+
+func ToLocationResults(locations []models2.Location) []LocationResult {
+	size := len(locations)
+	locationResults := make([]LocationResult, size)
+	for i, location := range locations {
+		nokocore.KeepVoid(i)
+		locationResults[i] = ToLocationResult(&location)
+	}
+
+	return locationResults
+}
+
+type ProductStockResult struct {
+	LocationID   uuid.UUID            `mapstructure:"location_id" json:"locationId"`
+	Code         string               `mapstructure:"code" json:"code"`
+	LocationName string               `mapstructure:"location_name" json:"locationName"`
+	Selling      bool                 `mapstructure:"selling" json:"selling"`
+	Stock        int                  `mapstructure:"stock" json:"stock"`
+	Units        []UnitQuantityResult `mapstructure:"units" json:"units"`
+}
+
+func ToProductStockResult(productStock *models2.ProductStock, product *models2.Product) ProductStockResult {
+	if productStock != nil && product != nil {
+		return ProductStockResult{
+			LocationID:   productStock.Location.UUID,
+			Code:         productStock.Location.Code,
+			LocationName: productStock.Location.LocationName,
+			Selling:      productStock.Location.Selling,
+			Stock:        productStock.Stock,
+			Units:        ToUnitQuantityResults(product.ToUnitQuantities(productStock.Stock)),
+		}
+	}
+
+	return ProductStockResult{}
+}
+
+func ToProductStockResults(productStocks []models2.ProductStock, product *models2.Product) []ProductStockResult {
+	size := len(productStocks)
+	productStockResults := make([]ProductStockResult, size)
+	for i, productStock := range productStocks {
+		nokocore.KeepVoid(i)
+		productStockResults[i] = ToProductStockResult(&productStock, product)
+	}
+
+	return productStockResults
+}
+
+type StockMovementResult struct {
+	UUID        uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	LocationID  uuid.UUID `mapstructure:"location_id" json:"locationId"`
+	Kind        string    `mapstructure:"kind" json:"kind"`
+	Quantity    int       `mapstructure:"quantity" json:"quantity"`
+	StockBefore int       `mapstructure:"stock_before" json:"stockBefore"`
+	StockAfter  int       `mapstructure:"stock_after" json:"stockAfter"`
+	Reference   string    `mapstructure:"reference" json:"reference"`
+	Note        string    `mapstructure:"note" json:"note"`
+	CreatedBy   uuid.UUID `mapstructure:"created_by" json:"createdBy"`
+	CreatedAt   string    `mapstructure:"created_at" json:"createdAt"`
+}
+
+func ToStockMovementResult(stockMovement *models2.StockMovement) StockMovementResult {
+	if stockMovement != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(stockMovement.CreatedAt)
+		return StockMovementResult{
+			UUID:        stockMovement.UUID,
+			LocationID:  stockMovement.Location.UUID,
+			Kind:        stockMovement.Kind,
+			Quantity:    stockMovement.Quantity,
+			StockBefore: stockMovement.StockBefore,
+			StockAfter:  stockMovement.StockAfter,
+			Reference:   stockMovement.Reference,
+			Note:        stockMovement.Note,
+			CreatedBy:   stockMovement.User.UUID,
+			CreatedAt:   createdAt,
+		}
+	}
+
+	return StockMovementResult{}
+}
+
+func ToStockMovementResults(stockMovements []models2.StockMovement) []StockMovementResult {
+	size := len(stockMovements)
+	stockMovementResults := make([]StockMovementResult, size)
+	for i, stockMovement := range stockMovements {
+		nokocore.KeepVoid(i)
+		stockMovementResults[i] = ToStockMovementResult(&stockMovement)
+	}
+
+	return stockMovementResults
+}
@@ -0,0 +1,6 @@
+package schemas
+
+// MergeBody struct, target of the merge, products of the merged lookup are moved into it.
+type MergeBody struct {
+	TargetID string `mapstructure:"target_id" json:"targetId" form:"target_id" validate:"uuid"`
+}
@@ -0,0 +1,38 @@
+package schemas
+
+import (
+	"nokowebapi/nokocore"
+)
+
+type PermissionBody struct {
+	Roles []string `mapstructure:"roles" json:"roles" form:"roles" validate:"omitempty"`
+}
+
+type PermissionResult struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Roles    []string `mapstructure:"roles" json:"roles"`
+	Assigned bool     `mapstructure:"assigned" json:"assigned"` // roles from database
+}
+
+func ToPermissionResult(policy nokocore.PolicyImpl, name string) PermissionResult {
+	roles := policy.GetRoles(name)
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return PermissionResult{
+		Name:     name,
+		Roles:    roles,
+		Assigned: policy.IsAssigned(name),
+	}
+}
+
+func ToPermissionResults(policy nokocore.PolicyImpl) []PermissionResult {
+	names := policy.GetNames()
+	permissionResults := make([]PermissionResult, len(names))
+	for i, name := range names {
+		permissionResults[i] = ToPermissionResult(policy, name)
+	}
+
+	return permissionResults
+}
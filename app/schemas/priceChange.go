@@ -0,0 +1,198 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"strings"
+)
+
+type PriceChangeItemBody struct {
+	ProductID     string `mapstructure:"product_id" json:"productId" form:"product_id" validate:"uuid"`
+	PricingRule   string `mapstructure:"pricing_rule" json:"pricingRule" form:"pricing_rule" validate:"ascii,omitempty"`
+	PurchasePrice string `mapstructure:"purchase_price" json:"purchasePrice" form:"purchase_price" validate:"decimal,omitempty"`
+	SalePrice     string `mapstructure:"sale_price" json:"salePrice" form:"sale_price" validate:"decimal,omitempty"` // fixed pricing rule
+}
+
+type PriceChangeBody struct {
+	Name        string                `mapstructure:"name" json:"name" form:"name"`
+	Description string                `mapstructure:"description" json:"description" form:"description" validate:"omitempty"`
+	EffectiveAt string                `mapstructure:"effective_at" json:"effectiveAt" form:"effective_at" validate:"datetimeISO"`
+	Items       []PriceChangeItemBody `mapstructure:"items" json:"items" form:"items"`
+}
+
+func ToPriceChangeModel(priceChange *PriceChangeBody) *models2.PriceChange {
+	if priceChange != nil {
+		effectiveAt, err := nokocore.ParseTimeUtcByStringISO8601(priceChange.EffectiveAt)
+		nokocore.NoErr(err)
+		return &models2.PriceChange{
+			Name:        strings.TrimSpace(priceChange.Name),
+			Description: strings.TrimSpace(priceChange.Description),
+			EffectiveAt: effectiveAt,
+			Status:      models2.PriceChangeStatusScheduled,
+		}
+	}
+
+	return nil
+}
+
+func ToPriceChangeItemModel(priceChangeItem *PriceChangeItemBody, product *models2.Product) *models2.PriceChangeItem {
+	if priceChangeItem != nil && product != nil {
+		var purchasePrice decimal.NullDecimal
+		var salePrice decimal.NullDecimal
+		if priceChangeItem.PurchasePrice != "" {
+			purchasePrice = decimal.NewNullDecimal(decimal.RequireFromString(priceChangeItem.PurchasePrice))
+		}
+		if priceChangeItem.SalePrice != "" {
+			salePrice = decimal.NewNullDecimal(decimal.RequireFromString(priceChangeItem.SalePrice))
+		}
+		return &models2.PriceChangeItem{
+			ProductID:     product.ID,
+			PricingRule:   strings.ToLower(priceChangeItem.PricingRule),
+			PurchasePrice: purchasePrice,
+			SalePrice:     salePrice,
+		}
+	}
+
+	return nil
+}
+
+type PriceChangeItemResult struct {
+	UUID                  uuid.UUID           `mapstructure:"uuid" json:"uuid"`
+	ProductID             uuid.UUID           `mapstructure:"product_id" json:"productId"`
+	ProductName           string              `mapstructure:"product_name" json:"productName"`
+	PricingRule           string              `mapstructure:"pricing_rule" json:"pricingRule"`
+	PurchasePrice         decimal.NullDecimal `mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice             decimal.NullDecimal `mapstructure:"sale_price" json:"salePrice"`
+	PreviousPricingRule   string              `mapstructure:"previous_pricing_rule" json:"previousPricingRule"`
+	PreviousPurchasePrice decimal.NullDecimal `mapstructure:"previous_purchase_price" json:"previousPurchasePrice"`
+	PreviousSalePrice     decimal.NullDecimal `mapstructure:"previous_sale_price" json:"previousSalePrice"`
+}
+
+func ToPriceChangeItemResult(priceChangeItem *models2.PriceChangeItem) PriceChangeItemResult {
+	if priceChangeItem != nil {
+		return PriceChangeItemResult{
+			UUID:                  priceChangeItem.UUID,
+			ProductID:             priceChangeItem.Product.UUID,
+			ProductName:           priceChangeItem.Product.ProductName,
+			PricingRule:           priceChangeItem.PricingRule,
+			PurchasePrice:         priceChangeItem.PurchasePrice,
+			SalePrice:             priceChangeItem.SalePrice,
+			PreviousPricingRule:   priceChangeItem.PreviousPricingRule,
+			PreviousPurchasePrice: priceChangeItem.PreviousPurchasePrice,
+			PreviousSalePrice:     priceChangeItem.PreviousSalePrice,
+		}
+	}
+
+	return PriceChangeItemResult{}
+}
+
+func ToPriceChangeItemResults(priceChangeItems []models2.PriceChangeItem) []PriceChangeItemResult {
+	size := len(priceChangeItems)
+	priceChangeItemResults := make([]PriceChangeItemResult, size)
+	for i, priceChangeItem := range priceChangeItems {
+		nokocore.KeepVoid(i)
+		priceChangeItemResults[i] = ToPriceChangeItemResult(&priceChangeItem)
+	}
+
+	return priceChangeItemResults
+}
+
+type PriceChangeResult struct {
+	UUID        uuid.UUID               `mapstructure:"uuid" json:"uuid"`
+	Name        string                  `mapstructure:"name" json:"name"`
+	Description string                  `mapstructure:"description" json:"description"`
+	EffectiveAt string                  `mapstructure:"effective_at" json:"effectiveAt"`
+	Status      string                  `mapstructure:"status" json:"status"`
+	CreatedBy   uuid.UUID               `mapstructure:"created_by" json:"createdBy"`
+	Items       []PriceChangeItemResult `mapstructure:"items" json:"items"`
+	AppliedAt   string                  `mapstructure:"applied_at" json:"appliedAt,omitempty"`
+	CancelledAt string                  `mapstructure:"cancelled_at" json:"cancelledAt,omitempty"`
+	RevertedAt  string                  `mapstructure:"reverted_at" json:"revertedAt,omitempty"`
+	CreatedAt   string                  `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt   string                  `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToPriceChangeResult(priceChange *models2.PriceChange) PriceChangeResult {
+	if priceChange != nil {
+		effectiveAt := nokocore.ToTimeUtcStringISO8601(priceChange.EffectiveAt)
+		createdAt := nokocore.ToTimeUtcStringISO8601(priceChange.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(priceChange.UpdatedAt)
+		var appliedAt string
+		var cancelledAt string
+		var revertedAt string
+		if priceChange.AppliedAt.Valid {
+			appliedAt = nokocore.ToTimeUtcStringISO8601(priceChange.AppliedAt.Time)
+		}
+		if priceChange.CancelledAt.Valid {
+			cancelledAt = nokocore.ToTimeUtcStringISO8601(priceChange.CancelledAt.Time)
+		}
+		if priceChange.RevertedAt.Valid {
+			revertedAt = nokocore.ToTimeUtcStringISO8601(priceChange.RevertedAt.Time)
+		}
+		return PriceChangeResult{
+			UUID:        priceChange.UUID,
+			Name:        priceChange.Name,
+			Description: priceChange.Description,
+			EffectiveAt: effectiveAt,
+			Status:      priceChange.Status,
+			CreatedBy:   priceChange.User.UUID,
+			Items:       ToPriceChangeItemResults(priceChange.Items),
+			AppliedAt:   appliedAt,
+			CancelledAt: cancelledAt,
+			RevertedAt:  revertedAt,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+		}
+	}
+
+	return PriceChangeResult{}
+}
+
+func ToPriceChangeResults(priceChanges []models2.PriceChange) []PriceChangeResult {
+	size := len(priceChanges)
+	priceChangeResults := make([]PriceChangeResult, size)
+	for i, priceChange := range priceChanges {
+		nokocore.KeepVoid(i)
+		priceChangeResults[i] = ToPriceChangeResult(&priceChange)
+	}
+
+	return priceChangeResults
+}
+
+type PriceChangePreviewResult struct {
+	ProductID        uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	ProductName      string          `mapstructure:"product_name" json:"productName"`
+	PricingRule      string          `mapstructure:"pricing_rule" json:"pricingRule"`
+	PurchasePrice    decimal.Decimal `mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice        decimal.Decimal `mapstructure:"sale_price" json:"salePrice"`
+	NewPricingRule   string          `mapstructure:"new_pricing_rule" json:"newPricingRule"`
+	NewPurchasePrice decimal.Decimal `mapstructure:"new_purchase_price" json:"newPurchasePrice"`
+	NewSalePrice     decimal.Decimal `mapstructure:"new_sale_price" json:"newSalePrice"`
+}
+
+// ToPriceChangePreviewResults method, current product prices from price change
+// items next to the changed products, skipping missing products.
+func ToPriceChangePreviewResults(priceChangeItems []models2.PriceChangeItem, products []models2.Product) []PriceChangePreviewResult {
+	priceChangePreviewResults := make([]PriceChangePreviewResult, 0, len(products))
+	for i, product := range products {
+		if product.ID == 0 || i >= len(priceChangeItems) {
+			continue
+		}
+
+		current := priceChangeItems[i].Product
+		priceChangePreviewResults = append(priceChangePreviewResults, PriceChangePreviewResult{
+			ProductID:        current.UUID,
+			ProductName:      current.ProductName,
+			PricingRule:      current.PricingRule,
+			PurchasePrice:    current.PurchasePrice,
+			SalePrice:        current.SalePrice,
+			NewPricingRule:   product.PricingRule,
+			NewPurchasePrice: product.PurchasePrice,
+			NewSalePrice:     product.SalePrice,
+		})
+	}
+
+	return priceChangePreviewResults
+}
@@ -0,0 +1,50 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type PriceHistoryResult struct {
+	UUID                  uuid.UUID       `mapstructure:"uuid" json:"uuid"`
+	ProductID             uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	Source                string          `mapstructure:"source" json:"source"`
+	PricingRule           string          `mapstructure:"pricing_rule" json:"pricingRule"`
+	PurchasePrice         decimal.Decimal `mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice             decimal.Decimal `mapstructure:"sale_price" json:"salePrice"`
+	PreviousPurchasePrice decimal.Decimal `mapstructure:"previous_purchase_price" json:"previousPurchasePrice"`
+	PreviousSalePrice     decimal.Decimal `mapstructure:"previous_sale_price" json:"previousSalePrice"`
+	CreatedAt             string          `mapstructure:"created_at" json:"createdAt"`
+}
+
+func ToPriceHistoryResult(priceHistory *models2.PriceHistory) PriceHistoryResult {
+	if priceHistory != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(priceHistory.CreatedAt)
+		return PriceHistoryResult{
+			UUID:                  priceHistory.UUID,
+			ProductID:             priceHistory.Product.UUID,
+			Source:                priceHistory.Source,
+			PricingRule:           priceHistory.PricingRule,
+			PurchasePrice:         priceHistory.PurchasePrice,
+			SalePrice:             priceHistory.SalePrice,
+			PreviousPurchasePrice: priceHistory.PreviousPurchasePrice,
+			PreviousSalePrice:     priceHistory.PreviousSalePrice,
+			CreatedAt:             createdAt,
+		}
+	}
+
+	return PriceHistoryResult{}
+}
+
+func ToPriceHistoryResults(priceHistories []models2.PriceHistory) []PriceHistoryResult {
+	size := len(priceHistories)
+	priceHistoryResults := make([]PriceHistoryResult, size)
+	for i, priceHistory := range priceHistories {
+		nokocore.KeepVoid(i)
+		priceHistoryResults[i] = ToPriceHistoryResult(&priceHistory)
+	}
+
+	return priceHistoryResults
+}
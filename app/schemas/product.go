@@ -7,6 +7,7 @@ import (
 	"nokowebapi/sqlx"
 	models2 "pharma-cash-go/app/models"
 	utils2 "pharma-cash-go/app/utils"
+	"strings"
 )
 
 type ProductBody struct {
@@ -20,6 +21,9 @@ type ProductBody struct {
 	SupplierDiscount int      `mapstructure:"supplier_discount" json:"supplierDiscount" form:"supplier_discount" validate:"numeric"`
 	VAT              int      `mapstructure:"vat" json:"tax" form:"tax" validate:"numeric"` // tax
 	ProfitMargin     int      `mapstructure:"profit_margin" json:"profitMargin" form:"profit_margin" validate:"numeric"`
+	PricingRule      string   `mapstructure:"pricing_rule" json:"pricingRule" form:"pricing_rule" validate:"ascii,omitempty"`
+	SalePrice        string   `mapstructure:"sale_price" json:"salePrice" form:"sale_price" validate:"decimal,omitempty"` // fixed pricing rule
+	PriceRounding    string   `mapstructure:"price_rounding" json:"priceRounding" form:"price_rounding" validate:"decimal,omitempty"`
 	PackageID        string   `mapstructure:"package_id" json:"packageId" form:"package_id" validate:"uuid,omitempty"`
 	PackageType      string   `mapstructure:"package_type" json:"packageType" form:"package_type" validate:"omitempty"`
 	PackageTotal     int      `mapstructure:"package_total" json:"packageTotal" form:"package_total" validate:"number"`
@@ -29,6 +33,11 @@ type ProductBody struct {
 	UnitExtra        int      `mapstructure:"unit_extra" json:"unitExtra" form:"unit_extra" validate:"number"`
 	Categories       []string `mapstructure:"categories" json:"categories" form:"categories" validate:"ascii,omitempty"`
 	Category         string   `mapstructure:"category" json:"category" form:"category" validate:"ascii,omitempty"`
+	DrugClass        string   `mapstructure:"drug_class" json:"drugClass" form:"drug_class" validate:"ascii,omitempty"`
+	DosageForm       string   `mapstructure:"dosage_form" json:"dosageForm" form:"dosage_form" validate:"omitempty"`
+	Route            string   `mapstructure:"route" json:"route" form:"route" validate:"omitempty"`
+	Manufacturer     string   `mapstructure:"manufacturer" json:"manufacturer" form:"manufacturer" validate:"omitempty"`
+	AuthorizationNo  string   `mapstructure:"authorization_no" json:"authorizationNo" form:"authorization_no" validate:"ascii,omitempty"` // marketing authorisation number
 }
 
 func ToProductModel(product *ProductBody) *models2.Product {
@@ -57,6 +66,23 @@ func ToProductModel(product *ProductBody) *models2.Product {
 		product.PackageTotal += div
 		product.UnitExtra = extra
 
+		pricingRule := strings.ToLower(product.PricingRule)
+		if pricingRule == "" {
+			pricingRule = models2.PricingRuleCostPlus
+		}
+		salePrice := decimal.Zero
+		if product.SalePrice != "" {
+			salePrice = decimal.RequireFromString(product.SalePrice)
+		}
+		priceRounding := decimal.Zero
+		if product.PriceRounding != "" {
+			priceRounding = decimal.RequireFromString(product.PriceRounding)
+		}
+		drugClass := strings.ToLower(strings.TrimSpace(product.DrugClass))
+		if drugClass == "" {
+			drugClass = models2.DrugClassOTC
+		}
+
 		return &models2.Product{
 			Barcode:          product.Barcode,
 			Brand:            product.Brand,
@@ -65,12 +91,21 @@ func ToProductModel(product *ProductBody) *models2.Product {
 			Description:      product.Description,
 			Expires:          sqlx.ParseDateOnlyNotNull(product.Expires),
 			PurchasePrice:    decimal.RequireFromString(product.PurchasePrice),
+			SalePrice:        salePrice,
 			SupplierDiscount: discount,
 			VAT:              vat,
 			ProfitMargin:     margin,
+			PricingRule:      pricingRule,
+			PriceRounding:    priceRounding,
 			PackageTotal:     product.PackageTotal,
 			UnitScale:        product.UnitScale,
 			UnitExtra:        product.UnitExtra,
+			Stock:            product.PackageTotal*product.UnitScale + product.UnitExtra,
+			DrugClass:        drugClass,
+			DosageForm:       strings.TrimSpace(product.DosageForm),
+			Route:            strings.TrimSpace(product.Route),
+			Manufacturer:     strings.TrimSpace(product.Manufacturer),
+			AuthorizationNo:  strings.TrimSpace(product.AuthorizationNo),
 			Categories:       categories,
 		}
 	}
@@ -79,31 +114,44 @@ func ToProductModel(product *ProductBody) *models2.Product {
 }
 
 type ProductResult struct {
-	UUID             uuid.UUID       `mapstructure:"uuid" json:"uuid"`
-	Barcode          string          `mapstructure:"barcode" json:"barcode"`
-	Brand            string          `mapstructure:"brand" json:"brand"`
-	ProductName      string          `mapstructure:"product_name" json:"productName"`
-	Supplier         string          `mapstructure:"supplier" json:"supplier"`
-	Description      string          `mapstructure:"description" json:"description"`
-	Expires          string          `mapstructure:"expires" json:"expires"`
-	PurchasePrice    decimal.Decimal `mapstructure:"purchase_price" json:"purchasePrice"`
-	SalePrice        decimal.Decimal `mapstructure:"sale_price" json:"salePrice"`
-	SupplierDiscount int             `mapstructure:"supplier_discount" json:"supplierDiscount"`
-	VAT              int             `mapstructure:"vat" json:"tax"` // tax
-	ProfitMargin     int             `mapstructure:"profit_margin" json:"profitMargin"`
-	PackageId        uuid.UUID       `mapstructure:"package_id" json:"packageId"`
-	PackageType      string          `mapstructure:"package_type" json:"packageType"`
-	PackageTotal     int             `mapstructure:"package_total" json:"packageTotal"`
-	UnitID           uuid.UUID       `mapstructure:"unit_id" json:"unitId"`
-	UnitType         string          `mapstructure:"unit_type" json:"unitType"`
-	UnitScale        int             `mapstructure:"unit_scale" json:"unitScale"`
-	UnitExtra        int             `mapstructure:"unit_extra" json:"unitExtra"`
-	UnitTotal        int             `mapstructure:"unit_total" json:"unitTotal"`
-	CreatedAt        string          `mapstructure:"created_at" json:"createdAt"`
-	UpdatedAt        string          `mapstructure:"updated_at" json:"updatedAt"`
-	DeletedAt        string          `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
-	Categories       []string        `mapstructure:"categories" json:"categories"`
-	Category         string          `mapstructure:"category" json:"category"`
+	UUID             uuid.UUID                 `mapstructure:"uuid" json:"uuid"`
+	Barcode          string                    `mapstructure:"barcode" json:"barcode"`
+	Brand            string                    `mapstructure:"brand" json:"brand"`
+	ProductName      string                    `mapstructure:"product_name" json:"productName"`
+	Supplier         string                    `mapstructure:"supplier" json:"supplier"`
+	Description      string                    `mapstructure:"description" json:"description"`
+	Expires          string                    `mapstructure:"expires" json:"expires"`
+	PurchasePrice    decimal.Decimal           `mapstructure:"purchase_price" json:"purchasePrice"`
+	SalePrice        decimal.Decimal           `mapstructure:"sale_price" json:"salePrice"`
+	SupplierDiscount int                       `mapstructure:"supplier_discount" json:"supplierDiscount"`
+	VAT              int                       `mapstructure:"vat" json:"tax"` // tax
+	ProfitMargin     int                       `mapstructure:"profit_margin" json:"profitMargin"`
+	PricingRule      string                    `mapstructure:"pricing_rule" json:"pricingRule"`
+	PriceRounding    decimal.Decimal           `mapstructure:"price_rounding" json:"priceRounding"`
+	PackageId        uuid.UUID                 `mapstructure:"package_id" json:"packageId"`
+	PackageType      string                    `mapstructure:"package_type" json:"packageType"`
+	PackageTotal     int                       `mapstructure:"package_total" json:"packageTotal"`
+	UnitID           uuid.UUID                 `mapstructure:"unit_id" json:"unitId"`
+	UnitType         string                    `mapstructure:"unit_type" json:"unitType"`
+	UnitScale        int                       `mapstructure:"unit_scale" json:"unitScale"`
+	UnitExtra        int                       `mapstructure:"unit_extra" json:"unitExtra"`
+	UnitTotal        int                       `mapstructure:"unit_total" json:"unitTotal"`
+	Units            []ProductUnitResult       `mapstructure:"units" json:"units"`
+	StockUnits       []UnitQuantityResult      `mapstructure:"stock_units" json:"stockUnits"`
+	CreatedAt        string                    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt        string                    `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt        string                    `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+	Categories       []string                  `mapstructure:"categories" json:"categories"`
+	Category         string                    `mapstructure:"category" json:"category"`
+	Barcodes         []BarcodeResult           `mapstructure:"barcodes" json:"barcodes"`
+	ImageURL         string                    `mapstructure:"image_url" json:"imageUrl"`
+	ThumbnailURL     string                    `mapstructure:"thumbnail_url" json:"thumbnailUrl"`
+	DrugClass        string                    `mapstructure:"drug_class" json:"drugClass"`
+	DosageForm       string                    `mapstructure:"dosage_form" json:"dosageForm"`
+	Route            string                    `mapstructure:"route" json:"route"`
+	Manufacturer     string                    `mapstructure:"manufacturer" json:"manufacturer"`
+	AuthorizationNo  string                    `mapstructure:"authorization_no" json:"authorizationNo"`
+	Ingredients      []ProductIngredientResult `mapstructure:"ingredients" json:"ingredients"`
 }
 
 func ToProductResult(product *models2.Product) ProductResult {
@@ -127,8 +175,12 @@ func ToProductResult(product *models2.Product) ProductResult {
 		vat := product.VAT * 100
 		margin := product.ProfitMargin * 100
 
-		unitTotal := product.UnitScale * product.PackageTotal
-		unitTotal += product.UnitExtra
+		var imageURL, thumbnailURL string
+		if image := ToPrimaryImage(product.Attachments); image != nil {
+			imageResult := ToProductAttachmentResult(image)
+			imageURL = imageResult.URL
+			thumbnailURL = imageResult.ThumbnailURL
+		}
 
 		return ProductResult{
 			UUID:             product.UUID,
@@ -143,6 +195,8 @@ func ToProductResult(product *models2.Product) ProductResult {
 			SupplierDiscount: int(discount),
 			VAT:              int(vat),
 			ProfitMargin:     int(margin),
+			PricingRule:      product.PricingRule,
+			PriceRounding:    product.PriceRounding,
 			PackageId:        product.Package.UUID,
 			PackageType:      product.Package.PackageType,
 			PackageTotal:     product.PackageTotal,
@@ -150,14 +204,48 @@ func ToProductResult(product *models2.Product) ProductResult {
 			UnitType:         product.Unit.UnitType,
 			UnitScale:        product.UnitScale,
 			UnitExtra:        product.UnitExtra,
-			UnitTotal:        unitTotal,
+			UnitTotal:        product.Stock,
+			Units:            ToProductUnitResults(product.GetUnitLevels()),
+			StockUnits:       ToUnitQuantityResults(product.ToUnitQuantities(product.Stock)),
 			CreatedAt:        createdAt,
 			UpdatedAt:        updatedAt,
 			DeletedAt:        deletedAt,
 			Categories:       categories,
 			Category:         category,
+			Barcodes:         ToBarcodeResults(product.Barcodes, product.Barcode),
+			ImageURL:         imageURL,
+			ThumbnailURL:     thumbnailURL,
+			DrugClass:        product.DrugClass,
+			DosageForm:       product.DosageForm,
+			Route:            product.Route,
+			Manufacturer:     product.Manufacturer,
+			AuthorizationNo:  product.AuthorizationNo,
+			Ingredients:      ToProductIngredientResults(product.Ingredients),
 		}
 	}
 
 	return ProductResult{}
 }
+
+func ToProductResults(products []models2.Product) []ProductResult {
+	size := len(products)
+	productResults := make([]ProductResult, size)
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+		productResults[i] = ToProductResult(&product)
+	}
+
+	return productResults
+}
+
+type ProductSearchResult struct {
+	ProductResult `mapstructure:",squash"`
+	Score         float64 `mapstructure:"score" json:"score"`
+}
+
+func ToProductSearchResult(product *models2.Product, score float64) ProductSearchResult {
+	return ProductSearchResult{
+		ProductResult: ToProductResult(product),
+		Score:         score,
+	}
+}
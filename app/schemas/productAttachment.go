@@ -0,0 +1,79 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	"pharma-cash-go/app/storages"
+)
+
+type ProductAttachmentResult struct {
+	UUID         uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	Kind         string    `mapstructure:"kind" json:"kind"`
+	FileName     string    `mapstructure:"file_name" json:"fileName"`
+	ContentType  string    `mapstructure:"content_type" json:"contentType"`
+	Size         int64     `mapstructure:"size" json:"size"`
+	URL          string    `mapstructure:"url" json:"url"`
+	ThumbnailURL string    `mapstructure:"thumbnail_url" json:"thumbnailUrl"`
+	IsPrimary    bool      `mapstructure:"is_primary" json:"isPrimary"`
+	CreatedAt    string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt    string    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToProductAttachmentResult(attachment *models2.ProductAttachment) ProductAttachmentResult {
+	if attachment != nil {
+		storage := storages.GetStorage()
+		createdAt := nokocore.ToTimeUtcStringISO8601(attachment.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(attachment.UpdatedAt)
+		var thumbnailURL string
+		if attachment.ThumbnailKey != "" {
+			thumbnailURL = storage.URL(attachment.ThumbnailKey)
+		}
+		return ProductAttachmentResult{
+			UUID:         attachment.UUID,
+			Kind:         attachment.Kind,
+			FileName:     attachment.FileName,
+			ContentType:  attachment.ContentType,
+			Size:         attachment.Size,
+			URL:          storage.URL(attachment.StorageKey),
+			ThumbnailURL: thumbnailURL,
+			IsPrimary:    attachment.IsPrimary,
+			CreatedAt:    createdAt,
+			UpdatedAt:    updatedAt,
+		}
+	}
+
+	return ProductAttachmentResult{}
+}
+
+func ToProductAttachmentResults(attachments []models2.ProductAttachment) []ProductAttachmentResult {
+	size := len(attachments)
+	attachmentResults := make([]ProductAttachmentResult, size)
+	for i, attachment := range attachments {
+		nokocore.KeepVoid(i)
+		attachmentResults[i] = ToProductAttachmentResult(&attachment)
+	}
+
+	return attachmentResults
+}
+
+// ToPrimaryImage method, primary image of product attachments or the first image found.
+func ToPrimaryImage(attachments []models2.ProductAttachment) *models2.ProductAttachment {
+	var image *models2.ProductAttachment
+	for i := range attachments {
+		attachment := &attachments[i]
+		if attachment.Kind != models2.AttachmentKindImage {
+			continue
+		}
+
+		if attachment.IsPrimary {
+			return attachment
+		}
+
+		if image == nil {
+			image = attachment
+		}
+	}
+
+	return image
+}
@@ -0,0 +1,120 @@
+package schemas
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type ProductUnitBody struct {
+	UnitID   string `mapstructure:"unit_id" json:"unitId" form:"unit_id" validate:"uuid,omitempty"`
+	UnitType string `mapstructure:"unit_type" json:"unitType" form:"unit_type" validate:"ascii,omitempty"`
+	Factor   int    `mapstructure:"factor" json:"factor" form:"factor" validate:"number,min=1"`
+}
+
+type ProductUnitsBody struct {
+	Units []ProductUnitBody `mapstructure:"units" json:"units" form:"units"`
+}
+
+type UnitQuantityBody struct {
+	UnitID   string `mapstructure:"unit_id" json:"unitId" form:"unit_id" validate:"uuid,omitempty"`
+	UnitType string `mapstructure:"unit_type" json:"unitType" form:"unit_type" validate:"ascii,omitempty"`
+	Quantity int    `mapstructure:"quantity" json:"quantity" form:"quantity" validate:"number,omitempty"`
+}
+
+// ToBaseQuantity method, sums quantities at any unit level into base units.
+func ToBaseQuantity(product *models2.Product, quantities []UnitQuantityBody) (int, error) {
+	var err error
+	var unitID uuid.UUID
+	var level *models2.ProductUnit
+	nokocore.KeepVoid(err, unitID, level)
+
+	total := 0
+	for i, quantity := range quantities {
+		nokocore.KeepVoid(i)
+
+		unitID = uuid.Nil
+		if quantity.UnitID != "" {
+			if unitID, err = uuid.Parse(quantity.UnitID); err != nil {
+				return 0, fmt.Errorf("invalid unit id '%s'", quantity.UnitID)
+			}
+		}
+
+		if level, err = product.GetUnitLevel(unitID, quantity.UnitType); err != nil {
+			return 0, err
+		}
+
+		total += quantity.Quantity * level.Factor
+	}
+
+	return total, nil
+}
+
+// ToUnitTotal method, quantities at any unit level are preferred over
+// package total and unit extra.
+func ToUnitTotal(product *models2.Product, quantities []UnitQuantityBody, packageTotal int, unitExtra int) (int, error) {
+	if len(quantities) > 0 {
+		return ToBaseQuantity(product, quantities)
+	}
+
+	quantity := product.UnitScale * packageTotal
+	quantity += unitExtra
+	return quantity, nil
+}
+
+type ProductUnitResult struct {
+	UUID     uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	UnitID   uuid.UUID `mapstructure:"unit_id" json:"unitId"`
+	UnitType string    `mapstructure:"unit_type" json:"unitType"`
+	Level    int       `mapstructure:"level" json:"level"`
+	Factor   int       `mapstructure:"factor" json:"factor"`
+}
+
+func ToProductUnitResult(productUnit *models2.ProductUnit) ProductUnitResult {
+	if productUnit != nil {
+		return ProductUnitResult{
+			UUID:     productUnit.UUID,
+			UnitID:   productUnit.Unit.UUID,
+			UnitType: productUnit.Unit.UnitType,
+			Level:    productUnit.Level,
+			Factor:   productUnit.Factor,
+		}
+	}
+
+	return ProductUnitResult{}
+}
+
+func ToProductUnitResults(productUnits []models2.ProductUnit) []ProductUnitResult {
+	size := len(productUnits)
+	productUnitResults := make([]ProductUnitResult, size)
+	for i, productUnit := range productUnits {
+		nokocore.KeepVoid(i)
+		productUnitResults[i] = ToProductUnitResult(&productUnit)
+	}
+
+	return productUnitResults
+}
+
+type UnitQuantityResult struct {
+	UnitID   uuid.UUID `mapstructure:"unit_id" json:"unitId"`
+	UnitType string    `mapstructure:"unit_type" json:"unitType"`
+	Level    int       `mapstructure:"level" json:"level"`
+	Quantity int       `mapstructure:"quantity" json:"quantity"`
+}
+
+func ToUnitQuantityResults(quantities []models2.UnitQuantity) []UnitQuantityResult {
+	size := len(quantities)
+	unitQuantityResults := make([]UnitQuantityResult, size)
+	for i, quantity := range quantities {
+		nokocore.KeepVoid(i)
+		unitQuantityResults[i] = UnitQuantityResult{
+			UnitID:   quantity.Unit.UUID,
+			UnitType: quantity.Unit.UnitType,
+			Level:    quantity.Level,
+			Quantity: quantity.Quantity,
+		}
+	}
+
+	return unitQuantityResults
+}
@@ -4,19 +4,48 @@ import (
 	"nokowebapi/nokocore"
 	"nokowebapi/sqlx"
 	models2 "pharma-cash-go/app/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
+type StockOpnameCancelBody struct {
+	Reason string `mapstructure:"reason" json:"reason" form:"reason" validate:"ascii"`
+}
+
+// CartVerificationOpnameScanBody, quantity is counted at the barcode level, a
+// single package or unit when empty.
+type CartVerificationOpnameScanBody struct {
+	Barcode  string `mapstructure:"barcode" json:"barcode" form:"barcode" validate:"ascii"`
+	Quantity int    `mapstructure:"quantity" json:"quantity" form:"quantity" validate:"number,omitempty"`
+}
+
+type StockOpnameStatusBody struct {
+	Comment string `mapstructure:"comment" json:"comment" form:"comment" validate:"ascii,omitempty"`
+}
+
 type StockOpnameBody struct {
 	UnitType string `mapstructure:"unit_type" json:"unitType" form:"unit_type" validate:"ascii"`
 }
 
 type CartVerificationOpnameBody struct {
 	NotMatchReason   string `mapstructure:"not_match_reason" json:"notMatchReason" form:"not_match_reason" validate:"ascii"`
-	RealPackageTotal int    `mapstructure:"real_package_total" json:"realPackageTotal" form:"real_package_total" validate:"number"`
-	RealUnitExtra    int    `mapstructure:"real_unit_extra" json:"realUnitExtra" form:"real_unit_extra" validate:"number"`
+	RealPackageTotal int    `mapstructure:"real_package_total" json:"realPackageTotal" form:"real_package_total" validate:"number,omitempty"`
+	RealUnitExtra    int    `mapstructure:"real_unit_extra" json:"realUnitExtra" form:"real_unit_extra" validate:"number,omitempty"`
+
+	// real quantities at any unit level, replaces real package total and real unit extra
+	RealQuantities []UnitQuantityBody `mapstructure:"real_quantities" json:"realQuantities" form:"real_quantities" validate:"omitempty"`
+}
+
+// ToRealQuantity method, normalises counted quantities into base units.
+func ToRealQuantity(cartVerificationOpname *CartVerificationOpnameBody, product *models2.Product) (int, error) {
+	if cartVerificationOpname != nil && product != nil {
+		return ToUnitTotal(product, cartVerificationOpname.RealQuantities, cartVerificationOpname.RealPackageTotal, cartVerificationOpname.RealUnitExtra)
+	}
+
+	return 0, nil
 }
 
 func ToStockOpnameModel(unit *StockOpnameBody) *models2.Unit {
@@ -39,6 +68,89 @@ type StockOpnameResultCreate struct {
 	DeletedAt  string            `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
 }
 
+type StockOpnameResult struct {
+	UUID         uuid.UUID         `mapstructure:"uuid" json:"uuid"`
+	Location     LocationResult    `mapstructure:"location" json:"location"`
+	Category     string            `mapstructure:"category" json:"category"`
+	SubmitedAt   sqlx.NullDateOnly `mapstructure:"submited_at" json:"submitedAt"`
+	IsVerified   bool              `mapstructure:"is_verified" json:"isVerified"`
+	IsBlind      bool              `mapstructure:"is_blind" json:"isBlind"`
+	Status       string            `mapstructure:"status" json:"status"`
+	IsCancelled  bool              `mapstructure:"is_cancelled" json:"isCancelled"`
+	CancelledAt  string            `mapstructure:"cancelled_at" json:"cancelledAt,omitempty"`
+	CancelReason string            `mapstructure:"cancel_reason" json:"cancelReason,omitempty"`
+	Scope        StockOpnameScope  `mapstructure:"scope" json:"scope"`
+	CreatedBy    uuid.UUID         `mapstructure:"created_by" json:"createdBy"`
+	CreatedAt    string            `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt    string            `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt    string            `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+}
+
+type StockOpnameStatusChangeResult struct {
+	FromStatus string    `mapstructure:"from_status" json:"fromStatus"`
+	ToStatus   string    `mapstructure:"to_status" json:"toStatus"`
+	Comment    string    `mapstructure:"comment" json:"comment"`
+	CreatedBy  uuid.UUID `mapstructure:"created_by" json:"createdBy"`
+	CreatedAt  string    `mapstructure:"created_at" json:"createdAt"`
+}
+
+func ToStockOpnameStatusChangeResult(stockOpnameStatusChange *models2.StockOpnameStatusChange) StockOpnameStatusChangeResult {
+	if stockOpnameStatusChange != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(stockOpnameStatusChange.CreatedAt)
+		return StockOpnameStatusChangeResult{
+			FromStatus: stockOpnameStatusChange.FromStatus,
+			ToStatus:   stockOpnameStatusChange.ToStatus,
+			Comment:    stockOpnameStatusChange.Comment,
+			CreatedBy:  stockOpnameStatusChange.User.UUID,
+			CreatedAt:  createdAt,
+		}
+	}
+
+	return StockOpnameStatusChangeResult{}
+}
+
+func ToStockOpnameStatusChangeResults(stockOpnameStatusChanges []models2.StockOpnameStatusChange) []StockOpnameStatusChangeResult {
+	size := len(stockOpnameStatusChanges)
+	stockOpnameStatusChangeResults := make([]StockOpnameStatusChangeResult, size)
+	for i, stockOpnameStatusChange := range stockOpnameStatusChanges {
+		nokocore.KeepVoid(i)
+		stockOpnameStatusChangeResults[i] = ToStockOpnameStatusChangeResult(&stockOpnameStatusChange)
+	}
+
+	return stockOpnameStatusChangeResults
+}
+
+type StockOpnameScope struct {
+	Categories     []string `mapstructure:"categories" json:"categories"`
+	Suppliers      []string `mapstructure:"suppliers" json:"suppliers"`
+	StockedOnly    bool     `mapstructure:"stocked_only" json:"stockedOnly"`
+	NotCountedDays int      `mapstructure:"not_counted_days" json:"notCountedDays"`
+	Class          string   `mapstructure:"class" json:"class"`
+}
+
+// ToStockOpnameScope method, filters of the counted products, empty lists when not filtered.
+func ToStockOpnameScope(stockOpname *models2.StockOpname) StockOpnameScope {
+	if stockOpname != nil {
+		categories := []string{}
+		if stockOpname.Categories != "" {
+			categories = strings.Split(stockOpname.Categories, ",")
+		}
+		suppliers := []string{}
+		if stockOpname.Suppliers != "" {
+			suppliers = strings.Split(stockOpname.Suppliers, ",")
+		}
+		return StockOpnameScope{
+			Categories:     categories,
+			Suppliers:      suppliers,
+			StockedOnly:    stockOpname.StockedOnly,
+			NotCountedDays: stockOpname.NotCountedDays,
+			Class:          stockOpname.Class,
+		}
+	}
+
+	return StockOpnameScope{}
+}
+
 type StockOpnameResultGet struct {
 	ProductUUID       uuid.UUID  `json:"productId"`
 	Barcode           string     `json:"barcode"`
@@ -59,22 +171,69 @@ type StockOpnameResultGet struct {
 	UpdatedAt         time.Time  `json:"updatedAt"`
 }
 
+// StockOpnameResultGetBlind, counted product of a blind count, system
+// quantities and matches are left out.
+type StockOpnameResultGetBlind struct {
+	ProductUUID       uuid.UUID  `json:"productId"`
+	Barcode           string     `json:"barcode"`
+	ProductName       string     `json:"productName"`
+	Brand             string     `json:"brand"`
+	PackageUUID       uuid.UUID  `json:"packageId"`
+	PackageType       string     `json:"packageType"`
+	UnitUUID          uuid.UUID  `json:"unitId"`
+	UnitType          string     `json:"unitType"`
+	UnitScale         int        `json:"unitScale"`
+	IsCounted         bool       `json:"isCounted"`
+	CartStockOpnameId *uuid.UUID `json:"cartStockOpnameId"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+func ToStockOpnameResultGetBlinds(stockOpnamesResultGet []StockOpnameResultGet) []StockOpnameResultGetBlind {
+	size := len(stockOpnamesResultGet)
+	stockOpnameResultGetBlinds := make([]StockOpnameResultGetBlind, size)
+	for i, stockOpnameResultGet := range stockOpnamesResultGet {
+		stockOpnameResultGetBlinds[i] = StockOpnameResultGetBlind{
+			ProductUUID:       stockOpnameResultGet.ProductUUID,
+			Barcode:           stockOpnameResultGet.Barcode,
+			ProductName:       stockOpnameResultGet.ProductName,
+			Brand:             stockOpnameResultGet.Brand,
+			PackageUUID:       stockOpnameResultGet.PackageUUID,
+			PackageType:       stockOpnameResultGet.PackageType,
+			UnitUUID:          stockOpnameResultGet.UnitUUID,
+			UnitType:          stockOpnameResultGet.UnitType,
+			UnitScale:         stockOpnameResultGet.UnitScale,
+			IsCounted:         stockOpnameResultGet.CartStockOpnameId != nil,
+			CartStockOpnameId: stockOpnameResultGet.CartStockOpnameId,
+			CreatedAt:         stockOpnameResultGet.CreatedAt,
+			UpdatedAt:         stockOpnameResultGet.UpdatedAt,
+		}
+	}
+
+	return stockOpnameResultGetBlinds
+}
+
 type StockOpnameResultGetVerify struct {
-	ProductUUID        uuid.UUID `json:"productId"`
-	Barcode            string    `json:"barcode"`
-	ProductName        string    `json:"productName"`
-	Brand              string    `json:"brand"`
-	SystemPackageTotal int       `json:"systemPackageTotal"`
-	SystemUnitScale    int       `json:"systemUnitScale"`
-	SystemUnitExtra    int       `json:"systemUnitExtra"`
-	SystemUnitTotal    int       `json:"systemUnitTotal"`
-	IsMatch            bool      `json:"isMatch"`
-	NotMatchReason     string    `json:"notMatchReason"`
-	RealPackageTotal   int       `json:"realPackageTotal"`
-	RealUnitExtra      int       `json:"realUnitExtra"`
-	RealUnitTotal      int       `json:"realUnitTotal"`
-	CreatedAt          time.Time `json:"createdAt"`
-	UpdatedAt          time.Time `json:"updatedAt"`
+	ProductID          uint            `json:"-"`
+	ProductUUID        uuid.UUID       `json:"productId"`
+	Barcode            string          `json:"barcode"`
+	ProductName        string          `json:"productName"`
+	Brand              string          `json:"brand"`
+	ProductStock       int             `json:"productStock"` // every location
+	SystemPackageTotal int             `json:"systemPackageTotal"`
+	SystemUnitScale    int             `json:"systemUnitScale"`
+	SystemUnitExtra    int             `json:"systemUnitExtra"`
+	SystemUnitTotal    int             `json:"systemUnitTotal"`
+	IsCounted          bool            `json:"-"`
+	IsMatch            bool            `json:"isMatch"`
+	NotMatchReason     string          `json:"notMatchReason"`
+	RealPackageTotal   int             `json:"realPackageTotal"`
+	RealUnitExtra      int             `json:"realUnitExtra"`
+	RealUnitTotal      int             `json:"realUnitTotal"`
+	PurchasePrice      decimal.Decimal `json:"purchasePrice"`
+	SalePrice          decimal.Decimal `json:"salePrice"`
+	CreatedAt          time.Time       `json:"createdAt"`
+	UpdatedAt          time.Time       `json:"updatedAt"`
 }
 
 type CartVerificationOpnameResult struct {
@@ -93,11 +252,27 @@ type CartVerificationOpnameResult struct {
 	UpdatedAt string    `mapstructure:"updated_at" json:"updatedAt"`
 	DeletedAt string    `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
 }
+
+// CartVerificationOpnameBlindResult, entry of a blind count, system quantities
+// and matches are left out.
+type CartVerificationOpnameBlindResult struct {
+	CartVerificationOpnameId uuid.UUID     `mapstructure:"cart_verification_opname_id" json:"cartVerificationOpnameId"`
+	ProductId                uuid.UUID     `mapstructure:"product_id" json:"productId"`
+	UnitScale                int           `mapstructure:"unit_scale" json:"unitScale"`
+	Warehouse                WarehouseInfo `mapstructure:"warehouse" json:"warehouse"`
+
+	CreatedBy uuid.UUID `mapstructure:"created_by" json:"createdBy"`
+	CreatedAt string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt string    `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt string    `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+}
+
 type WarehouseInfo struct {
-	RealPackageTotal int    `mapstructure:"real_package_total" json:"realPackageTotal"`
-	RealUnitExtra    int    `mapstructure:"real_unit_extra" json:"realUnitExtra"`
-	RealUnitTotal    int    `mapstructure:"real_unit_total" json:"realUnitTotal"`
-	NotMatchReason   string `mapstructure:"not_match_reason" json:"notMatchReason"`
+	RealPackageTotal int                  `mapstructure:"real_package_total" json:"realPackageTotal"`
+	RealUnitExtra    int                  `mapstructure:"real_unit_extra" json:"realUnitExtra"`
+	RealUnitTotal    int                  `mapstructure:"real_unit_total" json:"realUnitTotal"`
+	RealUnits        []UnitQuantityResult `mapstructure:"real_units" json:"realUnits"`
+	NotMatchReason   string               `mapstructure:"not_match_reason" json:"notMatchReason"`
 }
 
 type StockOpnameResultDates struct {
@@ -125,6 +300,46 @@ func ToStockOpnameResultCreate(stockOpname *models2.StockOpname) StockOpnameResu
 	return StockOpnameResultCreate{}
 }
 
+// ToStockOpnameResult method, location and category are looked up by the caller,
+// category is empty when every category is counted.
+func ToStockOpnameResult(stockOpname *models2.StockOpname, location *models2.Location, category *models2.Category) StockOpnameResult {
+	if stockOpname != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(stockOpname.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(stockOpname.UpdatedAt)
+		var deletedAt string
+		if stockOpname.DeletedAt.Valid {
+			deletedAt = nokocore.ToTimeUtcStringISO8601(stockOpname.DeletedAt.Time)
+		}
+		var cancelledAt string
+		if stockOpname.CancelledAt.Valid {
+			cancelledAt = nokocore.ToTimeUtcStringISO8601(stockOpname.CancelledAt.Time)
+		}
+		var categoryName string
+		if category != nil {
+			categoryName = category.CategoryName
+		}
+		return StockOpnameResult{
+			UUID:         stockOpname.UUID,
+			Location:     ToLocationResult(location),
+			Category:     categoryName,
+			SubmitedAt:   stockOpname.SubmitedAt,
+			IsVerified:   stockOpname.IsVerified,
+			IsBlind:      stockOpname.IsBlind,
+			Status:       stockOpname.Status,
+			IsCancelled:  stockOpname.IsCancelled,
+			CancelledAt:  cancelledAt,
+			CancelReason: stockOpname.CancelReason,
+			Scope:        ToStockOpnameScope(stockOpname),
+			CreatedBy:    stockOpname.User.UUID,
+			CreatedAt:    createdAt,
+			UpdatedAt:    updatedAt,
+			DeletedAt:    deletedAt,
+		}
+	}
+
+	return StockOpnameResult{}
+}
+
 func ToCartVerificationOpnameResult(cartVerificationOpname *models2.CartVerificationOpname) CartVerificationOpnameResult {
 	if cartVerificationOpname != nil {
 		createdAt := nokocore.ToTimeUtcStringISO8601(cartVerificationOpname.CreatedAt)
@@ -141,11 +356,12 @@ func ToCartVerificationOpnameResult(cartVerificationOpname *models2.CartVerifica
 			PackageTotal:             cartVerificationOpname.Product.PackageTotal,
 			UnitScale:                cartVerificationOpname.Product.UnitScale,
 			UnitExtra:                cartVerificationOpname.Product.UnitExtra,
-			UnitTotal:                (cartVerificationOpname.Product.PackageTotal * cartVerificationOpname.Product.UnitScale) + cartVerificationOpname.Product.UnitExtra,
+			UnitTotal:                cartVerificationOpname.Product.Stock,
 			Warehouse: WarehouseInfo{
 				RealPackageTotal: cartVerificationOpname.RealPackageTotal,
 				RealUnitExtra:    cartVerificationOpname.RealUnitExtra,
-				RealUnitTotal:    (cartVerificationOpname.RealPackageTotal * cartVerificationOpname.Product.UnitScale) + cartVerificationOpname.RealUnitExtra,
+				RealUnitTotal:    cartVerificationOpname.RealQuantity,
+				RealUnits:        ToUnitQuantityResults(cartVerificationOpname.Product.ToUnitQuantities(cartVerificationOpname.RealQuantity)),
 				NotMatchReason:   cartVerificationOpname.NotMatchReason,
 			},
 			CreatedBy: cartVerificationOpname.User.UUID,
@@ -157,3 +373,43 @@ func ToCartVerificationOpnameResult(cartVerificationOpname *models2.CartVerifica
 
 	return CartVerificationOpnameResult{}
 }
+
+func ToCartVerificationOpnameBlindResult(cartVerificationOpname *models2.CartVerificationOpname) CartVerificationOpnameBlindResult {
+	if cartVerificationOpname != nil {
+		cartVerificationOpnameResult := ToCartVerificationOpnameResult(cartVerificationOpname)
+		return CartVerificationOpnameBlindResult{
+			CartVerificationOpnameId: cartVerificationOpnameResult.CartVerificationOpnameId,
+			ProductId:                cartVerificationOpnameResult.ProductId,
+			UnitScale:                cartVerificationOpnameResult.UnitScale,
+			Warehouse:                cartVerificationOpnameResult.Warehouse,
+			CreatedBy:                cartVerificationOpnameResult.CreatedBy,
+			CreatedAt:                cartVerificationOpnameResult.CreatedAt,
+			UpdatedAt:                cartVerificationOpnameResult.UpdatedAt,
+			DeletedAt:                cartVerificationOpnameResult.DeletedAt,
+		}
+	}
+
+	return CartVerificationOpnameBlindResult{}
+}
+
+func ToCartVerificationOpnameBlindResults(cartVerificationOpnames []models2.CartVerificationOpname) []CartVerificationOpnameBlindResult {
+	size := len(cartVerificationOpnames)
+	cartVerificationOpnameBlindResults := make([]CartVerificationOpnameBlindResult, size)
+	for i, cartVerificationOpname := range cartVerificationOpnames {
+		nokocore.KeepVoid(i)
+		cartVerificationOpnameBlindResults[i] = ToCartVerificationOpnameBlindResult(&cartVerificationOpname)
+	}
+
+	return cartVerificationOpnameBlindResults
+}
+
+func ToCartVerificationOpnameResults(cartVerificationOpnames []models2.CartVerificationOpname) []CartVerificationOpnameResult {
+	size := len(cartVerificationOpnames)
+	cartVerificationOpnameResults := make([]CartVerificationOpnameResult, size)
+	for i, cartVerificationOpname := range cartVerificationOpnames {
+		nokocore.KeepVoid(i)
+		cartVerificationOpnameResults[i] = ToCartVerificationOpnameResult(&cartVerificationOpname)
+	}
+
+	return cartVerificationOpnameResults
+}
@@ -0,0 +1,115 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type StockTransferItemBody struct {
+	ProductID    string             `mapstructure:"product_id" json:"productId" form:"product_id" validate:"uuid"`
+	PackageTotal int                `mapstructure:"package_total" json:"packageTotal" form:"package_total" validate:"number,omitempty"`
+	UnitExtra    int                `mapstructure:"unit_extra" json:"unitExtra" form:"unit_extra" validate:"number,omitempty"`
+	Quantities   []UnitQuantityBody `mapstructure:"quantities" json:"quantities" form:"quantities" validate:"omitempty"`
+}
+
+type StockTransferBody struct {
+	FromLocationID string                  `mapstructure:"from_location_id" json:"fromLocationId" form:"from_location_id" validate:"uuid"`
+	ToLocationID   string                  `mapstructure:"to_location_id" json:"toLocationId" form:"to_location_id" validate:"uuid"`
+	Note           string                  `mapstructure:"note" json:"note" form:"note" validate:"omitempty"`
+	Items          []StockTransferItemBody `mapstructure:"items" json:"items" form:"items"`
+}
+
+type StockTransferItemResult struct {
+	UUID        uuid.UUID            `mapstructure:"uuid" json:"uuid"`
+	ProductID   uuid.UUID            `mapstructure:"product_id" json:"productId"`
+	ProductName string               `mapstructure:"product_name" json:"productName"`
+	Quantity    int                  `mapstructure:"quantity" json:"quantity"`
+	Units       []UnitQuantityResult `mapstructure:"units" json:"units"`
+}
+
+func ToStockTransferItemResult(stockTransferItem *models2.StockTransferItem) StockTransferItemResult {
+	if stockTransferItem != nil {
+		return StockTransferItemResult{
+			UUID:        stockTransferItem.UUID,
+			ProductID:   stockTransferItem.Product.UUID,
+			ProductName: stockTransferItem.Product.ProductName,
+			Quantity:    stockTransferItem.Quantity,
+			Units:       ToUnitQuantityResults(stockTransferItem.Product.ToUnitQuantities(stockTransferItem.Quantity)),
+		}
+	}
+
+	return StockTransferItemResult{}
+}
+
+func ToStockTransferItemResults(stockTransferItems []models2.StockTransferItem) []StockTransferItemResult {
+	size := len(stockTransferItems)
+	stockTransferItemResults := make([]StockTransferItemResult, size)
+	for i, stockTransferItem := range stockTransferItems {
+		nokocore.KeepVoid(i)
+		stockTransferItemResults[i] = ToStockTransferItemResult(&stockTransferItem)
+	}
+
+	return stockTransferItemResults
+}
+
+type StockTransferResult struct {
+	UUID         uuid.UUID                 `mapstructure:"uuid" json:"uuid"`
+	FromLocation LocationResult            `mapstructure:"from_location" json:"fromLocation"`
+	ToLocation   LocationResult            `mapstructure:"to_location" json:"toLocation"`
+	Status       string                    `mapstructure:"status" json:"status"`
+	Note         string                    `mapstructure:"note" json:"note"`
+	CreatedBy    uuid.UUID                 `mapstructure:"created_by" json:"createdBy"`
+	Items        []StockTransferItemResult `mapstructure:"items" json:"items"`
+	SentAt       string                    `mapstructure:"sent_at" json:"sentAt,omitempty"`
+	ReceivedAt   string                    `mapstructure:"received_at" json:"receivedAt,omitempty"`
+	CancelledAt  string                    `mapstructure:"cancelled_at" json:"cancelledAt,omitempty"`
+	CreatedAt    string                    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt    string                    `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToStockTransferResult(stockTransfer *models2.StockTransfer) StockTransferResult {
+	if stockTransfer != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(stockTransfer.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(stockTransfer.UpdatedAt)
+		var sentAt string
+		var receivedAt string
+		var cancelledAt string
+		if stockTransfer.SentAt.Valid {
+			sentAt = nokocore.ToTimeUtcStringISO8601(stockTransfer.SentAt.Time)
+		}
+		if stockTransfer.ReceivedAt.Valid {
+			receivedAt = nokocore.ToTimeUtcStringISO8601(stockTransfer.ReceivedAt.Time)
+		}
+		if stockTransfer.CancelledAt.Valid {
+			cancelledAt = nokocore.ToTimeUtcStringISO8601(stockTransfer.CancelledAt.Time)
+		}
+		return StockTransferResult{
+			UUID:         stockTransfer.UUID,
+			FromLocation: ToLocationResult(&stockTransfer.FromLocation),
+			ToLocation:   ToLocationResult(&stockTransfer.ToLocation),
+			Status:       stockTransfer.Status,
+			Note:         stockTransfer.Note,
+			CreatedBy:    stockTransfer.User.UUID,
+			Items:        ToStockTransferItemResults(stockTransfer.Items),
+			SentAt:       sentAt,
+			ReceivedAt:   receivedAt,
+			CancelledAt:  cancelledAt,
+			CreatedAt:    createdAt,
+			UpdatedAt:    updatedAt,
+		}
+	}
+
+	return StockTransferResult{}
+}
+
+func ToStockTransferResults(stockTransfers []models2.StockTransfer) []StockTransferResult {
+	size := len(stockTransfers)
+	stockTransferResults := make([]StockTransferResult, size)
+	for i, stockTransfer := range stockTransfers {
+		nokocore.KeepVoid(i)
+		stockTransferResults[i] = ToStockTransferResult(&stockTransfer)
+	}
+
+	return stockTransferResults
+}
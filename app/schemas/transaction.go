@@ -24,15 +24,21 @@ func ToTransactionModel(transaction *TransactionBody) *models2.Transaction {
 	return nil
 }
 
+type TransactionAcknowledgeBody struct {
+	Note string `mapstructure:"note" json:"note" form:"note" validate:"omitempty"`
+}
+
 type TransactionResult struct {
-	UUID      uuid.UUID       `mapstructure:"uuid" json:"uuid"`
-	Total     decimal.Decimal `mapstructure:"total" json:"total"`
-	Pay       decimal.Decimal `mapstructure:"pay" json:"pay"`
-	Exchange  decimal.Decimal `mapstructure:"exchange" json:"exchange"`
-	Verified  bool            `mapstructure:"verified" json:"verified"`
-	CreatedAt string          `mapstructure:"created_at" json:"createdAt"`
-	UpdatedAt string          `mapstructure:"updated_at" json:"updatedAt"`
-	DeletedAt string          `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+	UUID            uuid.UUID       `mapstructure:"uuid" json:"uuid"`
+	Total           decimal.Decimal `mapstructure:"total" json:"total"`
+	Pay             decimal.Decimal `mapstructure:"pay" json:"pay"`
+	Exchange        decimal.Decimal `mapstructure:"exchange" json:"exchange"`
+	Verified        bool            `mapstructure:"verified" json:"verified"`
+	AcknowledgedAt  string          `mapstructure:"acknowledged_at" json:"acknowledgedAt,omitempty"`
+	AcknowledgeNote string          `mapstructure:"acknowledge_note" json:"acknowledgeNote,omitempty"`
+	CreatedAt       string          `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt       string          `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt       string          `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
 }
 
 func ToTransactionResult(transaction *models2.Transaction) TransactionResult {
@@ -43,15 +49,21 @@ func ToTransactionResult(transaction *models2.Transaction) TransactionResult {
 		if transaction.DeletedAt.Valid {
 			deletedAt = nokocore.ToTimeUtcStringISO8601(transaction.DeletedAt.Time)
 		}
+		var acknowledgedAt string
+		if transaction.AcknowledgedAt.Valid {
+			acknowledgedAt = nokocore.ToTimeUtcStringISO8601(transaction.AcknowledgedAt.Time)
+		}
 		return TransactionResult{
-			UUID:      transaction.UUID,
-			Total:     transaction.Total,
-			Pay:       transaction.Pay,
-			Exchange:  transaction.Exchange,
-			Verified:  transaction.Verified,
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
-			DeletedAt: deletedAt,
+			UUID:            transaction.UUID,
+			Total:           transaction.Total,
+			Pay:             transaction.Pay,
+			Exchange:        transaction.Exchange,
+			Verified:        transaction.Verified,
+			AcknowledgedAt:  acknowledgedAt,
+			AcknowledgeNote: transaction.AcknowledgeNote,
+			CreatedAt:       createdAt,
+			UpdatedAt:       updatedAt,
+			DeletedAt:       deletedAt,
 		}
 	}
 
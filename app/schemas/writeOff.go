@@ -0,0 +1,127 @@
+package schemas
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+)
+
+type QuarantineBody struct {
+	ProductIDs []string `mapstructure:"product_ids" json:"productIds" form:"product_ids" validate:"omitempty"` // every expired product when empty
+	Note       string   `mapstructure:"note" json:"note" form:"note" validate:"omitempty"`
+}
+
+type WriteOffItemResult struct {
+	UUID        uuid.UUID       `mapstructure:"uuid" json:"uuid"`
+	ProductID   uuid.UUID       `mapstructure:"product_id" json:"productId"`
+	ProductName string          `mapstructure:"product_name" json:"productName"`
+	Quantity    int             `mapstructure:"quantity" json:"quantity"`
+	Expires     string          `mapstructure:"expires" json:"expires"`
+	TotalCost   decimal.Decimal `mapstructure:"total_cost" json:"totalCost"`
+}
+
+func ToWriteOffItemResult(writeOffItem *models2.WriteOffItem) WriteOffItemResult {
+	if writeOffItem != nil {
+		return WriteOffItemResult{
+			UUID:        writeOffItem.UUID,
+			ProductID:   writeOffItem.Product.UUID,
+			ProductName: writeOffItem.Product.ProductName,
+			Quantity:    writeOffItem.Quantity,
+			Expires:     writeOffItem.Expires.Format(nokocore.DateOnlyFormat),
+			TotalCost:   writeOffItem.TotalCost,
+		}
+	}
+
+	return WriteOffItemResult{}
+}
+
+func ToWriteOffItemResults(writeOffItems []models2.WriteOffItem) []WriteOffItemResult {
+	size := len(writeOffItems)
+	writeOffItemResults := make([]WriteOffItemResult, size)
+	for i, writeOffItem := range writeOffItems {
+		nokocore.KeepVoid(i)
+		writeOffItemResults[i] = ToWriteOffItemResult(&writeOffItem)
+	}
+
+	return writeOffItemResults
+}
+
+type WriteOffResult struct {
+	UUID      uuid.UUID            `mapstructure:"uuid" json:"uuid"`
+	Reason    string               `mapstructure:"reason" json:"reason"`
+	Note      string               `mapstructure:"note" json:"note"`
+	TotalCost decimal.Decimal      `mapstructure:"total_cost" json:"totalCost"`
+	CreatedBy uuid.UUID            `mapstructure:"created_by" json:"createdBy"`
+	Items     []WriteOffItemResult `mapstructure:"items" json:"items"`
+	CreatedAt string               `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt string               `mapstructure:"updated_at" json:"updatedAt"`
+}
+
+func ToWriteOffResult(writeOff *models2.WriteOff) WriteOffResult {
+	if writeOff != nil {
+		createdAt := nokocore.ToTimeUtcStringISO8601(writeOff.CreatedAt)
+		updatedAt := nokocore.ToTimeUtcStringISO8601(writeOff.UpdatedAt)
+		return WriteOffResult{
+			UUID:      writeOff.UUID,
+			Reason:    writeOff.Reason,
+			Note:      writeOff.Note,
+			TotalCost: writeOff.TotalCost,
+			CreatedBy: writeOff.User.UUID,
+			Items:     ToWriteOffItemResults(writeOff.Items),
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	return WriteOffResult{}
+}
+
+func ToWriteOffResults(writeOffs []models2.WriteOff) []WriteOffResult {
+	size := len(writeOffs)
+	writeOffResults := make([]WriteOffResult, size)
+	for i, writeOff := range writeOffs {
+		nokocore.KeepVoid(i)
+		writeOffResults[i] = ToWriteOffResult(&writeOff)
+	}
+
+	return writeOffResults
+}
+
+type ExpiryAlertResult struct {
+	UUID        uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	ProductID   uuid.UUID `mapstructure:"product_id" json:"productId"`
+	ProductName string    `mapstructure:"product_name" json:"productName"`
+	Expires     string    `mapstructure:"expires" json:"expires"`
+	WindowDays  int       `mapstructure:"window_days" json:"windowDays"`
+	Quantity    int       `mapstructure:"quantity" json:"quantity"`
+	FlaggedAt   string    `mapstructure:"flagged_at" json:"flaggedAt"`
+}
+
+func ToExpiryAlertResult(expiryAlert *models2.ExpiryAlert) ExpiryAlertResult {
+	if expiryAlert != nil {
+		flaggedAt := nokocore.ToTimeUtcStringISO8601(expiryAlert.FlaggedAt)
+		return ExpiryAlertResult{
+			UUID:        expiryAlert.UUID,
+			ProductID:   expiryAlert.Product.UUID,
+			ProductName: expiryAlert.Product.ProductName,
+			Expires:     expiryAlert.Expires.Format(nokocore.DateOnlyFormat),
+			WindowDays:  expiryAlert.WindowDays,
+			Quantity:    expiryAlert.Quantity,
+			FlaggedAt:   flaggedAt,
+		}
+	}
+
+	return ExpiryAlertResult{}
+}
+
+func ToExpiryAlertResults(expiryAlerts []models2.ExpiryAlert) []ExpiryAlertResult {
+	size := len(expiryAlerts)
+	expiryAlertResults := make([]ExpiryAlertResult, size)
+	for i, expiryAlert := range expiryAlerts {
+		nokocore.KeepVoid(i)
+		expiryAlertResults[i] = ToExpiryAlertResult(&expiryAlert)
+	}
+
+	return expiryAlertResults
+}
@@ -0,0 +1,78 @@
+package sessions
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"nokowebapi/apis/models"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"time"
+)
+
+const DefaultPurgeInterval = time.Hour
+const DefaultPurgeLimit = 1000
+
+// GetPurgeInterval method, session purge interval from 'jwt' config.
+func GetPurgeInterval() time.Duration {
+	config := globals.GetJwtConfig()
+	if interval, err := time.ParseDuration(config.PurgeInterval); err == nil && interval > 0 {
+		return interval
+	}
+	return DefaultPurgeInterval
+}
+
+// GetPurgeLimit method, max sessions removed on each run.
+func GetPurgeLimit() int {
+	config := globals.GetJwtConfig()
+	if config.PurgeLimit > 0 {
+		return config.PurgeLimit
+	}
+	return DefaultPurgeLimit
+}
+
+// Purge method, removes expired, idle, logged out and revoked sessions.
+func Purge(DB *gorm.DB, now time.Time, idleTimeout time.Duration, limit int) (int64, error) {
+	var err error
+	var sessionIDs []uint
+	nokocore.KeepVoid(err, sessionIDs)
+
+	query := DB.Unscoped().Model(&models.Session{}).
+		Where("expires <= ? OR deleted_at IS NOT NULL", now)
+
+	if idleTimeout > 0 {
+		query = query.Or("updated_at <= ?", now.Add(-idleTimeout))
+	}
+
+	if err = query.Order("id ASC").Limit(limit).Pluck("id", &sessionIDs).Error; err != nil {
+		return 0, err
+	}
+
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	tx := DB.Unscoped().Where("id IN ?", sessionIDs).Delete(&models.Session{})
+	if err = tx.Error; err != nil {
+		return 0, err
+	}
+
+	return tx.RowsAffected, nil
+}
+
+func PurgeJob(DB *gorm.DB, now time.Time) error {
+	var err error
+	var purged int64
+	nokocore.KeepVoid(err, purged)
+
+	idleTimeout := globals.GetJwtConfig().GetIdleTimeout()
+	if purged, err = Purge(DB, now, idleTimeout, GetPurgeLimit()); err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		console.Info(fmt.Sprintf("%d stale session(s) has been purged.", purged))
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package storages
+
+type LocalConfig struct {
+	Dir     string `mapstructure:"dir" json:"dir" yaml:"dir"`
+	BaseURL string `mapstructure:"base_url" json:"baseUrl" yaml:"base_url"`
+}
+
+type Config struct {
+	Driver  string      `mapstructure:"driver" json:"driver" yaml:"driver"`
+	MaxSize int64       `mapstructure:"max_size" json:"maxSize" yaml:"max_size"`
+	Local   LocalConfig `mapstructure:"local" json:"local" yaml:"local"`
+}
+
+func (Config) GetNameType() string {
+	return "Storage"
+}
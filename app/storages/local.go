@@ -0,0 +1,115 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"nokowebapi/nokocore"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	Register(DriverLocal, NewLocalStorage)
+}
+
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStorage(config *Config) (StorageImpl, error) {
+	dir := config.Local.Dir
+	if dir == "" {
+		dir = "./uploads"
+	}
+
+	baseURL := config.Local.BaseURL
+	if baseURL == "" {
+		baseURL = "/api/v1/files"
+	}
+
+	return &LocalStorage{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// pathFromKey method, keys are slash separated and never escape the storage directory.
+func (l *LocalStorage) pathFromKey(key string) (string, error) {
+	key = path.Clean("/" + key)
+	if key == "/" {
+		return "", errors.New("invalid storage key")
+	}
+
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+func (l *LocalStorage) Put(key string, reader io.Reader) error {
+	var err error
+	var filePath string
+	var file *os.File
+	nokocore.KeepVoid(err, filePath, file)
+
+	if filePath, err = l.pathFromKey(key); err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create storage directory, %w", err)
+	}
+
+	if file, err = os.Create(filePath); err != nil {
+		return fmt.Errorf("failed to create storage file, %w", err)
+	}
+
+	if _, err = io.Copy(file, reader); err != nil {
+		nokocore.KeepVoid(file.Close(), os.Remove(filePath))
+		return fmt.Errorf("failed to write storage file, %w", err)
+	}
+
+	return file.Close()
+}
+
+func (l *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	var err error
+	var filePath string
+	var file *os.File
+	nokocore.KeepVoid(err, filePath, file)
+
+	if filePath, err = l.pathFromKey(key); err != nil {
+		return nil, err
+	}
+
+	if file, err = os.Open(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	var err error
+	var filePath string
+	nokocore.KeepVoid(err, filePath)
+
+	if filePath, err = l.pathFromKey(key); err != nil {
+		return err
+	}
+
+	if err = os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LocalStorage) URL(key string) string {
+	return fmt.Sprintf("%s/%s", l.BaseURL, strings.TrimPrefix(path.Clean("/"+key), "/"))
+}
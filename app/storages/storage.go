@@ -0,0 +1,65 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	"sync"
+)
+
+const (
+	DriverLocal    = "local"
+	DefaultMaxSize = 10 << 20
+)
+
+var ErrNotFound = errors.New("storage object not found")
+
+type StorageImpl interface {
+	Put(key string, reader io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+type Factory func(config *Config) (StorageImpl, error)
+
+var factories = nokocore.NewMapLock[Factory]()
+
+// Register method, storage backends register themselves by driver name.
+func Register(driver string, factory Factory) {
+	factories.Set(driver, factory)
+}
+
+func NewStorage(config *Config) (StorageImpl, error) {
+	driver := config.Driver
+	if driver == "" {
+		driver = DriverLocal
+	}
+
+	if !factories.HasKey(driver) {
+		return nil, fmt.Errorf("storage driver '%s' is not registered", driver)
+	}
+
+	return factories.Get(driver)(config)
+}
+
+var storage StorageImpl
+var storageOnce sync.Once
+
+// GetStorage method, default storage from 'storage' config, panics if the storage is misconfigured.
+func GetStorage() StorageImpl {
+	storageOnce.Do(func() {
+		storage = nokocore.Unwrap(NewStorage(GetConfig()))
+	})
+	return storage
+}
+
+func GetConfig() *Config {
+	config := globals.GetConfigGlobals[Config]()
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultMaxSize
+	}
+	return config
+}
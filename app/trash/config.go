@@ -0,0 +1,9 @@
+package trash
+
+type Config struct {
+	Retention string `mapstructure:"retention" json:"retention" yaml:"retention"`
+}
+
+func (Config) GetNameType() string {
+	return "Trash"
+}
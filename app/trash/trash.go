@@ -0,0 +1,327 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"nokowebapi/apis/models"
+	"nokowebapi/apis/repositories"
+	"nokowebapi/console"
+	"nokowebapi/globals"
+	"nokowebapi/nokocore"
+	models2 "pharma-cash-go/app/models"
+	repositories2 "pharma-cash-go/app/repositories"
+	"pharma-cash-go/app/storages"
+	"time"
+)
+
+const DefaultRetention = 30 * 24 * time.Hour
+
+var ErrNotDeleted = errors.New("record is not deleted")
+
+// historic rows, cascading deletes would remove them with the purged rows
+var productReferences = []any{
+	&models2.Cart{},
+	&models2.CartVerificationOpname{},
+	&models2.VerificationOpname{},
+	&models2.GoodsReceipt{},
+	&models2.PriceChangeItem{},
+	&models2.ControlledRegister{},
+	&models2.CostEntry{},
+	&models2.WriteOffItem{},
+	&models2.StockTransferItem{},
+	&models2.StockMovement{},
+	&models2.StockOpnameItem{},
+	&models2.CountEntry{},
+}
+
+var userReferences = []any{
+	&models2.Cart{},
+	&models2.Transaction{},
+	&models2.StockOpname{},
+	&models2.CartVerificationOpname{},
+	&models2.VerificationOpname{},
+	&models2.GoodsReceipt{},
+	&models2.PriceChange{},
+	&models2.ControlledRegister{},
+	&models2.WriteOff{},
+	&models2.StockTransfer{},
+	&models2.StockMovement{},
+	&models2.StockOpnameStatusChange{},
+	&models2.CountEntry{},
+	&models2.Employee{},
+}
+
+// owned rows without cascading deletes, removed before the purged rows
+var productOwned = []any{
+	&models2.Barcode{},
+	&models2.ProductAttachment{},
+	&models2.ProductUnit{},
+}
+
+var userOwned = []any{
+	&models.Session{},
+	&models.UserRoles{},
+}
+
+type PurgeResult struct {
+	Employees    int `mapstructure:"employees" json:"employees"`
+	Users        int `mapstructure:"users" json:"users"`
+	Products     int `mapstructure:"products" json:"products"`
+	KeptUsers    int `mapstructure:"kept_users" json:"keptUsers"`
+	KeptProducts int `mapstructure:"kept_products" json:"keptProducts"`
+}
+
+// GetRetention method, how long deleted rows are kept from 'trash' config.
+func GetRetention() time.Duration {
+	config := globals.GetConfigGlobals[Config]()
+	if retention, err := time.ParseDuration(config.Retention); err == nil && retention > 0 {
+		return retention
+	}
+	return DefaultRetention
+}
+
+type TrashServiceImpl interface {
+	IsReferenced(references []any, column string, ID uint) (bool, error)
+	Restore(model any, ID uint) error
+	RestoreUser(user *models.User) error
+	RestoreEmployee(employee *models2.Employee) error
+	Purge(before time.Time) (*PurgeResult, error)
+}
+
+type TrashService struct {
+	DB *gorm.DB
+}
+
+func NewTrashService(DB *gorm.DB) TrashServiceImpl {
+	return &TrashService{
+		DB: DB,
+	}
+}
+
+// IsReferenced method, counts deleted rows too.
+func (t *TrashService) IsReferenced(references []any, column string, ID uint) (bool, error) {
+	var err error
+	nokocore.KeepVoid(err)
+
+	for i, reference := range references {
+		nokocore.KeepVoid(i)
+
+		var count int64
+		if err = t.DB.Unscoped().Model(reference).Where(fmt.Sprintf("%s = ?", column), ID).Count(&count).Error; err != nil {
+			return false, err
+		}
+
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Restore method, clears deleted at by columns, save hooks are never called,
+// so product categories and user roles are kept as it is.
+func (t *TrashService) Restore(model any, ID uint) error {
+	var err error
+	nokocore.KeepVoid(err)
+
+	tx := t.DB.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", ID).UpdateColumns(map[string]any{
+		"deleted_at": nil,
+		"updated_at": nokocore.GetTimeUtcNow(),
+	})
+
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	if tx.RowsAffected == 0 {
+		return ErrNotDeleted
+	}
+
+	return nil
+}
+
+// RestoreUser method, deleted employee of the user is restored too.
+func (t *TrashService) RestoreUser(user *models.User) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		trashService := NewTrashService(tx)
+		if err = trashService.Restore(&models.User{}, user.ID); err != nil {
+			return err
+		}
+
+		stmt := tx.Unscoped().Model(&models2.Employee{}).Where("user_id = ? AND deleted_at IS NOT NULL", user.ID)
+		if err = stmt.UpdateColumns(map[string]any{"deleted_at": nil, "updated_at": nokocore.GetTimeUtcNow()}).Error; err != nil {
+			return err
+		}
+
+		user.DeletedAt = gorm.DeletedAt{}
+		return nil
+	})
+}
+
+// RestoreEmployee method, deleted user of the employee is restored too.
+func (t *TrashService) RestoreEmployee(employee *models2.Employee) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		trashService := NewTrashService(tx)
+		if err = trashService.Restore(&models2.Employee{}, employee.ID); err != nil {
+			return err
+		}
+
+		if err = trashService.Restore(&models.User{}, employee.UserID); err != nil && !errors.Is(err, ErrNotDeleted) {
+			return err
+		}
+
+		employee.DeletedAt = gorm.DeletedAt{}
+		employee.User.DeletedAt = gorm.DeletedAt{}
+		return nil
+	})
+}
+
+// Purge method, permanently deletes rows deleted before the given time,
+// users and products still referenced by historic rows are kept.
+func (t *TrashService) Purge(before time.Time) (*PurgeResult, error) {
+	var err error
+	var referenced bool
+	var employees []models2.Employee
+	var users []models.User
+	var products []models2.Product
+	var attachments []models2.ProductAttachment
+	nokocore.KeepVoid(err, referenced, employees, users, products, attachments)
+
+	employeeRepository := repositories2.NewEmployeeRepository(t.DB)
+	userRepository := repositories.NewUserRepository(t.DB)
+	productRepository := repositories2.NewProductRepository(t.DB)
+	attachmentRepository := repositories2.NewProductAttachmentRepository(t.DB)
+
+	result := new(PurgeResult)
+	query := "deleted_at IS NOT NULL AND deleted_at < ?"
+	before = before.UTC()
+
+	// employees first, users are kept by their employees
+	if employees, err = employeeRepository.Many(0, -1, query, before); err != nil {
+		return nil, err
+	}
+
+	for i, employee := range employees {
+		nokocore.KeepVoid(i)
+
+		if err = employeeRepository.Delete(&employee, "id = ?", employee.ID); err != nil {
+			return nil, err
+		}
+
+		result.Employees += 1
+	}
+
+	if users, err = userRepository.Many(0, -1, query, before); err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		nokocore.KeepVoid(i)
+
+		if referenced, err = t.IsReferenced(userReferences, "user_id", user.ID); err != nil {
+			return nil, err
+		}
+
+		if referenced {
+			result.KeptUsers += 1
+			continue
+		}
+
+		if err = t.purge(&user, userOwned, "user_id", user.ID); err != nil {
+			return nil, err
+		}
+
+		result.Users += 1
+	}
+
+	if products, err = productRepository.Many(0, -1, query, before); err != nil {
+		return nil, err
+	}
+
+	for i, product := range products {
+		nokocore.KeepVoid(i)
+
+		if referenced, err = t.IsReferenced(productReferences, "product_id", product.ID); err != nil {
+			return nil, err
+		}
+
+		if referenced {
+			result.KeptProducts += 1
+			continue
+		}
+
+		if attachments, err = attachmentRepository.Many(0, -1, "product_id = ?", product.ID); err != nil {
+			return nil, err
+		}
+
+		if err = t.purge(&product, productOwned, "product_id", product.ID); err != nil {
+			return nil, err
+		}
+
+		// files are removed after the records, orphan files are harmless
+		if len(attachments) > 0 {
+			storage := storages.GetStorage()
+			for j, attachment := range attachments {
+				nokocore.KeepVoid(j)
+
+				for k, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+					nokocore.KeepVoid(k)
+
+					if key == "" {
+						continue
+					}
+
+					if err = storage.Delete(key); err != nil {
+						console.Warn(fmt.Sprintf("unable to delete attachment file '%s', %s", key, err.Error()))
+					}
+				}
+			}
+		}
+
+		result.Products += 1
+	}
+
+	return result, nil
+}
+
+func (t *TrashService) purge(model any, owned []any, column string, ID uint) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		nokocore.KeepVoid(err)
+
+		for i, reference := range owned {
+			nokocore.KeepVoid(i)
+
+			if err = tx.Unscoped().Where(fmt.Sprintf("%s = ?", column), ID).Delete(reference).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(model).Error
+	})
+}
+
+// PurgeJob method, scheduler job purging rows deleted before the retention period.
+func PurgeJob(DB *gorm.DB, now time.Time) error {
+	var err error
+	var result *PurgeResult
+	nokocore.KeepVoid(err, result)
+
+	if result, err = NewTrashService(DB).Purge(now.Add(-GetRetention())); err != nil {
+		return err
+	}
+
+	if purged := result.Employees + result.Users + result.Products; purged > 0 {
+		console.Info(fmt.Sprintf("%d deleted row(s) has been purged.", purged))
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const ThumbnailSize = 256
+
+// MakeThumbnail method, scales down the image to fit in size x size box
+// using box sampling, the result is always encoded as jpeg.
+func MakeThumbnail(reader io.Reader, size int) ([]byte, error) {
+	var err error
+	var src image.Image
+
+	if src, _, err = image.Decode(reader); err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 {
+		return nil, image.ErrFormat
+	}
+
+	// keep aspect ratio, never upscale
+	dstWidth, dstHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			dstWidth = size
+			dstHeight = max(1, height*size/width)
+		} else {
+			dstHeight = size
+			dstWidth = max(1, width*size/height)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	// jpeg has no alpha channel, flatten into white background
+	flat := image.NewRGBA(dst.Bounds())
+	for i := 0; i < len(flat.Pix); i += 4 {
+		alpha := uint32(dst.Pix[i+3])
+		for j := 0; j < 3; j++ {
+			flat.Pix[i+j] = uint8((uint32(dst.Pix[i+j])*255 + 255*(255-alpha)) / 255)
+		}
+		flat.Pix[i+3] = 255
+	}
+
+	buff := new(bytes.Buffer)
+	if err = jpeg.Encode(buff, flat, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
@@ -55,6 +55,7 @@ func Migrations(DB *gorm.DB, tables []any) error {
 		&models.Role{},
 		&models.UserRoles{},
 		&models.Session{},
+		&models.Permission{},
 	}
 
 	if err = DB.AutoMigrate(defaults...); err != nil {
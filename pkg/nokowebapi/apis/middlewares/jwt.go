@@ -1,7 +1,6 @@
 package middlewares
 
 import (
-	"database/sql"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -12,6 +11,7 @@ import (
 	"nokowebapi/console"
 	"nokowebapi/globals"
 	"nokowebapi/nokocore"
+	"time"
 )
 
 func JWTAuth(DB *gorm.DB) echo.MiddlewareFunc {
@@ -19,6 +19,7 @@ func JWTAuth(DB *gorm.DB) echo.MiddlewareFunc {
 
 	jwtConfig := globals.GetJwtConfig()
 	signingMethod := jwtConfig.GetSigningMethod()
+	idleTimeout := jwtConfig.GetIdleTimeout()
 
 	sessionRepository := repositories.NewSessionRepository(DB)
 
@@ -47,14 +48,13 @@ func JWTAuth(DB *gorm.DB) echo.MiddlewareFunc {
 
 			sessionID := jwtClaimsDataAccess.GetSessionID()
 			tokenID := jwtClaimsDataAccess.GetIdentity()
-			refreshTokenID := jwtClaimsDataAccess.GetIdentity()
 
 			// initial session
 			session = new(models.Session)
 
 			// get current session
 			preloads := []string{"User.Roles"}
-			if session, err = sessionRepository.SafePreFirst(preloads, "uuid = ? AND (token_id = ? OR refresh_token_id = ?)", sessionID, tokenID, refreshTokenID); err != nil {
+			if session, err = sessionRepository.SafePreFirst(preloads, "uuid = ? AND token_id = ?", sessionID, tokenID); err != nil {
 				console.Error(fmt.Sprintf("panic: %s", err.Error()))
 
 				return extras.NewMessageBodyUnauthorized(ctx, "Invalid JWT token.", nil)
@@ -62,13 +62,19 @@ func JWTAuth(DB *gorm.DB) echo.MiddlewareFunc {
 
 			if session != nil {
 
-				// update refresh token id
-				if session.RefreshTokenID.String == tokenID {
-					session.TokenID = tokenID
-					session.RefreshTokenID = sql.NullString{}
-					if err = sessionRepository.SafeUpdate(session, "uuid = ?", sessionID); err != nil {
+				timeUtcNow := nokocore.GetTimeUtcNow()
+				if session.IsExpired(timeUtcNow, idleTimeout) {
+					return extras.NewMessageBodyUnauthorized(ctx, "Session expired.", nil)
+				}
+
+				// slide idle timeout, written once a minute at most
+				if timeUtcNow.Sub(session.UpdatedAt) >= time.Minute {
+					tx := DB.Model(&models.Session{}).Where("id = ?", session.ID).UpdateColumn("updated_at", timeUtcNow)
+					if err = tx.Error; err != nil {
 						console.Error(fmt.Sprintf("panic: %s", err.Error()))
 					}
+
+					session.UpdatedAt = timeUtcNow
 				}
 
 				// get user data
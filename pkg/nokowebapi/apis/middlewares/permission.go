@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"nokowebapi/apis/extras"
+	"nokowebapi/apis/utils"
+	"nokowebapi/nokocore"
+)
+
+// RequirePermission method, must be placed after JWTAuth middleware.
+func RequirePermission(names ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			var err error
+			nokocore.KeepVoid(err)
+
+			jwtAuthInfo := extras.GetJwtAuthInfoFromEchoContext(ctx)
+			if !utils.HasPermission(jwtAuthInfo, names...) {
+				return extras.NewMessageBodyUnauthorized(ctx, "Unauthorized access attempt.", nil)
+			}
+
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "nokowebapi/nokocore"
+
+// Permission, roles assigned to the named permission, replaces the roles of the
+// policy from defaults and config, no roles will deny everyone.
+type Permission struct {
+	BaseModel
+	PermissionName string `db:"permission_name" gorm:"unique;index;not null;" mapstructure:"permission_name" json:"permissionName"`
+	Roles          string `db:"roles" gorm:"not null;" mapstructure:"roles" json:"roles"` // ex. Admin;Supervisor
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+func (p *Permission) GetRoles() []string {
+	return nokocore.RolesUnpack(p.Roles)
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"nokowebapi/apis/models"
+)
+
+type PermissionRepositoryImpl interface {
+	BaseRepositoryImpl[models.Permission]
+}
+
+type PermissionRepository struct {
+	BaseRepositoryImpl[models.Permission]
+}
+
+func NewPermissionRepository(DB *gorm.DB) PermissionRepositoryImpl {
+	return &PermissionRepository{
+		NewBaseRepository[models.Permission](DB),
+	}
+}
@@ -28,18 +28,21 @@ func ToSessionModel(session *SessionBody) *models.Session {
 	return nil
 }
 
+type RefreshTokenBody struct {
+	RefreshToken string `mapstructure:"refresh_token" json:"refreshToken" form:"refresh_token" validate:"ascii"`
+}
+
 type SessionResult struct {
-	UUID           uuid.UUID `mapstructure:"uuid" json:"uuid"`
-	UserID         uuid.UUID `mapstructure:"user_id" json:"userId"`
-	TokenId        string    `mapstructure:"token_id" json:"tokenId"`
-	RefreshTokenId string    `mapstructure:"refresh_token_id" json:"refreshTokenId,omitempty"`
-	IPAddress      string    `mapstructure:"ip_addr" json:"ipAddr"`
-	UserAgent      string    `mapstructure:"user_agent" json:"userAgent"`
-	Expires        string    `mapstructure:"expires" json:"expires"`
-	CreatedAt      string    `mapstructure:"created_at" json:"createdAt"`
-	UpdatedAt      string    `mapstructure:"updated_at" json:"updatedAt"`
-	DeletedAt      string    `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
-	Used           bool      `mapstructure:"used" json:"used"`
+	UUID      uuid.UUID `mapstructure:"uuid" json:"uuid"`
+	UserID    uuid.UUID `mapstructure:"user_id" json:"userId"`
+	TokenId   string    `mapstructure:"token_id" json:"tokenId"`
+	IPAddress string    `mapstructure:"ip_addr" json:"ipAddr"`
+	UserAgent string    `mapstructure:"user_agent" json:"userAgent"`
+	Expires   string    `mapstructure:"expires" json:"expires"`
+	CreatedAt string    `mapstructure:"created_at" json:"createdAt"`
+	UpdatedAt string    `mapstructure:"updated_at" json:"updatedAt"`
+	DeletedAt string    `mapstructure:"deleted_at" json:"deletedAt,omitempty"`
+	Used      bool      `mapstructure:"used" json:"used"`
 }
 
 func ToSessionResult(session *models.Session) SessionResult {
@@ -52,16 +55,15 @@ func ToSessionResult(session *models.Session) SessionResult {
 			deletedAt = nokocore.ToTimeUtcStringISO8601(session.DeletedAt.Time)
 		}
 		return SessionResult{
-			UUID:           session.UUID,
-			UserID:         session.User.UUID,
-			TokenId:        session.TokenID,
-			RefreshTokenId: session.RefreshTokenID.String,
-			IPAddress:      session.IPAddress,
-			UserAgent:      session.UserAgent,
-			Expires:        expires,
-			CreatedAt:      createdAt,
-			UpdatedAt:      updatedAt,
-			DeletedAt:      deletedAt,
+			UUID:      session.UUID,
+			UserID:    session.User.UUID,
+			TokenId:   session.TokenID,
+			IPAddress: session.IPAddress,
+			UserAgent: session.UserAgent,
+			Expires:   expires,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+			DeletedAt: deletedAt,
 		}
 	}
 
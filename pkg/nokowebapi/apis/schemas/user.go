@@ -31,6 +31,8 @@ func normRoleDW(role string) models.Role {
 		roleModel.RoleName = nokocore.ToRoleString(nokocore.RoleAssistant)
 	case "Supervisor":
 		roleModel.RoleName = nokocore.ToRoleString(nokocore.RoleSupervisor)
+	case "Pharmacist":
+		roleModel.RoleName = nokocore.ToRoleString(nokocore.RolePharmacist)
 	default:
 		roleModel.RoleName = nokocore.ToRoleString(nokocore.RoleUser)
 	}
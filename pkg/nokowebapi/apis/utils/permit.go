@@ -4,6 +4,7 @@ import (
 	"errors"
 	"gorm.io/gorm"
 	"nokowebapi/apis/models"
+	"nokowebapi/globals"
 	"nokowebapi/nokocore"
 )
 
@@ -82,3 +83,31 @@ func SetUserRoles[T UserOrJwtAuthInfoImpl](DB *gorm.DB, userOrJwtAuthInfo T, rol
 
 	return errors.New("user not found")
 }
+
+// HasPermission method, all the named permissions must be granted by the policy.
+func HasPermission[T UserOrJwtAuthInfoImpl](userOrJwtAuthInfo T, names ...string) bool {
+	return globals.GetPolicy().Allows(GetUserRoles(userOrJwtAuthInfo), names...)
+}
+
+func GetUserPermissions[T UserOrJwtAuthInfoImpl](userOrJwtAuthInfo T) []string {
+	return globals.GetPolicy().GetPermissions(GetUserRoles(userOrJwtAuthInfo))
+}
+
+// LoadPermissions method, roles assigned from database replace the roles of the policy.
+func LoadPermissions(DB *gorm.DB) error {
+	var err error
+	var permissions []models.Permission
+	nokocore.KeepVoid(err, permissions)
+
+	if err = DB.Where("deleted_at IS NULL").Find(&permissions).Error; err != nil {
+		return err
+	}
+
+	policy := globals.GetPolicy()
+	for i, permission := range permissions {
+		nokocore.KeepVoid(i)
+		policy.Assign(permission.PermissionName, permission.GetRoles())
+	}
+
+	return nil
+}
@@ -28,9 +28,13 @@ var defaultConfig = &nokocore.MapAny{
 			"your-audience-2",
 			"your-audience-3",
 		},
-		"issuer":     "your-issuer",
-		"secret_key": "your-super-secret-key-keep-it-mind-dont-tell-anyone",
-		"expires_in": "1h",
+		"issuer":             "your-issuer",
+		"secret_key":         "your-super-secret-key-keep-it-mind-dont-tell-anyone",
+		"expires_in":         "1h",
+		"refresh_expires_in": "720h",
+		"idle_timeout":       "2h",
+		"purge_interval":     "1h",
+		"purge_limit":        1000,
 	},
 	"logger": &nokocore.MapAny{
 		"level":               "debug",
@@ -38,7 +42,8 @@ var defaultConfig = &nokocore.MapAny{
 		"stack_trace_enabled": true,
 		"colorable":           true,
 	},
-	"tasks": &nokocore.ArrayAny{},
+	"tasks":       &nokocore.ArrayAny{},
+	"permissions": &nokocore.ArrayAny{},
 }
 
 func getNameKeyType[T any]() string {
@@ -59,6 +64,7 @@ type ConfigImpl interface {
 	GetJwtConfig() *nokocore.JwtConfig
 	GetLoggerConfig() *nokocore.LoggerConfig
 	GetTasks() *task.Tasks
+	GetPolicy() nokocore.PolicyImpl
 	Keys() []string
 	Values() []any
 	Get(key string) any
@@ -70,6 +76,7 @@ type Config struct {
 	jwt    *nokocore.JwtConfig
 	logger *nokocore.LoggerConfig
 	tasks  *task.Tasks
+	policy nokocore.PolicyImpl
 	locker nokocore.LockerImpl
 }
 
@@ -78,6 +85,7 @@ func NewConfig() ConfigImpl {
 		jwt:    nil,
 		logger: nil,
 		tasks:  nil,
+		policy: nil,
 		locker: nokocore.NewLocker(),
 	}
 }
@@ -118,6 +126,17 @@ func (c *Config) GetTasks() *task.Tasks {
 	return c.tasks
 }
 
+func (c *Config) GetPolicy() nokocore.PolicyImpl {
+	c.locker.Lock(func() {
+		if c.policy != nil {
+			return
+		}
+		c.policy = nokocore.NewPolicy()
+		c.policy.Apply(*GetConfigGlobals[nokocore.Permissions]())
+	})
+	return c.policy
+}
+
 func (c *Config) Keys() []string {
 	return defaultConfig.Keys()
 }
@@ -160,6 +179,10 @@ func GetTaskConfig(name string) task.ConfigImpl {
 	return globals.GetTasks().GetTaskConfig(name)
 }
 
+func GetPolicy() nokocore.PolicyImpl {
+	return globals.GetPolicy()
+}
+
 func Keys() []string {
 	return globals.Keys()
 }
@@ -21,13 +21,21 @@ var ErrJwtSessionIdNotFound = errors.New("jwt session id not found")
 var ErrJwtUserNotFound = errors.New("jwt user not found")
 var ErrJwtEmailNotFound = errors.New("jwt email not found")
 var ErrJwtSecretKeyNotFound = errors.New("jwt secret key not found")
+var ErrJwtDurationInvalid = errors.New("invalid jwt duration")
+
+const DefaultJwtRefreshExpiresIn = 720 * time.Hour
+const DefaultJwtIdleTimeout = 2 * time.Hour
 
 type JwtConfig struct {
-	Algorithm string   `mapstructure:"algorithm" json:"algorithm" yaml:"algorithm"`
-	SecretKey string   `mapstructure:"secret_key" json:"secretKey" yaml:"secret_key"`
-	Audience  []string `mapstructure:"audience" json:"audience" yaml:"audience"`
-	Issuer    string   `mapstructure:"issuer" json:"issuer" yaml:"issuer"`
-	ExpiresIn string   `mapstructure:"expires_in" json:"expiresIn" yaml:"expires_in"`
+	Algorithm        string   `mapstructure:"algorithm" json:"algorithm" yaml:"algorithm"`
+	SecretKey        string   `mapstructure:"secret_key" json:"secretKey" yaml:"secret_key"`
+	Audience         []string `mapstructure:"audience" json:"audience" yaml:"audience"`
+	Issuer           string   `mapstructure:"issuer" json:"issuer" yaml:"issuer"`
+	ExpiresIn        string   `mapstructure:"expires_in" json:"expiresIn" yaml:"expires_in"`
+	RefreshExpiresIn string   `mapstructure:"refresh_expires_in" json:"refreshExpiresIn" yaml:"refresh_expires_in"`
+	IdleTimeout      string   `mapstructure:"idle_timeout" json:"idleTimeout" yaml:"idle_timeout"`
+	PurgeInterval    string   `mapstructure:"purge_interval" json:"purgeInterval" yaml:"purge_interval"`
+	PurgeLimit       int      `mapstructure:"purge_limit" json:"purgeLimit" yaml:"purge_limit"`
 }
 
 func NewJwtConfig() *JwtConfig {
@@ -73,6 +81,45 @@ func (j *JwtConfig) GetExpiresIn() time.Duration {
 	return Unwrap(time.ParseDuration(j.ExpiresIn))
 }
 
+// GetRefreshExpiresIn method, missing or malformed values fall back to the default.
+func (j *JwtConfig) GetRefreshExpiresIn() time.Duration {
+	if refreshExpiresIn, err := time.ParseDuration(j.RefreshExpiresIn); err == nil && refreshExpiresIn > 0 {
+		return refreshExpiresIn
+	}
+	return DefaultJwtRefreshExpiresIn
+}
+
+// GetIdleTimeout method, zero duration disables the idle timeout, missing or
+// malformed values fall back to the default.
+func (j *JwtConfig) GetIdleTimeout() time.Duration {
+	if idleTimeout, err := time.ParseDuration(j.IdleTimeout); err == nil && idleTimeout >= 0 {
+		return idleTimeout
+	}
+	return DefaultJwtIdleTimeout
+}
+
+// Validate method, reports session durations falling back to their defaults.
+func (j *JwtConfig) Validate() error {
+	var temp []string
+	if refreshExpiresIn, err := time.ParseDuration(j.RefreshExpiresIn); err != nil || refreshExpiresIn <= 0 {
+		temp = append(temp, fmt.Sprintf("refresh_expires_in '%s'", j.RefreshExpiresIn))
+	}
+
+	if idleTimeout, err := time.ParseDuration(j.IdleTimeout); err != nil || idleTimeout < 0 {
+		temp = append(temp, fmt.Sprintf("idle_timeout '%s'", j.IdleTimeout))
+	}
+
+	if purgeInterval, err := time.ParseDuration(j.PurgeInterval); err != nil || purgeInterval <= 0 {
+		temp = append(temp, fmt.Sprintf("purge_interval '%s'", j.PurgeInterval))
+	}
+
+	if len(temp) > 0 {
+		return fmt.Errorf("%w, %s", ErrJwtDurationInvalid, strings.Join(temp, ", "))
+	}
+
+	return nil
+}
+
 type JwtClaimNamed string
 
 const (
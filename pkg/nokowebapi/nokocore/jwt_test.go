@@ -0,0 +1,43 @@
+package nokocore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJwtConfigDurations(t *testing.T) {
+	for i, test := range []struct {
+		refreshExpiresIn string
+		idleTimeout      string
+		wantRefresh      time.Duration
+		wantIdle         time.Duration
+		wantValid        bool
+	}{
+		{"24h", "30m", 24 * time.Hour, 30 * time.Minute, true},
+		{"24h", "0", 24 * time.Hour, 0, true},
+		{"", "", DefaultJwtRefreshExpiresIn, DefaultJwtIdleTimeout, false},
+		{"one day", "2 hours", DefaultJwtRefreshExpiresIn, DefaultJwtIdleTimeout, false},
+		{"-1h", "-1h", DefaultJwtRefreshExpiresIn, DefaultJwtIdleTimeout, false},
+		{"0", "1h", DefaultJwtRefreshExpiresIn, time.Hour, false},
+	} {
+		KeepVoid(i)
+
+		config := &JwtConfig{
+			RefreshExpiresIn: test.refreshExpiresIn,
+			IdleTimeout:      test.idleTimeout,
+			PurgeInterval:    "1h",
+		}
+
+		if got := config.GetRefreshExpiresIn(); got != test.wantRefresh {
+			t.Errorf("GetRefreshExpiresIn(%q) = %s, want %s", test.refreshExpiresIn, got, test.wantRefresh)
+		}
+
+		if got := config.GetIdleTimeout(); got != test.wantIdle {
+			t.Errorf("GetIdleTimeout(%q) = %s, want %s", test.idleTimeout, got, test.wantIdle)
+		}
+
+		if err := config.Validate(); (err == nil) != test.wantValid {
+			t.Errorf("Validate(%q, %q) = %v, want valid %t", test.refreshExpiresIn, test.idleTimeout, err, test.wantValid)
+		}
+	}
+}
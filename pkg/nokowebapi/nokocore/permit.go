@@ -12,6 +12,7 @@ const (
 	RoleOfficer    RoleTyped = "Officer"
 	RoleAssistant  RoleTyped = "Assistant"
 	RoleSupervisor RoleTyped = "Supervisor"
+	RolePharmacist RoleTyped = "Pharmacist"
 	RoleDeveloper  RoleTyped = "Developer"
 )
 
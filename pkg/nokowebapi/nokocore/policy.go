@@ -0,0 +1,209 @@
+package nokocore
+
+import (
+	"slices"
+	"strings"
+)
+
+type PermissionConfigImpl interface {
+	GetName() string
+	GetRoles() []string
+}
+
+// PermissionConfig struct, roles granted to the named permission ex. product.write
+type PermissionConfig struct {
+	Name  string   `mapstructure:"name" json:"name" yaml:"name"`
+	Roles []string `mapstructure:"roles" json:"roles" yaml:"roles"`
+}
+
+func (p *PermissionConfig) GetName() string {
+	return p.Name
+}
+
+func (p *PermissionConfig) GetRoles() []string {
+	return p.Roles
+}
+
+// Permissions struct, keep it mind, parsing by viper config file
+type Permissions []PermissionConfig
+
+func NewPermissions() Permissions {
+	var temp Permissions
+	return temp
+}
+
+func (Permissions) GetNameType() string {
+	return "Permissions"
+}
+
+type PolicyImpl interface {
+	Grant(name string, roles ...RoleTyped)
+	Apply(permissions Permissions)
+	Assign(name string, roles []string)
+	Unassign(name string)
+	IsGranted(name string) bool
+	IsAssigned(name string) bool
+	GetNames() []string
+	GetRoles(name string) []string
+	Allows(roles []string, names ...string) bool
+	GetPermissions(roles []string) []string
+}
+
+// Policy struct, granted roles are defaults, applied roles from config replace them,
+// assigned roles from database replace both.
+type Policy struct {
+	grants      map[string][]string
+	overrides   map[string][]string
+	assignments map[string][]string
+	locker      LockerImpl
+}
+
+func NewPolicy() PolicyImpl {
+	return &Policy{
+		grants:      make(map[string][]string),
+		overrides:   make(map[string][]string),
+		assignments: make(map[string][]string),
+		locker:      NewLocker(),
+	}
+}
+
+func (p *Policy) Grant(name string, roles ...RoleTyped) {
+	p.locker.Lock(func() {
+		name = strings.ToLower(strings.TrimSpace(name))
+		p.grants[name] = RolesAppend(p.grants[name], roles...)
+	})
+}
+
+func (p *Policy) Apply(permissions Permissions) {
+	p.locker.Lock(func() {
+		for i, permission := range permissions {
+			KeepVoid(i)
+
+			name := strings.ToLower(strings.TrimSpace(permission.GetName()))
+			if name == "" {
+				continue
+			}
+
+			// empty roles will deny everyone, it's intended
+			p.overrides[name] = RolesAppend([]string{}, permission.GetRoles()...)
+		}
+	})
+}
+
+// Assign method, empty roles will deny everyone, same as applied roles.
+func (p *Policy) Assign(name string, roles []string) {
+	p.locker.Lock(func() {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return
+		}
+
+		p.assignments[name] = RolesAppend([]string{}, roles...)
+	})
+}
+
+func (p *Policy) Unassign(name string) {
+	p.locker.Lock(func() {
+		delete(p.assignments, strings.ToLower(strings.TrimSpace(name)))
+	})
+}
+
+func (p *Policy) IsGranted(name string) bool {
+	var ok bool
+	p.locker.Lock(func() {
+		_, ok = p.grants[strings.ToLower(strings.TrimSpace(name))]
+	})
+	return ok
+}
+
+func (p *Policy) IsAssigned(name string) bool {
+	var ok bool
+	p.locker.Lock(func() {
+		_, ok = p.assignments[strings.ToLower(strings.TrimSpace(name))]
+	})
+	return ok
+}
+
+func (p *Policy) getNames() []string {
+	names := make(map[string]bool)
+	for name := range p.grants {
+		names[name] = true
+	}
+	for name := range p.overrides {
+		names[name] = true
+	}
+	for name := range p.assignments {
+		names[name] = true
+	}
+
+	temp := make([]string, 0, len(names))
+	for name := range names {
+		temp = append(temp, name)
+	}
+
+	slices.Sort(temp)
+	return temp
+}
+
+func (p *Policy) GetNames() []string {
+	var names []string
+	p.locker.Lock(func() {
+		names = p.getNames()
+	})
+	return names
+}
+
+func (p *Policy) getRoles(name string) []string {
+	var ok bool
+	var roles []string
+	KeepVoid(ok, roles)
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if roles, ok = p.assignments[name]; ok {
+		return roles
+	}
+
+	if roles, ok = p.overrides[name]; ok {
+		return roles
+	}
+
+	return p.grants[name]
+}
+
+func (p *Policy) GetRoles(name string) []string {
+	var roles []string
+	p.locker.Lock(func() {
+		roles = p.getRoles(name)
+	})
+	return roles
+}
+
+// Allows method, all the named permissions must be granted to any of the roles.
+func (p *Policy) Allows(roles []string, names ...string) bool {
+	allowed := true
+	p.locker.Lock(func() {
+		for i, name := range names {
+			KeepVoid(i)
+
+			if !RolesContains(roles, p.getRoles(name)...) {
+				allowed = false
+				return
+			}
+		}
+	})
+	return allowed
+}
+
+func (p *Policy) GetPermissions(roles []string) []string {
+	temp := make([]string, 0)
+	p.locker.Lock(func() {
+		for i, name := range p.getNames() {
+			KeepVoid(i)
+
+			if RolesContains(roles, p.getRoles(name)...) {
+				temp = append(temp, name)
+			}
+		}
+	})
+	return temp
+}
@@ -0,0 +1,38 @@
+package nokocore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"strings"
+)
+
+var ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+
+// NewRefreshToken method, opaque token ex. <session_id>.<secret>, only the hash is kept.
+func NewRefreshToken(sessionID string) (string, string) {
+	buff := make([]byte, 32)
+	KeepVoid(Unwrap(rand.Read(buff)))
+
+	token := sessionID + "." + Base64EncodeURLSafe(buff)
+	return token, HashRefreshToken(token)
+}
+
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return HexEncodeToString(hash[:])
+}
+
+func RefreshTokenEquals(token string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashRefreshToken(token)), []byte(hash)) == 1
+}
+
+func GetSessionIDFromRefreshToken(token string) (string, error) {
+	sessionID, secret, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || sessionID == "" || secret == "" {
+		return "", ErrRefreshTokenInvalid
+	}
+
+	return sessionID, nil
+}
@@ -3,6 +3,7 @@ package pdf
 import (
 	"fmt"
 	"github.com/signintech/gopdf"
+	"io"
 	"nokowebapi/nokocore"
 	"path/filepath"
 	"strconv"
@@ -16,6 +17,9 @@ type DocPdfImpl interface {
 	Page1Print(formData *FormDataPdf, rows Page1TableRowsPdf) (err error)
 	Page2Print(formData *FormDataPdf, rows Page2TableRowsPdf) (err error)
 	Page3Print(formData *FormDataPdf, rows Page3TableRowsPdf) (err error)
+	TablePrint(table *TablePdf) (err error)
+	GetPageTotal() int
+	Write(writer io.Writer) (err error)
 	Save() (err error)
 }
 
@@ -29,14 +33,35 @@ type DocPdf struct {
 }
 
 func NewDocPdf(templateId int, config *Config) DocPdfImpl {
+	doc, err := OpenDocPdf(templateId, config)
+	if err != nil {
+		panic(err)
+	}
+
+	return doc
+}
+
+// OpenDocPdf method, pages are plain landscape a4 without template if template id is negative.
+func OpenDocPdf(templateId int, config *Config) (DocPdfImpl, error) {
+	var err error
+	nokocore.KeepVoid(err)
+
 	fontFamily := nokocore.ToPascalCase(config.FontFamily)
 	fontFileName := fmt.Sprintf("%s-%s.ttf", fontFamily, config.FontType)
 	fontFilePath := filepath.Join(config.Assets, "fonts", fontFamily, fontFileName)
 
-	template := config.Templates[templateId]
-	pageSize := GetTemplatePageSize(template)
-	if IsTemplateLayoutLandscape(template) {
-		pageSize.W, pageSize.H = pageSize.H, pageSize.W
+	var template *TemplateConfig
+	pageSize := gopdf.Rect{W: 842, H: 595}
+	if templateId >= 0 {
+		if templateId >= len(config.Templates) {
+			return nil, fmt.Errorf("template %d not found", templateId)
+		}
+
+		template = &config.Templates[templateId]
+		pageSize = GetTemplatePageSize(*template)
+		if IsTemplateLayoutLandscape(*template) {
+			pageSize.W, pageSize.H = pageSize.H, pageSize.W
+		}
 	}
 
 	pdf := &gopdf.GoPdf{}
@@ -49,12 +74,12 @@ func NewDocPdf(templateId int, config *Config) DocPdfImpl {
 		Protection:        gopdf.PDFProtectionConfig{},
 	})
 
-	if err := pdf.AddTTFFont(fontFamily, fontFilePath); err != nil {
-		panic(err)
+	if err = pdf.AddTTFFont(fontFamily, fontFilePath); err != nil {
+		return nil, err
 	}
 
-	if err := pdf.SetFont(fontFamily, "", config.FontSize); err != nil {
-		panic(err)
+	if err = pdf.SetFont(fontFamily, "", config.FontSize); err != nil {
+		return nil, err
 	}
 
 	tableStyle := gopdf.CellStyle{
@@ -84,8 +109,11 @@ func NewDocPdf(templateId int, config *Config) DocPdfImpl {
 		FontSize: config.FontSize,
 	}
 
-	templateFilePath := filepath.Join(config.Assets, template.PageFile)
-	templatePageId := pdf.ImportPage(templateFilePath, 1, "/MediaBox")
+	templatePageId := -1
+	if template != nil {
+		templateFilePath := filepath.Join(config.Assets, template.PageFile)
+		templatePageId = pdf.ImportPage(templateFilePath, 1, "/MediaBox")
+	}
 
 	return &DocPdf{
 		PDF:            pdf,
@@ -94,7 +122,7 @@ func NewDocPdf(templateId int, config *Config) DocPdfImpl {
 		TableStyle:     tableStyle,
 		PageSize:       pageSize,
 		Date:           time.Now(),
-	}
+	}, nil
 }
 
 func (d *DocPdf) SlicePage1Print(formData *FormDataPdf, start int, rows Page1TableRowsPdf) (err error) {
@@ -384,3 +412,15 @@ func (d *DocPdf) Save() (err error) {
 
 	return nil
 }
+
+func (d *DocPdf) GetPageTotal() int {
+	return d.PDF.GetNumberOfPages()
+}
+
+func (d *DocPdf) Write(writer io.Writer) (err error) {
+	if _, err = d.PDF.WriteTo(writer); err != nil {
+		return err
+	}
+
+	return nil
+}
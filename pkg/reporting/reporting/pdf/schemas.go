@@ -75,3 +75,25 @@ type Page3TableRowsPdf []Page3TableColsPdf
 func (Page3TableRowsPdf) GetNameType() string {
 	return "Page3TableRows"
 }
+
+type TableColPdf struct {
+	Header string  `mapstructure:"header" json:"header"`
+	Width  float64 `mapstructure:"width" json:"width"`
+	Align  string  `mapstructure:"align" json:"align"`
+}
+
+func (TableColPdf) GetNameType() string {
+	return "TableCol"
+}
+
+type TablePdf struct {
+	Title  string        `mapstructure:"title" json:"title"`
+	Lines  [][2]string   `mapstructure:"lines" json:"lines"`
+	Cols   []TableColPdf `mapstructure:"cols" json:"cols"`
+	Rows   [][]string    `mapstructure:"rows" json:"rows"`
+	Totals []string      `mapstructure:"totals" json:"totals"`
+}
+
+func (TablePdf) GetNameType() string {
+	return "Table"
+}
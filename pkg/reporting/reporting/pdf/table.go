@@ -0,0 +1,238 @@
+package pdf
+
+import (
+	"fmt"
+	"github.com/signintech/gopdf"
+	"nokowebapi/nokocore"
+)
+
+const (
+	tableMargin    = 35
+	tableTop       = 112 // below the header of templates
+	tableFontSize  = 8
+	tableRowHeight = 16
+	tablePadding   = 2
+)
+
+// FitTextPdf method, truncates the text to the width of the cell with the current font.
+func FitTextPdf(pdf *gopdf.GoPdf, text string, width float64) (string, error) {
+	var err error
+	var size float64
+	nokocore.KeepVoid(err, size)
+
+	if size, err = pdf.MeasureTextWidth(text); err != nil {
+		return "", err
+	}
+
+	if size <= width {
+		return text, nil
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if size, err = pdf.MeasureTextWidth(string(runes) + "..."); err != nil {
+			return "", err
+		}
+
+		if size <= width {
+			break
+		}
+	}
+
+	return string(runes) + "...", nil
+}
+
+func (d *DocPdf) tablePageStart() float64 {
+	if d.TemplatePageId < 0 {
+		return tableMargin
+	}
+
+	return tableTop
+}
+
+func (d *DocPdf) tableHeadPrint(table *TablePdf, Y float64) (float64, error) {
+	var err error
+	nokocore.KeepVoid(err)
+
+	X := float64(tableMargin)
+
+	d.PDF.SetXY(X, Y)
+	if err = d.PDF.SetFontSize(14); err != nil {
+		return 0, err
+	}
+
+	if err = d.PDF.Cell(nil, table.Title); err != nil {
+		return 0, err
+	}
+
+	Y += 22
+	if err = d.PDF.SetFontSize(9); err != nil {
+		return 0, err
+	}
+
+	for i, line := range table.Lines {
+		nokocore.KeepVoid(i)
+
+		d.PDF.SetXY(X, Y)
+		if err = d.PDF.Cell(nil, line[0]); err != nil {
+			return 0, err
+		}
+
+		d.PDF.SetXY(X+110, Y)
+		if err = d.PDF.Cell(nil, line[1]); err != nil {
+			return 0, err
+		}
+
+		Y += 12
+	}
+
+	return Y + 10, nil
+}
+
+// TablePrint method, prints the table over as many pages as needed, the title and
+// lines on the first page, headers on every page and the totals below the last row.
+func (d *DocPdf) TablePrint(table *TablePdf) (err error) {
+	W, H := d.PageSize.W, d.PageSize.H
+
+	// columns are scaled to the printable width
+	var total float64
+	for i, col := range table.Cols {
+		nokocore.KeepVoid(i)
+		total += col.Width
+	}
+
+	cols := make([]TableColPdf, len(table.Cols))
+	for i, col := range table.Cols {
+		cols[i] = col
+		cols[i].Width = col.Width / total * (W - tableMargin*2)
+	}
+
+	rows := table.Rows
+	if len(table.Totals) > 0 {
+		rows = append(rows[:len(rows):len(rows)], table.Totals)
+	}
+
+	if err = d.PDF.SetFontSize(tableFontSize); err != nil {
+		return err
+	}
+
+	// rows of the table are truncated before the pages are counted
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(cols))
+		for j, col := range cols {
+			if j >= len(row) {
+				continue
+			}
+
+			if cells[i][j], err = FitTextPdf(d.PDF, row[j], col.Width-tablePadding*2); err != nil {
+				return err
+			}
+		}
+	}
+
+	headHeight := float64(22 + len(table.Lines)*12 + 10)
+	bottom := H - tableMargin - tableRowHeight
+
+	var pages [][][]string
+	first := d.tablePageStart() + headHeight
+	for start := 0; start < len(cells) || len(pages) == 0; {
+		Y := d.tablePageStart()
+		if len(pages) == 0 {
+			Y = first
+		}
+
+		m := int((bottom-Y)/tableRowHeight) - 1
+		m = max(m, 1)
+
+		end := min(start+m, len(cells))
+		pages = append(pages, cells[start:end])
+		start = end
+	}
+
+	for i, page := range pages {
+		d.PDF.AddPage()
+		if d.TemplatePageId >= 0 {
+			d.PDF.UseImportedTemplate(d.TemplatePageId, 0, 0, W, H)
+		}
+
+		Y := d.tablePageStart()
+		if i == 0 {
+			if Y, err = d.tableHeadPrint(table, Y); err != nil {
+				return err
+			}
+		}
+
+		if err = d.PDF.SetFontSize(tableFontSize); err != nil {
+			return err
+		}
+
+		// totals are drawn apart from the table layout to be filled
+		var totals []string
+		if len(table.Totals) > 0 && i == len(pages)-1 && len(page) > 0 {
+			totals = page[len(page)-1]
+			page = page[:len(page)-1]
+		}
+
+		layout := d.PDF.NewTableLayout(tableMargin, Y, tableRowHeight, len(page))
+		for j, col := range cols {
+			nokocore.KeepVoid(j)
+			layout.AddColumn(col.Header, col.Width, col.Align)
+		}
+
+		for j, row := range page {
+			nokocore.KeepVoid(j)
+			layout.AddRow(row)
+		}
+
+		if err = layout.DrawTable(); err != nil {
+			return err
+		}
+
+		if totals != nil {
+			if err = d.tableTotalsPrint(cols, totals, Y+float64(len(page)+1)*tableRowHeight); err != nil {
+				return err
+			}
+		}
+
+		text := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		d.PDF.SetXY(tableMargin, H-tableMargin)
+		if err = d.PDF.CellWithOption(&gopdf.Rect{W: W - tableMargin*2, H: tableRowHeight}, text, gopdf.CellOption{Align: gopdf.Right | gopdf.Middle}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DocPdf) tableTotalsPrint(cols []TableColPdf, totals []string, Y float64) (err error) {
+	X := float64(tableMargin)
+
+	for i, col := range cols {
+		d.PDF.SetFillColor(240, 240, 240)
+		d.PDF.RectFromUpperLeftWithStyle(X, Y, col.Width, tableRowHeight, "FD")
+
+		align := gopdf.Left | gopdf.Middle
+		switch col.Align {
+		case "right":
+			align = gopdf.Right | gopdf.Middle
+		case "center":
+			align = gopdf.Center | gopdf.Middle
+		}
+
+		if totals[i] == "" {
+			X += col.Width
+			continue
+		}
+
+		d.PDF.SetXY(X+tablePadding, Y)
+		if err = d.PDF.CellWithOption(&gopdf.Rect{W: col.Width - tablePadding*2, H: tableRowHeight}, totals[i], gopdf.CellOption{Align: align}); err != nil {
+			return err
+		}
+
+		X += col.Width
+	}
+
+	return nil
+}
@@ -23,7 +23,7 @@ fi
 unzip "$ZipFile" -d "$AppDir"
 
 cd "$AppDir" || exit 1
-go build -o "$AppExe" .
+go build -tags sqlite_fts5 -o "$AppExe" .
 
 if [ -f "$DstExe" ]; then
   sudo rm -f "$DstExe"